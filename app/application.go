@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/go-playground/validator"
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	"github.com/spf13/viper"
+	_ "modernc.org/sqlite"
+
+	_articleMemoryRepo "go-postgres-clean-arch/article/repository/memory"
+	_articleMysqlRepo "go-postgres-clean-arch/article/repository/mysql"
+	_articleRepo "go-postgres-clean-arch/article/repository/postgresql"
+	_articleSqliteRepo "go-postgres-clean-arch/article/repository/sqlite"
+	"go-postgres-clean-arch/article/sitemap"
+	_articleUcase "go-postgres-clean-arch/article/usecase"
+	"go-postgres-clean-arch/config"
+	"go-postgres-clean-arch/domain"
+	_idempotencyMemoryRepo "go-postgres-clean-arch/idempotency/repository/memory"
+	_idempotencyMysqlRepo "go-postgres-clean-arch/idempotency/repository/mysql"
+	_idempotencyRepo "go-postgres-clean-arch/idempotency/repository/postgresql"
+	_idempotencySqliteRepo "go-postgres-clean-arch/idempotency/repository/sqlite"
+	_idempotencyUcase "go-postgres-clean-arch/idempotency/usecase"
+	"go-postgres-clean-arch/migration"
+	_tagMemoryRepo "go-postgres-clean-arch/tag/repository/memory"
+	_tagMysqlRepo "go-postgres-clean-arch/tag/repository/mysql"
+	_tagRepo "go-postgres-clean-arch/tag/repository/postgresql"
+	_tagSqliteRepo "go-postgres-clean-arch/tag/repository/sqlite"
+	_tagUcase "go-postgres-clean-arch/tag/usecase"
+)
+
+// application holds the usecases shared by the HTTP server and the admin commands
+type application struct {
+	articleUsecase     domain.ArticleUsecase
+	tagUsecase         domain.TagUseCase
+	idempotencyUsecase domain.IdempotencyUsecase
+	sitemap            *sitemap.Sitemap
+	close              func()
+}
+
+// repositories are the repositories of the store selected by the configuration
+type repositories struct {
+	article     domain.ArticleRepository
+	tag         domain.TagRepository
+	idempotency domain.IdempotencyRepository
+	close       func()
+}
+
+// loadConfig reads the configuration file into viper
+func loadConfig(path string) error {
+	viper.SetConfigFile(path)
+	if err := viper.ReadInConfig(); err != nil {
+		return fmt.Errorf("read config %s: %w", path, err)
+	}
+
+	if viper.GetBool(`debug`) {
+		log.Println("Service RUN on DEBUG mode")
+	}
+	return nil
+}
+
+// newApplication loads the configuration and builds the repositories and usecases it selects
+func newApplication(configPath string) (*application, error) {
+	if err := loadConfig(configPath); err != nil {
+		return nil, err
+	}
+
+	repos, err := newRepositories(viper.GetString(`repository.driver`))
+	if err != nil {
+		return nil, err
+	}
+
+	// the sitemap is generated from the repository once, then follows the changes made through it
+	articleSitemap := sitemap.New(repos.article, sitemap.MaxURLs)
+	articleRepo := articleSitemap.Track(repos.article)
+	tagRepo := articleSitemap.TrackTags(repos.tag)
+
+	timeoutContext := time.Duration(viper.GetInt("context.timeout")) * time.Second
+	var validate *validator.Validate
+	return &application{
+		articleUsecase:     _articleUcase.NewArticleUsecase(articleRepo, tagRepo, timeoutContext),
+		tagUsecase:         _tagUcase.NewTagUsecase(tagRepo, timeoutContext, validate),
+		idempotencyUsecase: _idempotencyUcase.NewIdempotencyUsecase(repos.idempotency, viper.GetDuration("idempotency.ttl"), timeoutContext),
+		sitemap:            articleSitemap,
+		close:              repos.close,
+	}, nil
+}
+
+// newRepositories builds the repositories selected by the repository.driver config (sql, gorm or memory)
+// against the database chosen by config.Database (postgres, mysql or sqlite)
+func newRepositories(driver string) (repositories, error) {
+	switch driver {
+	case "memory":
+		// the memory tags count their articles in the memory articles
+		articleRepo := _articleMemoryRepo.NewMemoryArticleRepository()
+		return repositories{
+			article:     articleRepo,
+			tag:         _tagMemoryRepo.NewMemoryTagRepositoryWithArticles(articleRepo),
+			idempotency: _idempotencyMemoryRepo.NewMemoryIdempotencyRepository(),
+			close:       func() {},
+		}, nil
+	case "gorm":
+		dialect, dsn, err := config.Database()
+		if err != nil {
+			return repositories{}, err
+		}
+		if dialect != "postgres" {
+			return repositories{}, fmt.Errorf("repository.driver gorm only supports postgres, got %q", dialect)
+		}
+		// gorm connection (for ORM)
+		db, err := config.DatabaseConnection(dsn)
+		if err != nil {
+			return repositories{}, err
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			return repositories{}, err
+		}
+		return repositories{
+			article:     _articleRepo.NewGormArticleRepository(db),
+			tag:         _tagRepo.NewGormTagRepository(db),
+			idempotency: _idempotencyRepo.NewPostgresqlIdempotencyRepository(sqlDB),
+			close:       closeDB(sqlDB),
+		}, nil
+	case "sql", "":
+		// sql/database golang connection (for raw queries)
+		dialect, dbConn, err := openDatabase()
+		if err != nil {
+			return repositories{}, err
+		}
+
+		switch dialect {
+		case "sqlite":
+			// sqlite databases are created on first use, keep them at the latest schema
+			if err = migrate(context.Background(), dbConn, dialect); err != nil {
+				dbConn.Close()
+				return repositories{}, err
+			}
+			return repositories{
+				article:     _articleSqliteRepo.NewSqliteArticleRepository(dbConn),
+				tag:         _tagSqliteRepo.NewSqliteTagRepository(dbConn),
+				idempotency: _idempotencySqliteRepo.NewSqliteIdempotencyRepository(dbConn),
+				close:       closeDB(dbConn),
+			}, nil
+		case "mysql":
+			return repositories{
+				article:     _articleMysqlRepo.NewMysqlArticleRepository(dbConn),
+				tag:         _tagMysqlRepo.NewMysqlTagRepository(dbConn),
+				idempotency: _idempotencyMysqlRepo.NewMysqlIdempotencyRepository(dbConn),
+				close:       closeDB(dbConn),
+			}, nil
+		default:
+			return repositories{
+				article:     _articleRepo.NewPostgresqlArticleRepository(dbConn),
+				tag:         _tagRepo.NewPostgresqlTagRepository(dbConn),
+				idempotency: _idempotencyRepo.NewPostgresqlIdempotencyRepository(dbConn),
+				close:       closeDB(dbConn),
+			}, nil
+		}
+	default:
+		return repositories{}, fmt.Errorf("unknown repository.driver %q, expected sql, gorm or memory", driver)
+	}
+}
+
+// openDatabase opens and pings the database chosen by config.Database
+func openDatabase() (dialect string, dbConn *sql.DB, err error) {
+	dialect, dsn, err := config.Database()
+	if err != nil {
+		return "", nil, err
+	}
+
+	// the database/sql driver names match the dialects
+	dbConn, err = sql.Open(dialect, dsn)
+	if err != nil {
+		return "", nil, err
+	}
+	if dialect == "sqlite" {
+		// sqlite allows a single writer, and every connection to :memory: is a different database
+		dbConn.SetMaxOpenConns(1)
+	}
+
+	if err = dbConn.Ping(); err != nil {
+		dbConn.Close()
+		return "", nil, err
+	}
+	return dialect, dbConn, nil
+}
+
+// migrate applies the pending migrations and logs the applied versions
+func migrate(ctx context.Context, dbConn *sql.DB, dialect string) error {
+	applied, err := migration.Up(ctx, dbConn, dialect)
+	if err != nil {
+		return err
+	}
+	if len(applied) > 0 {
+		log.Printf("applied %s migrations %v", dialect, applied)
+	}
+	return nil
+}
+
+func closeDB(db *sql.DB) func() {
+	return func() {
+		err := db.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go-postgres-clean-arch/article/transfer"
+)
+
+// articlesExportCommand writes every article, with its tag, as JSON, NDJSON or CSV
+func articlesExportCommand(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs, configPath := newFlagSet("articles export", stderr)
+	format := fs.String("format", transfer.FormatNDJSON, "output format, ndjson, json or csv")
+	output := fs.String("output", "-", "file to write to, - for stdout")
+	pageSize := fs.Int64("page-size", 100, "number of articles fetched per query")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if _, err := transfer.ContentType(*format); err != nil {
+		return err
+	}
+
+	app, err := newApplication(*configPath)
+	if err != nil {
+		return err
+	}
+	defer app.close()
+
+	out := stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	w, err := transfer.NewWriter(out, *format)
+	if err != nil {
+		return err
+	}
+	count, err := transfer.Export(ctx, app.articleUsecase, w, *pageSize)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stderr, "exported %d articles\n", count)
+	return nil
+}
+
+// articlesImportCommand stores the articles of a JSON, NDJSON or CSV document, or of a directory or
+// zip archive of markdown files with --format markdown. Tags are resolved by name and created when
+// missing. Articles whose title already exists are skipped, or updated with --upsert; markdown
+// imports always upsert so the same tree can be imported again.
+func articlesImportCommand(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs, configPath := newFlagSet("articles import", stderr)
+	input := fs.String("input", "-", "file to read from, - for stdin, a directory or a zip archive for markdown")
+	format := fs.String("format", "", "input format, ndjson, json, csv or markdown, detected from the content when empty")
+	dryRun := fs.Bool("dry-run", false, "only validate the articles")
+	upsert := fs.Bool("upsert", false, "update the articles whose title already exists")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var r transfer.Reader
+	if *format == transfer.FormatMarkdown {
+		*upsert = true
+		files, closeFiles, err := markdownFiles(*input)
+		if err != nil {
+			return err
+		}
+		defer closeFiles()
+		r = transfer.NewMarkdownReader(files)
+	} else {
+		in := io.Reader(os.Stdin)
+		if *input != "-" {
+			f, err := os.Open(*input)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			in = f
+		}
+
+		var err error
+		if r, err = transfer.NewReader(in, *format); err != nil {
+			return err
+		}
+	}
+
+	app, err := newApplication(*configPath)
+	if err != nil {
+		return err
+	}
+	defer app.close()
+
+	opts := transfer.ImportOptions{DryRun: *dryRun, Upsert: *upsert}
+	report, err := transfer.NewImporter(app.articleUsecase, app.tagUsecase).Import(ctx, r, opts)
+	for _, rowErr := range report.Errors {
+		fmt.Fprintf(stderr, "%s %q: %s\n", rowSource(rowErr), rowErr.Title, rowErr.Message)
+	}
+	for _, warning := range report.Warnings {
+		fmt.Fprintf(stderr, "warning: %s %q: %s\n", rowSource(warning), warning.Title, warning.Message)
+	}
+	if err != nil {
+		return err
+	}
+
+	verb := "imported"
+	if report.DryRun {
+		verb = "would import"
+	}
+	fmt.Fprintf(stdout, "%s %d articles, updated %d, skipped %d, %d failed\n", verb, report.Imported, report.Updated, report.Skipped, report.Failed)
+	if len(report.CreatedTags) > 0 {
+		fmt.Fprintf(stdout, "created tags %v\n", report.CreatedTags)
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d articles failed to import", report.Failed)
+	}
+	return nil
+}
+
+// rowSource locates the row of the report, by the file it was read from when there is one
+func rowSource(rowErr transfer.RowError) string {
+	if rowErr.Source != "" {
+		return rowErr.Source
+	}
+	return fmt.Sprintf("row %d", rowErr.Row)
+}
+
+// markdownFiles lists the markdown files of a directory or of a zip archive
+func markdownFiles(input string) ([]transfer.MarkdownFile, func(), error) {
+	info, err := os.Stat(input)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.IsDir() {
+		files, err := transfer.MarkdownFiles(os.DirFS(input))
+		return files, func() {}, err
+	}
+
+	archive, err := zip.OpenReader(input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s is neither a directory nor a zip archive: %w", input, err)
+	}
+	files, err := transfer.MarkdownFiles(archive)
+	if err != nil {
+		archive.Close()
+		return nil, nil, err
+	}
+	return files, func() { archive.Close() }, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const redacted = "xxxxx"
+
+// configPrintCommand prints the configuration as loaded, with passwords redacted
+func configPrintCommand(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs, configPath := newFlagSet("config print", stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := loadConfig(*configPath); err != nil {
+		return err
+	}
+
+	settings := viper.AllSettings()
+	if database, ok := settings["database"].(map[string]interface{}); ok {
+		if pass, _ := database["pass"].(string); pass != "" {
+			database["pass"] = redacted
+		}
+		if dsn, _ := database["dsn"].(string); dsn != "" {
+			database["dsn"] = redactDSN(dsn)
+		}
+	}
+
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(settings)
+}
+
+// redactDSN hides the password of a URL style DSN
+func redactDSN(dsn string) string {
+	if !strings.Contains(dsn, "://") {
+		return dsn
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return redacted
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+	}
+	return u.String()
+}
@@ -1,111 +1,114 @@
 package main
 
 import (
-	"database/sql"
-	"log"
-	"time"
-
-	"github.com/go-playground/validator"
-	"github.com/labstack/echo"
-	_ "github.com/lib/pq"
-	"github.com/spf13/viper"
-
-	_articleHttpDelivery "go-postgres-clean-arch/article/delivery/http"
-	_articleRepo "go-postgres-clean-arch/article/repository/postgresql"
-	_articleUcase "go-postgres-clean-arch/article/usecase"
-	"go-postgres-clean-arch/config"
-	_tagHttpDelivery "go-postgres-clean-arch/tag/delivery/http"
-	_tagHttpDeliveryMiddleware "go-postgres-clean-arch/tag/delivery/http/middleware"
-	_tagRepo "go-postgres-clean-arch/tag/repository/postgresql"
-	_tagUcase "go-postgres-clean-arch/tag/usecase"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
-func init() {
-	viper.SetConfigFile(`config.json`)
-	err := viper.ReadInConfig()
-	if err != nil {
-		panic(err)
-	}
-
-	if viper.GetBool(`debug`) {
-		log.Println("Service RUN on DEBUG mode")
-	}
+const usage = `Usage: app <command> [flags]
+
+Commands:
+  serve              start the HTTP server (default when no command is given)
+  migrate            apply the pending database migrations
+  seed               store the sample tags and articles
+  tags list          list the tags
+  tags create        create a tag
+  tags delete        delete a tag
+  articles import    import articles from JSON, NDJSON, CSV or markdown files
+  articles export    export every article as JSON, NDJSON or CSV
+  config print       print the effective configuration
+
+Every command accepts --config (default config.json).
+Run "app <command> -h" for the flags of a command.
+`
+
+// command is a single subcommand of the CLI, stdout receives the command output
+type command func(ctx context.Context, args []string, stdout, stderr io.Writer) error
+
+var commands = map[string]command{
+	"serve":   serveCommand,
+	"migrate": migrateCommand,
+	"seed":    seedCommand,
+	"tags": group("tags", map[string]command{
+		"list":   tagsListCommand,
+		"create": tagsCreateCommand,
+		"delete": tagsDeleteCommand,
+	}),
+	"articles": group("articles", map[string]command{
+		"import": articlesImportCommand,
+		"export": articlesExportCommand,
+	}),
+	"config": group("config", map[string]command{
+		"print": configPrintCommand,
+	}),
 }
 
 func main() {
-	// sql/database golang connection (for raw queries)
-	// dbHost := viper.GetString(`database.host`)
-	// dbPort := viper.GetString(`database.port`)
-	// dbUser := viper.GetString(`database.user`)
-	// dbPass := viper.GetString(`database.pass`)
-	// dbName := viper.GetString(`database.name`)
-	// connection := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", dbUser, dbPass, dbHost, dbPort, dbName)
-	// connection := fmt.Sprintf("postgres://%s:%s@%s/%s:%s?sslmode=verify-full", dbUser, dbPass, dbHost, dbPort, dbName)
-	// connection := "postgres://postgres@localhost/clean_arch_test?sslmode=verify-full"
-	dsn := "host=localhost port=5432 user=postgres dbname=clean_arch_test sslmode=disable"
-	// val := url.Values{}
-	// val.Add("parseTime", "1")
-	// val.Add("loc", "Asia/Jakarta")
-	// dsn := fmt.Sprintf("%s?%s", connection, val.Encode())
-	dbConn, err := sql.Open(`postgres`, dsn)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
 
-	// gorm connection (for ORM)
-	db := config.DatabaseConnection()
-
-	if err != nil {
-		log.Fatal(err)
+	if errors.Is(err, flag.ErrHelp) {
+		return
 	}
-	err = dbConn.Ping()
 	if err != nil {
-		log.Fatal(err)
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
 	}
+}
 
-	defer func() {
-		err := dbConn.Close()
-		if err != nil {
-			log.Fatal(err)
-		}
-	}()
-
-	e := echo.New()
-	middL := _tagHttpDeliveryMiddleware.InitMiddleware()
-	e.Use(middL.CORS)
-	tagRepo := _tagRepo.NewPostgresqlTagRepository(dbConn, db)
-	articleRepo := _articleRepo.NewPostgresqlArticleRepository(dbConn)
-
-	timeoutContext := time.Duration(viper.GetInt("context.timeout")) * time.Second
-	var validate *validator.Validate
-	tu := _tagUcase.NewTagUsecase(tagRepo, timeoutContext, validate)
-	au := _articleUcase.NewArticleUsecase(articleRepo, tagRepo, timeoutContext)
-	_tagHttpDelivery.NewTagHandler(e, tu)
-	_articleHttpDelivery.NewArticleHandler(e, au)
-
-	log.Fatal(e.Start(viper.GetString("server.address"))) //nolint
-
-	// log.Info().Msg("Started Server!")
-	// Database
-	// db := config.DatabaseConnection()
-	// validate := validator.New()
-
-	// db.Table("tags").AutoMigrate(&domain.Tag{})
+// run dispatches the arguments to the matching command
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 || (len(args[0]) > 0 && args[0][0] == '-' && !isHelp(args[0])) {
+		// keep "app" and "app --config x" starting the server as they always did
+		return serveCommand(ctx, args, stdout, stderr)
+	}
 
-	// // Repository
-	// tagRepository := _tagRepo.NewPostgresqlTagRepository(db)
+	name := args[0]
+	if isHelp(name) || name == "help" {
+		fmt.Fprint(stdout, usage)
+		return nil
+	}
 
-	// // Usecase
-	// tagUsecase := _tagUcase.NewTagUsecase(tagRepository, validate)
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprint(stderr, usage)
+		return fmt.Errorf("unknown command %q", name)
+	}
+	return cmd(ctx, args[1:], stdout, stderr)
+}
 
-	// // Delivery
-	// tagDelivery := _tagHttpDelivery.NewTagHandler(tagUsecase)
+// group builds a command dispatching to the given subcommands, e.g. "tags list"
+func group(name string, subcommands map[string]command) command {
+	return func(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+		if len(args) == 0 || isHelp(args[0]) {
+			fmt.Fprint(stderr, usage)
+			return fmt.Errorf("%s expects a subcommand", name)
+		}
 
-	// // Router
-	// routes := router.NewRouter(tagDelivery)
+		cmd, ok := subcommands[args[0]]
+		if !ok {
+			fmt.Fprint(stderr, usage)
+			return fmt.Errorf("unknown command %q", name+" "+args[0])
+		}
+		return cmd(ctx, args[1:], stdout, stderr)
+	}
+}
 
-	// server := &http.Server{
-	// 	Addr:    ":8080",
-	// 	Handler: routes,
-	// }
+func isHelp(arg string) bool {
+	return arg == "-h" || arg == "-help" || arg == "--help"
+}
 
-	// err := server.ListenAndServe()
-	// helper.ErrorPanic(err)
+// newFlagSet creates the flag set of a command with the --config flag every command shares
+func newFlagSet(name string, stderr io.Writer) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("config", "config.json", "path of the configuration file")
+	return fs, configPath
 }
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/spf13/viper"
+
+	"go-postgres-clean-arch/migration"
+)
+
+// migrateCommand applies the pending migrations of the configured database
+func migrateCommand(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs, configPath := newFlagSet("migrate", stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := loadConfig(*configPath); err != nil {
+		return err
+	}
+	if viper.GetString(`repository.driver`) == "memory" {
+		fmt.Fprintln(stdout, "repository.driver memory has no schema to migrate")
+		return nil
+	}
+
+	dialect, dbConn, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer dbConn.Close()
+
+	applied, err := migration.Up(ctx, dbConn, dialect)
+	for _, version := range applied {
+		fmt.Fprintf(stdout, "applied %s\n", version)
+	}
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		fmt.Fprintf(stdout, "%s schema is up to date\n", dialect)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"go-postgres-clean-arch/domain"
+)
+
+// seedArticle is a sample article stored under the tag of the same name by the seed command
+type seedArticle struct {
+	Tag     string
+	Title   string
+	Content string
+}
+
+var seedTags = []string{"golang", "postgresql", "clean-architecture"}
+
+var seedArticles = []seedArticle{
+	{Tag: "golang", Title: "Getting started with Go", Content: "Install the toolchain, run go mod init and write your first main package."},
+	{Tag: "golang", Title: "Concurrency with errgroup", Content: "errgroup runs goroutines that share a context and reports the first error."},
+	{Tag: "postgresql", Title: "Keyset pagination", Content: "Paginate with WHERE created_at > $1 ORDER BY created_at instead of OFFSET."},
+	{Tag: "clean-architecture", Title: "Layers of a clean architecture", Content: "Domain, repository, usecase and delivery only depend inwards."},
+}
+
+// seedCommand stores the sample tags and articles through the usecases, existing ones are skipped
+func seedCommand(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs, configPath := newFlagSet("seed", stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	app, err := newApplication(*configPath)
+	if err != nil {
+		return err
+	}
+	defer app.close()
+
+	tagIDs := map[string]int64{}
+	var created, skipped int
+	for _, name := range seedTags {
+		tag := domain.Tag{Name: name}
+		switch err = app.tagUsecase.Store(ctx, &tag); err {
+		case nil:
+			created++
+		case domain.ErrConflict:
+			skipped++
+			if tag, err = app.tagUsecase.FetchByName(ctx, name); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("seed tag %q: %w", name, err)
+		}
+		tagIDs[name] = tag.ID
+	}
+
+	for _, a := range seedArticles {
+		input := domain.CreateArticleInput{Title: a.Title, Content: a.Content, TagID: tagIDs[a.Tag]}
+		switch err = app.articleUsecase.Store(ctx, &input); err {
+		case nil:
+			created++
+		case domain.ErrConflict:
+			skipped++
+		default:
+			return fmt.Errorf("seed article %q: %w", a.Title, err)
+		}
+	}
+
+	fmt.Fprintf(stdout, "seeded %d records, %d already existed\n", created, skipped)
+	return nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/spf13/viper"
+
+	_articleHttpDelivery "go-postgres-clean-arch/article/delivery/http"
+	"go-postgres-clean-arch/domain"
+	"go-postgres-clean-arch/helper"
+	_idempotencyHttpDelivery "go-postgres-clean-arch/idempotency/delivery/http"
+	"go-postgres-clean-arch/openapi"
+	_tagHttpDelivery "go-postgres-clean-arch/tag/delivery/http"
+	_tagHttpDeliveryMiddleware "go-postgres-clean-arch/tag/delivery/http/middleware"
+)
+
+// shutdownTimeout bounds how long in-flight requests may run once the server is asked to stop
+const shutdownTimeout = 10 * time.Second
+
+// purgeInterval is how often the expired idempotent requests are deleted while the server runs
+const purgeInterval = time.Hour
+
+// serveCommand starts the HTTP server until the context is cancelled
+func serveCommand(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs, configPath := newFlagSet("serve", stderr)
+	address := fs.String("address", "", "address to listen on, overrides server.address")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	app, err := newApplication(*configPath)
+	if err != nil {
+		return err
+	}
+	defer app.close()
+
+	if *address == "" {
+		*address = viper.GetString("server.address")
+	}
+
+	var cachePolicy helper.CachePolicy
+	if err = viper.UnmarshalKey("cache_control", &cachePolicy); err != nil {
+		return fmt.Errorf("read cache_control: %w", err)
+	}
+
+	e := newServer(app, cachePolicy)
+	go purgeIdempotentRequests(ctx, app.idempotencyUsecase, purgeInterval)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- e.Start(*address)
+	}()
+
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		log.Println("shutting down the server")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		err = e.Shutdown(shutdownCtx)
+	}
+
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// newServer registers the middlewares, the handlers and the API documentation on a new echo instance,
+// the GET responses carry the Cache-Control header of the policy of their route
+func newServer(app *application, cachePolicy helper.CachePolicy) *echo.Echo {
+	e := echo.New()
+	e.HideBanner = true
+	middL := _tagHttpDeliveryMiddleware.InitMiddleware()
+	e.Use(middL.CORS)
+	e.Use(cachePolicy.Middleware)
+
+	// the retries of the creations are answered the response of the first request
+	idempotency := _idempotencyHttpDelivery.NewIdempotencyMiddleware(app.idempotencyUsecase)
+	_tagHttpDelivery.NewTagHandler(e, app.tagUsecase, idempotency.Idempotent)
+	_articleHttpDelivery.NewArticleHandler(e, app.articleUsecase, idempotency.Idempotent)
+	_articleHttpDelivery.NewArticleTransferHandler(e, app.articleUsecase, app.tagUsecase)
+	_articleHttpDelivery.NewArticleFeedHandler(e, app.articleUsecase, app.tagUsecase)
+	_articleHttpDelivery.NewArticleSitemapHandler(e, app.sitemap)
+	_articleHttpDelivery.NewTagArticlesHandler(e, app.articleUsecase, app.tagUsecase)
+
+	routes := append(_articleHttpDelivery.OpenAPIRoutes(), _tagHttpDelivery.OpenAPIRoutes()...)
+	doc := openapi.NewDocument("Article Management API", "2.0.0", routes...)
+	doc.Info.Description = `The routes are versioned. /api/v2 wraps every response in an envelope with its data, and the
+meta and links of the listing pages, and lists the tags of the articles. /api/v1 and its /api alias are frozen and
+deprecated, their responses carry the Deprecation and Sunset headers and are only wrapped in the envelope when the
+Accept header asks for application/json; profile="envelope" or version=2.
+
+The creations of the articles and of the tags accept an Idempotency-Key header. A request sent again with the
+key is answered the response of the first request, with the Idempotent-Replayed header, and a key used for
+another request is refused with 422.`
+	openapi.NewOpenAPIHandler(e, doc)
+
+	return e
+}
+
+// purgeIdempotentRequests deletes the expired idempotent requests every interval until the context is cancelled
+func purgeIdempotentRequests(ctx context.Context, u domain.IdempotencyUsecase, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := u.Purge(ctx)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+			if deleted > 0 {
+				log.Printf("deleted %d expired idempotent requests", deleted)
+			}
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go-postgres-clean-arch/domain"
+)
+
+// tagsListCommand prints a page of tags, or every tag with --all
+func tagsListCommand(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs, configPath := newFlagSet("tags list", stderr)
+	num := fs.Int64("num", 10, "number of tags per page")
+	cursor := fs.String("cursor", "", "cursor returned by the previous page")
+	all := fs.Bool("all", false, "follow the cursor until every tag is listed")
+	format := fs.String("format", "table", "output format, table or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format != "table" && *format != "json" {
+		return fmt.Errorf("unknown format %q, expected table or json", *format)
+	}
+
+	app, err := newApplication(*configPath)
+	if err != nil {
+		return err
+	}
+	defer app.close()
+
+	var tags []domain.Tag
+	next := *cursor
+	for {
+		var page []domain.Tag
+		page, next, err = app.tagUsecase.Fetch(ctx, next, *num)
+		if err != nil {
+			return err
+		}
+		tags = append(tags, page...)
+		if !*all || next == "" || len(page) == 0 {
+			break
+		}
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if tags == nil {
+			tags = []domain.Tag{}
+		}
+		return enc.Encode(tags)
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tCREATED AT\tUPDATED AT")
+	for _, t := range tags {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", t.ID, t.Name, t.CreatedAt.Format(time.RFC3339), t.UpdatedAt.Format(time.RFC3339))
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	if !*all && next != "" {
+		fmt.Fprintf(stderr, "next cursor: %s\n", next)
+	}
+	return nil
+}
+
+// tagsCreateCommand stores a new tag
+func tagsCreateCommand(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs, configPath := newFlagSet("tags create", stderr)
+	name := fs.String("name", "", "name of the tag")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if strings.TrimSpace(*name) == "" {
+		return errors.New("--name is required")
+	}
+
+	app, err := newApplication(*configPath)
+	if err != nil {
+		return err
+	}
+	defer app.close()
+
+	tag := domain.Tag{Name: *name}
+	if err = app.tagUsecase.Store(ctx, &tag); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "created tag %d %s\n", tag.ID, tag.Name)
+	return nil
+}
+
+// tagsDeleteCommand deletes the tag with the given id, --policy decides what happens to its articles
+func tagsDeleteCommand(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs, configPath := newFlagSet("tags delete", stderr)
+	id := fs.Int64("id", 0, "id of the tag")
+	policy := fs.String("policy", string(domain.TagDeleteRestrict), "what happens to the articles of the tag, restrict, reassign or cascade-detach")
+	reassignTo := fs.Int64("reassign-to", 0, "id of the tag the articles move to with the reassign policy")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *id <= 0 {
+		return errors.New("--id is required")
+	}
+	if !validDeletePolicy(domain.TagDeletePolicy(*policy)) {
+		return fmt.Errorf("unknown policy %q, expected restrict, reassign or cascade-detach", *policy)
+	}
+	if domain.TagDeletePolicy(*policy) == domain.TagDeleteReassign && *reassignTo <= 0 {
+		return errors.New("--reassign-to is required with the reassign policy")
+	}
+	if domain.TagDeletePolicy(*policy) != domain.TagDeleteReassign && *reassignTo != 0 {
+		return errors.New("--reassign-to is only used with the reassign policy")
+	}
+
+	app, err := newApplication(*configPath)
+	if err != nil {
+		return err
+	}
+	defer app.close()
+
+	err = app.tagUsecase.DeleteWithPolicy(ctx, *id, domain.TagDeletePolicy(*policy), *reassignTo)
+	var inUse *domain.TagInUseError
+	if errors.As(err, &inUse) {
+		return fmt.Errorf("tag %d is used by %d articles, delete it with --policy reassign or cascade-detach: %w", *id, inUse.ArticleCount, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "deleted tag %d\n", *id)
+	return nil
+}
+
+// validDeletePolicy reports whether the policy is one of domain.TagDeletePolicies
+func validDeletePolicy(policy domain.TagDeletePolicy) bool {
+	for _, p := range domain.TagDeletePolicies {
+		if p == policy {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+CREATE TABLE IF NOT EXISTS tag (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  name VARCHAR(200) NOT NULL UNIQUE,
+  slug VARCHAR(255) NULL UNIQUE,
+  parent_id BIGINT NULL,
+  name_key VARCHAR(200) NULL,
+  description VARCHAR(1000) NOT NULL DEFAULT '',
+  color VARCHAR(7) NOT NULL DEFAULT '',
+  icon VARCHAR(64) NOT NULL DEFAULT '',
+  hidden BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at DATETIME(6) NOT NULL,
+  updated_at DATETIME(6) NOT NULL,
+  INDEX tag_parent_idx (parent_id),
+  UNIQUE INDEX tag_name_key_idx (name_key)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS article (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  title VARCHAR(255) NOT NULL UNIQUE,
+  slug VARCHAR(255) NULL UNIQUE,
+  content TEXT NOT NULL,
+  content_format VARCHAR(16) NOT NULL DEFAULT 'markdown',
+  tag_id BIGINT NOT NULL,
+  version BIGINT NOT NULL DEFAULT 1,
+  updated_at DATETIME(6) NOT NULL,
+  created_at DATETIME(6) NOT NULL,
+  INDEX article_tag_id_idx (tag_id, created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS article_slug_history (
+  slug VARCHAR(255) NOT NULL PRIMARY KEY,
+  article_id BIGINT NOT NULL,
+  created_at DATETIME(6) NOT NULL,
+  INDEX article_slug_history_article_idx (article_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS tag_alias (
+  name VARCHAR(200) NOT NULL PRIMARY KEY,
+  tag_id BIGINT NOT NULL,
+  name_key VARCHAR(200) NULL,
+  INDEX tag_alias_tag_idx (tag_id),
+  UNIQUE INDEX tag_alias_name_key_idx (name_key)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS idempotency_request (
+  idempotency_key VARCHAR(255) NOT NULL PRIMARY KEY,
+  fingerprint VARCHAR(64) NOT NULL,
+  status INT NOT NULL DEFAULT 0,
+  header TEXT NOT NULL,
+  body LONGBLOB,
+  created_at DATETIME(6) NOT NULL,
+  expires_at DATETIME(6) NOT NULL,
+  INDEX idempotency_request_expires_at_idx (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -1,42 +1,60 @@
 package http
 
 import (
+	"fmt"
+	"go-postgres-clean-arch/article/render"
+	"go-postgres-clean-arch/data/request"
+	"go-postgres-clean-arch/data/response"
 	"go-postgres-clean-arch/domain"
+	"go-postgres-clean-arch/helper"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/go-playground/validator"
 	"github.com/labstack/echo"
 	"github.com/sirupsen/logrus"
 )
 
-type ResponseError struct {
-	Message string `json:"message"`
-}
+// ResponseError is the body of a failed request
+type ResponseError = response.ErrorResponse
 
-type ArticleResponse struct {
-	Title   string `json:"title"`
-	Content string `json:"content"`
-}
+// renderHTML is the value of the render query param adding the rendered HTML to the articles
+const renderHTML = "html"
 
 // ArticleHandler  represent the httphandler for article
 type ArticleHandler struct {
 	AUsecase domain.ArticleUsecase
+	Renderer *render.Renderer
+	// Version is the API version of the routes of the handler, mounted under Prefix
+	Version helper.APIVersion
+	Prefix  string
 }
 
-// NewArticleHandler will initialize the articles/ resources endpoint
-func NewArticleHandler(e *echo.Echo, us domain.ArticleUsecase) {
-	handler := &ArticleHandler{
-		AUsecase: us,
+// NewArticleHandler will initialize the articles/ resources endpoint of every API version, the store
+// middlewares only wrap the creation of the articles, e.g. to make it idempotent
+func NewArticleHandler(e *echo.Echo, us domain.ArticleUsecase, store ...echo.MiddlewareFunc) {
+	renderer := render.NewRenderer(render.DefaultCacheSize)
+	for _, version := range helper.APIVersions {
+		for _, prefix := range version.Prefixes() {
+			handler := &ArticleHandler{
+				AUsecase: us,
+				Renderer: renderer,
+				Version:  version,
+				Prefix:   prefix,
+			}
+
+			baseRouter := e.Group(prefix, version.Middleware()...)
+			tagsRouter := baseRouter.Group("/articles")
+			tagsRouter.GET("", handler.FetchArticle)
+			tagsRouter.POST("", handler.Store, store...)
+			tagsRouter.GET("/:articleId", handler.GetByID)
+			tagsRouter.GET("/by-slug/:slug", handler.GetBySlug)
+			tagsRouter.PATCH("/:articleId", handler.Update)
+			tagsRouter.DELETE("/:articleId", handler.Delete)
+		}
 	}
-
-	baseRouter := e.Group("/api")
-	tagsRouter := baseRouter.Group("/articles")
-	tagsRouter.GET("", handler.FetchArticle)
-	tagsRouter.POST("", handler.Store)
-	tagsRouter.GET("/:articleId", handler.GetByID)
-	tagsRouter.PATCH("/:articleId", handler.Update)
-	tagsRouter.DELETE("/:articleId", handler.Delete)
 }
 
 // FetchArticle will fetch the article based on given params
@@ -46,13 +64,45 @@ func (a *ArticleHandler) FetchArticle(c echo.Context) error {
 	cursor := c.QueryParam("cursor")
 	ctx := c.Request().Context()
 
-	listAr, nextCursor, err := a.AUsecase.Fetch(ctx, cursor, int64(num))
+	withHTML, err := parseRender(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: err.Error()})
+	}
+
+	filter, err := parseArticleFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: err.Error()})
+	}
+
+	listAr, nextCursor, err := a.AUsecase.FetchFiltered(ctx, filter, cursor, int64(num))
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	err = helper.SetPage(c, helper.Page{
+		Cursor:     cursor,
+		NextCursor: nextCursor,
+		Size:       pageSize(num),
+		Total:      func() (int64, error) { return a.AUsecase.Count(ctx, filter) },
+	})
 	if err != nil {
 		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
 	}
 
 	c.Response().Header().Set(`X-Cursor`, nextCursor)
-	return c.JSON(http.StatusOK, listAr)
+	if articlesNotModified(c, listAr, nextCursor) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	if withHTML {
+		for i := range listAr {
+			if err = a.Renderer.Apply(&listAr[i]); err != nil {
+				return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+			}
+		}
+	}
+
+	return c.JSON(http.StatusOK, articleResponses(a.Version, listAr))
 }
 
 // GetByID will get article by given id
@@ -65,38 +115,96 @@ func (a *ArticleHandler) GetByID(c echo.Context) error {
 	id := int64(idP)
 	ctx := c.Request().Context()
 
+	withHTML, err := parseRender(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: err.Error()})
+	}
+
 	art, err := a.AUsecase.GetByID(ctx, id)
 	if err != nil {
 		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
 	}
 
-	return c.JSON(http.StatusOK, art)
+	// the version is checked before the article is rendered
+	if articleNotModified(c, art) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	if withHTML {
+		if err = a.Renderer.Apply(&art); err != nil {
+			return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+		}
+	}
+
+	return c.JSON(http.StatusOK, articleResponse(a.Version, art))
 }
 
-// Store will store the article by given request body
+// GetBySlug will get article by given slug, a previous slug of the article redirects to the current one
+func (a *ArticleHandler) GetBySlug(c echo.Context) error {
+	requested := c.Param("slug")
+	ctx := c.Request().Context()
+
+	withHTML, err := parseRender(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: err.Error()})
+	}
+
+	art, err := a.AUsecase.GetBySlug(ctx, requested)
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	if art.Slug != requested {
+		location := a.Prefix + "/articles/by-slug/" + url.PathEscape(art.Slug)
+		if query := c.QueryString(); query != "" {
+			location += "?" + query
+		}
+		return c.Redirect(http.StatusMovedPermanently, location)
+	}
+
+	// the version is checked before the article is rendered
+	if articleNotModified(c, art) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	if withHTML {
+		if err = a.Renderer.Apply(&art); err != nil {
+			return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+		}
+	}
+
+	return c.JSON(http.StatusOK, articleResponse(a.Version, art))
+}
+
+// Store will store the article by given request body. The v2 body lists the tag in tag_ids, which
+// holds exactly one id while an article has a single tag, more ids are a 400.
 func (a *ArticleHandler) Store(c echo.Context) (err error) {
-	var article domain.CreateArticleInput
-	err = c.Bind(&article)
+	req := createRequest(a.Version)
+	err = c.Bind(req)
 	if err != nil {
 		return c.JSON(http.StatusUnprocessableEntity, err.Error())
 	}
 
 	var ok bool
-	if ok, err = isCreateRequestValid(&article); !ok {
+	if ok, err = isRequestValid(req); !ok {
 		return c.JSON(http.StatusBadRequest, err.Error())
 	}
 
+	// the id and the timestamps are set by the usecase, not by the client
+	article := req.Input()
 	ctx := c.Request().Context()
 	err = a.AUsecase.Store(ctx, &article)
 	if err != nil {
 		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
 	}
 
-	var articleResponse ArticleResponse
-	articleResponse.Title = article.Title
-	articleResponse.Content = article.Content
+	art, err := a.AUsecase.GetByID(ctx, article.ID)
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
 
-	return c.JSON(http.StatusCreated, articleResponse)
+	setArticleValidators(c, art)
+	return c.JSON(http.StatusCreated, articleResponse(a.Version, art))
 }
 
 // Update will update the article by request body based on param id
@@ -108,33 +216,40 @@ func (a *ArticleHandler) Update(c echo.Context) (err error) {
 
 	id := int64(idP)
 	ctx := c.Request().Context()
-	_, err = a.AUsecase.GetByID(ctx, id)
+	current, err := a.AUsecase.GetByID(ctx, id)
 	if err != nil {
 		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
 	}
+	version, ok := ifMatch(c.Request(), current)
+	if !ok {
+		return c.JSON(http.StatusPreconditionFailed, ResponseError{Message: domain.ErrPreconditionFailed.Error()})
+	}
 
-	var article domain.UpdateArticleInput
-	err = c.Bind(&article)
-	article.ID = id
+	// the id comes from the path, the body can't set it
+	req := updateRequest(a.Version, id)
+	err = c.Bind(req)
 	if err != nil {
 		return c.JSON(http.StatusUnprocessableEntity, err.Error())
 	}
 
-	var ok bool
-	if ok, err = isUpdateRequestValid(&article); !ok {
+	if ok, err = isRequestValid(req); !ok {
 		return c.JSON(http.StatusBadRequest, err.Error())
 	}
 
-	var articleResponse ArticleResponse
-	articleResponse.Title = article.Title
-	articleResponse.Content = article.Content
-
+	article := req.Input()
+	article.Version = version
 	err = a.AUsecase.Update(ctx, &article)
 	if err != nil {
 		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
 	}
 
-	return c.JSON(http.StatusOK, articleResponse)
+	art, err := a.AUsecase.GetByID(ctx, id)
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	setArticleValidators(c, art)
+	return c.JSON(http.StatusOK, articleResponse(a.Version, art))
 }
 
 // Delete will delete article by given param
@@ -147,7 +262,20 @@ func (a *ArticleHandler) Delete(c echo.Context) error {
 	id := int64(idP)
 	ctx := c.Request().Context()
 
-	err = a.AUsecase.Delete(ctx, id)
+	// without If-Match the article is deleted whatever its version
+	var version int64
+	if c.Request().Header.Get(headerIfMatch) != "" {
+		current, err := a.AUsecase.GetByID(ctx, id)
+		if err != nil {
+			return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+		}
+		var ok bool
+		if version, ok = ifMatch(c.Request(), current); !ok {
+			return c.JSON(http.StatusPreconditionFailed, ResponseError{Message: domain.ErrPreconditionFailed.Error()})
+		}
+	}
+
+	err = a.AUsecase.Delete(ctx, id, version)
 	if err != nil {
 		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
 	}
@@ -155,15 +283,101 @@ func (a *ArticleHandler) Delete(c echo.Context) error {
 	return c.NoContent(http.StatusNoContent)
 }
 
-func isCreateRequestValid(m *domain.CreateArticleInput) (bool, error) {
-	validate := validator.New()
-	err := validate.Struct(m)
-	if err != nil {
-		return false, err
+// createRequest is the body of a new article in the version of the API
+func createRequest(v helper.APIVersion) interface {
+	Input() domain.CreateArticleInput
+} {
+	if v == helper.APIv1 {
+		return &request.CreateArticlesRequest{}
+	}
+	return &request.CreateArticlesV2Request{}
+}
+
+// updateRequest is the body of the update of the article by given id in the version of the API
+func updateRequest(v helper.APIVersion, id int64) interface {
+	Input() domain.UpdateArticleInput
+} {
+	if v == helper.APIv1 {
+		return &request.UpdateArticlesRequest{ID: id}
+	}
+	return &request.UpdateArticlesV2Request{ID: id}
+}
+
+// articleResponse maps the article to its response in the version of the API
+func articleResponse(v helper.APIVersion, art domain.Article) interface{} {
+	if v == helper.APIv1 {
+		return response.NewArticleResponse(art)
+	}
+	return response.NewArticleV2Response(art)
+}
+
+// articleResponses maps the articles to their responses in the version of the API
+func articleResponses(v helper.APIVersion, articles []domain.Article) interface{} {
+	if v == helper.APIv1 {
+		return response.NewArticleResponses(articles)
+	}
+	return response.NewArticleV2Responses(articles)
+}
+
+// pageSize is the size of the pages the usecase lists for the num param
+func pageSize(num int) int64 {
+	if num == 0 {
+		return domain.DefaultPageSize
+	}
+	return int64(num)
+}
+
+// parseRender reports whether the request asks for the rendered HTML with ?render=html
+func parseRender(c echo.Context) (bool, error) {
+	switch c.QueryParam("render") {
+	case "":
+		return false, nil
+	case renderHTML:
+		return true, nil
+	default:
+		return false, fmt.Errorf("render must be %q", renderHTML)
 	}
-	return true, nil
 }
-func isUpdateRequestValid(m *domain.UpdateArticleInput) (bool, error) {
+
+// parseArticleFilter reads the filter of the listing from the query params, the timestamps are RFC 3339
+func parseArticleFilter(c echo.Context) (filter domain.ArticleFilter, err error) {
+	if v := c.QueryParam("tag_id"); v != "" {
+		if filter.TagID, err = strconv.ParseInt(v, 10, 64); err != nil || filter.TagID <= 0 {
+			return filter, fmt.Errorf("tag_id must be a positive integer")
+		}
+	}
+	filter.Tag = c.QueryParam("tag")
+	filter.Query = c.QueryParam("q")
+	if v := c.QueryParam("include_descendants"); v != "" {
+		if filter.IncludeDescendants, err = strconv.ParseBool(v); err != nil {
+			return filter, fmt.Errorf("include_descendants must be a boolean")
+		}
+	}
+
+	times := []struct {
+		param string
+		t     *time.Time
+	}{
+		{"created_after", &filter.CreatedAfter},
+		{"created_before", &filter.CreatedBefore},
+		{"updated_since", &filter.UpdatedSince},
+	}
+	for _, p := range times {
+		if v := c.QueryParam(p.param); v != "" {
+			if *p.t, err = time.Parse(time.RFC3339, v); err != nil {
+				return filter, fmt.Errorf("%s must be an RFC 3339 timestamp", p.param)
+			}
+		}
+	}
+
+	filter.Sort = domain.ArticleSort(c.QueryParam("sort"))
+	if !filter.Sort.Valid() {
+		return filter, fmt.Errorf("sort must be one of %v", domain.ArticleSorts)
+	}
+	return filter, nil
+}
+
+func isRequestValid(m interface{}) (bool, error) {
 	validate := validator.New()
 	err := validate.Struct(m)
 	if err != nil {
@@ -185,6 +399,10 @@ func getStatusCode(err error) int {
 		return http.StatusNotFound
 	case domain.ErrConflict:
 		return http.StatusConflict
+	case domain.ErrBadParamInput:
+		return http.StatusBadRequest
+	case domain.ErrPreconditionFailed:
+		return http.StatusPreconditionFailed
 	default:
 		return http.StatusInternalServerError
 	}
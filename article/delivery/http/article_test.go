@@ -1 +1,552 @@
-package http
+package http_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	articleHttp "go-postgres-clean-arch/article/delivery/http"
+	"go-postgres-clean-arch/domain"
+	"go-postgres-clean-arch/helper"
+	"go-postgres-clean-arch/openapi"
+
+	"github.com/labstack/echo"
+)
+
+func TestOpenAPIRoutesMatchHandler(t *testing.T) {
+	e := echo.New()
+	articleHttp.NewArticleHandler(e, nil)
+	articleHttp.NewArticleTransferHandler(e, nil, nil)
+	articleHttp.NewArticleFeedHandler(e, nil, nil)
+	articleHttp.NewArticleSitemapHandler(e, nil)
+	articleHttp.NewTagArticlesHandler(e, nil, nil)
+
+	undocumented, stale := openapi.Drift(e, articleHttp.OpenAPIRoutes())
+	for _, r := range undocumented {
+		t.Errorf("route %s is registered but missing from OpenAPIRoutes", r)
+	}
+	for _, r := range stale {
+		t.Errorf("route %s is documented but not registered", r)
+	}
+
+	// the envelopes of v2 are named after their data
+	doc := openapi.NewDocument("test", "test", articleHttp.OpenAPIRoutes()...)
+	for _, name := range []string{"EnvelopeArticleV2Response", "EnvelopeArticleV2ResponseList", "EnvelopeErrorResponse", "ArticleResponse"} {
+		if doc.Components.Schemas[name] == nil {
+			t.Errorf("component %s is missing", name)
+		}
+	}
+
+	// an article has a single tag, the v2 bodies list at most one
+	for _, name := range []string{"CreateArticlesV2Request", "UpdateArticlesV2Request"} {
+		body := doc.Components.Schemas[name]
+		if body == nil || body.Properties["tag_ids"] == nil {
+			t.Fatalf("component %s is missing its tag_ids", name)
+		}
+		if tagIDs := body.Properties["tag_ids"]; tagIDs.MaxItems == nil || *tagIDs.MaxItems != 1 || tagIDs.Description == "" {
+			t.Errorf("the tag_ids of %s are not documented to hold one id: %+v", name, tagIDs)
+		}
+	}
+}
+
+func TestRenderHTML(t *testing.T) {
+	e := newTransferServer()
+	body := `{"title": "Rendered", "content": "# Title\n\n<script>x</script>text", "tag_id": 1}`
+
+	// the article needs an existing tag
+	req := httptest.NewRequest(http.MethodPost, "/api/articles/import", strings.NewReader(`{"title": "Seed", "content": "x", "tag": {"name": "go"}}`))
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/articles", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/articles/2?render=html", nil))
+	var article domain.Article
+	if err := json.Unmarshal(rec.Body.Bytes(), &article); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+	if article.ContentFormat != domain.ContentFormatMarkdown || strings.Contains(article.HTML, "<script") {
+		t.Fatalf("unexpected article %+v", article)
+	}
+	if len(article.TOC) != 1 || article.TOC[0].ID != "title" || article.ReadingTime != 1 {
+		t.Fatalf("unexpected toc %+v", article)
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/articles", nil))
+	if strings.Contains(rec.Body.String(), `"html"`) {
+		t.Fatalf("html is rendered without ?render=html: %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/articles?render=pdf", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown render value, got %d", rec.Code)
+	}
+}
+
+func TestGetBySlugRedirectsPreviousSlug(t *testing.T) {
+	e := newTransferServer()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/articles/import", strings.NewReader(`{"title": "Crème Brûlée", "content": "x", "tag": {"name": "go"}}`))
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/articles/by-slug/creme-brulee", nil))
+	var article domain.Article
+	if err := json.Unmarshal(rec.Body.Bytes(), &article); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPatch, "/api/articles/1", strings.NewReader(`{"title": "Tarte Tatin"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"slug":"tarte-tatin"`) {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/articles/by-slug/creme-brulee?render=html", nil))
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get(echo.HeaderLocation) != "/api/articles/by-slug/tarte-tatin?render=html" {
+		t.Fatalf("unexpected response %d %v", rec.Code, rec.Header())
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/articles/by-slug/missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown slug, got %d", rec.Code)
+	}
+}
+
+func TestStoreIgnoresServerFields(t *testing.T) {
+	e := newTransferServer()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/articles/import", strings.NewReader(`{"title": "Seed", "content": "x", "tag": {"name": "go"}}`))
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	// the id and the timestamps of the body are not the client's to set
+	body := `{"id": 42, "title": "Mass", "content": "x", "tag_id": 1, "created_at": "2001-01-01T00:00:00Z", "updated_at": "2001-01-01T00:00:00Z"}`
+	req = httptest.NewRequest(http.MethodPost, "/api/articles", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	var article domain.Article
+	if err := json.Unmarshal(rec.Body.Bytes(), &article); err != nil || rec.Code != http.StatusCreated {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+	if article.ID != 2 || article.CreatedAt.Year() == 2001 || article.UpdatedAt.Year() == 2001 {
+		t.Fatalf("the client set the server fields %+v", article)
+	}
+	if article.Slug != "mass" || article.Tag.Name != "go" {
+		t.Fatalf("unexpected article %+v", article)
+	}
+
+	req = httptest.NewRequest(http.MethodPatch, "/api/articles/2", strings.NewReader(`{"id": 1, "title": "Mass", "content": "y", "created_at": "2001-01-01T00:00:00Z"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	article = domain.Article{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &article); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+	if article.ID != 2 || article.Title != "Mass" || article.Content != "y" || article.CreatedAt.Year() == 2001 {
+		t.Fatalf("unexpected article %+v", article)
+	}
+}
+
+func TestFetchArticleFilters(t *testing.T) {
+	e := newTransferServer()
+
+	body := `{"title": "Alpha", "content": "x", "tag": {"name": "go"}}
+{"title": "Beta", "content": "x", "tag": {"name": "go"}}
+{"title": "Gamma", "content": "x", "tag": {"name": "rust"}}`
+	req := httptest.NewRequest(http.MethodPost, "/api/articles/import", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, "application/x-ndjson")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+
+	titles := func(target string) []string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		var articles []domain.Article
+		if err := json.Unmarshal(rec.Body.Bytes(), &articles); err != nil || rec.Code != http.StatusOK {
+			t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+		}
+		res := []string{}
+		for _, a := range articles {
+			res = append(res, a.Title)
+		}
+		return res
+	}
+
+	if got := titles("/api/articles?tag=go&sort=-title"); strings.Join(got, ",") != "Beta,Alpha" {
+		t.Fatalf("unexpected listing %v", got)
+	}
+	if got := titles("/api/articles?q=gam&created_after=2000-01-01T00:00:00Z"); strings.Join(got, ",") != "Gamma" {
+		t.Fatalf("unexpected listing %v", got)
+	}
+	if got := titles("/api/articles?tag=missing"); len(got) != 0 {
+		t.Fatalf("unexpected listing %v", got)
+	}
+
+	for _, target := range []string{
+		"/api/articles?sort=id",
+		"/api/articles?created_after=yesterday",
+		"/api/articles?tag_id=abc",
+		"/api/articles?sort=title&cursor=bm90IGEgY3Vyc29y",
+	} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s answered %d, want 400", target, rec.Code)
+		}
+	}
+}
+
+func TestFetchArticleEnvelope(t *testing.T) {
+	e := newTransferServer()
+
+	body := `{"title": "Alpha", "content": "x", "tag": {"name": "go"}}
+{"title": "Beta", "content": "x", "tag": {"name": "go"}}
+{"title": "Gamma", "content": "x", "tag": {"name": "rust"}}`
+	req := httptest.NewRequest(http.MethodPost, "/api/articles/import", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, "application/x-ndjson")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	serve := func(target string) (res struct {
+		helper.Response
+		Data []domain.Article `json:"data"`
+	}) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set(echo.HeaderAccept, `application/json; profile="envelope"`)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil || rec.Code != http.StatusOK || res.Meta == nil || res.Links == nil {
+			t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+		}
+		return
+	}
+
+	res := serve("/api/articles?tag=go&num=1")
+	if len(res.Data) != 1 || res.Data[0].Title != "Alpha" || res.Meta.Total != 2 || res.Meta.PageSize != 1 {
+		t.Fatalf("unexpected page %+v %+v", res.Data, res.Meta)
+	}
+	if res.Links.Next == "" || res.Links.First != "/api/articles?num=1&tag=go" {
+		t.Fatalf("unexpected links %+v", res.Links)
+	}
+
+	res = serve(res.Links.Next)
+	if len(res.Data) != 1 || res.Data[0].Title != "Beta" || res.Meta.Cursor == "" || res.Meta.Total != 2 {
+		t.Fatalf("unexpected page %+v %+v", res.Data, res.Meta)
+	}
+	if res.Links.Prev != "/api/articles?num=1&tag=go" {
+		t.Fatalf("unexpected links %+v", res.Links)
+	}
+
+	// the last, empty page links back to the page of Beta
+	cursor := res.Meta.Cursor
+	res = serve(res.Links.Next)
+	if len(res.Data) != 0 || res.Links.Prev != "/api/articles?cursor="+url.QueryEscape(cursor)+"&num=1&tag=go" {
+		t.Fatalf("unexpected page %+v %+v", res.Data, res.Links)
+	}
+	if prev := serve(res.Links.Prev); len(prev.Data) != 1 || prev.Data[0].Title != "Beta" {
+		t.Fatalf("unexpected previous page %+v", prev.Data)
+	}
+
+	res = serve("/api/tags/2/articles")
+	if len(res.Data) != 1 || res.Meta.Total != 1 || res.Meta.PageSize != domain.DefaultPageSize || res.Links.Next != "" {
+		t.Fatalf("unexpected page %+v %+v %+v", res.Data, res.Meta, res.Links)
+	}
+
+	rec := httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/api/articles/9", nil)
+	req.Header.Set(echo.HeaderAccept, "application/json; version=2")
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound || !strings.HasPrefix(rec.Body.String(), `{"code":404,"status":"Error","data":{"message":`) {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestFetchTagArticles(t *testing.T) {
+	e := newTransferServer()
+
+	body := `{"title": "Alpha", "content": "x", "tag": {"name": "go"}}
+{"title": "Beta", "content": "x", "tag": {"name": "go"}}
+{"title": "Gamma", "content": "x", "tag": {"name": "rust"}}`
+	req := httptest.NewRequest(http.MethodPost, "/api/articles/import", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, "application/x-ndjson")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/tags/1/articles?num=1&sort=-title", nil))
+	var articles []domain.Article
+	if err := json.Unmarshal(rec.Body.Bytes(), &articles); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+	if len(articles) != 1 || articles[0].Title != "Beta" || articles[0].Tag.Name != "go" {
+		t.Fatalf("unexpected listing %+v", articles)
+	}
+
+	cursor := rec.Header().Get("X-Cursor")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/tags/1/articles?num=1&sort=-title&tag_id=2&cursor="+cursor, nil))
+	if err := json.Unmarshal(rec.Body.Bytes(), &articles); err != nil || len(articles) != 1 || articles[0].Title != "Alpha" {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+
+	// rust below go, the articles of go include the ones of rust
+	req = httptest.NewRequest(http.MethodPatch, "/api/tags/2", strings.NewReader(`{"name": "rust", "parent_id": 1}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+	for target, want := range map[string]int{
+		"/api/tags/1/articles?include_descendants=true": 3,
+		"/api/articles?tag=go&include_descendants=1":    3,
+		"/api/articles?tag=rust&include_descendants=1":  1,
+		"/api/tags/1/articles":                          2,
+	} {
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if err := json.Unmarshal(rec.Body.Bytes(), &articles); err != nil || len(articles) != want {
+			t.Errorf("%s answered %d %s, want %d articles", target, rec.Code, rec.Body.String(), want)
+		}
+	}
+
+	for target, status := range map[string]int{
+		"/api/tags/1/articles?include_descendants=maybe": http.StatusBadRequest,
+		"/api/tags/9/articles":                           http.StatusNotFound,
+		"/api/tags/abc/articles":                         http.StatusNotFound,
+		"/api/tags/1/articles?sort=id":                   http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != status {
+			t.Errorf("%s answered %d, want %d", target, rec.Code, status)
+		}
+	}
+}
+
+func TestAPIVersions(t *testing.T) {
+	e := newTransferServer()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/articles/import", strings.NewReader(`{"title": "Seed", "content": "x", "tag": {"name": "go"}}`))
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	// v1 and its /api alias keep the single tag and announce their sunset
+	for _, target := range []string{"/api/articles/1", "/api/v1/articles/1"} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		var article domain.Article
+		if err := json.Unmarshal(rec.Body.Bytes(), &article); err != nil || rec.Code != http.StatusOK || article.Tag.Name != "go" {
+			t.Fatalf("%s answered %d %s", target, rec.Code, rec.Body.String())
+		}
+		header := rec.Header()
+		if header.Get("Deprecation") == "" || header.Get("Sunset") == "" || header.Get("Link") != `</api/v2/articles/1>; rel="successor-version"` {
+			t.Fatalf("%s answered the headers %v", target, header)
+		}
+	}
+
+	// v2 always answers the envelope and lists the tags
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Header().Get("Deprecation") != "" {
+			t.Fatalf("%s is deprecated", target)
+		}
+		return rec
+	}
+	var res struct {
+		helper.Response
+		Data struct {
+			ID   int64                    `json:"id"`
+			Tags []map[string]interface{} `json:"tags"`
+		} `json:"data"`
+	}
+	rec := serve(http.MethodPost, "/api/v2/articles", `{"title": "Second", "content": "x", "tag_ids": [1]}`)
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil || rec.Code != http.StatusCreated || res.Status != "Ok" {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+	if len(res.Data.Tags) != 1 || res.Data.Tags[0]["name"] != "go" || strings.Contains(rec.Body.String(), `"tag"`) {
+		t.Fatalf("unexpected article %s", rec.Body.String())
+	}
+
+	for body, status := range map[string]int{
+		`{"title": "Third", "content": "x", "tag_id": 1}`:       http.StatusBadRequest,
+		`{"title": "Third", "content": "x", "tag_ids": [1, 2]}`: http.StatusBadRequest,
+		`{"title": "Third", "content": "x", "tag_ids": [9]}`:    http.StatusNotFound,
+		`{"title": "Third", "content": "x", "tag_ids": ["go"]}`: http.StatusUnprocessableEntity,
+	} {
+		if rec := serve(http.MethodPost, "/api/v2/articles", body); rec.Code != status {
+			t.Errorf("%s answered %d %s, want %d", body, rec.Code, rec.Body.String(), status)
+		}
+	}
+
+	rec = serve(http.MethodPatch, "/api/v2/articles/2", `{"title": "Renamed"}`)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"tags":[{"id":1`) {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = serve(http.MethodGet, "/api/v2/articles/by-slug/second", "")
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get(echo.HeaderLocation) != "/api/v2/articles/by-slug/renamed" {
+		t.Fatalf("unexpected response %d %v", rec.Code, rec.Header())
+	}
+
+	rec = serve(http.MethodGet, "/api/v2/tags/1/articles", "")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Body.String(), `{"code":200,"status":"Ok","data":[{`) || !strings.Contains(rec.Body.String(), `"meta"`) {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	e := newTransferServer()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/articles/import", strings.NewReader(`{"title": "Seed", "content": "x", "tag": {"name": "go"}}`))
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	serve := func(method, target, ifMatch, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve(http.MethodGet, "/api/articles/1", "", "")
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(etag, `"1-`) {
+		t.Fatalf("unexpected response %d %v", rec.Code, rec.Header())
+	}
+
+	rec = serve(http.MethodPatch, "/api/articles/1", etag, `{"title": "First"}`)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("ETag"), `"2-`) {
+		t.Fatalf("unexpected response %d %v %s", rec.Code, rec.Header(), rec.Body.String())
+	}
+
+	// a stale or weak validator does not match, the article is left as it is
+	for _, ifMatch := range []string{etag, `"1"`, `W/"2"`} {
+		for _, method := range []string{http.MethodPatch, http.MethodDelete} {
+			if rec := serve(method, "/api/articles/1", ifMatch, `{"title": "Stale"}`); rec.Code != http.StatusPreconditionFailed {
+				t.Errorf("%s with If-Match %s answered %d %s", method, ifMatch, rec.Code, rec.Body.String())
+			}
+		}
+	}
+
+	rec = serve(http.MethodPatch, "/api/articles/1", `*`, `{"title": "Second"}`)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("ETag"), `"3-`) {
+		t.Fatalf("unexpected response %d %v %s", rec.Code, rec.Header(), rec.Body.String())
+	}
+
+	rec = serve(http.MethodGet, "/api/v2/articles/1", "", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"version":3`) {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := serve(http.MethodDelete, "/api/articles/1", `"3"`, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := serve(http.MethodGet, "/api/articles/1", "", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("the article was not deleted, got %d", rec.Code)
+	}
+}
+
+func TestConditionalGet(t *testing.T) {
+	e := newTransferServer()
+
+	body := `{"title": "Alpha", "content": "x", "tag": {"name": "go"}}
+{"title": "Beta", "content": "x", "tag": {"name": "go"}}`
+	req := httptest.NewRequest(http.MethodPost, "/api/articles/import", strings.NewReader(body))
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	serve := func(method, target string, header map[string]string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		for name, value := range header {
+			req.Header.Set(name, value)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve(http.MethodGet, "/api/articles/1", nil, "")
+	etag, lastModified := rec.Header().Get("ETag"), rec.Header().Get("Last-Modified")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(etag, `"1-`) || lastModified == "" {
+		t.Fatalf("unexpected response %d %v", rec.Code, rec.Header())
+	}
+	for _, header := range []map[string]string{
+		{"If-None-Match": etag},
+		{"If-None-Match": `"9", W/` + etag},
+		{"If-Modified-Since": lastModified},
+	} {
+		for _, target := range []string{"/api/articles/1?render=html", "/api/articles/by-slug/alpha"} {
+			if rec := serve(http.MethodGet, target, header, ""); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+				t.Errorf("%s with %v answered %d %s", target, header, rec.Code, rec.Body.String())
+			}
+		}
+	}
+
+	// the enveloped representations have their own validator
+	enveloped := map[string]string{"If-None-Match": etag, "Accept": `application/json; profile="envelope"`}
+	for _, target := range []string{"/api/v2/articles/by-slug/alpha", "/api/v1/articles/1"} {
+		if rec := serve(http.MethodGet, target, enveloped, ""); rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+			t.Errorf("%s answered %d with the ETag %s of the plain article", target, rec.Code, rec.Header().Get("ETag"))
+		}
+	}
+
+	// the article embeds its tag, a change of the tag changes the validator of the article
+	if rec := serve(http.MethodPatch, "/api/tags/1", nil, `{"name": "golang"}`); rec.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+	rec = serve(http.MethodGet, "/api/articles/1", map[string]string{"If-None-Match": etag}, "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"golang"`) {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+	etag = rec.Header().Get("ETag")
+
+	rec = serve(http.MethodGet, "/api/v2/articles", nil, "")
+	pageETag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(pageETag, `W/"`) {
+		t.Fatalf("unexpected response %d %v", rec.Code, rec.Header())
+	}
+	if rec := serve(http.MethodGet, "/api/v2/articles", map[string]string{"If-None-Match": pageETag}, ""); rec.Code != http.StatusNotModified {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+
+	// an update changes the validators of the article and of its pages
+	if rec := serve(http.MethodPatch, "/api/articles/1", nil, `{"title": "Alpha", "content": "y"}`); rec.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := serve(http.MethodGet, "/api/articles/1", map[string]string{"If-None-Match": etag}, ""); rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("ETag"), `"2-`) {
+		t.Fatalf("unexpected response %d %v", rec.Code, rec.Header())
+	}
+	rec = serve(http.MethodGet, "/api/v2/articles", map[string]string{"If-None-Match": pageETag}, "")
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == pageETag {
+		t.Fatalf("unexpected response %d %v", rec.Code, rec.Header())
+	}
+	if rec := serve(http.MethodGet, "/api/tags/1/articles", map[string]string{"If-None-Match": pageETag}, ""); rec.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,129 @@
+package http
+
+import (
+	"fmt"
+	"go-postgres-clean-arch/domain"
+	"go-postgres-clean-arch/helper"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+const (
+	headerETag = "ETag"
+	// headerIfMatch makes an update or a delete conditional on the ETag of the article
+	headerIfMatch = "If-Match"
+)
+
+// articleETag is the strong validator of the article answered to c, "<version>-<hash>". The version
+// changes with every update of the article, the hash with the tag the article embeds and with the
+// envelope, so the plain and the enveloped representations of the article have their own ETag.
+func articleETag(c echo.Context, a domain.Article) string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d %q %d %t", a.Tag.ID, a.Tag.Name, a.Tag.UpdatedAt.UnixNano(), helper.Enveloped(c))
+	return `"` + strconv.FormatInt(a.Version, 10) + "-" + strconv.FormatUint(h.Sum64(), 16) + `"`
+}
+
+// articleLastModified is the latest change of the article or of the tag it embeds
+func articleLastModified(a domain.Article) time.Time {
+	if a.Tag.UpdatedAt.After(a.UpdatedAt) {
+		return a.Tag.UpdatedAt
+	}
+	return a.UpdatedAt
+}
+
+// setArticleValidators will answer the ETag and the Last-Modified of the article the response carries
+func setArticleValidators(c echo.Context, a domain.Article) {
+	header := c.Response().Header()
+	header.Set(headerETag, articleETag(c, a))
+	if lastModified := articleLastModified(a); !lastModified.IsZero() {
+		header.Set(echo.HeaderLastModified, lastModified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// articleNotModified will answer the validators of the article, it reports whether the request
+// already has this version of the article and can be answered with 304 Not Modified
+func articleNotModified(c echo.Context, a domain.Article) bool {
+	setArticleValidators(c, a)
+	return notModified(c.Request(), articleETag(c, a), articleLastModified(a))
+}
+
+// articlesETag is the weak validator of a page of articles. It is computed from the ids, the versions
+// and the tags of the articles rather than from the body, a page is revalidated without rendering it.
+// The next cursor and the total of the enveloped pages change it too. The pages have no Last-Modified,
+// the removal of an article would not move it.
+func articlesETag(c echo.Context, articles []domain.Article, nextCursor string) string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%q", nextCursor)
+	if meta, ok := helper.PageMeta(c); ok {
+		fmt.Fprintf(h, " %d", meta.Total)
+	}
+	for _, a := range articles {
+		fmt.Fprintf(h, "\n%d %d %d %q", a.ID, a.Version, a.Tag.ID, a.Tag.Name)
+	}
+	return `W/"` + strconv.FormatUint(h.Sum64(), 16) + `"`
+}
+
+// articlesNotModified will answer the validator of the page, it reports whether the request
+// already has this page and can be answered with 304 Not Modified
+func articlesNotModified(c echo.Context, articles []domain.Article, nextCursor string) bool {
+	etag := articlesETag(c, articles, nextCursor)
+	c.Response().Header().Set(headerETag, etag)
+	return notModified(c.Request(), etag, time.Time{})
+}
+
+// ifMatch evaluates the If-Match header of the request against the current article. It returns the
+// version the change applies to, 0 without the header or with *, and false when no ETag of the
+// header matches. Only the version of the ETags is compared, a change of the tag does not conflict
+// with the change of the article, and "<version>" alone matches too. A weak ETag never matches,
+// as with the strong comparison of RFC 9110.
+func ifMatch(r *http.Request, current domain.Article) (version int64, ok bool) {
+	header := r.Header.Get(headerIfMatch)
+	if header == "" {
+		return 0, true
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return 0, true
+		}
+		if !strings.HasPrefix(candidate, `"`) || !strings.HasSuffix(candidate, `"`) || len(candidate) < 2 {
+			continue
+		}
+		v, _, _ := strings.Cut(candidate[1:len(candidate)-1], "-")
+		if v == strconv.FormatInt(current.Version, 10) {
+			return current.Version, true
+		}
+	}
+	return 0, false
+}
+
+// notModified evaluates the conditional headers of the request, If-None-Match takes precedence
+// over If-Modified-Since as in RFC 7232
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	ims := r.Header.Get(echo.HeaderIfModifiedSince)
+	if ims == "" || lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	// the header has a second precision
+	return !lastModified.Truncate(time.Second).After(since)
+}
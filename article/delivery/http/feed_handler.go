@@ -0,0 +1,102 @@
+package http
+
+import (
+	"bytes"
+	"go-postgres-clean-arch/article/feed"
+	"go-postgres-clean-arch/article/render"
+	"go-postgres-clean-arch/domain"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo"
+)
+
+const (
+	atomContentType = "application/atom+xml; charset=utf-8"
+	rssContentType  = "application/rss+xml; charset=utf-8"
+)
+
+// FeedHandler represent the httphandler for the Atom and RSS feeds of the articles
+type FeedHandler struct {
+	AUsecase domain.ArticleUsecase
+	TUsecase domain.TagUseCase
+	Renderer *render.Renderer
+}
+
+// NewArticleFeedHandler will initialize the feeds/ resources endpoint
+func NewArticleFeedHandler(e *echo.Echo, au domain.ArticleUsecase, tu domain.TagUseCase) {
+	handler := &FeedHandler{
+		AUsecase: au,
+		TUsecase: tu,
+		Renderer: render.NewRenderer(render.DefaultCacheSize),
+	}
+
+	feedsRouter := e.Group("/feeds")
+	feedsRouter.GET("/articles.atom", handler.ArticlesAtom)
+	feedsRouter.GET("/articles.rss", handler.ArticlesRSS)
+	// echo params span the whole path segment, the handler strips the .atom extension itself
+	feedsRouter.GET("/tags/:feed", handler.TagAtom)
+}
+
+// ArticlesAtom will write the Atom feed of the newest articles
+func (f *FeedHandler) ArticlesAtom(c echo.Context) error {
+	return f.serve(c, "Articles", 0, atomContentType, feed.WriteAtom)
+}
+
+// ArticlesRSS will write the RSS 2.0 feed of the newest articles
+func (f *FeedHandler) ArticlesRSS(c echo.Context) error {
+	return f.serve(c, "Articles", 0, rssContentType, feed.WriteRSS)
+}
+
+// TagAtom will write the Atom feed of the newest articles of the tag named in the path
+func (f *FeedHandler) TagAtom(c echo.Context) error {
+	name := strings.TrimSuffix(c.Param("feed"), ".atom")
+	if name == c.Param("feed") || name == "" {
+		return c.JSON(http.StatusNotFound, ResponseError{Message: domain.ErrNotFound.Error()})
+	}
+
+	tag, err := f.TUsecase.FetchByName(c.Request().Context(), name)
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+	return f.serve(c, "Articles tagged "+tag.Name, tag.ID, atomContentType, feed.WriteAtom)
+}
+
+func (f *FeedHandler) serve(c echo.Context, title string, tagID int64, contentType string, write func(io.Writer, feed.Feed, feed.Link, feed.Content) error) error {
+	doc, err := feed.Collect(c.Request().Context(), f.AUsecase, title, tagID, feed.DefaultSize)
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	// the validators only need the listing, a revalidated feed renders no article
+	etag := doc.ETag()
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, contentType)
+	header.Set(headerETag, etag)
+	if !doc.Updated.IsZero() {
+		header.Set(echo.HeaderLastModified, doc.Updated.UTC().Format(http.TimeFormat))
+	}
+	if notModified(c.Request(), etag, doc.Updated) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	base := baseURL(c)
+	link := feed.Link{
+		Self: base + c.Request().URL.Path,
+		Article: func(a domain.Article) string {
+			return base + "/api/articles/" + strconv.FormatInt(a.ID, 10)
+		},
+	}
+	content := func(a domain.Article) (string, error) {
+		res, err := f.Renderer.Render(a)
+		return res.HTML, err
+	}
+
+	var buf bytes.Buffer
+	if err = write(&buf, doc, link, content); err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+	return c.Blob(http.StatusOK, contentType, buf.Bytes())
+}
@@ -0,0 +1,114 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	articleHttp "go-postgres-clean-arch/article/delivery/http"
+	articleMemory "go-postgres-clean-arch/article/repository/memory"
+	"go-postgres-clean-arch/article/transfer"
+	articleUcase "go-postgres-clean-arch/article/usecase"
+	tagMemory "go-postgres-clean-arch/tag/repository/memory"
+	tagUcase "go-postgres-clean-arch/tag/usecase"
+
+	"github.com/labstack/echo"
+)
+
+func newFeedServer(t *testing.T) *echo.Echo {
+	tagRepo := tagMemory.NewMemoryTagRepository()
+	au := articleUcase.NewArticleUsecase(articleMemory.NewMemoryArticleRepository(), tagRepo, time.Second)
+	tu := tagUcase.NewTagUsecase(tagRepo, time.Second, nil)
+
+	records := `{"title": "Go one", "content": "# Go", "tag": {"name": "go"}, "created_at": "2023-01-01T00:00:00Z"}
+{"title": "Sql one", "content": "select", "tag": {"name": "sql"}, "created_at": "2023-01-02T00:00:00Z"}
+`
+	r, err := transfer.NewReader(strings.NewReader(records), transfer.FormatNDJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = transfer.NewImporter(au, tu).Import(context.Background(), r, transfer.ImportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	e := echo.New()
+	articleHttp.NewArticleFeedHandler(e, au, tu)
+	return e
+}
+
+func getFeed(e *echo.Echo, path string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestFeeds(t *testing.T) {
+	e := newFeedServer(t)
+
+	rec := getFeed(e, "/feeds/articles.atom", nil)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get(echo.HeaderContentType), "application/atom+xml") {
+		t.Fatalf("unexpected response %d %v", rec.Code, rec.Header())
+	}
+	body := rec.Body.String()
+	if strings.Index(body, "Sql one") > strings.Index(body, "Go one") || !strings.Contains(body, "http://example.com/api/articles/1") {
+		t.Fatalf("unexpected feed:\n%s", body)
+	}
+
+	rec = getFeed(e, "/feeds/articles.rss", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `<rss version="2.0"`) {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = getFeed(e, "/feeds/tags/go.atom", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Go one") || strings.Contains(rec.Body.String(), "Sql one") {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+
+	for _, path := range []string{"/feeds/tags/missing.atom", "/feeds/tags/go.rss"} {
+		if rec = getFeed(e, path, nil); rec.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected 404, got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestFeedConditionalRequests(t *testing.T) {
+	e := newFeedServer(t)
+
+	rec := getFeed(e, "/feeds/articles.atom", nil)
+	etag, lastModified := rec.Header().Get("ETag"), rec.Header().Get(echo.HeaderLastModified)
+	if etag == "" || lastModified == "" {
+		t.Fatalf("missing validators %v", rec.Header())
+	}
+
+	tests := []struct {
+		header map[string]string
+		code   int
+	}{
+		{map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+		{map[string]string{"If-None-Match": `"other", ` + etag}, http.StatusNotModified},
+		{map[string]string{"If-None-Match": `"other"`, echo.HeaderIfModifiedSince: lastModified}, http.StatusOK},
+		{map[string]string{echo.HeaderIfModifiedSince: lastModified}, http.StatusNotModified},
+		{map[string]string{echo.HeaderIfModifiedSince: "Mon, 02 Jan 2006 15:04:05 GMT"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec = getFeed(e, "/feeds/articles.atom", tt.header)
+		if rec.Code != tt.code {
+			t.Fatalf("%v: expected %d, got %d", tt.header, tt.code, rec.Code)
+		}
+		if tt.code == http.StatusNotModified && rec.Body.Len() != 0 {
+			t.Fatalf("%v: 304 with a body %q", tt.header, rec.Body.String())
+		}
+	}
+
+	// the tag feed has its own validator
+	if rec = getFeed(e, "/feeds/tags/go.atom", map[string]string{"If-None-Match": etag}); rec.Code != http.StatusOK {
+		t.Fatalf("expected the tag feed to differ, got %d", rec.Code)
+	}
+}
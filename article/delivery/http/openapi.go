@@ -0,0 +1,340 @@
+package http
+
+import (
+	"go-postgres-clean-arch/article/transfer"
+	"go-postgres-clean-arch/data/request"
+	"go-postgres-clean-arch/data/response"
+	"go-postgres-clean-arch/domain"
+	"go-postgres-clean-arch/helper"
+	"go-postgres-clean-arch/openapi"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenAPIRoutes describes the routes registered by NewArticleHandler, NewArticleTransferHandler,
+// NewArticleFeedHandler, NewArticleSitemapHandler and NewTagArticlesHandler
+func OpenAPIRoutes() []openapi.Route {
+	var routes []openapi.Route
+	for _, version := range helper.APIVersions {
+		for _, prefix := range version.Prefixes() {
+			routes = append(routes, apiRoutes(version, prefix)...)
+		}
+	}
+
+	return append(routes,
+		feedRoute("/feeds/articles.atom", "articlesAtomFeed", "Atom feed of the newest articles", atomContentType),
+		feedRoute("/feeds/articles.rss", "articlesRSSFeed", "RSS 2.0 feed of the newest articles", rssContentType),
+		feedRoute("/feeds/tags/:feed", "tagAtomFeed", "Atom feed of the newest articles of a tag, the path ends with <tag name>.atom", atomContentType),
+		openapi.Route{
+			Method:      http.MethodGet,
+			Path:        "/sitemap.xml",
+			OperationID: "sitemap",
+			Summary:     "Sitemap of the articles, a sitemap index once they pass 50,000",
+			Tag:         "sitemap",
+			Replies: []openapi.Reply{
+				{Status: http.StatusOK, Body: "", ContentType: "application/xml"},
+				{Status: http.StatusInternalServerError, Body: ResponseError{}},
+			},
+		},
+		openapi.Route{
+			Method:      http.MethodGet,
+			Path:        "/sitemaps/:file",
+			OperationID: "sitemapFile",
+			Summary:     "File of the sitemap index, named sitemap-<n>.xml",
+			Tag:         "sitemap",
+			Replies: []openapi.Reply{
+				{Status: http.StatusOK, Body: "", ContentType: "application/xml"},
+				{Status: http.StatusNotFound, Body: ResponseError{}},
+				{Status: http.StatusInternalServerError, Body: ResponseError{}},
+			},
+		},
+	)
+}
+
+// apiRoutes describes the routes of the version of the API mounted under prefix
+func apiRoutes(version helper.APIVersion, prefix string) []openapi.Route {
+	const tag = "articles"
+	// the routes of v2 answer every JSON body in the envelope
+	var article, articles, errorBody, messageBody, report, createBody, updateBody interface{}
+	if version == helper.APIv1 {
+		article, articles = response.ArticleResponse{}, []response.ArticleResponse{}
+		errorBody, messageBody, report = ResponseError{}, "", transfer.Report{}
+		createBody, updateBody = request.CreateArticlesRequest{}, request.UpdateArticlesRequest{}
+	} else {
+		article, articles = helper.Envelope[response.ArticleV2Response]{}, helper.Envelope[[]response.ArticleV2Response]{}
+		errorBody, messageBody, report = helper.Envelope[ResponseError]{}, helper.Envelope[string]{}, helper.Envelope[transfer.Report]{}
+		createBody, updateBody = request.CreateArticlesV2Request{}, request.UpdateArticlesV2Request{}
+	}
+	errorReply := func(status int) openapi.Reply {
+		return openapi.Reply{Status: status, Body: errorBody}
+	}
+	formats := []interface{}{transfer.FormatNDJSON, transfer.FormatJSON, transfer.FormatCSV}
+	invalidReply := openapi.Reply{Status: http.StatusBadRequest, Description: "Validation error message", Body: messageBody}
+	unprocessableReply := openapi.Reply{Status: http.StatusUnprocessableEntity, Description: "Malformed request body", Body: messageBody}
+	idempotencyKeyHeader := openapi.Param{Name: "Idempotency-Key", Description: "Key sent again with the retries of the request, a retry is answered the response of the first request"}
+	replayedHeader := openapi.Param{Name: "Idempotent-Replayed", Description: "Set to true on the response replayed for a retry"}
+	storeUnprocessableReply := openapi.Reply{Status: http.StatusUnprocessableEntity, Description: "Malformed request body, or an Idempotency-Key used for another request", Body: messageBody}
+	storeConflictReply := openapi.Reply{Status: http.StatusConflict, Description: "The item already exists, or the first request with the Idempotency-Key is still processed", Body: errorBody}
+	sorts := make([]interface{}, 0, len(domain.ArticleSorts))
+	for _, sort := range domain.ArticleSorts {
+		sorts = append(sorts, string(sort))
+	}
+	etagHeader := openapi.Param{Name: "ETag", Description: "Strong validator of the article and of its tag, \"<version>-<hash>\", the enveloped article has its own"}
+	lastModifiedHeader := openapi.Param{Name: "Last-Modified", Description: "Latest updated_at of the article and of its tag"}
+	pageETagHeader := openapi.Param{Name: "ETag", Description: "Weak validator of the articles of the page"}
+	ifNoneMatchHeader := openapi.Param{Name: "If-None-Match", Description: "ETag of a previous response"}
+	ifModifiedSinceHeader := openapi.Param{Name: "If-Modified-Since", Description: "Last-Modified of a previous response, ignored with If-None-Match"}
+	notModifiedReply := openapi.Reply{Status: http.StatusNotModified, Description: "The response did not change since the previous one"}
+	ifMatchHeader := openapi.Param{Name: "If-Match", Description: "ETag of the version the change applies to, only its version is compared. * or no header applies it to any version"}
+	descendantsParam := openapi.Param{Name: "include_descendants", Description: "With a tag, also the articles of the visible tags below it", Type: false}
+	renderParam := openapi.Param{Name: "render", Description: "Set to html to add the sanitized html, toc and reading_time fields", Enum: []interface{}{renderHTML}}
+
+	routes := []openapi.Route{
+		{
+			Method:      http.MethodGet,
+			Path:        prefix + "/articles",
+			OperationID: version.OperationID(prefix, "fetchArticles"),
+			Summary:     "List articles ordered by creation time",
+			Tag:         tag,
+			Headers:     []openapi.Param{ifNoneMatchHeader},
+			Query: []openapi.Param{
+				{Name: "num", Description: "Page size, default to 10", Type: int64(0)},
+				{Name: "cursor", Description: "Cursor returned by the previous page in X-Cursor, only valid for the same sort"},
+				{Name: "prev_cursor", Description: "Cursor of the page before, set by the next link of the envelope so the page links back to it"},
+				{Name: "tag_id", Description: "Only the articles of the tag", Type: int64(0)},
+				{Name: "tag", Description: "Only the articles of the tag with this name"},
+				descendantsParam,
+				{Name: "created_after", Description: "Only the articles created after this RFC 3339 timestamp", Type: time.Time{}},
+				{Name: "created_before", Description: "Only the articles created before this RFC 3339 timestamp", Type: time.Time{}},
+				{Name: "updated_since", Description: "Only the articles updated at or after this RFC 3339 timestamp", Type: time.Time{}},
+				{Name: "q", Description: "Case insensitive prefix of the title"},
+				{Name: "sort", Description: "Order of the listing, a leading dash sorts in descending order, default to created_at", Enum: sorts},
+				renderParam,
+			},
+			Replies: []openapi.Reply{
+				{
+					Status:  http.StatusOK,
+					Body:    articles,
+					Headers: []openapi.Param{{Name: "X-Cursor", Description: "Cursor of the next page, empty on the last page"}, pageETagHeader},
+				},
+				notModifiedReply,
+				errorReply(http.StatusBadRequest),
+				errorReply(http.StatusInternalServerError),
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        prefix + "/articles",
+			OperationID: version.OperationID(prefix, "storeArticle"),
+			Summary:     "Create an article",
+			Tag:         tag,
+			Headers:     []openapi.Param{idempotencyKeyHeader},
+			Body:        createBody,
+			Replies: []openapi.Reply{
+				{Status: http.StatusCreated, Body: article, Headers: []openapi.Param{etagHeader, lastModifiedHeader, replayedHeader}},
+				invalidReply,
+				errorReply(http.StatusNotFound),
+				storeConflictReply,
+				storeUnprocessableReply,
+				errorReply(http.StatusInternalServerError),
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        prefix + "/articles/:articleId",
+			OperationID: version.OperationID(prefix, "getArticle"),
+			Summary:     "Get an article by id",
+			Tag:         tag,
+			Headers:     []openapi.Param{ifNoneMatchHeader, ifModifiedSinceHeader},
+			Query:       []openapi.Param{renderParam},
+			Replies: []openapi.Reply{
+				{Status: http.StatusOK, Body: article, Headers: []openapi.Param{etagHeader, lastModifiedHeader}},
+				notModifiedReply,
+				errorReply(http.StatusBadRequest),
+				errorReply(http.StatusNotFound),
+				errorReply(http.StatusInternalServerError),
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        prefix + "/articles/by-slug/:slug",
+			OperationID: version.OperationID(prefix, "getArticleBySlug"),
+			Summary:     "Get an article by slug, a previous slug redirects to the current one",
+			Tag:         tag,
+			Headers:     []openapi.Param{ifNoneMatchHeader, ifModifiedSinceHeader},
+			Query:       []openapi.Param{renderParam},
+			Replies: []openapi.Reply{
+				{Status: http.StatusOK, Body: article, Headers: []openapi.Param{etagHeader, lastModifiedHeader}},
+				notModifiedReply,
+				{
+					Status:      http.StatusMovedPermanently,
+					Description: "The slug was renamed",
+					Headers:     []openapi.Param{{Name: "Location", Description: "URL of the article under its current slug"}},
+				},
+				errorReply(http.StatusBadRequest),
+				errorReply(http.StatusNotFound),
+				errorReply(http.StatusInternalServerError),
+			},
+		},
+		{
+			Method:      http.MethodPatch,
+			Path:        prefix + "/articles/:articleId",
+			OperationID: version.OperationID(prefix, "updateArticle"),
+			Summary:     "Update an article, empty fields keep their current value",
+			Tag:         tag,
+			Headers:     []openapi.Param{ifMatchHeader},
+			Body:        updateBody,
+			Replies: []openapi.Reply{
+				{Status: http.StatusOK, Body: article, Headers: []openapi.Param{etagHeader, lastModifiedHeader}},
+				invalidReply,
+				errorReply(http.StatusNotFound),
+				errorReply(http.StatusConflict),
+				{Status: http.StatusPreconditionFailed, Description: "The article was changed since the version of If-Match", Body: errorBody},
+				unprocessableReply,
+				errorReply(http.StatusInternalServerError),
+			},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        prefix + "/articles/:articleId",
+			OperationID: version.OperationID(prefix, "deleteArticle"),
+			Summary:     "Delete an article",
+			Tag:         tag,
+			Headers:     []openapi.Param{ifMatchHeader},
+			Replies: []openapi.Reply{
+				{Status: http.StatusNoContent},
+				errorReply(http.StatusNotFound),
+				{Status: http.StatusPreconditionFailed, Description: "The article was changed since the version of If-Match", Body: errorBody},
+				errorReply(http.StatusInternalServerError),
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        prefix + "/articles/export",
+			OperationID: version.OperationID(prefix, "exportArticles"),
+			Summary:     "Stream every article with its tag",
+			Tag:         tag,
+			Query: []openapi.Param{
+				{Name: "format", Description: "Export format, default to ndjson", Enum: formats},
+			},
+			Replies: []openapi.Reply{
+				{
+					Status:      http.StatusOK,
+					Description: "One article per line, a JSON array with format=json or CSV rows with format=csv",
+					Body:        domain.Article{},
+					ContentType: "application/x-ndjson",
+				},
+				errorReply(http.StatusBadRequest),
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        prefix + "/articles/import",
+			OperationID: version.OperationID(prefix, "importArticles"),
+			Summary:     "Import articles, resolving or creating their tags by name",
+			Tag:         tag,
+			Query: []openapi.Param{
+				{Name: "format", Description: "Import format, default to the Content-Type or detected from the body", Enum: formats},
+				{Name: "dry_run", Description: "Only validate the articles", Type: false},
+			},
+			Body: []transfer.Record{},
+			Replies: []openapi.Reply{
+				{Status: http.StatusOK, Description: "Import report with the errors of the rejected rows", Body: report},
+				errorReply(http.StatusBadRequest),
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        prefix + "/articles/import/markdown",
+			OperationID: version.OperationID(prefix, "importMarkdownArticles"),
+			Summary:     "Import markdown files with a YAML front matter, upserting the articles by title",
+			Tag:         tag,
+			Query: []openapi.Param{
+				{Name: "dry_run", Description: "Only validate the articles", Type: false},
+			},
+			Body:     MarkdownUpload{},
+			BodyType: "multipart/form-data",
+			Replies: []openapi.Reply{
+				{Status: http.StatusOK, Description: "Import report with the errors of the rejected files", Body: report},
+				errorReply(http.StatusBadRequest),
+				errorReply(http.StatusRequestEntityTooLarge),
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        prefix + "/tags/:tagId/articles",
+			OperationID: version.OperationID(prefix, "fetchTagArticles"),
+			Summary:     "List the articles of a tag",
+			Tag:         tag,
+			Headers:     []openapi.Param{ifNoneMatchHeader},
+			Query: []openapi.Param{
+				{Name: "num", Description: "Page size, default to 10", Type: int64(0)},
+				{Name: "cursor", Description: "Cursor returned by the previous page in X-Cursor, only valid for the same sort"},
+				{Name: "prev_cursor", Description: "Cursor of the page before, set by the next link of the envelope so the page links back to it"},
+				descendantsParam,
+				{Name: "created_after", Description: "Only the articles created after this RFC 3339 timestamp", Type: time.Time{}},
+				{Name: "created_before", Description: "Only the articles created before this RFC 3339 timestamp", Type: time.Time{}},
+				{Name: "updated_since", Description: "Only the articles updated at or after this RFC 3339 timestamp", Type: time.Time{}},
+				{Name: "q", Description: "Case insensitive prefix of the title"},
+				{Name: "sort", Description: "Order of the listing, a leading dash sorts in descending order, default to created_at", Enum: sorts},
+				renderParam,
+			},
+			Replies: []openapi.Reply{
+				{
+					Status:  http.StatusOK,
+					Body:    articles,
+					Headers: []openapi.Param{{Name: "X-Cursor", Description: "Cursor of the next page, empty on the last page"}, pageETagHeader},
+				},
+				notModifiedReply,
+				errorReply(http.StatusBadRequest),
+				errorReply(http.StatusNotFound),
+				errorReply(http.StatusInternalServerError),
+			},
+		},
+	}
+
+	// v1 is frozen, its routes announce their sunset
+	for i := range routes {
+		routes[i].Deprecated = version == helper.APIv1
+	}
+	return routes
+}
+
+// feedRoute documents a feed route, feeds answer 304 when the conditional headers match
+func feedRoute(path, operationID, summary, contentType string) openapi.Route {
+	route := openapi.Route{
+		Method:      http.MethodGet,
+		Path:        path,
+		OperationID: operationID,
+		Summary:     summary,
+		Tag:         "feeds",
+		Headers: []openapi.Param{
+			{Name: "If-None-Match", Description: "ETag of a previous response"},
+			{Name: "If-Modified-Since", Description: "Last-Modified of a previous response"},
+		},
+		Replies: []openapi.Reply{
+			{
+				Status:      http.StatusOK,
+				Body:        "",
+				ContentType: contentType,
+				Headers: []openapi.Param{
+					{Name: "ETag", Description: "Validator of the listed articles"},
+					{Name: "Last-Modified", Description: "Latest updated_at of the listed articles"},
+				},
+			},
+			{Status: http.StatusNotModified, Description: "The feed did not change"},
+			{Status: http.StatusInternalServerError, Body: ResponseError{}},
+		},
+	}
+	if strings.HasPrefix(path, "/feeds/tags/") {
+		route.Replies = append(route.Replies, openapi.Reply{Status: http.StatusNotFound, Body: ResponseError{}})
+	}
+	return route
+}
+
+// MarkdownUpload documents the multipart form of the markdown import, a zip archive
+// can also be sent as the whole request body with the application/zip content type
+type MarkdownUpload struct {
+	Files [][]byte `json:"files"`
+}
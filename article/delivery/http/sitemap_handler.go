@@ -0,0 +1,76 @@
+package http
+
+import (
+	"bytes"
+	"go-postgres-clean-arch/article/sitemap"
+	"go-postgres-clean-arch/domain"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo"
+)
+
+const xmlContentType = "application/xml; charset=utf-8"
+
+// SitemapHandler represent the httphandler for the XML sitemap of the articles
+type SitemapHandler struct {
+	Sitemap *sitemap.Sitemap
+}
+
+// NewArticleSitemapHandler will initialize the sitemap.xml and sitemaps/ endpoints
+func NewArticleSitemapHandler(e *echo.Echo, s *sitemap.Sitemap) {
+	handler := &SitemapHandler{
+		Sitemap: s,
+	}
+
+	e.GET("/sitemap.xml", handler.Index)
+	e.GET("/sitemaps/:file", handler.File)
+}
+
+// Index will write the urlset of the articles, or the sitemap index once they need several files
+func (h *SitemapHandler) Index(c echo.Context) error {
+	ctx := c.Request().Context()
+	files, err := h.Sitemap.Files(ctx)
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+	if files == 1 {
+		return h.writeFile(c, 1)
+	}
+
+	base := baseURL(c)
+	var buf bytes.Buffer
+	err = h.Sitemap.WriteIndex(ctx, &buf, base, func(n int) string {
+		return base + "/sitemaps/" + sitemap.FileName(n)
+	})
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+	return c.Blob(http.StatusOK, xmlContentType, buf.Bytes())
+}
+
+// File will write one of the files listed by the sitemap index
+func (h *SitemapHandler) File(c echo.Context) error {
+	n, ok := sitemap.ParseFileName(c.Param("file"))
+	if !ok {
+		return c.JSON(http.StatusNotFound, ResponseError{Message: domain.ErrNotFound.Error()})
+	}
+	return h.writeFile(c, n)
+}
+
+func (h *SitemapHandler) writeFile(c echo.Context, n int) error {
+	base := baseURL(c)
+	var buf bytes.Buffer
+	err := h.Sitemap.WriteFile(c.Request().Context(), &buf, n, base, func(id int64) string {
+		return base + "/api/articles/" + strconv.FormatInt(id, 10)
+	})
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+	return c.Blob(http.StatusOK, xmlContentType, buf.Bytes())
+}
+
+// baseURL returns the scheme and host the request was sent to, e.g. https://example.com
+func baseURL(c echo.Context) string {
+	return c.Scheme() + "://" + c.Request().Host
+}
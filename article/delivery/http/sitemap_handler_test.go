@@ -0,0 +1,56 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	articleHttp "go-postgres-clean-arch/article/delivery/http"
+	articleMemory "go-postgres-clean-arch/article/repository/memory"
+	"go-postgres-clean-arch/article/sitemap"
+	"go-postgres-clean-arch/domain"
+
+	"github.com/labstack/echo"
+)
+
+func TestSitemap(t *testing.T) {
+	repo := articleMemory.NewMemoryArticleRepository()
+	s := sitemap.New(repo, 1)
+	tracked := s.Track(repo)
+	e := echo.New()
+	articleHttp.NewArticleSitemapHandler(e, s)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	if rec := get("/sitemap.xml"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "<urlset") {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+
+	now := time.Now()
+	for _, title := range []string{"one", "two"} {
+		a := &domain.CreateArticleInput{Title: title, Content: "c", TagID: 1, CreatedAt: now, UpdatedAt: now}
+		if err := tracked.Store(context.Background(), a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := get("/sitemap.xml")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "<loc>http://example.com/sitemaps/sitemap-2.xml</loc>") {
+		t.Fatalf("expected a sitemap index, got %d %s", rec.Code, rec.Body.String())
+	}
+	if rec = get("/sitemaps/sitemap-2.xml"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "<loc>http://example.com/api/articles/2</loc>") {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+	for _, path := range []string{"/sitemaps/sitemap-3.xml", "/sitemaps/other.xml"} {
+		if rec = get(path); rec.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected 404, got %d", path, rec.Code)
+		}
+	}
+}
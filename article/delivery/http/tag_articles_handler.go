@@ -0,0 +1,98 @@
+package http
+
+import (
+	"go-postgres-clean-arch/article/render"
+	"go-postgres-clean-arch/domain"
+	"go-postgres-clean-arch/helper"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo"
+)
+
+// TagArticlesHandler represent the httphandler for the articles of a tag
+type TagArticlesHandler struct {
+	AUsecase domain.ArticleUsecase
+	TUsecase domain.TagUseCase
+	Renderer *render.Renderer
+	// Version is the API version of the route of the handler
+	Version helper.APIVersion
+}
+
+// NewTagArticlesHandler will initialize the tags/:tagId/articles resources endpoint of every API version
+func NewTagArticlesHandler(e *echo.Echo, au domain.ArticleUsecase, tu domain.TagUseCase) {
+	renderer := render.NewRenderer(render.DefaultCacheSize)
+	for _, version := range helper.APIVersions {
+		handler := &TagArticlesHandler{
+			AUsecase: au,
+			TUsecase: tu,
+			Renderer: renderer,
+			Version:  version,
+		}
+
+		// the tags groups belong to the tag handler, the route is registered on the root
+		for _, prefix := range version.Prefixes() {
+			e.GET(prefix+"/tags/:tagId/articles", handler.FetchArticle, version.Middleware()...)
+		}
+	}
+}
+
+// FetchArticle will fetch the articles of the tag, the filters of the article listing apply
+func (h *TagArticlesHandler) FetchArticle(c echo.Context) error {
+	tagID, err := strconv.ParseInt(c.Param("tagId"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ResponseError{Message: domain.ErrNotFound.Error()})
+	}
+
+	numS := c.QueryParam("num")
+	num, _ := strconv.Atoi(numS)
+	cursor := c.QueryParam("cursor")
+	ctx := c.Request().Context()
+
+	withHTML, err := parseRender(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: err.Error()})
+	}
+
+	filter, err := parseArticleFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: err.Error()})
+	}
+	filter.TagID = tagID
+	filter.Tag = ""
+
+	// an unknown tag is a 404, not an empty listing
+	if _, err = h.TUsecase.FetchByID(ctx, tagID); err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	listAr, nextCursor, err := h.AUsecase.FetchFiltered(ctx, filter, cursor, int64(num))
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	err = helper.SetPage(c, helper.Page{
+		Cursor:     cursor,
+		NextCursor: nextCursor,
+		Size:       pageSize(num),
+		Total:      func() (int64, error) { return h.AUsecase.Count(ctx, filter) },
+	})
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	c.Response().Header().Set(`X-Cursor`, nextCursor)
+	if articlesNotModified(c, listAr, nextCursor) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	if withHTML {
+		for i := range listAr {
+			if err = h.Renderer.Apply(&listAr[i]); err != nil {
+				return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+			}
+		}
+	}
+
+	return c.JSON(http.StatusOK, articleResponses(h.Version, listAr))
+}
@@ -0,0 +1,225 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go-postgres-clean-arch/article/transfer"
+	"go-postgres-clean-arch/domain"
+	"go-postgres-clean-arch/helper"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+)
+
+// exportPageSize is the number of articles fetched per query while streaming an export
+const exportPageSize = 100
+
+// maxUploadSize bounds the markdown uploads
+const maxUploadSize = 64 << 20
+
+var errUploadTooLarge = fmt.Errorf("the upload is larger than %d bytes", maxUploadSize)
+
+// TransferHandler represent the httphandler for the bulk import and export of articles
+type TransferHandler struct {
+	AUsecase domain.ArticleUsecase
+	Importer *transfer.Importer
+}
+
+// NewArticleTransferHandler will initialize the articles/export and articles/import endpoints
+func NewArticleTransferHandler(e *echo.Echo, au domain.ArticleUsecase, tu domain.TagUseCase) {
+	handler := &TransferHandler{
+		AUsecase: au,
+		Importer: transfer.NewImporter(au, tu),
+	}
+
+	// a second /articles group would register its catch-all routes over the ones of NewArticleHandler
+	for _, version := range helper.APIVersions {
+		for _, prefix := range version.Prefixes() {
+			baseRouter := e.Group(prefix, version.Middleware()...)
+			baseRouter.GET("/articles/export", handler.Export)
+			baseRouter.POST("/articles/import", handler.Import)
+			baseRouter.POST("/articles/import/markdown", handler.ImportMarkdown)
+		}
+	}
+}
+
+// Export will stream every article in the format given by the format param, default to ndjson
+func (t *TransferHandler) Export(c echo.Context) error {
+	format := c.QueryParam("format")
+	if format == "" {
+		format = transfer.FormatNDJSON
+	}
+	contentType, err := transfer.ContentType(format)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: err.Error()})
+	}
+
+	res := c.Response()
+	w, err := transfer.NewWriter(res, format)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: err.Error()})
+	}
+
+	res.Header().Set(echo.HeaderContentType, contentType)
+	res.Header().Set(echo.HeaderContentDisposition, `attachment; filename="articles.`+format+`"`)
+	res.WriteHeader(http.StatusOK)
+
+	count, err := transfer.Export(c.Request().Context(), t.AUsecase, w, exportPageSize)
+	if err != nil {
+		// the status is already sent, the client sees a truncated document
+		logrus.Errorf("export aborted after %d articles: %v", count, err)
+	}
+	return nil
+}
+
+// Import will validate and store the articles of the request body. The format is given by the
+// format param or the Content-Type header, dry_run=true only validates the articles.
+func (t *TransferHandler) Import(c echo.Context) error {
+	format := c.QueryParam("format")
+	if format == "" {
+		format = transfer.FormatOf(c.Request().Header.Get(echo.HeaderContentType))
+	}
+	if format != "" {
+		if _, err := transfer.ContentType(format); err != nil {
+			return c.JSON(http.StatusBadRequest, ResponseError{Message: err.Error()})
+		}
+	}
+
+	dryRun, err := parseDryRun(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: err.Error()})
+	}
+
+	r, err := transfer.NewReader(c.Request().Body, format)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: err.Error()})
+	}
+
+	report, err := t.Importer.Import(c.Request().Context(), r, transfer.ImportOptions{DryRun: dryRun})
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
+
+// ImportMarkdown will import the markdown files of a zip body, or of the .md and .zip files of a
+// multipart upload. Articles are upserted by title so the same files can be imported again.
+func (t *TransferHandler) ImportMarkdown(c echo.Context) error {
+	dryRun, err := parseDryRun(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: err.Error()})
+	}
+
+	var files []transfer.MarkdownFile
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		var closeFiles func()
+		files, closeFiles, err = uploadedMarkdownFiles(c)
+		defer closeFiles()
+	} else {
+		files, err = bodyMarkdownFiles(c.Request().Body)
+	}
+	if err == errUploadTooLarge {
+		return c.JSON(http.StatusRequestEntityTooLarge, ResponseError{Message: err.Error()})
+	}
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: err.Error()})
+	}
+
+	r := transfer.NewMarkdownReader(files)
+	report, err := t.Importer.Import(c.Request().Context(), r, transfer.ImportOptions{DryRun: dryRun, Upsert: true})
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
+
+func parseDryRun(c echo.Context) (bool, error) {
+	s := c.QueryParam("dry_run")
+	if s == "" {
+		return false, nil
+	}
+	dryRun, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, errors.New("dry_run must be a boolean")
+	}
+	return dryRun, nil
+}
+
+// bodyMarkdownFiles reads a zip archive sent as the request body
+func bodyMarkdownFiles(body io.Reader) ([]transfer.MarkdownFile, error) {
+	archive, err := io.ReadAll(io.LimitReader(body, maxUploadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(archive) > maxUploadSize {
+		return nil, errUploadTooLarge
+	}
+	return transfer.ZipMarkdownFiles(bytes.NewReader(archive), int64(len(archive)))
+}
+
+// uploadedMarkdownFiles lists the markdown files of every part of a multipart upload, zip archives are
+// opened in place. The returned function closes the archives once the import is done.
+func uploadedMarkdownFiles(c echo.Context) ([]transfer.MarkdownFile, func(), error) {
+	var archives []io.Closer
+	closeFiles := func() {
+		for _, archive := range archives {
+			archive.Close()
+		}
+	}
+
+	c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, maxUploadSize)
+	form, err := c.MultipartForm()
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) || strings.Contains(err.Error(), "request body too large") {
+			return nil, closeFiles, errUploadTooLarge
+		}
+		return nil, closeFiles, err
+	}
+
+	fields := make([]string, 0, len(form.File))
+	for field := range form.File {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var files []transfer.MarkdownFile
+	for _, field := range fields {
+		for _, fh := range form.File[field] {
+			fh := fh
+			switch strings.ToLower(path.Ext(fh.Filename)) {
+			case ".md", ".markdown":
+				files = append(files, transfer.MarkdownFile{
+					Path: fh.Filename,
+					Open: func() (io.ReadCloser, error) { return fh.Open() },
+				})
+			case ".zip":
+				archive, err := fh.Open()
+				if err != nil {
+					return nil, closeFiles, err
+				}
+				archives = append(archives, archive)
+				inner, err := transfer.ZipMarkdownFiles(archive, fh.Size)
+				if err != nil {
+					return nil, closeFiles, fmt.Errorf("%s: %w", fh.Filename, err)
+				}
+				for _, file := range inner {
+					file.Path = fh.Filename + "/" + file.Path
+					files = append(files, file)
+				}
+			default:
+				return nil, closeFiles, fmt.Errorf("%s is neither a markdown file nor a zip archive", fh.Filename)
+			}
+		}
+	}
+	return files, closeFiles, nil
+}
@@ -0,0 +1,135 @@
+package http_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	articleHttp "go-postgres-clean-arch/article/delivery/http"
+	articleMemory "go-postgres-clean-arch/article/repository/memory"
+	"go-postgres-clean-arch/article/transfer"
+	articleUcase "go-postgres-clean-arch/article/usecase"
+	tagHttp "go-postgres-clean-arch/tag/delivery/http"
+	tagMemory "go-postgres-clean-arch/tag/repository/memory"
+	tagUcase "go-postgres-clean-arch/tag/usecase"
+
+	"github.com/labstack/echo"
+)
+
+func newTransferServer() *echo.Echo {
+	articleRepo := articleMemory.NewMemoryArticleRepository()
+	tagRepo := tagMemory.NewMemoryTagRepositoryWithArticles(articleRepo)
+	au := articleUcase.NewArticleUsecase(articleRepo, tagRepo, time.Second)
+	tu := tagUcase.NewTagUsecase(tagRepo, time.Second, nil)
+
+	e := echo.New()
+	articleHttp.NewArticleHandler(e, au)
+	articleHttp.NewArticleTransferHandler(e, au, tu)
+	articleHttp.NewTagArticlesHandler(e, au, tu)
+	tagHttp.NewTagHandler(e, tu)
+	return e
+}
+
+func TestImportExport(t *testing.T) {
+	e := newTransferServer()
+
+	body := "title,content,tag_name\nFirst,one,go\n,missing title,go\n"
+	req := httptest.NewRequest(http.MethodPost, "/api/articles/import?dry_run=true", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, "text/csv")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	var report transfer.Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+	if !report.DryRun || report.Imported != 1 || report.Failed != 1 || report.Errors[0].Row != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/articles/import", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, "text/csv")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/articles/export", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get(echo.HeaderContentType) != "application/x-ndjson" {
+		t.Fatalf("unexpected response %d %v", rec.Code, rec.Header())
+	}
+	if !strings.Contains(rec.Body.String(), `"title":"First"`) {
+		t.Fatalf("export is missing the imported article: %s", rec.Body.String())
+	}
+
+	// the transfer routes must not shadow the listing
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/articles", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("listing answered %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/articles/export?format=xml", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown format answered %d", rec.Code)
+	}
+}
+
+func TestImportMarkdown(t *testing.T) {
+	e := newTransferServer()
+
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	w, _ := zw.Create("posts/zipped.md")
+	w.Write([]byte("---\ntitle: Zipped\ntags: [go]\n---\nfrom the archive"))
+	zw.Close()
+
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	part, _ := mw.CreateFormFile("files", "single.md")
+	part.Write([]byte("---\ntitle: Single\ntags: [go]\n---\nuploaded alone"))
+	part, _ = mw.CreateFormFile("files", "posts.zip")
+	part.Write(archive.Bytes())
+	mw.Close()
+
+	importMarkdown := func(body []byte, contentType string) transfer.Report {
+		req := httptest.NewRequest(http.MethodPost, "/api/articles/import/markdown", bytes.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, contentType)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		var report transfer.Report
+		if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil || rec.Code != http.StatusOK {
+			t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+		}
+		return report
+	}
+
+	report := importMarkdown(form.Bytes(), mw.FormDataContentType())
+	if report.Imported != 2 || report.Failed != 0 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	// the same archive sent as the body is already imported
+	report = importMarkdown(archive.Bytes(), "application/zip")
+	if report.Imported != 0 || report.Skipped != 1 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/articles/import/markdown", strings.NewReader("not a zip"))
+	req.Header.Set(echo.HeaderContentType, "application/zip")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid archive answered %d", rec.Code)
+	}
+}
@@ -0,0 +1,214 @@
+// Package feed builds the Atom and RSS 2.0 feeds of the articles.
+package feed
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+
+	"go-postgres-clean-arch/domain"
+)
+
+// DefaultSize is the number of articles a feed lists
+const DefaultSize = 20
+
+// Feed is the newest articles of a feed, newest first
+type Feed struct {
+	Title    string
+	Articles []domain.Article
+	// Updated is the latest updated_at of the listed articles, zero for an empty feed
+	Updated time.Time
+}
+
+// Collect lists the size newest articles with ArticleUsecase.FetchFiltered, only the articles of
+// the tag are listed when tagID is not zero
+func Collect(ctx context.Context, au domain.ArticleUsecase, title string, tagID int64, size int) (Feed, error) {
+	f := Feed{Title: title}
+	if size <= 0 {
+		size = DefaultSize
+	}
+
+	filter := domain.ArticleFilter{TagID: tagID, Sort: domain.SortCreatedAtDesc}
+	articles, _, err := au.FetchFiltered(ctx, filter, "", int64(size))
+	if err != nil {
+		return Feed{}, err
+	}
+
+	for _, a := range articles {
+		f.Articles = append(f.Articles, a)
+		if a.UpdatedAt.After(f.Updated) {
+			f.Updated = a.UpdatedAt
+		}
+	}
+	return f, nil
+}
+
+// ETag returns a weak validator of the listed articles, it changes when an article of the feed is
+// added, removed or updated
+func (f Feed) ETag() string {
+	h := sha256.New()
+	io.WriteString(h, f.Title)
+	for _, a := range f.Articles {
+		fmt.Fprintf(h, "\n%d %d", a.ID, a.UpdatedAt.UnixNano())
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// Link builds the URLs of a feed
+type Link struct {
+	// Self is the absolute URL of the feed document
+	Self string
+	// Article returns the absolute URL of an article
+	Article func(a domain.Article) string
+}
+
+// Content returns the HTML content of an entry, e.g. the rendered article
+type Content func(a domain.Article) (string, error)
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Published string      `xml:"published"`
+	Updated   string      `xml:"updated"`
+	Link      atomLink    `xml:"link"`
+	Category  *atomTerm   `xml:"category,omitempty"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomTerm struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// WriteAtom writes the feed as an Atom 1.0 document
+func WriteAtom(w io.Writer, f Feed, link Link, content Content) error {
+	doc := atomFeed{
+		ID:      link.Self,
+		Title:   f.Title,
+		Updated: f.Updated.UTC().Format(time.RFC3339),
+		Links:   []atomLink{{Rel: "self", Type: "application/atom+xml", Href: link.Self}},
+	}
+	for _, a := range f.Articles {
+		body, err := content(a)
+		if err != nil {
+			return err
+		}
+		entry := atomEntry{
+			ID:        link.Article(a),
+			Title:     a.Title,
+			Published: a.CreatedAt.UTC().Format(time.RFC3339),
+			Updated:   a.UpdatedAt.UTC().Format(time.RFC3339),
+			Link:      atomLink{Rel: "alternate", Href: link.Article(a)},
+			Content:   atomContent{Type: "html", Body: body},
+		}
+		if a.Tag.Name != "" {
+			entry.Category = &atomTerm{Term: a.Tag.Name}
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	return encode(w, doc)
+}
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Self          rssSelf   `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssSelf struct {
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+	Href string `xml:"href,attr"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Category    string  `xml:"category,omitempty"`
+	Description string  `xml:"description"`
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// WriteRSS writes the feed as an RSS 2.0 document
+func WriteRSS(w io.Writer, f Feed, link Link, content Content) error {
+	doc := rssDocument{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:       f.Title,
+			Link:        link.Self,
+			Description: f.Title,
+			Self:        rssSelf{Rel: "self", Type: "application/rss+xml", Href: link.Self},
+		},
+	}
+	if !f.Updated.IsZero() {
+		doc.Channel.LastBuildDate = f.Updated.UTC().Format(time.RFC1123Z)
+	}
+	for _, a := range f.Articles {
+		body, err := content(a)
+		if err != nil {
+			return err
+		}
+		doc.Channel.Items = append(doc.Channel.Items, rssItem{
+			Title:       a.Title,
+			Link:        link.Article(a),
+			GUID:        rssGUID{IsPermaLink: "true", Value: link.Article(a)},
+			PubDate:     a.CreatedAt.UTC().Format(time.RFC1123Z),
+			Category:    a.Tag.Name,
+			Description: body,
+		})
+	}
+	return encode(w, doc)
+}
+
+func encode(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,163 @@
+package feed_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"go-postgres-clean-arch/article/feed"
+	articleMemory "go-postgres-clean-arch/article/repository/memory"
+	articleUcase "go-postgres-clean-arch/article/usecase"
+	"go-postgres-clean-arch/domain"
+	tagMemory "go-postgres-clean-arch/tag/repository/memory"
+)
+
+var base = time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// newUsecase stores count articles, the even ones tagged go and the odd ones sql
+func newUsecase(t *testing.T, count int) domain.ArticleUsecase {
+	t.Helper()
+	ctx := context.Background()
+	tagRepo := tagMemory.NewMemoryTagRepository()
+	for _, name := range []string{"go", "sql"} {
+		if err := tagRepo.Store(ctx, &domain.Tag{Name: name, CreatedAt: base, UpdatedAt: base}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	au := articleUcase.NewArticleUsecase(articleMemory.NewMemoryArticleRepository(), tagRepo, time.Second)
+	for i := 0; i < count; i++ {
+		a := &domain.CreateArticleInput{
+			Title:     fmt.Sprintf("article %d", i),
+			Content:   "body",
+			TagID:     int64(i%2 + 1),
+			CreatedAt: base.Add(time.Duration(i) * time.Minute),
+		}
+		if err := au.Store(ctx, a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return au
+}
+
+func TestCollectKeepsTheNewestArticles(t *testing.T) {
+	au := newUsecase(t, 250)
+
+	f, err := feed.Collect(context.Background(), au, "All", 0, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Articles) != 3 || f.Articles[0].Title != "article 249" || f.Articles[2].Title != "article 247" {
+		t.Fatalf("unexpected articles %+v", f.Articles)
+	}
+
+	f, err = feed.Collect(context.Background(), au, "go", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Articles) != 2 || f.Articles[0].Title != "article 248" || f.Articles[1].Title != "article 246" {
+		t.Fatalf("unexpected articles %+v", f.Articles)
+	}
+	if f.Articles[0].Tag.Name != "go" {
+		t.Fatalf("the tag is not filled %+v", f.Articles[0])
+	}
+}
+
+// countingUsecase counts the listings made to collect a feed
+type countingUsecase struct {
+	domain.ArticleUsecase
+	listings int
+}
+
+func (c *countingUsecase) Fetch(ctx context.Context, cursor string, num int64) ([]domain.Article, string, error) {
+	c.listings++
+	return c.ArticleUsecase.Fetch(ctx, cursor, num)
+}
+
+func (c *countingUsecase) FetchFiltered(ctx context.Context, filter domain.ArticleFilter, cursor string, num int64) ([]domain.Article, string, error) {
+	c.listings++
+	return c.ArticleUsecase.FetchFiltered(ctx, filter, cursor, num)
+}
+
+func TestCollectListsOnlyTheNewestArticles(t *testing.T) {
+	au := &countingUsecase{ArticleUsecase: newUsecase(t, 250)}
+
+	if _, err := feed.Collect(context.Background(), au, "go", 1, feed.DefaultSize); err != nil {
+		t.Fatal(err)
+	}
+	if au.listings != 1 {
+		t.Fatalf("expected a single listing of the newest articles, got %d", au.listings)
+	}
+}
+
+func TestETagFollowsTheArticles(t *testing.T) {
+	a := domain.Article{ID: 1, UpdatedAt: base}
+	f := feed.Feed{Title: "All", Articles: []domain.Article{a}, Updated: base}
+	etag := f.ETag()
+
+	if (feed.Feed{Title: "All", Articles: []domain.Article{a}}).ETag() != etag {
+		t.Fatal("the etag is not stable")
+	}
+	a.UpdatedAt = base.Add(time.Second)
+	if (feed.Feed{Title: "All", Articles: []domain.Article{a}}).ETag() == etag {
+		t.Fatal("the etag did not change with updated_at")
+	}
+}
+
+func TestWriteAtomAndRSS(t *testing.T) {
+	f := feed.Feed{
+		Title:   "All",
+		Updated: base.Add(time.Hour),
+		Articles: []domain.Article{
+			{ID: 7, Title: "Seven & more", Tag: domain.Tag{Name: "go"}, CreatedAt: base, UpdatedAt: base.Add(time.Hour)},
+		},
+	}
+	link := feed.Link{
+		Self:    "http://example.com/feeds/articles.atom",
+		Article: func(a domain.Article) string { return "http://example.com/api/articles/" + strconv.FormatInt(a.ID, 10) },
+	}
+	content := func(a domain.Article) (string, error) { return "<p>body</p>", nil }
+
+	var buf bytes.Buffer
+	if err := feed.WriteAtom(&buf, f, link, content); err != nil {
+		t.Fatal(err)
+	}
+	var atom struct {
+		Updated string `xml:"updated"`
+		Entries []struct {
+			Title   string `xml:"title"`
+			Updated string `xml:"updated"`
+			Content string `xml:"content"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &atom); err != nil {
+		t.Fatal(err)
+	}
+	if atom.Updated != "2023-05-01T13:00:00Z" || len(atom.Entries) != 1 || atom.Entries[0].Title != "Seven & more" || atom.Entries[0].Content != "<p>body</p>" {
+		t.Fatalf("unexpected atom feed %+v\n%s", atom, buf.String())
+	}
+
+	buf.Reset()
+	if err := feed.WriteRSS(&buf, f, link, content); err != nil {
+		t.Fatal(err)
+	}
+	var rss struct {
+		Channel struct {
+			LastBuildDate string `xml:"lastBuildDate"`
+			Items         []struct {
+				GUID     string `xml:"guid"`
+				Category string `xml:"category"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &rss); err != nil {
+		t.Fatal(err)
+	}
+	if rss.Channel.LastBuildDate != "Mon, 01 May 2023 13:00:00 +0000" || rss.Channel.Items[0].GUID != "http://example.com/api/articles/7" || rss.Channel.Items[0].Category != "go" {
+		t.Fatalf("unexpected rss feed %+v\n%s", rss, buf.String())
+	}
+}
@@ -0,0 +1,322 @@
+// Package render turns the stored content of an article into sanitized HTML, with the table of
+// contents and the reading time derived from the rendered output.
+package render
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"html"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"go-postgres-clean-arch/domain"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	nethtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// WordsPerMinute is the reading speed the reading time is estimated with
+const WordsPerMinute = 200
+
+// DefaultCacheSize is the number of rendered articles a Renderer keeps by default
+const DefaultCacheSize = 1024
+
+// Result is the rendered form of an article
+type Result struct {
+	HTML        string
+	TOC         []domain.Heading
+	ReadingTime int
+}
+
+// Renderer renders articles and caches the output per article, an entry is reused as long as the
+// article keeps the same updated_at
+type Renderer struct {
+	markdown goldmark.Markdown
+	policy   *bluemonday.Policy
+
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[int64]*list.Element
+}
+
+type cacheEntry struct {
+	id        int64
+	updatedAt time.Time
+	format    string
+	result    Result
+}
+
+// NewRenderer will create a Renderer caching up to size articles, size <= 0 disables the cache
+func NewRenderer(size int) *Renderer {
+	// raw HTML is dropped by goldmark and the output is sanitized again, so a markdown article
+	// can never carry a script whatever it contains
+	markdown := goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+	)
+
+	return &Renderer{
+		markdown: markdown,
+		policy:   bluemonday.UGCPolicy(),
+		size:     size,
+		order:    list.New(),
+		entries:  make(map[int64]*list.Element),
+	}
+}
+
+// Render will render the content of the article according to its content format
+func (r *Renderer) Render(a domain.Article) (Result, error) {
+	if res, ok := r.cached(a); ok {
+		return res, nil
+	}
+
+	res, err := r.render(a.Content, a.ContentFormat)
+	if err != nil {
+		return Result{}, err
+	}
+
+	r.store(a, res)
+	return res, nil
+}
+
+// Apply will fill the HTML, TOC and ReadingTime fields of the article
+func (r *Renderer) Apply(a *domain.Article) error {
+	res, err := r.Render(*a)
+	if err != nil {
+		return err
+	}
+
+	a.HTML = res.HTML
+	a.TOC = res.TOC
+	a.ReadingTime = res.ReadingTime
+	return nil
+}
+
+func (r *Renderer) render(content, format string) (Result, error) {
+	var unsafe string
+	switch format {
+	case domain.ContentFormatMarkdown, "":
+		var buf bytes.Buffer
+		if err := r.markdown.Convert([]byte(content), &buf); err != nil {
+			return Result{}, err
+		}
+		unsafe = buf.String()
+	case domain.ContentFormatHTML:
+		unsafe = content
+	case domain.ContentFormatPlain:
+		unsafe = plainToHTML(content)
+	default:
+		return Result{}, fmt.Errorf("unknown content format %q", format)
+	}
+
+	return annotate(r.policy.Sanitize(unsafe))
+}
+
+// plainToHTML escapes plain text and turns its blank line separated blocks into paragraphs
+func plainToHTML(content string) string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+
+	var b strings.Builder
+	for _, block := range strings.Split(content, "\n\n") {
+		block = strings.TrimSpace(block)
+		if block == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(html.EscapeString(block), "\n", "<br>\n"))
+		b.WriteString("</p>\n")
+	}
+	return b.String()
+}
+
+// annotate gives an id to the headings missing one, and derives the table of contents and the
+// reading time from the sanitized HTML
+func annotate(sanitized string) (Result, error) {
+	body := &nethtml.Node{Type: nethtml.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := nethtml.ParseFragment(strings.NewReader(sanitized), body)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var (
+		res   Result
+		words int
+		ids   = make(map[string]int)
+	)
+	var walk func(n *nethtml.Node)
+	walk = func(n *nethtml.Node) {
+		if n.Type == nethtml.TextNode {
+			words += len(strings.Fields(n.Data))
+			return
+		}
+		if level := headingLevel(n); level > 0 {
+			text := strings.Join(strings.Fields(textOf(n)), " ")
+			id := attr(n, "id")
+			if id == "" {
+				id = slugify(text)
+			}
+			id = unique(ids, id)
+			setAttr(n, "id", id)
+			res.TOC = append(res.TOC, domain.Heading{Level: level, ID: id, Text: text})
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, n := range nodes {
+		walk(n)
+		if err := nethtml.Render(&buf, n); err != nil {
+			return Result{}, err
+		}
+	}
+
+	res.HTML = buf.String()
+	if words > 0 {
+		res.ReadingTime = (words + WordsPerMinute - 1) / WordsPerMinute
+	}
+	return res, nil
+}
+
+func headingLevel(n *nethtml.Node) int {
+	if n.Type != nethtml.ElementNode {
+		return 0
+	}
+	switch n.DataAtom {
+	case atom.H1:
+		return 1
+	case atom.H2:
+		return 2
+	case atom.H3:
+		return 3
+	case atom.H4:
+		return 4
+	case atom.H5:
+		return 5
+	case atom.H6:
+		return 6
+	}
+	return 0
+}
+
+func textOf(n *nethtml.Node) string {
+	if n.Type == nethtml.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(textOf(c))
+	}
+	return b.String()
+}
+
+func attr(n *nethtml.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func setAttr(n *nethtml.Node, key, val string) {
+	for i, a := range n.Attr {
+		if a.Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, nethtml.Attribute{Key: key, Val: val})
+}
+
+// slugify builds a heading id the way goldmark does: lower case letters and digits joined by dashes
+func slugify(text string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		default:
+			dash = true
+		}
+	}
+	if b.Len() == 0 {
+		return "heading"
+	}
+	return b.String()
+}
+
+// unique suffixes the id when it was already used in the document
+func unique(ids map[string]int, id string) string {
+	n := ids[id]
+	ids[id] = n + 1
+	if n == 0 {
+		return id
+	}
+	return unique(ids, fmt.Sprintf("%s-%d", id, n))
+}
+
+func (r *Renderer) cached(a domain.Article) (Result, bool) {
+	if r.size <= 0 || a.ID == 0 {
+		return Result{}, false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	el, ok := r.entries[a.ID]
+	if !ok {
+		return Result{}, false
+	}
+	entry := el.Value.(*cacheEntry)
+	if !entry.updatedAt.Equal(a.UpdatedAt) || entry.format != a.ContentFormat {
+		return Result{}, false
+	}
+
+	r.order.MoveToFront(el)
+	return entry.result, true
+}
+
+func (r *Renderer) store(a domain.Article, res Result) {
+	if r.size <= 0 || a.ID == 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry := &cacheEntry{id: a.ID, updatedAt: a.UpdatedAt, format: a.ContentFormat, result: res}
+	if el, ok := r.entries[a.ID]; ok {
+		el.Value = entry
+		r.order.MoveToFront(el)
+		return
+	}
+
+	r.entries[a.ID] = r.order.PushFront(entry)
+	for r.order.Len() > r.size {
+		oldest := r.order.Back()
+		r.order.Remove(oldest)
+		delete(r.entries, oldest.Value.(*cacheEntry).id)
+	}
+}
+
+// Len returns the number of cached articles
+func (r *Renderer) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.order.Len()
+}
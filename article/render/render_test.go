@@ -0,0 +1,104 @@
+package render_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go-postgres-clean-arch/article/render"
+	"go-postgres-clean-arch/domain"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	r := render.NewRenderer(0)
+	content := "# Intro\n\nSome *text*.\n\n## Details\n\n<script>alert(1)</script>\n\n[link](javascript:alert(1))\n\n## Details\n"
+
+	res, err := r.Render(domain.Article{Content: content, ContentFormat: domain.ContentFormatMarkdown})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(res.HTML, "<script") || strings.Contains(res.HTML, "javascript:") {
+		t.Fatalf("html is not sanitized:\n%s", res.HTML)
+	}
+	if !strings.Contains(res.HTML, `<h1 id="intro">Intro</h1>`) || !strings.Contains(res.HTML, "<em>text</em>") {
+		t.Fatalf("unexpected html:\n%s", res.HTML)
+	}
+
+	want := []domain.Heading{{Level: 1, ID: "intro", Text: "Intro"}, {Level: 2, ID: "details", Text: "Details"}, {Level: 2, ID: "details-1", Text: "Details"}}
+	if len(res.TOC) != len(want) {
+		t.Fatalf("unexpected toc %+v", res.TOC)
+	}
+	for i := range want {
+		if res.TOC[i] != want[i] {
+			t.Fatalf("unexpected toc %+v", res.TOC)
+		}
+	}
+	if res.ReadingTime != 1 {
+		t.Fatalf("unexpected reading time %d", res.ReadingTime)
+	}
+}
+
+func TestRenderHTMLAndPlain(t *testing.T) {
+	r := render.NewRenderer(0)
+
+	res, err := r.Render(domain.Article{Content: `<h2>Hello World</h2><p onclick="x()">body</p><iframe src="x"></iframe>`, ContentFormat: domain.ContentFormatHTML})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.HTML != `<h2 id="hello-world">Hello World</h2><p>body</p>` {
+		t.Fatalf("unexpected html %q", res.HTML)
+	}
+
+	res, err = r.Render(domain.Article{Content: "a <b>\nline\n\nsecond", ContentFormat: domain.ContentFormatPlain})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.HTML != "<p>a &lt;b&gt;<br/>\nline</p>\n<p>second</p>\n" || len(res.TOC) != 0 {
+		t.Fatalf("unexpected html %q", res.HTML)
+	}
+
+	if _, err = r.Render(domain.Article{Content: "x", ContentFormat: "rtf"}); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
+
+func TestReadingTime(t *testing.T) {
+	r := render.NewRenderer(0)
+	res, err := r.Render(domain.Article{Content: strings.Repeat("word ", 401), ContentFormat: domain.ContentFormatPlain})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ReadingTime != 3 {
+		t.Fatalf("expected 3 minutes, got %d", res.ReadingTime)
+	}
+}
+
+func TestCacheFollowsUpdatedAt(t *testing.T) {
+	r := render.NewRenderer(2)
+	at := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := domain.Article{ID: 1, Content: "first", ContentFormat: domain.ContentFormatPlain, UpdatedAt: at}
+
+	if err := r.Apply(&a); err != nil || a.HTML != "<p>first</p>\n" {
+		t.Fatalf("unexpected article %+v %v", a, err)
+	}
+
+	// the same updated_at is served from the cache even if the content differs
+	a.Content = "second"
+	if err := r.Apply(&a); err != nil || a.HTML != "<p>first</p>\n" {
+		t.Fatalf("expected the cached html, got %q", a.HTML)
+	}
+
+	a.UpdatedAt = at.Add(time.Second)
+	if err := r.Apply(&a); err != nil || a.HTML != "<p>second</p>\n" {
+		t.Fatalf("expected the html to be rendered again, got %q", a.HTML)
+	}
+
+	for id := int64(2); id <= 3; id++ {
+		if _, err := r.Render(domain.Article{ID: id, Content: "x", ContentFormat: domain.ContentFormatPlain}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if r.Len() != 2 {
+		t.Fatalf("expected the cache to keep 2 articles, got %d", r.Len())
+	}
+}
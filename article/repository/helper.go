@@ -1,12 +1,13 @@
 package repository
 
 import (
+	"database/sql"
 	"encoding/base64"
 	"time"
 )
 
 const (
-	timeFormat = "2006-01-02T15:04:05.999Z07:00" // reduce precision from RFC3339Nano as date format
+	timeFormat = "2006-01-02T15:04:05.999999Z07:00" // reduce precision from RFC3339Nano to the microseconds the database keeps
 )
 
 // DecodeCursor will decode cursor from user for mysql
@@ -30,3 +31,8 @@ func EncodeCursor(t time.Time) string {
 
 	return base64.StdEncoding.EncodeToString([]byte(timeString))
 }
+
+// NullString stores an empty string as NULL, e.g. a missing slug the unique index must not compare
+func NullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
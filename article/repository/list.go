@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"go-postgres-clean-arch/domain"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sortKey is the column an article listing is sorted on, the sorts are whitelisted so the
+// column names are the only part of a listing query not passed as an argument
+type sortKey struct {
+	column string
+	desc   bool
+}
+
+var sortKeys = map[domain.ArticleSort]sortKey{
+	domain.SortCreatedAt:     {"created_at", false},
+	domain.SortCreatedAtDesc: {"created_at", true},
+	domain.SortUpdatedAt:     {"updated_at", false},
+	domain.SortUpdatedAtDesc: {"updated_at", true},
+	domain.SortTitle:         {"title", false},
+	domain.SortTitleDesc:     {"title", true},
+}
+
+// ListQuery is the WHERE and ORDER BY of an article listing, with ? placeholders for its Args
+type ListQuery struct {
+	Where   string
+	Args    []interface{}
+	OrderBy string
+}
+
+// NewListQuery translates the filter and the cursor of a listing, timeArg converts the timestamps
+// to the representation of the database. An invalid sort or cursor is domain.ErrBadParamInput.
+func NewListQuery(filter domain.ArticleFilter, cursor string, timeArg func(time.Time) interface{}) (q ListQuery, err error) {
+	key, ok := sortKeys[sortOf(filter)]
+	if !ok {
+		return q, domain.ErrBadParamInput
+	}
+
+	var conditions []string
+	add := func(condition string, arg interface{}) {
+		conditions = append(conditions, condition)
+		q.Args = append(q.Args, arg)
+	}
+	switch {
+	case len(filter.TagIDs) > 0:
+		placeholders := make([]string, len(filter.TagIDs))
+		for i, id := range filter.TagIDs {
+			placeholders[i] = "?"
+			q.Args = append(q.Args, id)
+		}
+		conditions = append(conditions, "tag_id IN ("+strings.Join(placeholders, ", ")+")")
+	case filter.TagID != 0:
+		add("tag_id = ?", filter.TagID)
+	}
+	if !filter.CreatedAfter.IsZero() {
+		add("created_at > ?", timeArg(filter.CreatedAfter))
+	}
+	if !filter.CreatedBefore.IsZero() {
+		add("created_at < ?", timeArg(filter.CreatedBefore))
+	}
+	if !filter.UpdatedSince.IsZero() {
+		add("updated_at >= ?", timeArg(filter.UpdatedSince))
+	}
+	if filter.Query != "" {
+		add("LOWER(title) LIKE ? ESCAPE '!'", likePrefix(filter.Query))
+	}
+
+	if cursor != "" {
+		c, err := DecodeSortCursor(sortOf(filter), cursor)
+		switch {
+		case err != nil && sortOf(filter) == domain.SortCreatedAt:
+			// the default order keeps the cursors handed out before the listing could be sorted
+			decodedCursor, err := DecodeCursor(cursor)
+			if err != nil {
+				return q, domain.ErrBadParamInput
+			}
+			add("created_at > ?", timeArg(decodedCursor))
+		case err != nil:
+			return q, err
+		default:
+			var value interface{} = c.Value
+			if key.column != "title" {
+				t, err := c.Time()
+				if err != nil {
+					return q, domain.ErrBadParamInput
+				}
+				value = timeArg(t)
+			}
+			op := ">"
+			if key.desc {
+				op = "<"
+			}
+			conditions = append(conditions, "("+key.column+" "+op+" ? OR ("+key.column+" = ? AND id "+op+" ?))")
+			q.Args = append(q.Args, value, value, c.ID)
+		}
+	}
+	q.Where = strings.Join(conditions, " AND ")
+
+	dir := ""
+	if key.desc {
+		dir = " DESC"
+	}
+	q.OrderBy = key.column + dir + ", id" + dir
+	return q, nil
+}
+
+// SQL builds the statement selecting the columns of the article table, numbered turns the
+// placeholders into the $1, $2... of postgresql
+func (q ListQuery) SQL(columns string, num int64, numbered bool) (string, []interface{}) {
+	var b strings.Builder
+	b.WriteString("SELECT " + columns + " FROM article")
+	if q.Where != "" {
+		b.WriteString(" WHERE " + q.Where)
+	}
+	b.WriteString(" ORDER BY " + q.OrderBy + " LIMIT ?")
+	args := append(append([]interface{}{}, q.Args...), num)
+
+	if numbered {
+		return numberPlaceholders(b.String()), args
+	}
+	return b.String(), args
+}
+
+// CountSQL builds the statement counting the articles of the listing, the query must be built
+// without a cursor to count every page
+func (q ListQuery) CountSQL(numbered bool) (string, []interface{}) {
+	query := "SELECT COUNT(*) FROM article"
+	if q.Where != "" {
+		query += " WHERE " + q.Where
+	}
+
+	if numbered {
+		return numberPlaceholders(query), q.Args
+	}
+	return query, q.Args
+}
+
+// numberPlaceholders turns the ? placeholders into the $1, $2... of postgresql
+func numberPlaceholders(query string) string {
+	var n strings.Builder
+	i := 0
+	for _, r := range query {
+		if r == '?' {
+			i++
+			n.WriteString("$" + strconv.Itoa(i))
+			continue
+		}
+		n.WriteRune(r)
+	}
+	return n.String()
+}
+
+// NextCursor returns the cursor of the page after res, it is empty when res is not a full page
+func NextCursor(filter domain.ArticleFilter, res []domain.Article, num int64) string {
+	if len(res) == 0 || len(res) != int(num) {
+		return ""
+	}
+	last := res[len(res)-1]
+	sort := sortOf(filter)
+	switch sort {
+	case domain.SortCreatedAt, domain.SortCreatedAtDesc:
+		return EncodeSortCursor(SortCursor{Sort: sort, Value: last.CreatedAt.Format(timeFormat), ID: last.ID})
+	case domain.SortUpdatedAt, domain.SortUpdatedAtDesc:
+		return EncodeSortCursor(SortCursor{Sort: sort, Value: last.UpdatedAt.Format(timeFormat), ID: last.ID})
+	default:
+		return EncodeSortCursor(SortCursor{Sort: sort, Value: last.Title, ID: last.ID})
+	}
+}
+
+// SortCursor is the position after the last article of a page, it holds the sort key of the article
+// and its id to break the ties of the articles sharing the key, e.g. imported at the same time
+type SortCursor struct {
+	Sort  domain.ArticleSort `json:"s"`
+	Value string             `json:"v"`
+	ID    int64              `json:"id"`
+}
+
+// Time parses the value of a cursor sorted on a timestamp
+func (c SortCursor) Time() (time.Time, error) {
+	return time.Parse(timeFormat, c.Value)
+}
+
+// EncodeSortCursor will encode the cursor for the user
+func EncodeSortCursor(c SortCursor) string {
+	byt, _ := json.Marshal(c)
+	return base64.StdEncoding.EncodeToString(byt)
+}
+
+// DecodeSortCursor will decode the cursor from the user, a cursor of another sort is domain.ErrBadParamInput
+func DecodeSortCursor(sort domain.ArticleSort, cursor string) (c SortCursor, err error) {
+	byt, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil {
+		return c, domain.ErrBadParamInput
+	}
+	if err = json.Unmarshal(byt, &c); err != nil || c.Sort != sort {
+		return SortCursor{}, domain.ErrBadParamInput
+	}
+	return c, nil
+}
+
+// likePrefix is the LIKE pattern of the lower case prefix, with ! escaping the wildcards
+func likePrefix(prefix string) string {
+	r := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+	return r.Replace(strings.ToLower(prefix)) + "%"
+}
+
+func sortOf(filter domain.ArticleFilter) domain.ArticleSort {
+	if filter.Sort == "" {
+		return domain.SortCreatedAt
+	}
+	return filter.Sort
+}
@@ -0,0 +1,334 @@
+package memory
+
+import (
+	"context"
+	"go-postgres-clean-arch/article/repository"
+	"go-postgres-clean-arch/domain"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryArticleRepository struct {
+	mu       sync.RWMutex
+	lastID   int64
+	articles map[int64]domain.Article
+	// oldSlugs maps the previous slugs to their article
+	oldSlugs map[string]int64
+}
+
+// NewMemoryArticleRepository will create a thread-safe in-memory object that represent the article.Repository interface
+func NewMemoryArticleRepository() domain.ArticleRepository {
+	return &memoryArticleRepository{
+		articles: map[int64]domain.Article{},
+		oldSlugs: map[string]int64{},
+	}
+}
+
+// sorted returns the articles in the order of the sort, the caller must hold the lock
+func (m *memoryArticleRepository) sorted(order domain.ArticleSort) []domain.Article {
+	list := make([]domain.Article, 0, len(m.articles))
+	for _, a := range m.articles {
+		list = append(list, a)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return less(order, list[i], list[j])
+	})
+	return list
+}
+
+func (m *memoryArticleRepository) Fetch(ctx context.Context, cursor string, num int64) (res []domain.Article, nextCursor string, err error) {
+	return m.FetchFiltered(ctx, domain.ArticleFilter{}, cursor, num)
+}
+
+func (m *memoryArticleRepository) FetchFiltered(ctx context.Context, filter domain.ArticleFilter, cursor string, num int64) (res []domain.Article, nextCursor string, err error) {
+	order := filter.Sort
+	if order == "" {
+		order = domain.SortCreatedAt
+	}
+	if !order.Valid() {
+		return nil, "", domain.ErrBadParamInput
+	}
+
+	after, err := afterCursor(order, cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	res = make([]domain.Article, 0)
+	for _, a := range m.sorted(order) {
+		if int64(len(res)) == num {
+			break
+		}
+		if !matches(filter, a) || !after(a) {
+			continue
+		}
+		res = append(res, a)
+	}
+
+	return res, repository.NextCursor(filter, res, num), nil
+}
+
+func (m *memoryArticleRepository) Count(ctx context.Context, filter domain.ArticleFilter) (total int64, err error) {
+	if !filter.Sort.Valid() {
+		return 0, domain.ErrBadParamInput
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, a := range m.articles {
+		if matches(filter, a) {
+			total++
+		}
+	}
+	return total, nil
+}
+
+func (m *memoryArticleRepository) GetByID(ctx context.Context, id int64) (domain.Article, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	a, ok := m.articles[id]
+	if !ok {
+		return domain.Article{}, domain.ErrNotFound
+	}
+	return a, nil
+}
+
+func (m *memoryArticleRepository) GetByTitle(ctx context.Context, title string) (domain.Article, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, a := range m.articles {
+		if a.Title == title {
+			return a, nil
+		}
+	}
+	return domain.Article{}, domain.ErrNotFound
+}
+
+func (m *memoryArticleRepository) GetBySlug(ctx context.Context, slug string) (domain.Article, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, a := range m.articles {
+		if slug != "" && a.Slug == slug {
+			return a, nil
+		}
+	}
+	return domain.Article{}, domain.ErrNotFound
+}
+
+func (m *memoryArticleRepository) GetByOldSlug(ctx context.Context, slug string) (domain.Article, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	id, ok := m.oldSlugs[slug]
+	if !ok {
+		return domain.Article{}, domain.ErrNotFound
+	}
+	return m.articles[id], nil
+}
+
+func (m *memoryArticleRepository) Store(ctx context.Context, a *domain.CreateArticleInput) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.titleTaken(a.Title, 0) || m.slugTaken(a.Slug, 0) {
+		return domain.ErrConflict
+	}
+
+	m.lastID++
+	a.ID = m.lastID
+	m.articles[a.ID] = domain.Article{
+		ID:            a.ID,
+		Title:         a.Title,
+		Slug:          a.Slug,
+		Content:       a.Content,
+		ContentFormat: a.ContentFormat,
+		Tag:           domain.Tag{ID: a.TagID},
+		Version:       1,
+		UpdatedAt:     roundTime(a.UpdatedAt),
+		CreatedAt:     roundTime(a.CreatedAt),
+	}
+	return nil
+}
+
+func (m *memoryArticleRepository) Update(ctx context.Context, ar *domain.UpdateArticleInput) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.articles[ar.ID]
+	if !ok {
+		return domain.ErrNotFound
+	}
+	if ar.Version != 0 && ar.Version != existing.Version {
+		return domain.ErrPreconditionFailed
+	}
+	if ar.Slug == "" {
+		ar.Slug = existing.Slug
+	}
+	if m.titleTaken(ar.Title, ar.ID) || m.slugTaken(ar.Slug, ar.ID) {
+		return domain.ErrConflict
+	}
+	if existing.Slug != "" && existing.Slug != ar.Slug {
+		if id, ok := m.oldSlugs[existing.Slug]; ok && id != ar.ID {
+			return domain.ErrConflict
+		}
+		m.oldSlugs[existing.Slug] = ar.ID
+	}
+	// the new slug may be a previous one, it no longer redirects
+	delete(m.oldSlugs, ar.Slug)
+
+	existing.Title = ar.Title
+	existing.Slug = ar.Slug
+	existing.Content = ar.Content
+	existing.ContentFormat = ar.ContentFormat
+	existing.Tag = domain.Tag{ID: ar.TagID}
+	existing.UpdatedAt = roundTime(ar.UpdatedAt)
+	existing.Version++
+	m.articles[ar.ID] = existing
+	ar.Version = existing.Version
+	return nil
+}
+
+func (m *memoryArticleRepository) Delete(ctx context.Context, id int64, version int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.articles[id]
+	if !ok {
+		return domain.ErrNotFound
+	}
+	if version != 0 && version != existing.Version {
+		return domain.ErrPreconditionFailed
+	}
+	delete(m.articles, id)
+	for slug, articleID := range m.oldSlugs {
+		if articleID == id {
+			delete(m.oldSlugs, slug)
+		}
+	}
+	return nil
+}
+
+// titleTaken reports whether another article than exceptID already uses the title, the caller must hold the lock
+func (m *memoryArticleRepository) titleTaken(title string, exceptID int64) bool {
+	for id, a := range m.articles {
+		if id != exceptID && a.Title == title {
+			return true
+		}
+	}
+	return false
+}
+
+// slugTaken reports whether another article than exceptID already uses the slug, the caller must hold the lock
+func (m *memoryArticleRepository) slugTaken(slug string, exceptID int64) bool {
+	if slug == "" {
+		return false
+	}
+	for id, a := range m.articles {
+		if id != exceptID && a.Slug == slug {
+			return true
+		}
+	}
+	return false
+}
+
+// less orders the articles like the ORDER BY of the sql repositories, the id breaks the ties
+func less(order domain.ArticleSort, a, b domain.Article) bool {
+	var cmp int
+	switch order {
+	case domain.SortCreatedAt, domain.SortCreatedAtDesc:
+		cmp = compareTime(a.CreatedAt, b.CreatedAt)
+	case domain.SortUpdatedAt, domain.SortUpdatedAtDesc:
+		cmp = compareTime(a.UpdatedAt, b.UpdatedAt)
+	default:
+		cmp = strings.Compare(a.Title, b.Title)
+	}
+	if cmp == 0 && a.ID != b.ID {
+		cmp = 1
+		if a.ID < b.ID {
+			cmp = -1
+		}
+	}
+	if strings.HasPrefix(string(order), "-") {
+		return cmp > 0
+	}
+	return cmp < 0
+}
+
+// afterCursor returns whether an article comes after the cursor in the order of the sort
+func afterCursor(order domain.ArticleSort, cursor string) (func(domain.Article) bool, error) {
+	if cursor == "" {
+		return func(domain.Article) bool { return true }, nil
+	}
+	c, err := repository.DecodeSortCursor(order, cursor)
+	if err != nil && order == domain.SortCreatedAt {
+		decodedCursor, err := repository.DecodeCursor(cursor)
+		if err != nil {
+			return nil, domain.ErrBadParamInput
+		}
+		return func(a domain.Article) bool { return a.CreatedAt.After(decodedCursor) }, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	last := domain.Article{ID: c.ID, Title: c.Value}
+	if order != domain.SortTitle && order != domain.SortTitleDesc {
+		t, err := c.Time()
+		if err != nil {
+			return nil, domain.ErrBadParamInput
+		}
+		last.CreatedAt, last.UpdatedAt = t, t
+	}
+	return func(a domain.Article) bool { return less(order, last, a) }, nil
+}
+
+func compareTime(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
+
+// matches reports whether the article passes the conditions of the filter
+func matches(filter domain.ArticleFilter, a domain.Article) bool {
+	switch {
+	case len(filter.TagIDs) > 0 && !containsID(filter.TagIDs, a.Tag.ID):
+		return false
+	case len(filter.TagIDs) == 0 && filter.TagID != 0 && a.Tag.ID != filter.TagID:
+		return false
+	case !filter.CreatedAfter.IsZero() && !a.CreatedAt.After(filter.CreatedAfter):
+		return false
+	case !filter.CreatedBefore.IsZero() && !a.CreatedAt.Before(filter.CreatedBefore):
+		return false
+	case !filter.UpdatedSince.IsZero() && a.UpdatedAt.Before(filter.UpdatedSince):
+		return false
+	case filter.Query != "" && !strings.HasPrefix(strings.ToLower(a.Title), strings.ToLower(filter.Query)):
+		return false
+	}
+	return true
+}
+
+func containsID(ids []int64, id int64) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+// roundTime keeps the microsecond precision a database column would
+func roundTime(t time.Time) time.Time {
+	return t.Round(time.Microsecond)
+}
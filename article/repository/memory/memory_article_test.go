@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"go-postgres-clean-arch/article/repository/memory"
+	"go-postgres-clean-arch/article/repository/repositorytest"
+	"go-postgres-clean-arch/domain"
+)
+
+func TestMemoryArticleRepositoryContract(t *testing.T) {
+	repositorytest.RunArticleContract(t, func(t *testing.T) domain.ArticleRepository {
+		return memory.NewMemoryArticleRepository()
+	})
+}
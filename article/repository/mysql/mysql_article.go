@@ -0,0 +1,306 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-postgres-clean-arch/article/repository"
+	"go-postgres-clean-arch/domain"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/sirupsen/logrus"
+)
+
+// errDupEntry is the mysql error number raised when a unique key is violated
+const errDupEntry = 1062
+
+type mysqlArticleRepository struct {
+	Conn *sql.DB
+}
+
+// NewMysqlArticleRepository will create an object that represent the article.Repository interface.
+// The connection must be opened with parseTime=true so the timestamps scan into time.Time.
+func NewMysqlArticleRepository(conn *sql.DB) domain.ArticleRepository {
+	return &mysqlArticleRepository{conn}
+}
+
+func (m *mysqlArticleRepository) fetch(ctx context.Context, query string, args ...interface{}) (result []domain.Article, err error) {
+	rows, err := m.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(errRow)
+		}
+	}()
+
+	result = make([]domain.Article, 0)
+	for rows.Next() {
+		t := domain.Article{}
+		tagID := int64(0)
+		slug := sql.NullString{}
+		err = rows.Scan(
+			&t.ID,
+			&t.Title,
+			&slug,
+			&t.Content,
+			&t.ContentFormat,
+			&tagID,
+			&t.Version,
+			&t.UpdatedAt,
+			&t.CreatedAt,
+		)
+
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		t.Slug = slug.String
+		t.Tag = domain.Tag{
+			ID: tagID,
+		}
+		result = append(result, t)
+	}
+
+	return result, nil
+}
+
+func (m *mysqlArticleRepository) Fetch(ctx context.Context, cursor string, num int64) (res []domain.Article, nextCursor string, err error) {
+	return m.FetchFiltered(ctx, domain.ArticleFilter{}, cursor, num)
+}
+
+func (m *mysqlArticleRepository) FetchFiltered(ctx context.Context, filter domain.ArticleFilter, cursor string, num int64) (res []domain.Article, nextCursor string, err error) {
+	q, err := repository.NewListQuery(filter, cursor, timeArg)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query, args := q.SQL(`id,title, slug, content, content_format, tag_id, version, updated_at, created_at`, num, false)
+	res, err = m.fetch(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return res, repository.NextCursor(filter, res, num), nil
+}
+
+func (m *mysqlArticleRepository) Count(ctx context.Context, filter domain.ArticleFilter) (total int64, err error) {
+	q, err := repository.NewListQuery(filter, "", timeArg)
+	if err != nil {
+		return 0, err
+	}
+
+	query, args := q.CountSQL(false)
+	err = m.Conn.QueryRowContext(ctx, query, args...).Scan(&total)
+	return
+}
+
+func (m *mysqlArticleRepository) GetByID(ctx context.Context, id int64) (res domain.Article, err error) {
+	query := `SELECT id,title, slug, content, content_format, tag_id, version, updated_at, created_at
+				FROM article
+				WHERE id = ?`
+
+	list, err := m.fetch(ctx, query, id)
+	if err != nil {
+		return domain.Article{}, err
+	}
+
+	if len(list) > 0 {
+		res = list[0]
+	} else {
+		return res, domain.ErrNotFound
+	}
+
+	return
+}
+
+func (m *mysqlArticleRepository) GetByTitle(ctx context.Context, title string) (res domain.Article, err error) {
+	query := `SELECT id,title, slug, content, content_format, tag_id, version, updated_at, created_at
+				FROM article
+				WHERE title = ?`
+
+	list, err := m.fetch(ctx, query, title)
+	if err != nil {
+		return
+	}
+
+	if len(list) > 0 {
+		res = list[0]
+	} else {
+		return res, domain.ErrNotFound
+	}
+	return
+}
+
+func (m *mysqlArticleRepository) GetBySlug(ctx context.Context, slug string) (res domain.Article, err error) {
+	query := `SELECT id,title, slug, content, content_format, tag_id, version, updated_at, created_at
+				FROM article
+				WHERE slug = ?`
+
+	return m.getOne(ctx, query, slug)
+}
+
+func (m *mysqlArticleRepository) GetByOldSlug(ctx context.Context, slug string) (res domain.Article, err error) {
+	query := `SELECT id,title, slug, content, content_format, tag_id, version, updated_at, created_at
+				FROM article
+				WHERE id = (SELECT article_id FROM article_slug_history WHERE slug = ?)`
+
+	return m.getOne(ctx, query, slug)
+}
+
+func (m *mysqlArticleRepository) getOne(ctx context.Context, query string, args ...interface{}) (res domain.Article, err error) {
+	list, err := m.fetch(ctx, query, args...)
+	if err != nil {
+		return
+	}
+
+	if len(list) == 0 {
+		return res, domain.ErrNotFound
+	}
+	return list[0], nil
+}
+
+func (m *mysqlArticleRepository) Store(ctx context.Context, a *domain.CreateArticleInput) (err error) {
+	query := `INSERT INTO article (title, slug, content, content_format, tag_id, updated_at, created_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?)`
+	stmt, err := m.Conn.PrepareContext(ctx, query)
+	if err != nil {
+		return
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, a.Title, repository.NullString(a.Slug), a.Content, a.ContentFormat, a.TagID, a.UpdatedAt, a.CreatedAt)
+	if err != nil {
+		return translateError(err)
+	}
+
+	a.ID, err = res.LastInsertId()
+	return
+}
+
+func (m *mysqlArticleRepository) Delete(ctx context.Context, id int64, version int64) (err error) {
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback() //nolint
+
+	if version != 0 {
+		var current int64
+		err = tx.QueryRowContext(ctx, "SELECT version FROM article WHERE id = ? FOR UPDATE", id).Scan(&current)
+		if err == sql.ErrNoRows {
+			return domain.ErrNotFound
+		}
+		if err != nil {
+			return
+		}
+		if current != version {
+			return domain.ErrPreconditionFailed
+		}
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM article_slug_history WHERE article_id = ?", id); err != nil {
+		return
+	}
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM article WHERE id = ?", id)
+	if err != nil {
+		return
+	}
+
+	rowsAfected, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+
+	if rowsAfected == 0 {
+		return domain.ErrNotFound
+	}
+	if rowsAfected != 1 {
+		err = fmt.Errorf("weird  Behavior. Total Affected: %d", rowsAfected)
+		return
+	}
+
+	return tx.Commit()
+}
+
+func (m *mysqlArticleRepository) Update(ctx context.Context, ar *domain.UpdateArticleInput) (err error) {
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback() //nolint
+
+	var oldSlug sql.NullString
+	var version int64
+	err = tx.QueryRowContext(ctx, "SELECT slug, version FROM article WHERE id = ? FOR UPDATE", ar.ID).Scan(&oldSlug, &version)
+	if err == sql.ErrNoRows {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		return
+	}
+	if ar.Version != 0 && ar.Version != version {
+		return domain.ErrPreconditionFailed
+	}
+	if ar.Slug == "" {
+		ar.Slug = oldSlug.String
+	}
+
+	// mysql reports the changed rows, the incremented version changes the row even when the other
+	// values are the same
+	query := `UPDATE article SET title=?, slug=?, content=?, content_format=?, tag_id=?, updated_at=?, version=version+1
+				WHERE id = ? AND version = ?`
+	res, err := tx.ExecContext(ctx, query, ar.Title, repository.NullString(ar.Slug), ar.Content, ar.ContentFormat, ar.TagID, ar.UpdatedAt, ar.ID, version)
+	if err != nil {
+		return translateError(err)
+	}
+	affect, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if affect == 0 {
+		return domain.ErrPreconditionFailed
+	}
+	if affect > 1 {
+		err = fmt.Errorf("weird  Behavior. Total Affected: %d", affect)
+		return
+	}
+
+	// the new slug may be a previous one, it no longer redirects
+	if _, err = tx.ExecContext(ctx, "DELETE FROM article_slug_history WHERE slug = ?", ar.Slug); err != nil {
+		return
+	}
+	if oldSlug.String != "" && oldSlug.String != ar.Slug {
+		history := "INSERT INTO article_slug_history (slug, article_id, created_at) VALUES (?, ?, ?)"
+		if _, err = tx.ExecContext(ctx, history, oldSlug.String, ar.ID, ar.UpdatedAt); err != nil {
+			return translateError(err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return
+	}
+	ar.Version = version + 1
+	return nil
+}
+
+// translateError maps the mysql constraint violations to the domain errors
+func translateError(err error) error {
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) && myErr.Number == errDupEntry {
+		return domain.ErrConflict
+	}
+	return err
+}
+
+// timeArg passes the timestamps of a listing query unchanged, the driver converts them
+func timeArg(t time.Time) interface{} {
+	return t
+}
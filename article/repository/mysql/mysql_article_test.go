@@ -0,0 +1,23 @@
+package mysql_test
+
+import (
+	"testing"
+
+	"go-postgres-clean-arch/article/repository/mysql"
+	"go-postgres-clean-arch/article/repository/repositorytest"
+	"go-postgres-clean-arch/domain"
+	"go-postgres-clean-arch/helper/mysqltest"
+)
+
+func TestMysqlArticleRepositoryContract(t *testing.T) {
+	db := mysqltest.OpenMigrated(t)
+
+	repositorytest.RunArticleContract(t, func(t *testing.T) domain.ArticleRepository {
+		for _, table := range []string{"article", "article_slug_history"} {
+			if _, err := db.Exec(`TRUNCATE TABLE ` + table); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return mysql.NewMysqlArticleRepository(db)
+	})
+}
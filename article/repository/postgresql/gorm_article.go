@@ -0,0 +1,251 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go-postgres-clean-arch/article/repository"
+	"go-postgres-clean-arch/domain"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// gormArticle is the GORM model of the article table
+type gormArticle struct {
+	ID            int64 `gorm:"primaryKey"`
+	Title         string
+	Slug          sql.NullString
+	Content       string
+	ContentFormat string
+	TagID         int64
+	Version       int64     `gorm:"default:1"`
+	UpdatedAt     time.Time `gorm:"autoUpdateTime:false"`
+	CreatedAt     time.Time `gorm:"autoCreateTime:false"`
+}
+
+func (gormArticle) TableName() string {
+	return "article"
+}
+
+// gormSlugHistory is the GORM model of the article_slug_history table
+type gormSlugHistory struct {
+	Slug      string `gorm:"primaryKey"`
+	ArticleID int64
+	CreatedAt time.Time `gorm:"autoCreateTime:false"`
+}
+
+func (gormSlugHistory) TableName() string {
+	return "article_slug_history"
+}
+
+func (g gormArticle) toDomain() domain.Article {
+	return domain.Article{
+		ID:            g.ID,
+		Title:         g.Title,
+		Slug:          g.Slug.String,
+		Content:       g.Content,
+		ContentFormat: g.ContentFormat,
+		Tag:           domain.Tag{ID: g.TagID},
+		Version:       g.Version,
+		UpdatedAt:     g.UpdatedAt,
+		CreatedAt:     g.CreatedAt,
+	}
+}
+
+type gormArticleRepository struct {
+	Db *gorm.DB
+}
+
+// NewGormArticleRepository will create a GORM based object that represent the article.Repository interface.
+// The db must be opened with TranslateError enabled so unique violations surface as domain.ErrConflict.
+func NewGormArticleRepository(db *gorm.DB) domain.ArticleRepository {
+	return &gormArticleRepository{db}
+}
+
+func (m *gormArticleRepository) Fetch(ctx context.Context, cursor string, num int64) (res []domain.Article, nextCursor string, err error) {
+	return m.FetchFiltered(ctx, domain.ArticleFilter{}, cursor, num)
+}
+
+func (m *gormArticleRepository) FetchFiltered(ctx context.Context, filter domain.ArticleFilter, cursor string, num int64) (res []domain.Article, nextCursor string, err error) {
+	q, err := repository.NewListQuery(filter, cursor, timeArg)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query := m.Db.WithContext(ctx).Order(q.OrderBy).Limit(int(num))
+	if q.Where != "" {
+		query = query.Where(q.Where, q.Args...)
+	}
+
+	var rows []gormArticle
+	if err = query.Find(&rows).Error; err != nil {
+		return nil, "", err
+	}
+
+	res = make([]domain.Article, 0, len(rows))
+	for _, row := range rows {
+		res = append(res, row.toDomain())
+	}
+
+	return res, repository.NextCursor(filter, res, num), nil
+}
+
+func (m *gormArticleRepository) Count(ctx context.Context, filter domain.ArticleFilter) (total int64, err error) {
+	q, err := repository.NewListQuery(filter, "", timeArg)
+	if err != nil {
+		return 0, err
+	}
+
+	query := m.Db.WithContext(ctx).Model(&gormArticle{})
+	if q.Where != "" {
+		query = query.Where(q.Where, q.Args...)
+	}
+	err = query.Count(&total).Error
+	return
+}
+
+func (m *gormArticleRepository) GetByID(ctx context.Context, id int64) (domain.Article, error) {
+	var row gormArticle
+	err := m.Db.WithContext(ctx).Where("id = ?", id).Take(&row).Error
+	if err != nil {
+		return domain.Article{}, translateGormError(err)
+	}
+
+	return row.toDomain(), nil
+}
+
+func (m *gormArticleRepository) GetByTitle(ctx context.Context, title string) (domain.Article, error) {
+	var row gormArticle
+	err := m.Db.WithContext(ctx).Where("title = ?", title).Take(&row).Error
+	if err != nil {
+		return domain.Article{}, translateGormError(err)
+	}
+
+	return row.toDomain(), nil
+}
+
+func (m *gormArticleRepository) GetBySlug(ctx context.Context, slug string) (domain.Article, error) {
+	var row gormArticle
+	err := m.Db.WithContext(ctx).Where("slug = ?", slug).Take(&row).Error
+	if err != nil {
+		return domain.Article{}, translateGormError(err)
+	}
+
+	return row.toDomain(), nil
+}
+
+func (m *gormArticleRepository) GetByOldSlug(ctx context.Context, slug string) (domain.Article, error) {
+	var row gormArticle
+	history := m.Db.Model(&gormSlugHistory{}).Select("article_id").Where("slug = ?", slug)
+	err := m.Db.WithContext(ctx).Where("id = (?)", history).Take(&row).Error
+	if err != nil {
+		return domain.Article{}, translateGormError(err)
+	}
+
+	return row.toDomain(), nil
+}
+
+func (m *gormArticleRepository) Store(ctx context.Context, a *domain.CreateArticleInput) error {
+	row := gormArticle{
+		Title:         a.Title,
+		Slug:          repository.NullString(a.Slug),
+		Content:       a.Content,
+		ContentFormat: a.ContentFormat,
+		TagID:         a.TagID,
+		UpdatedAt:     a.UpdatedAt,
+		CreatedAt:     a.CreatedAt,
+	}
+	if err := m.Db.WithContext(ctx).Create(&row).Error; err != nil {
+		return translateGormError(err)
+	}
+
+	a.ID = row.ID
+	return nil
+}
+
+func (m *gormArticleRepository) Update(ctx context.Context, ar *domain.UpdateArticleInput) error {
+	return m.Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current gormArticle
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("slug", "version").Where("id = ?", ar.ID).Take(&current).Error
+		if err != nil {
+			return translateGormError(err)
+		}
+		if ar.Version != 0 && ar.Version != current.Version {
+			return domain.ErrPreconditionFailed
+		}
+		if ar.Slug == "" {
+			ar.Slug = current.Slug.String
+		}
+
+		result := tx.Model(&gormArticle{}).Where("id = ? AND version = ?", ar.ID, current.Version).Updates(map[string]interface{}{
+			"title":          ar.Title,
+			"slug":           repository.NullString(ar.Slug),
+			"content":        ar.Content,
+			"content_format": ar.ContentFormat,
+			"tag_id":         ar.TagID,
+			"updated_at":     ar.UpdatedAt,
+			"version":        gorm.Expr("version + 1"),
+		})
+		if result.Error != nil {
+			return translateGormError(result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrPreconditionFailed
+		}
+
+		// the new slug may be a previous one, it no longer redirects
+		if err = tx.Where("slug = ?", ar.Slug).Delete(&gormSlugHistory{}).Error; err != nil {
+			return err
+		}
+		if current.Slug.String != "" && current.Slug.String != ar.Slug {
+			history := gormSlugHistory{Slug: current.Slug.String, ArticleID: ar.ID, CreatedAt: ar.UpdatedAt}
+			if err = tx.Create(&history).Error; err != nil {
+				return translateGormError(err)
+			}
+		}
+		ar.Version = current.Version + 1
+		return nil
+	})
+}
+
+func (m *gormArticleRepository) Delete(ctx context.Context, id int64, version int64) error {
+	return m.Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if version != 0 {
+			var current gormArticle
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("version").Where("id = ?", id).Take(&current).Error
+			if err != nil {
+				return translateGormError(err)
+			}
+			if current.Version != version {
+				return domain.ErrPreconditionFailed
+			}
+		}
+
+		if err := tx.Where("article_id = ?", id).Delete(&gormSlugHistory{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Delete(&gormArticle{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrNotFound
+		}
+		return nil
+	})
+}
+
+// translateGormError maps the GORM errors to the domain errors
+func translateGormError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return domain.ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return domain.ErrConflict
+	default:
+		return err
+	}
+}
@@ -3,13 +3,19 @@ package postgresql
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"go-postgres-clean-arch/article/repository"
 	"go-postgres-clean-arch/domain"
+	"time"
 
+	"github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 )
 
+// uniqueViolation is the postgresql error code raised when a unique constraint is violated
+const uniqueViolation = "23505"
+
 type postgresqlArticleRepository struct {
 	Conn *sql.DB
 }
@@ -37,11 +43,15 @@ func (m *postgresqlArticleRepository) fetch(ctx context.Context, query string, a
 	for rows.Next() {
 		t := domain.Article{}
 		tagID := int64(0)
+		slug := sql.NullString{}
 		err = rows.Scan(
 			&t.ID,
 			&t.Title,
+			&slug,
 			&t.Content,
+			&t.ContentFormat,
 			&tagID,
+			&t.Version,
 			&t.UpdatedAt,
 			&t.CreatedAt,
 		)
@@ -50,6 +60,7 @@ func (m *postgresqlArticleRepository) fetch(ctx context.Context, query string, a
 			logrus.Error(err)
 			return nil, err
 		}
+		t.Slug = slug.String
 		t.Tag = domain.Tag{
 			ID: tagID,
 		}
@@ -60,50 +71,37 @@ func (m *postgresqlArticleRepository) fetch(ctx context.Context, query string, a
 }
 
 func (m *postgresqlArticleRepository) Fetch(ctx context.Context, cursor string, num int64) (res []domain.Article, nextCursor string, err error) {
-	var query string
-
-	if cursor != "" {
-		query = `SELECT id,title,content, tag_id, updated_at, created_at
-					FROM article 
-					WHERE created_at > $1 
-					ORDER BY created_at 
-					LIMIT $2 `
-
-		// Example decodedCursor or cursor: 2023-11-30 15:20:33.682 +0000 UTC
-		// Example encodeCursor and cursor: MjAyMy0xMS0zMFQxNToyMDozMy42ODJa
-
-		decodedCursor, err := repository.DecodeCursor(cursor)
-		if err != nil && cursor != "" {
-			return nil, "", domain.ErrBadParamInput
-		}
-
-		res, err = m.fetch(ctx, query, decodedCursor, num)
-		if err != nil {
-			return nil, "", err
-		}
+	return m.FetchFiltered(ctx, domain.ArticleFilter{}, cursor, num)
+}
 
-		if len(res) == int(num) {
-			nextCursor = repository.EncodeCursor(res[len(res)-1].CreatedAt)
-		}
+func (m *postgresqlArticleRepository) FetchFiltered(ctx context.Context, filter domain.ArticleFilter, cursor string, num int64) (res []domain.Article, nextCursor string, err error) {
+	q, err := repository.NewListQuery(filter, cursor, timeArg)
+	if err != nil {
+		return nil, "", err
+	}
 
-		return res, nextCursor, nil
+	query, args := q.SQL(`id,title, slug, content, content_format, tag_id, version, updated_at, created_at`, num, true)
+	res, err = m.fetch(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
 	}
 
-	query = `SELECT id,title,content, tag_id, updated_at, created_at
-				FROM article 
-				ORDER BY created_at 
-				LIMIT $1 `
+	return res, repository.NextCursor(filter, res, num), nil
+}
 
-	res, err = m.fetch(ctx, query, num)
+func (m *postgresqlArticleRepository) Count(ctx context.Context, filter domain.ArticleFilter) (total int64, err error) {
+	q, err := repository.NewListQuery(filter, "", timeArg)
 	if err != nil {
-		return nil, "", err
+		return 0, err
 	}
 
+	query, args := q.CountSQL(true)
+	err = m.Conn.QueryRowContext(ctx, query, args...).Scan(&total)
 	return
 }
 
 func (m *postgresqlArticleRepository) GetByID(ctx context.Context, id int64) (res domain.Article, err error) {
-	query := `SELECT id,title,content, tag_id, updated_at, created_at
+	query := `SELECT id,title, slug, content, content_format, tag_id, version, updated_at, created_at
 				FROM article 
 				WHERE ID = $1`
 
@@ -122,7 +120,7 @@ func (m *postgresqlArticleRepository) GetByID(ctx context.Context, id int64) (re
 }
 
 func (m *postgresqlArticleRepository) GetByTitle(ctx context.Context, title string) (res domain.Article, err error) {
-	query := `SELECT id,title,content, tag_id, updated_at, created_at
+	query := `SELECT id,title, slug, content, content_format, tag_id, version, updated_at, created_at
 				FROM article 
 				WHERE title = $1`
 
@@ -139,31 +137,76 @@ func (m *postgresqlArticleRepository) GetByTitle(ctx context.Context, title stri
 	return
 }
 
+func (m *postgresqlArticleRepository) GetBySlug(ctx context.Context, slug string) (res domain.Article, err error) {
+	query := `SELECT id,title, slug, content, content_format, tag_id, version, updated_at, created_at
+				FROM article 
+				WHERE slug = $1`
+
+	return m.getOne(ctx, query, slug)
+}
+
+func (m *postgresqlArticleRepository) GetByOldSlug(ctx context.Context, slug string) (res domain.Article, err error) {
+	query := `SELECT id,title, slug, content, content_format, tag_id, version, updated_at, created_at
+				FROM article 
+				WHERE id = (SELECT article_id FROM article_slug_history WHERE slug = $1)`
+
+	return m.getOne(ctx, query, slug)
+}
+
+func (m *postgresqlArticleRepository) getOne(ctx context.Context, query string, args ...interface{}) (res domain.Article, err error) {
+	list, err := m.fetch(ctx, query, args...)
+	if err != nil {
+		return
+	}
+
+	if len(list) == 0 {
+		return res, domain.ErrNotFound
+	}
+	return list[0], nil
+}
+
 func (m *postgresqlArticleRepository) Store(ctx context.Context, a *domain.CreateArticleInput) (err error) {
-	query := `INSERT INTO article (title, content, tag_id, updated_at , created_at) 
-				VALUES ($1, $2, $3, $4, $5)
+	query := `INSERT INTO article (title, slug, content, content_format, tag_id, updated_at , created_at) 
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
 				RETURNING ID`
 	stmt, err := m.Conn.PrepareContext(ctx, query)
 	if err != nil {
 		return
 	}
 
-	err = stmt.QueryRowContext(ctx, a.Title, a.Content, a.TagID, a.UpdatedAt, a.CreatedAt).Err()
+	err = stmt.QueryRowContext(ctx, a.Title, repository.NullString(a.Slug), a.Content, a.ContentFormat, a.TagID, a.UpdatedAt, a.CreatedAt).Scan(&a.ID)
 	if err != nil {
-		return
+		return translateError(err)
 	}
 	return
 }
 
-func (m *postgresqlArticleRepository) Delete(ctx context.Context, id int64) (err error) {
-	query := "DELETE FROM article WHERE id = $1"
-
-	stmt, err := m.Conn.PrepareContext(ctx, query)
+func (m *postgresqlArticleRepository) Delete(ctx context.Context, id int64, version int64) (err error) {
+	tx, err := m.Conn.BeginTx(ctx, nil)
 	if err != nil {
 		return
 	}
+	defer tx.Rollback() //nolint
+
+	if version != 0 {
+		var current int64
+		err = tx.QueryRowContext(ctx, "SELECT version FROM article WHERE id = $1 FOR UPDATE", id).Scan(&current)
+		if err == sql.ErrNoRows {
+			return domain.ErrNotFound
+		}
+		if err != nil {
+			return
+		}
+		if current != version {
+			return domain.ErrPreconditionFailed
+		}
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM article_slug_history WHERE article_id = $1", id); err != nil {
+		return
+	}
 
-	res, err := stmt.ExecContext(ctx, id)
+	res, err := tx.ExecContext(ctx, "DELETE FROM article WHERE id = $1", id)
 	if err != nil {
 		return
 	}
@@ -173,34 +216,86 @@ func (m *postgresqlArticleRepository) Delete(ctx context.Context, id int64) (err
 		return
 	}
 
+	if rowsAfected == 0 {
+		return domain.ErrNotFound
+	}
 	if rowsAfected != 1 {
 		err = fmt.Errorf("weird  Behavior. Total Affected: %d", rowsAfected)
 		return
 	}
 
-	return
+	return tx.Commit()
 }
 
 func (m *postgresqlArticleRepository) Update(ctx context.Context, ar *domain.UpdateArticleInput) (err error) {
-	query := `UPDATE article SET title=$1, content=$2, tag_id=$3, updated_at=$4 WHERE id = $5;`
-
-	stmt, err := m.Conn.PrepareContext(ctx, query)
+	tx, err := m.Conn.BeginTx(ctx, nil)
 	if err != nil {
 		return
 	}
+	defer tx.Rollback() //nolint
 
-	res, err := stmt.ExecContext(ctx, ar.Title, ar.Content, ar.TagID, ar.UpdatedAt, ar.ID)
+	var oldSlug sql.NullString
+	var version int64
+	err = tx.QueryRowContext(ctx, "SELECT slug, version FROM article WHERE id = $1 FOR UPDATE", ar.ID).Scan(&oldSlug, &version)
+	if err == sql.ErrNoRows {
+		return domain.ErrNotFound
+	}
 	if err != nil {
 		return
 	}
+	if ar.Version != 0 && ar.Version != version {
+		return domain.ErrPreconditionFailed
+	}
+	if ar.Slug == "" {
+		ar.Slug = oldSlug.String
+	}
+
+	query := `UPDATE article SET title=$1, slug=$2, content=$3, content_format=$4, tag_id=$5, updated_at=$6, version=version+1
+				WHERE id = $7 AND version = $8;`
+	res, err := tx.ExecContext(ctx, query, ar.Title, repository.NullString(ar.Slug), ar.Content, ar.ContentFormat, ar.TagID, ar.UpdatedAt, ar.ID, version)
+	if err != nil {
+		return translateError(err)
+	}
 	affect, err := res.RowsAffected()
 	if err != nil {
 		return
 	}
+	if affect == 0 {
+		return domain.ErrPreconditionFailed
+	}
 	if affect != 1 {
 		err = fmt.Errorf("weird  Behavior. Total Affected: %d", affect)
 		return
 	}
 
-	return
+	// the new slug may be a previous one, it no longer redirects
+	if _, err = tx.ExecContext(ctx, "DELETE FROM article_slug_history WHERE slug = $1", ar.Slug); err != nil {
+		return
+	}
+	if oldSlug.String != "" && oldSlug.String != ar.Slug {
+		history := "INSERT INTO article_slug_history (slug, article_id, created_at) VALUES ($1, $2, $3)"
+		if _, err = tx.ExecContext(ctx, history, oldSlug.String, ar.ID, ar.UpdatedAt); err != nil {
+			return translateError(err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return
+	}
+	ar.Version = version + 1
+	return nil
+}
+
+// translateError maps the postgresql constraint violations to the domain errors
+func translateError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return domain.ErrConflict
+	}
+	return err
+}
+
+// timeArg passes the timestamps of a listing query unchanged, the driver converts them
+func timeArg(t time.Time) interface{} {
+	return t
 }
@@ -0,0 +1,406 @@
+// Package repositorytest holds the contract every domain.ArticleRepository implementation must satisfy.
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-postgres-clean-arch/article/repository"
+	"go-postgres-clean-arch/domain"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RunArticleContract runs the shared contract against the repositories built by newRepo,
+// newRepo is called once per subtest and must return a repository backed by an empty store.
+func RunArticleContract(t *testing.T, newRepo func(t *testing.T) domain.ArticleRepository) {
+	base := time.Date(2023, 11, 30, 15, 20, 33, 682000, time.UTC)
+
+	store := func(t *testing.T, repo domain.ArticleRepository, title string, createdAt time.Time) *domain.CreateArticleInput {
+		t.Helper()
+		a := &domain.CreateArticleInput{
+			Title:         title,
+			Slug:          strings.ReplaceAll(title, " ", "-"),
+			Content:       "content of " + title,
+			ContentFormat: domain.ContentFormatMarkdown,
+			TagID:         1,
+			CreatedAt:     createdAt,
+			UpdatedAt:     createdAt,
+		}
+		if err := repo.Store(context.Background(), a); err != nil {
+			t.Fatalf("Store(%q): %v", title, err)
+		}
+		return a
+	}
+
+	t.Run("StoreAssignsIDAndGetByID", func(t *testing.T) {
+		repo := newRepo(t)
+		a := store(t, repo, "first", base)
+		if a.ID == 0 {
+			t.Fatal("Store did not assign an id")
+		}
+
+		got, err := repo.GetByID(context.Background(), a.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ID != a.ID || got.Title != a.Title || got.Slug != a.Slug || got.Content != a.Content || got.ContentFormat != a.ContentFormat || got.Tag.ID != a.TagID {
+			t.Fatalf("GetByID returned %+v, stored %+v", got, a)
+		}
+		if !got.CreatedAt.Equal(base) || !got.UpdatedAt.Equal(base) {
+			t.Fatalf("timestamps not kept: %v %v", got.CreatedAt, got.UpdatedAt)
+		}
+	})
+
+	t.Run("GetByTitle", func(t *testing.T) {
+		repo := newRepo(t)
+		a := store(t, repo, "by title", base)
+
+		got, err := repo.GetByTitle(context.Background(), "by title")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ID != a.ID {
+			t.Fatalf("GetByTitle returned id %d, want %d", got.ID, a.ID)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := context.Background()
+
+		if _, err := repo.GetByID(ctx, 404); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("GetByID: expected ErrNotFound, got %v", err)
+		}
+		if _, err := repo.GetByTitle(ctx, "missing"); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("GetByTitle: expected ErrNotFound, got %v", err)
+		}
+		if _, err := repo.GetBySlug(ctx, "missing"); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("GetBySlug: expected ErrNotFound, got %v", err)
+		}
+		if _, err := repo.GetByOldSlug(ctx, "missing"); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("GetByOldSlug: expected ErrNotFound, got %v", err)
+		}
+		if err := repo.Update(ctx, &domain.UpdateArticleInput{ID: 404, Title: "missing", Content: "c", TagID: 1, UpdatedAt: base}); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("Update: expected ErrNotFound, got %v", err)
+		}
+		if err := repo.Delete(ctx, 404, 0); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("Delete: expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("Conflict", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := context.Background()
+		store(t, repo, "taken", base)
+		other := store(t, repo, "other", base.Add(time.Second))
+
+		dup := &domain.CreateArticleInput{Title: "taken", Content: "c", TagID: 1, CreatedAt: base, UpdatedAt: base}
+		if err := repo.Store(ctx, dup); !errors.Is(err, domain.ErrConflict) {
+			t.Errorf("Store: expected ErrConflict, got %v", err)
+		}
+
+		rename := &domain.UpdateArticleInput{ID: other.ID, Title: "taken", Content: "c", TagID: 1, UpdatedAt: base}
+		if err := repo.Update(ctx, rename); !errors.Is(err, domain.ErrConflict) {
+			t.Errorf("Update: expected ErrConflict, got %v", err)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := context.Background()
+		a := store(t, repo, "before", base)
+
+		updatedAt := base.Add(time.Hour)
+		err := repo.Update(ctx, &domain.UpdateArticleInput{ID: a.ID, Title: "after", Content: "new content", ContentFormat: domain.ContentFormatHTML, TagID: 2, UpdatedAt: updatedAt})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := repo.GetByID(ctx, a.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Title != "after" || got.Content != "new content" || got.ContentFormat != domain.ContentFormatHTML || got.Tag.ID != 2 {
+			t.Fatalf("Update not applied: %+v", got)
+		}
+		if !got.UpdatedAt.Equal(updatedAt) || !got.CreatedAt.Equal(base) {
+			t.Fatalf("unexpected timestamps: created %v updated %v", got.CreatedAt, got.UpdatedAt)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := context.Background()
+		a := store(t, repo, "doomed", base)
+
+		if err := repo.Delete(ctx, a.ID, 0); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.GetByID(ctx, a.ID); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound after Delete, got %v", err)
+		}
+	})
+
+	t.Run("Version", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := context.Background()
+		a := store(t, repo, "versioned", base)
+
+		if got, err := repo.GetByID(ctx, a.ID); err != nil || got.Version != 1 {
+			t.Fatalf("GetByID returned %+v %v, want version 1", got, err)
+		}
+
+		change := &domain.UpdateArticleInput{ID: a.ID, Title: "versioned", Content: "first", TagID: 1, UpdatedAt: base, Version: 1}
+		if err := repo.Update(ctx, change); err != nil || change.Version != 2 {
+			t.Fatalf("Update returned version %d %v, want 2", change.Version, err)
+		}
+
+		// a change applying to the previous version is refused and leaves the article as it is
+		stale := &domain.UpdateArticleInput{ID: a.ID, Title: "versioned", Content: "stale", TagID: 1, UpdatedAt: base, Version: 1}
+		if err := repo.Update(ctx, stale); !errors.Is(err, domain.ErrPreconditionFailed) {
+			t.Fatalf("Update: expected ErrPreconditionFailed, got %v", err)
+		}
+		if got, _ := repo.GetByID(ctx, a.ID); got.Content != "first" || got.Version != 2 {
+			t.Fatalf("stale update applied: %+v", got)
+		}
+
+		// an unconditional change applies to any version
+		any := &domain.UpdateArticleInput{ID: a.ID, Title: "versioned", Content: "second", TagID: 1, UpdatedAt: base}
+		if err := repo.Update(ctx, any); err != nil || any.Version != 3 {
+			t.Fatalf("Update returned version %d %v, want 3", any.Version, err)
+		}
+
+		if err := repo.Delete(ctx, a.ID, 2); !errors.Is(err, domain.ErrPreconditionFailed) {
+			t.Fatalf("Delete: expected ErrPreconditionFailed, got %v", err)
+		}
+		if err := repo.Delete(ctx, 404, 1); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("Delete: expected ErrNotFound, got %v", err)
+		}
+		if err := repo.Delete(ctx, a.ID, 3); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Slugs", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := context.Background()
+		a := store(t, repo, "first slug", base)
+		other := store(t, repo, "other", base.Add(time.Second))
+
+		got, err := repo.GetBySlug(ctx, "first-slug")
+		if err != nil || got.ID != a.ID {
+			t.Fatalf("GetBySlug returned %+v %v", got, err)
+		}
+
+		dup := &domain.CreateArticleInput{Title: "dup", Slug: "first-slug", Content: "c", TagID: 1, CreatedAt: base, UpdatedAt: base}
+		if err := repo.Store(ctx, dup); !errors.Is(err, domain.ErrConflict) {
+			t.Errorf("Store: expected ErrConflict, got %v", err)
+		}
+
+		// an update without slug keeps the current one
+		if err := repo.Update(ctx, &domain.UpdateArticleInput{ID: a.ID, Title: "first slug", Content: "c", TagID: 1, UpdatedAt: base}); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ = repo.GetByID(ctx, a.ID); got.Slug != "first-slug" {
+			t.Fatalf("slug not kept: %q", got.Slug)
+		}
+
+		if err := repo.Update(ctx, &domain.UpdateArticleInput{ID: a.ID, Title: "renamed", Slug: "renamed", Content: "c", TagID: 1, UpdatedAt: base}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.GetBySlug(ctx, "first-slug"); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("GetBySlug: expected ErrNotFound for the old slug, got %v", err)
+		}
+		if got, err = repo.GetByOldSlug(ctx, "first-slug"); err != nil || got.Slug != "renamed" {
+			t.Fatalf("GetByOldSlug returned %+v %v", got, err)
+		}
+
+		// taking back a previous slug drops it from the history
+		if err := repo.Update(ctx, &domain.UpdateArticleInput{ID: other.ID, Title: "other", Slug: "first-slug", Content: "c", TagID: 1, UpdatedAt: base}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.GetByOldSlug(ctx, "first-slug"); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("GetByOldSlug: expected ErrNotFound for a reused slug, got %v", err)
+		}
+		if got, _ = repo.GetBySlug(ctx, "first-slug"); got.ID != other.ID {
+			t.Fatalf("GetBySlug returned %+v, want article %d", got, other.ID)
+		}
+		if _, err := repo.GetByOldSlug(ctx, "other"); err != nil {
+			t.Fatalf("GetByOldSlug(other): %v", err)
+		}
+
+		if err := repo.Delete(ctx, other.ID, 0); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.GetByOldSlug(ctx, "other"); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("GetByOldSlug: expected ErrNotFound after Delete, got %v", err)
+		}
+	})
+
+	t.Run("FetchCursor", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := context.Background()
+		for i := 0; i < 5; i++ {
+			store(t, repo, fmt.Sprintf("article %d", i), base.Add(time.Duration(i)*time.Minute))
+		}
+
+		var titles []string
+		cursor := ""
+		for page := 0; ; page++ {
+			if page > 5 {
+				t.Fatal("pagination does not terminate")
+			}
+			res, next, err := repo.Fetch(ctx, cursor, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, a := range res {
+				titles = append(titles, a.Title)
+			}
+			if next == "" {
+				if len(res) == 2 {
+					t.Fatalf("full page %d returned no cursor", page)
+				}
+				break
+			}
+			cursor = next
+		}
+
+		want := "[article 0 article 1 article 2 article 3 article 4]"
+		if fmt.Sprint(titles) != want {
+			t.Fatalf("Fetch walked %v, want %s", titles, want)
+		}
+	})
+
+	t.Run("FetchCursorTiedTimestamps", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := context.Background()
+		for i := 0; i < 5; i++ {
+			store(t, repo, fmt.Sprintf("article %d", i), base)
+		}
+
+		var titles []string
+		cursor := ""
+		for page := 0; ; page++ {
+			if page > 5 {
+				t.Fatal("pagination does not terminate")
+			}
+			res, next, err := repo.Fetch(ctx, cursor, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, a := range res {
+				titles = append(titles, a.Title)
+			}
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+
+		want := "[article 0 article 1 article 2 article 3 article 4]"
+		if fmt.Sprint(titles) != want {
+			t.Fatalf("Fetch walked %v, want %s", titles, want)
+		}
+
+		// the cursors handed out before the id broke the ties still page on the creation time
+		res, _, err := repo.Fetch(ctx, repository.EncodeCursor(base.Add(-time.Second)), 10)
+		if err != nil || len(res) != 5 {
+			t.Fatalf("Fetch with a creation time cursor returned %d articles, %v", len(res), err)
+		}
+	})
+
+	t.Run("FetchFiltered", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := context.Background()
+		articles := []struct {
+			title   string
+			tagID   int64
+			updated time.Duration
+		}{
+			{"beta", 1, 3 * time.Hour},
+			{"alpha", 2, time.Hour},
+			{"delta", 1, 3 * time.Hour},
+			{"alphabet", 2, 2 * time.Hour},
+			{"gamma", 1, 0},
+		}
+		for i, a := range articles {
+			createdAt := base.Add(time.Duration(i) * time.Minute)
+			input := &domain.CreateArticleInput{Title: a.title, Content: "c", TagID: a.tagID, CreatedAt: createdAt, UpdatedAt: base.Add(a.updated)}
+			if err := repo.Store(ctx, input); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		walk := func(t *testing.T, filter domain.ArticleFilter) string {
+			t.Helper()
+			var titles []string
+			cursor := ""
+			for page := 0; ; page++ {
+				if page > 5 {
+					t.Fatal("pagination does not terminate")
+				}
+				res, next, err := repo.FetchFiltered(ctx, filter, cursor, 2)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, a := range res {
+					titles = append(titles, a.Title)
+				}
+				if next == "" {
+					return fmt.Sprint(titles)
+				}
+				cursor = next
+			}
+		}
+
+		tests := []struct {
+			name   string
+			filter domain.ArticleFilter
+			want   string
+		}{
+			{"Default", domain.ArticleFilter{}, "[beta alpha delta alphabet gamma]"},
+			{"Tag", domain.ArticleFilter{TagID: 2}, "[alpha alphabet]"},
+			{"CreatedRange", domain.ArticleFilter{CreatedAfter: base, CreatedBefore: base.Add(3 * time.Minute)}, "[alpha delta]"},
+			{"UpdatedSince", domain.ArticleFilter{UpdatedSince: base.Add(2 * time.Hour)}, "[beta delta alphabet]"},
+			{"TitlePrefix", domain.ArticleFilter{Query: "ALPHA"}, "[alpha alphabet]"},
+			{"EscapedPrefix", domain.ArticleFilter{Query: "a%"}, "[]"},
+			{"SortTitle", domain.ArticleFilter{Sort: domain.SortTitle}, "[alpha alphabet beta delta gamma]"},
+			{"SortTitleDesc", domain.ArticleFilter{Sort: domain.SortTitleDesc, TagID: 1}, "[gamma delta beta]"},
+			// beta and delta share their update time, the id breaks the tie
+			{"SortUpdatedAtDesc", domain.ArticleFilter{Sort: domain.SortUpdatedAtDesc}, "[delta beta alphabet alpha gamma]"},
+			{"SortCreatedAtDesc", domain.ArticleFilter{Sort: domain.SortCreatedAtDesc, Query: "a"}, "[alphabet alpha]"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if got := walk(t, tt.filter); got != tt.want {
+					t.Fatalf("FetchFiltered walked %s, want %s", got, tt.want)
+				}
+				want := int64(len(strings.Fields(strings.Trim(tt.want, "[]"))))
+				if total, err := repo.Count(ctx, tt.filter); err != nil || total != want {
+					t.Fatalf("Count returned %d %v, want %d", total, err, want)
+				}
+			})
+		}
+
+		_, next, err := repo.FetchFiltered(ctx, domain.ArticleFilter{Sort: domain.SortTitle}, "", 2)
+		if err != nil || next == "" {
+			t.Fatalf("expected a cursor, got %q %v", next, err)
+		}
+		if _, _, err := repo.FetchFiltered(ctx, domain.ArticleFilter{Sort: domain.SortUpdatedAt}, next, 2); !errors.Is(err, domain.ErrBadParamInput) {
+			t.Errorf("cursor of another sort: expected ErrBadParamInput, got %v", err)
+		}
+		if _, _, err := repo.FetchFiltered(ctx, domain.ArticleFilter{Sort: "id; DROP TABLE article"}, "", 2); !errors.Is(err, domain.ErrBadParamInput) {
+			t.Errorf("unknown sort: expected ErrBadParamInput, got %v", err)
+		}
+	})
+
+	t.Run("FetchInvalidCursor", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, _, err := repo.Fetch(context.Background(), "not a cursor", 10); !errors.Is(err, domain.ErrBadParamInput) {
+			t.Fatalf("expected ErrBadParamInput, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,317 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-postgres-clean-arch/article/repository"
+	"go-postgres-clean-arch/domain"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// timestampFormat is fixed width so the text columns compare in chronological order
+const timestampFormat = "2006-01-02T15:04:05.000000Z"
+
+type sqliteArticleRepository struct {
+	Conn *sql.DB
+}
+
+// NewSqliteArticleRepository will create an object that represent the article.Repository interface.
+// The schema is created by the sqlite migrations of the migration package.
+func NewSqliteArticleRepository(conn *sql.DB) domain.ArticleRepository {
+	return &sqliteArticleRepository{conn}
+}
+
+func (m *sqliteArticleRepository) fetch(ctx context.Context, query string, args ...interface{}) (result []domain.Article, err error) {
+	rows, err := m.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(errRow)
+		}
+	}()
+
+	result = make([]domain.Article, 0)
+	for rows.Next() {
+		t := domain.Article{}
+		tagID := int64(0)
+		var updatedAt, createdAt string
+		slug := sql.NullString{}
+		err = rows.Scan(
+			&t.ID,
+			&t.Title,
+			&slug,
+			&t.Content,
+			&t.ContentFormat,
+			&tagID,
+			&t.Version,
+			&updatedAt,
+			&createdAt,
+		)
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+
+		if t.UpdatedAt, err = parseTimestamp(updatedAt); err != nil {
+			return nil, err
+		}
+		if t.CreatedAt, err = parseTimestamp(createdAt); err != nil {
+			return nil, err
+		}
+		t.Slug = slug.String
+		t.Tag = domain.Tag{
+			ID: tagID,
+		}
+		result = append(result, t)
+	}
+
+	return result, rows.Err()
+}
+
+func (m *sqliteArticleRepository) Fetch(ctx context.Context, cursor string, num int64) (res []domain.Article, nextCursor string, err error) {
+	return m.FetchFiltered(ctx, domain.ArticleFilter{}, cursor, num)
+}
+
+func (m *sqliteArticleRepository) FetchFiltered(ctx context.Context, filter domain.ArticleFilter, cursor string, num int64) (res []domain.Article, nextCursor string, err error) {
+	q, err := repository.NewListQuery(filter, cursor, timeArg)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query, args := q.SQL(`id, title, slug, content, content_format, tag_id, version, updated_at, created_at`, num, false)
+	res, err = m.fetch(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return res, repository.NextCursor(filter, res, num), nil
+}
+
+func (m *sqliteArticleRepository) Count(ctx context.Context, filter domain.ArticleFilter) (total int64, err error) {
+	q, err := repository.NewListQuery(filter, "", timeArg)
+	if err != nil {
+		return 0, err
+	}
+
+	query, args := q.CountSQL(false)
+	err = m.Conn.QueryRowContext(ctx, query, args...).Scan(&total)
+	return
+}
+
+func (m *sqliteArticleRepository) GetByID(ctx context.Context, id int64) (res domain.Article, err error) {
+	query := `SELECT id, title, slug, content, content_format, tag_id, version, updated_at, created_at
+				FROM article
+				WHERE id = ?`
+
+	list, err := m.fetch(ctx, query, id)
+	if err != nil {
+		return domain.Article{}, err
+	}
+
+	if len(list) > 0 {
+		res = list[0]
+	} else {
+		return res, domain.ErrNotFound
+	}
+
+	return
+}
+
+func (m *sqliteArticleRepository) GetByTitle(ctx context.Context, title string) (res domain.Article, err error) {
+	query := `SELECT id, title, slug, content, content_format, tag_id, version, updated_at, created_at
+				FROM article
+				WHERE title = ?`
+
+	list, err := m.fetch(ctx, query, title)
+	if err != nil {
+		return
+	}
+
+	if len(list) > 0 {
+		res = list[0]
+	} else {
+		return res, domain.ErrNotFound
+	}
+	return
+}
+
+func (m *sqliteArticleRepository) GetBySlug(ctx context.Context, slug string) (res domain.Article, err error) {
+	query := `SELECT id, title, slug, content, content_format, tag_id, version, updated_at, created_at
+				FROM article
+				WHERE slug = ?`
+
+	return m.getOne(ctx, query, slug)
+}
+
+func (m *sqliteArticleRepository) GetByOldSlug(ctx context.Context, slug string) (res domain.Article, err error) {
+	query := `SELECT id, title, slug, content, content_format, tag_id, version, updated_at, created_at
+				FROM article
+				WHERE id = (SELECT article_id FROM article_slug_history WHERE slug = ?)`
+
+	return m.getOne(ctx, query, slug)
+}
+
+func (m *sqliteArticleRepository) getOne(ctx context.Context, query string, args ...interface{}) (res domain.Article, err error) {
+	list, err := m.fetch(ctx, query, args...)
+	if err != nil {
+		return
+	}
+
+	if len(list) == 0 {
+		return res, domain.ErrNotFound
+	}
+	return list[0], nil
+}
+
+func (m *sqliteArticleRepository) Store(ctx context.Context, a *domain.CreateArticleInput) (err error) {
+	query := `INSERT INTO article (title, slug, content, content_format, tag_id, updated_at, created_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	res, err := m.Conn.ExecContext(ctx, query, a.Title, repository.NullString(a.Slug), a.Content, a.ContentFormat, a.TagID, formatTimestamp(a.UpdatedAt), formatTimestamp(a.CreatedAt))
+	if err != nil {
+		return translateError(err)
+	}
+
+	a.ID, err = res.LastInsertId()
+	return
+}
+
+func (m *sqliteArticleRepository) Delete(ctx context.Context, id int64, version int64) (err error) {
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback() //nolint
+
+	if version != 0 {
+		var current int64
+		err = tx.QueryRowContext(ctx, "SELECT version FROM article WHERE id = ?", id).Scan(&current)
+		if err == sql.ErrNoRows {
+			return domain.ErrNotFound
+		}
+		if err != nil {
+			return
+		}
+		if current != version {
+			return domain.ErrPreconditionFailed
+		}
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM article_slug_history WHERE article_id = ?", id); err != nil {
+		return
+	}
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM article WHERE id = ?", id)
+	if err != nil {
+		return
+	}
+
+	rowsAfected, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+
+	if rowsAfected == 0 {
+		return domain.ErrNotFound
+	}
+	if rowsAfected != 1 {
+		err = fmt.Errorf("weird  Behavior. Total Affected: %d", rowsAfected)
+		return
+	}
+
+	return tx.Commit()
+}
+
+func (m *sqliteArticleRepository) Update(ctx context.Context, ar *domain.UpdateArticleInput) (err error) {
+	// sqlite serializes the write transactions, the slug and the version read below can not change before the commit
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback() //nolint
+
+	var oldSlug sql.NullString
+	var version int64
+	err = tx.QueryRowContext(ctx, "SELECT slug, version FROM article WHERE id = ?", ar.ID).Scan(&oldSlug, &version)
+	if err == sql.ErrNoRows {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		return
+	}
+	if ar.Version != 0 && ar.Version != version {
+		return domain.ErrPreconditionFailed
+	}
+	if ar.Slug == "" {
+		ar.Slug = oldSlug.String
+	}
+
+	query := `UPDATE article SET title = ?, slug = ?, content = ?, content_format = ?, tag_id = ?, updated_at = ?, version = version + 1
+				WHERE id = ? AND version = ?`
+
+	res, err := tx.ExecContext(ctx, query, ar.Title, repository.NullString(ar.Slug), ar.Content, ar.ContentFormat, ar.TagID, formatTimestamp(ar.UpdatedAt), ar.ID, version)
+	if err != nil {
+		return translateError(err)
+	}
+	affect, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if affect == 0 {
+		return domain.ErrPreconditionFailed
+	}
+	if affect != 1 {
+		err = fmt.Errorf("weird  Behavior. Total Affected: %d", affect)
+		return
+	}
+
+	// the new slug may be a previous one, it no longer redirects
+	if _, err = tx.ExecContext(ctx, "DELETE FROM article_slug_history WHERE slug = ?", ar.Slug); err != nil {
+		return
+	}
+	if oldSlug.String != "" && oldSlug.String != ar.Slug {
+		history := "INSERT INTO article_slug_history (slug, article_id, created_at) VALUES (?, ?, ?)"
+		if _, err = tx.ExecContext(ctx, history, oldSlug.String, ar.ID, formatTimestamp(ar.UpdatedAt)); err != nil {
+			return translateError(err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return
+	}
+	ar.Version = version + 1
+	return nil
+}
+
+// translateError maps the sqlite constraint violations to the domain errors
+func translateError(err error) error {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return domain.ErrConflict
+	}
+	return err
+}
+
+// timeArg passes the timestamps of a listing query as the text the columns hold
+func timeArg(t time.Time) interface{} {
+	return formatTimestamp(t)
+}
+
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format(timestampFormat)
+}
+
+func parseTimestamp(s string) (time.Time, error) {
+	return time.Parse(timestampFormat, s)
+}
@@ -0,0 +1,341 @@
+// Package sitemap keeps the XML sitemap of the articles. It is generated once by streaming the
+// keyset pages of the article repository, then kept up to date by the repository returned by
+// Track, so a request only encodes the files changed since the previous one.
+package sitemap
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go-postgres-clean-arch/domain"
+)
+
+// MaxURLs is the number of URLs a sitemap file may list, a sitemap index lists the files past it
+const MaxURLs = 50000
+
+// pageSize is the number of articles fetched per query while generating the sitemap
+const pageSize = 1000
+
+const namespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+type state int
+
+const (
+	empty state = iota
+	loading
+	loaded
+)
+
+// change is an article stored, updated or deleted while the sitemap was generated
+type change struct {
+	id        int64
+	updatedAt time.Time
+	deleted   bool
+}
+
+// Sitemap is the sitemap of the articles, split in files of at most urlsPerFile URLs
+type Sitemap struct {
+	repo        domain.ArticleRepository
+	urlsPerFile int
+
+	// load serializes the generation, mu guards the fields below
+	load    sync.Mutex
+	mu      sync.Mutex
+	state   state
+	pending []change
+	// ids is sorted so an article keeps its file, new articles are appended to the last one
+	ids     []int64
+	lastmod map[int64]time.Time
+	// files caches the encoded files for base, nil entries are encoded again on the next request
+	base  string
+	files [][]byte
+	index []byte
+}
+
+// New will create the sitemap of the articles of repo, urlsPerFile <= 0 default to MaxURLs
+func New(repo domain.ArticleRepository, urlsPerFile int) *Sitemap {
+	if urlsPerFile <= 0 || urlsPerFile > MaxURLs {
+		urlsPerFile = MaxURLs
+	}
+	return &Sitemap{repo: repo, urlsPerFile: urlsPerFile}
+}
+
+// Files returns the number of sitemap files, 1 means the sitemap is a single urlset without index
+func (s *Sitemap) Files(ctx context.Context) (int, error) {
+	if err := s.ensureLoaded(ctx); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fileCount(), nil
+}
+
+// WriteIndex writes the sitemap index listing the files at fileURL(n), n starting at 1
+func (s *Sitemap) WriteIndex(ctx context.Context, w io.Writer, base string, fileURL func(n int) string) error {
+	if err := s.ensureLoaded(ctx); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.reset(base)
+	if s.index == nil {
+		doc := sitemapIndex{XMLNS: namespace}
+		for n := 1; n <= s.fileCount(); n++ {
+			doc.Sitemaps = append(doc.Sitemaps, location{Loc: fileURL(n), LastMod: format(s.fileLastmod(n))})
+		}
+		buf, err := encode(doc)
+		if err != nil {
+			s.mu.Unlock()
+			return err
+		}
+		s.index = buf
+	}
+	index := s.index
+	s.mu.Unlock()
+
+	_, err := w.Write(index)
+	return err
+}
+
+// WriteFile writes the urlset of the file n, starting at 1, articleURL gives the location of an article.
+// It returns domain.ErrNotFound when the sitemap has less files.
+func (s *Sitemap) WriteFile(ctx context.Context, w io.Writer, n int, base string, articleURL func(id int64) string) error {
+	if err := s.ensureLoaded(ctx); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if n < 1 || n > s.fileCount() {
+		s.mu.Unlock()
+		return domain.ErrNotFound
+	}
+	s.reset(base)
+	if s.files[n-1] == nil {
+		doc := urlSet{XMLNS: namespace, URLs: []location{}}
+		for _, id := range s.fileIDs(n) {
+			doc.URLs = append(doc.URLs, location{Loc: articleURL(id), LastMod: format(s.lastmod[id])})
+		}
+		buf, err := encode(doc)
+		if err != nil {
+			s.mu.Unlock()
+			return err
+		}
+		s.files[n-1] = buf
+	}
+	file := s.files[n-1]
+	s.mu.Unlock()
+
+	_, err := w.Write(file)
+	return err
+}
+
+// Invalidate drops the generated sitemap, the next request generates it again from the repository
+func (s *Sitemap) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state == loaded {
+		s.state = empty
+		s.ids, s.lastmod, s.files, s.index, s.base = nil, nil, nil, nil, ""
+	}
+}
+
+func (s *Sitemap) ensureLoaded(ctx context.Context) error {
+	s.load.Lock()
+	defer s.load.Unlock()
+
+	s.mu.Lock()
+	if s.state == loaded {
+		s.mu.Unlock()
+		return nil
+	}
+	s.state = loading
+	s.mu.Unlock()
+
+	// the keyset of Fetch breaks the ties of the creation times with the id, so a page ending
+	// between articles created at the same time does not skip the rest of them
+	lastmod := make(map[int64]time.Time)
+	cursor := ""
+	for {
+		articles, nextCursor, err := s.repo.Fetch(ctx, cursor, pageSize)
+		if err != nil {
+			s.mu.Lock()
+			s.state, s.pending = empty, nil
+			s.mu.Unlock()
+			return err
+		}
+		for _, a := range articles {
+			lastmod[a.ID] = a.UpdatedAt
+		}
+		if nextCursor == "" || len(articles) == 0 {
+			break
+		}
+		cursor = nextCursor
+	}
+
+	ids := make([]int64, 0, len(lastmod))
+	for id := range lastmod {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ids, s.lastmod = ids, lastmod
+	s.files, s.index = make([][]byte, s.fileCount()), nil
+	s.state = loaded
+	// the changes made while the pages were read may be missing from them
+	for _, c := range s.pending {
+		s.apply(c)
+	}
+	s.pending = nil
+	return nil
+}
+
+// notify records a change of the repository, it is dropped while the sitemap was never generated
+func (s *Sitemap) notify(c change) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch s.state {
+	case loading:
+		s.pending = append(s.pending, c)
+	case loaded:
+		s.apply(c)
+	}
+}
+
+// apply updates the sitemap and drops the encoded files the change affects, s.mu must be held
+func (s *Sitemap) apply(c change) {
+	i := sort.Search(len(s.ids), func(i int) bool { return s.ids[i] >= c.id })
+	found := i < len(s.ids) && s.ids[i] == c.id
+
+	switch {
+	case c.deleted && !found:
+		return
+	case c.deleted:
+		// the following articles move to the previous file
+		s.ids = append(s.ids[:i], s.ids[i+1:]...)
+		delete(s.lastmod, c.id)
+		s.files = s.files[:s.fileCount()]
+		s.dropFrom(i)
+	case found:
+		s.lastmod[c.id] = c.updatedAt
+		s.files[i/s.urlsPerFile] = nil
+	default:
+		s.ids = append(s.ids, 0)
+		copy(s.ids[i+1:], s.ids[i:])
+		s.ids[i] = c.id
+		s.lastmod[c.id] = c.updatedAt
+		for len(s.files) < s.fileCount() {
+			s.files = append(s.files, nil)
+		}
+		s.dropFrom(i)
+	}
+	s.index = nil
+}
+
+// dropFrom drops the encoded files from the one holding the position i
+func (s *Sitemap) dropFrom(i int) {
+	for n := i / s.urlsPerFile; n < len(s.files); n++ {
+		s.files[n] = nil
+	}
+}
+
+// reset drops the encoded files when they were encoded for another base URL, s.mu must be held
+func (s *Sitemap) reset(base string) {
+	if s.base == base {
+		return
+	}
+	s.base = base
+	s.files = make([][]byte, s.fileCount())
+	s.index = nil
+}
+
+// fileCount returns the number of files, an empty sitemap is still one empty urlset
+func (s *Sitemap) fileCount() int {
+	if len(s.ids) == 0 {
+		return 1
+	}
+	return (len(s.ids) + s.urlsPerFile - 1) / s.urlsPerFile
+}
+
+func (s *Sitemap) fileIDs(n int) []int64 {
+	from := (n - 1) * s.urlsPerFile
+	to := from + s.urlsPerFile
+	if to > len(s.ids) {
+		to = len(s.ids)
+	}
+	return s.ids[from:to]
+}
+
+func (s *Sitemap) fileLastmod(n int) time.Time {
+	var latest time.Time
+	for _, id := range s.fileIDs(n) {
+		if s.lastmod[id].After(latest) {
+			latest = s.lastmod[id]
+		}
+	}
+	return latest
+}
+
+type urlSet struct {
+	XMLName xml.Name   `xml:"urlset"`
+	XMLNS   string     `xml:"xmlns,attr"`
+	URLs    []location `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name   `xml:"sitemapindex"`
+	XMLNS    string     `xml:"xmlns,attr"`
+	Sitemaps []location `xml:"sitemap"`
+}
+
+type location struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+func format(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func encode(doc interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, fmt.Errorf("encode sitemap: %w", err)
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// FileName returns the name of the file n of a sitemap index, e.g. sitemap-2.xml
+func FileName(n int) string {
+	return "sitemap-" + strconv.Itoa(n) + ".xml"
+}
+
+// ParseFileName returns the file number of a name built by FileName
+func ParseFileName(name string) (int, bool) {
+	const prefix, suffix = "sitemap-", ".xml"
+	if len(name) <= len(prefix)+len(suffix) || name[:len(prefix)] != prefix || name[len(name)-len(suffix):] != suffix {
+		return 0, false
+	}
+	n, err := strconv.Atoi(name[len(prefix) : len(name)-len(suffix)])
+	if err != nil || n < 1 {
+		return 0, false
+	}
+	return n, true
+}
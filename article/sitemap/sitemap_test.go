@@ -0,0 +1,229 @@
+package sitemap_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	articleMemory "go-postgres-clean-arch/article/repository/memory"
+	"go-postgres-clean-arch/article/sitemap"
+	"go-postgres-clean-arch/domain"
+	tagMemory "go-postgres-clean-arch/tag/repository/memory"
+)
+
+var base = time.Date(2023, 6, 1, 8, 0, 0, 0, time.UTC)
+
+type urlSet struct {
+	URLs []struct {
+		Loc     string `xml:"loc"`
+		LastMod string `xml:"lastmod"`
+	} `xml:"url"`
+}
+
+type sitemapIndex struct {
+	Sitemaps []struct {
+		Loc     string `xml:"loc"`
+		LastMod string `xml:"lastmod"`
+	} `xml:"sitemap"`
+}
+
+func articleURL(id int64) string {
+	return "http://example.com/api/articles/" + strconv.FormatInt(id, 10)
+}
+
+func fileURL(n int) string {
+	return "http://example.com/sitemaps/" + sitemap.FileName(n)
+}
+
+// countingRepository counts the queries made to build the sitemap
+type countingRepository struct {
+	domain.ArticleRepository
+	fetches int
+}
+
+func (c *countingRepository) Fetch(ctx context.Context, cursor string, num int64) ([]domain.Article, string, error) {
+	c.fetches++
+	return c.ArticleRepository.Fetch(ctx, cursor, num)
+}
+
+func store(t *testing.T, repo domain.ArticleRepository, i int) *domain.CreateArticleInput {
+	t.Helper()
+	a := &domain.CreateArticleInput{
+		Title:     fmt.Sprintf("article %d", i),
+		Content:   "body",
+		TagID:     1,
+		CreatedAt: base.Add(time.Duration(i) * time.Minute),
+		UpdatedAt: base.Add(time.Duration(i) * time.Minute),
+	}
+	if err := repo.Store(context.Background(), a); err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func readFile(t *testing.T, s *sitemap.Sitemap, n int) urlSet {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := s.WriteFile(context.Background(), &buf, n, "http://example.com", articleURL); err != nil {
+		t.Fatal(err)
+	}
+	var set urlSet
+	if err := xml.Unmarshal(buf.Bytes(), &set); err != nil {
+		t.Fatal(err)
+	}
+	return set
+}
+
+func TestSitemapFollowsTheRepository(t *testing.T) {
+	repo := &countingRepository{ArticleRepository: articleMemory.NewMemoryArticleRepository()}
+	s := sitemap.New(repo, 0)
+	tracked := s.Track(repo)
+	for i := 1; i <= 3; i++ {
+		store(t, tracked, i)
+	}
+
+	set := readFile(t, s, 1)
+	if len(set.URLs) != 3 || set.URLs[0].Loc != articleURL(1) || set.URLs[0].LastMod != "2023-06-01T08:01:00Z" {
+		t.Fatalf("unexpected sitemap %+v", set)
+	}
+	fetches := repo.fetches
+
+	store(t, tracked, 4)
+	updatedAt := base.Add(48 * time.Hour)
+	if err := tracked.Update(context.Background(), &domain.UpdateArticleInput{ID: 2, Title: "renamed", Content: "c", TagID: 1, UpdatedAt: updatedAt}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracked.Delete(context.Background(), 1, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	set = readFile(t, s, 1)
+	if len(set.URLs) != 3 || set.URLs[0].Loc != articleURL(2) || set.URLs[0].LastMod != "2023-06-03T08:00:00Z" || set.URLs[2].Loc != articleURL(4) {
+		t.Fatalf("unexpected sitemap %+v", set)
+	}
+	if repo.fetches != fetches {
+		t.Fatalf("the sitemap was generated again from the repository")
+	}
+
+	s.Invalidate()
+	readFile(t, s, 1)
+	if repo.fetches == fetches {
+		t.Fatalf("expected Invalidate to generate the sitemap again")
+	}
+}
+
+func TestSitemapFollowsTagDeletes(t *testing.T) {
+	repo := &countingRepository{ArticleRepository: articleMemory.NewMemoryArticleRepository()}
+	s := sitemap.New(repo, 0)
+	tracked := s.Track(repo)
+	tags := s.TrackTags(tagMemory.NewMemoryTagRepositoryWithArticles(repo))
+	ctx := context.Background()
+	from := &domain.Tag{Name: "from", CreatedAt: base, UpdatedAt: base}
+	to := &domain.Tag{Name: "to", CreatedAt: base, UpdatedAt: base}
+	for _, tag := range []*domain.Tag{from, to} {
+		if err := tags.Store(ctx, tag); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a := store(t, tracked, 1)
+	if err := tracked.Update(ctx, &domain.UpdateArticleInput{ID: a.ID, Title: a.Title, Content: a.Content, TagID: from.ID, UpdatedAt: a.UpdatedAt}); err != nil {
+		t.Fatal(err)
+	}
+
+	set := readFile(t, s, 1)
+	if len(set.URLs) != 1 || set.URLs[0].LastMod != "2023-06-01T08:01:00Z" {
+		t.Fatalf("unexpected sitemap %+v", set)
+	}
+
+	// the tag repository moves the article without the tracked article repository
+	if err := tags.Reassign(ctx, from.ID, to.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	set = readFile(t, s, 1)
+	if len(set.URLs) != 1 || set.URLs[0].LastMod == "2023-06-01T08:01:00Z" {
+		t.Fatalf("expected the lastmod of the moved article to change, got %+v", set)
+	}
+}
+
+func TestSitemapIndex(t *testing.T) {
+	repo := articleMemory.NewMemoryArticleRepository()
+	s := sitemap.New(repo, 2)
+	tracked := s.Track(repo)
+	for i := 1; i <= 5; i++ {
+		store(t, tracked, i)
+	}
+
+	files, err := s.Files(context.Background())
+	if err != nil || files != 3 {
+		t.Fatalf("expected 3 files, got %d %v", files, err)
+	}
+
+	var buf bytes.Buffer
+	if err = s.WriteIndex(context.Background(), &buf, "http://example.com", fileURL); err != nil {
+		t.Fatal(err)
+	}
+	var index sitemapIndex
+	if err = xml.Unmarshal(buf.Bytes(), &index); err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Sitemaps) != 3 || index.Sitemaps[2].Loc != fileURL(3) || index.Sitemaps[1].LastMod != "2023-06-01T08:04:00Z" {
+		t.Fatalf("unexpected index %+v", index)
+	}
+
+	if set := readFile(t, s, 3); len(set.URLs) != 1 || set.URLs[0].Loc != articleURL(5) {
+		t.Fatalf("unexpected last file %+v", set)
+	}
+
+	// deleting an article moves the next ones to the previous file
+	if err = tracked.Delete(context.Background(), 2, 0); err != nil {
+		t.Fatal(err)
+	}
+	if set := readFile(t, s, 2); len(set.URLs) != 2 || set.URLs[0].Loc != articleURL(4) || set.URLs[1].Loc != articleURL(5) {
+		t.Fatalf("unexpected second file %+v", set)
+	}
+	if err = s.WriteFile(context.Background(), &buf, 3, "http://example.com", articleURL); err != domain.ErrNotFound {
+		t.Fatalf("expected %v, got %v", domain.ErrNotFound, err)
+	}
+}
+
+func TestSitemapTiedTimestamps(t *testing.T) {
+	repo := &countingRepository{ArticleRepository: articleMemory.NewMemoryArticleRepository()}
+	// more articles than a page of the generation, all created at the same time
+	const n = 1500
+	for i := 1; i <= n; i++ {
+		a := &domain.CreateArticleInput{Title: fmt.Sprintf("article %d", i), Content: "body", TagID: 1, CreatedAt: base, UpdatedAt: base}
+		if err := repo.Store(context.Background(), a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	set := readFile(t, sitemap.New(repo, 0), 1)
+	if len(set.URLs) != n || set.URLs[n-1].Loc != articleURL(n) {
+		t.Fatalf("expected the %d articles, got %d", n, len(set.URLs))
+	}
+	if repo.fetches < 2 {
+		t.Fatalf("expected the sitemap to be generated from several pages, got %d", repo.fetches)
+	}
+}
+
+func TestEmptySitemap(t *testing.T) {
+	s := sitemap.New(articleMemory.NewMemoryArticleRepository(), 0)
+	if set := readFile(t, s, 1); len(set.URLs) != 0 {
+		t.Fatalf("unexpected sitemap %+v", set)
+	}
+}
+
+func TestFileName(t *testing.T) {
+	if n, ok := sitemap.ParseFileName(sitemap.FileName(12)); !ok || n != 12 {
+		t.Fatalf("unexpected file number %d", n)
+	}
+	for _, name := range []string{"sitemap-0.xml", "sitemap-x.xml", "sitemap-.xml", "other-1.xml", "sitemap-1.txt"} {
+		if _, ok := sitemap.ParseFileName(name); ok {
+			t.Fatalf("%s: expected an invalid name", name)
+		}
+	}
+}
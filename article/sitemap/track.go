@@ -0,0 +1,68 @@
+package sitemap
+
+import (
+	"context"
+
+	"go-postgres-clean-arch/domain"
+)
+
+// trackingRepository forwards to an article repository and reports its changes to the sitemap
+type trackingRepository struct {
+	domain.ArticleRepository
+	sitemap *Sitemap
+}
+
+// Track will wrap the article repository so the articles it stores, updates and deletes are
+// reflected in the sitemap without generating it again
+func (s *Sitemap) Track(repo domain.ArticleRepository) domain.ArticleRepository {
+	return &trackingRepository{ArticleRepository: repo, sitemap: s}
+}
+
+// Store implements domain.ArticleRepository.
+func (t *trackingRepository) Store(ctx context.Context, a *domain.CreateArticleInput) error {
+	if err := t.ArticleRepository.Store(ctx, a); err != nil {
+		return err
+	}
+	t.sitemap.notify(change{id: a.ID, updatedAt: a.UpdatedAt})
+	return nil
+}
+
+// Update implements domain.ArticleRepository.
+func (t *trackingRepository) Update(ctx context.Context, ar *domain.UpdateArticleInput) error {
+	if err := t.ArticleRepository.Update(ctx, ar); err != nil {
+		return err
+	}
+	t.sitemap.notify(change{id: ar.ID, updatedAt: ar.UpdatedAt})
+	return nil
+}
+
+// Delete implements domain.ArticleRepository.
+func (t *trackingRepository) Delete(ctx context.Context, id int64, version int64) error {
+	if err := t.ArticleRepository.Delete(ctx, id, version); err != nil {
+		return err
+	}
+	t.sitemap.notify(change{id: id, deleted: true})
+	return nil
+}
+
+// trackingTagRepository forwards to a tag repository and invalidates the sitemap when the articles
+// of a deleted tag move, the tag repository updates them without the article repository
+type trackingTagRepository struct {
+	domain.TagRepository
+	sitemap *Sitemap
+}
+
+// TrackTags will wrap the tag repository so the articles moved by a tag delete or merge are
+// reflected in the sitemap
+func (s *Sitemap) TrackTags(repo domain.TagRepository) domain.TagRepository {
+	return &trackingTagRepository{TagRepository: repo, sitemap: s}
+}
+
+// Reassign implements domain.TagRepository.
+func (t *trackingTagRepository) Reassign(ctx context.Context, id, to int64, keepAlias bool) error {
+	if err := t.TagRepository.Reassign(ctx, id, to, keepAlias); err != nil {
+		return err
+	}
+	t.sitemap.Invalidate()
+	return nil
+}
@@ -0,0 +1,74 @@
+// Package transfer moves articles in and out of the service as JSON, NDJSON or CSV.
+// It only goes through the usecases so the HTTP endpoints and the CLI share the same rules.
+package transfer
+
+import (
+	"errors"
+	"mime"
+	"time"
+
+	"go-postgres-clean-arch/domain"
+)
+
+// Supported formats of an export or import
+const (
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+)
+
+// ErrUnknownFormat will throw if the requested format is not json, ndjson or csv
+var ErrUnknownFormat = errors.New("unknown format, expected json, ndjson or csv")
+
+// csvHeader lists the columns written by the CSV export, the import only requires title and content
+var csvHeader = []string{"id", "title", "content", "content_format", "tag_id", "tag_name", "created_at", "updated_at"}
+
+// csvTimeFormat keeps the microsecond precision of the repositories
+const csvTimeFormat = time.RFC3339Nano
+
+var contentTypes = map[string]string{
+	FormatJSON:   "application/json",
+	FormatNDJSON: "application/x-ndjson",
+	FormatCSV:    "text/csv",
+}
+
+// Record is a single article read by an import. The JSON shape matches domain.Article so an export
+// of one environment can be imported into another, the tag is resolved by name when one is given.
+type Record struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+	// ContentFormat is markdown, html or plain, markdown when empty
+	ContentFormat string      `json:"content_format"`
+	TagID         int64       `json:"tag_id"`
+	Tag           *domain.Tag `json:"tag"`
+	// Tags names the tags of the article, created when missing. The article references Tag or else the
+	// first of Tags, the others are left out with a warning of the import.
+	Tags []string `json:"tags,omitempty"`
+	// CreatedAt keeps the original creation time, the import time is used when it is zero
+	CreatedAt time.Time `json:"created_at"`
+	// Source locates the record in the imported files, e.g. the path of a markdown file
+	Source string `json:"-"`
+}
+
+// ContentType returns the media type of the format
+func ContentType(format string) (string, error) {
+	contentType, ok := contentTypes[format]
+	if !ok {
+		return "", ErrUnknownFormat
+	}
+	return contentType, nil
+}
+
+// FormatOf returns the format of the given media type, or an empty string when it is not supported
+func FormatOf(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	for format, t := range contentTypes {
+		if t == mediaType {
+			return format
+		}
+	}
+	return ""
+}
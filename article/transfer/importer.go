@@ -0,0 +1,227 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/go-playground/validator"
+
+	"go-postgres-clean-arch/domain"
+	"go-postgres-clean-arch/helper/fold"
+)
+
+// ImportOptions changes how the records of an import are stored
+type ImportOptions struct {
+	// DryRun validates the records without storing anything
+	DryRun bool
+	// Upsert updates the article with the same title instead of skipping it
+	Upsert bool
+}
+
+// Report is the outcome of an import, in a dry run the counts are what the import would do
+type Report struct {
+	DryRun      bool       `json:"dry_run"`
+	Imported    int        `json:"imported"`
+	Updated     int        `json:"updated"`
+	Skipped     int        `json:"skipped"`
+	Failed      int        `json:"failed"`
+	CreatedTags []string   `json:"created_tags"`
+	Errors      []RowError `json:"errors"`
+	// Warnings are the records stored without a part of them, e.g. the tags after the first
+	Warnings []RowError `json:"warnings"`
+}
+
+// RowError describes why a record of the import was rejected or what was left out of it, rows are
+// numbered from 1
+type RowError struct {
+	Row     int    `json:"row"`
+	Source  string `json:"source,omitempty"`
+	Title   string `json:"title,omitempty"`
+	Message string `json:"message"`
+}
+
+// outcome is what happened to a single record
+type outcome int
+
+const (
+	imported outcome = iota
+	updated
+	skipped
+)
+
+// Importer stores the records of an import through the article and tag usecases
+type Importer struct {
+	articles domain.ArticleUsecase
+	tags     domain.TagUseCase
+	validate *validator.Validate
+}
+
+// NewImporter will create an Importer storing articles with au and resolving their tags with tu
+func NewImporter(au domain.ArticleUsecase, tu domain.TagUseCase) *Importer {
+	return &Importer{
+		articles: au,
+		tags:     tu,
+		validate: validator.New(),
+	}
+}
+
+// Import validates and stores every record of r. Tags are resolved by name and created when missing,
+// records whose title already exists are skipped, or updated with opts.Upsert, and invalid records
+// are reported in Report.Errors. An article has a single tag, the other tags of a record are neither
+// created nor linked and reported in Report.Warnings. The error is only set when the document itself can't be read,
+// the report then covers the rows read so far.
+func (i *Importer) Import(ctx context.Context, r Reader, opts ImportOptions) (Report, error) {
+	report := Report{DryRun: opts.DryRun, CreatedTags: []string{}, Errors: []RowError{}, Warnings: []RowError{}}
+	tagIDs := map[string]int64{}
+
+	for row := 1; ; row++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			return report, nil
+		}
+
+		result := imported
+		var invalid *InvalidRecordError
+		switch {
+		case errors.As(err, &invalid):
+		case err != nil:
+			return report, fmt.Errorf("row %d: %w", row, err)
+		default:
+			result, err = i.importRecord(ctx, record, opts, tagIDs, &report)
+		}
+
+		switch {
+		case err == domain.ErrConflict:
+			report.Skipped++
+		case err != nil:
+			report.Failed++
+			report.Errors = append(report.Errors, RowError{Row: row, Source: record.Source, Title: record.Title, Message: err.Error()})
+		case result == imported:
+			report.Imported++
+		case result == updated:
+			report.Updated++
+		default:
+			report.Skipped++
+		}
+
+		if err == nil && result != skipped {
+			if dropped := droppedTags(record); len(dropped) > 0 {
+				message := fmt.Sprintf("the article only takes the tag %q, the tags %q were left out", tagNames(record)[0], dropped)
+				report.Warnings = append(report.Warnings, RowError{Row: row, Source: record.Source, Title: record.Title, Message: message})
+			}
+		}
+	}
+}
+
+// tagNames returns the names of the tags of the record, the name of Tag first
+func tagNames(record Record) []string {
+	names := record.Tags
+	if record.Tag != nil && record.Tag.Name != "" {
+		names = append([]string{record.Tag.Name}, names...)
+	}
+	return names
+}
+
+// droppedTags returns the tag names of the record after the first, the names of the first tag left aside
+func droppedTags(record Record) []string {
+	names := tagNames(record)
+	var dropped []string
+	for _, name := range names {
+		if fold.Key(name) != fold.Key(names[0]) {
+			dropped = append(dropped, name)
+		}
+	}
+	return dropped
+}
+
+func (i *Importer) importRecord(ctx context.Context, record Record, opts ImportOptions, tagIDs map[string]int64, report *Report) (outcome, error) {
+	input := domain.CreateArticleInput{Title: record.Title, Content: record.Content, ContentFormat: record.ContentFormat, TagID: record.TagID, CreatedAt: record.CreatedAt}
+	if err := i.validate.Struct(input); err != nil {
+		return 0, err
+	}
+
+	// the article references the first tag, the others are reported by Import
+	if names := tagNames(record); len(names) > 0 {
+		tagID, err := i.resolveTag(ctx, names[0], tagIDs, report)
+		if err != nil {
+			return 0, err
+		}
+		input.TagID = tagID
+	}
+	if input.TagID == 0 && record.Tag != nil {
+		input.TagID = record.Tag.ID
+	}
+	if input.TagID == 0 {
+		return 0, errors.New("the article has no tag, set tag_id or the tag name")
+	}
+
+	existing, err := i.articles.GetByTitle(ctx, input.Title)
+	switch err {
+	case nil:
+		if !opts.Upsert {
+			return 0, domain.ErrConflict
+		}
+		return i.updateRecord(ctx, existing, input, opts.DryRun)
+	case domain.ErrNotFound:
+	default:
+		return 0, err
+	}
+
+	if opts.DryRun {
+		return imported, i.checkTag(ctx, input.TagID)
+	}
+	return imported, i.articles.Store(ctx, &input)
+}
+
+// updateRecord updates the existing article when the record changes its content, format or tag
+func (i *Importer) updateRecord(ctx context.Context, existing domain.Article, input domain.CreateArticleInput, dryRun bool) (outcome, error) {
+	sameFormat := input.ContentFormat == "" || input.ContentFormat == existing.ContentFormat
+	if existing.Content == input.Content && sameFormat && existing.Tag.ID == input.TagID {
+		return skipped, nil
+	}
+	if dryRun {
+		return updated, i.checkTag(ctx, input.TagID)
+	}
+
+	update := domain.UpdateArticleInput{ID: existing.ID, Title: input.Title, Content: input.Content, ContentFormat: input.ContentFormat, TagID: input.TagID}
+	return updated, i.articles.Update(ctx, &update)
+}
+
+// checkTag replays the tag check of the article usecase, negative ids are tags a dry run would create
+func (i *Importer) checkTag(ctx context.Context, tagID int64) error {
+	if tagID < 0 {
+		return nil
+	}
+	_, err := i.tags.FetchByID(ctx, tagID)
+	return err
+}
+
+// resolveTag returns the id of the tag with the given name, creating the tag when it does not exist.
+// A dry run records the tags it would create under a negative id.
+func (i *Importer) resolveTag(ctx context.Context, name string, tagIDs map[string]int64, report *Report) (int64, error) {
+	if id, ok := tagIDs[name]; ok {
+		return id, nil
+	}
+
+	tag, err := i.tags.FetchByName(ctx, name)
+	if err == domain.ErrNotFound {
+		tag = domain.Tag{Name: name}
+		if report.DryRun {
+			tag.ID = -int64(len(report.CreatedTags) + 1)
+			err = nil
+		} else {
+			err = i.tags.Store(ctx, &tag)
+		}
+		if err == nil {
+			report.CreatedTags = append(report.CreatedTags, name)
+		}
+	}
+	if err != nil {
+		return 0, fmt.Errorf("tag %q: %w", name, err)
+	}
+
+	tagIDs[name] = tag.ID
+	return tag.ID, nil
+}
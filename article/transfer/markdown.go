@@ -0,0 +1,240 @@
+package transfer
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	"go-postgres-clean-arch/domain"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FormatMarkdown imports a tree of markdown files with a YAML front matter, it has no export
+const FormatMarkdown = "markdown"
+
+// maxMarkdownSize bounds the size of a single markdown file, archives can be small and inflate a lot
+const maxMarkdownSize = 10 << 20
+
+// dateLayouts are the accepted layouts of the date field of the front matter
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// MarkdownFile is a markdown document of an import
+type MarkdownFile struct {
+	Path string
+	Open func() (io.ReadCloser, error)
+}
+
+// frontMatter is the YAML block between the --- lines at the top of a markdown file
+type frontMatter struct {
+	Title string   `yaml:"title"`
+	Tag   string   `yaml:"tag"`
+	Tags  nameList `yaml:"tags"`
+	Date  string   `yaml:"date"`
+}
+
+// nameList accepts a YAML sequence or a comma separated string
+type nameList []string
+
+func (l *nameList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = nil
+		for _, name := range strings.Split(node.Value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				*l = append(*l, name)
+			}
+		}
+		return nil
+	}
+
+	var names []string
+	if err := node.Decode(&names); err != nil {
+		return err
+	}
+	*l = names
+	return nil
+}
+
+// MarkdownFiles lists the .md and .markdown files of fsys in lexical order, e.g. of an os.DirFS
+// or a *zip.Reader. Hidden directories and the __MACOSX folder of archives are skipped.
+func MarkdownFiles(fsys fs.FS) ([]MarkdownFile, error) {
+	var files []MarkdownFile
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		base := d.Name()
+		if d.IsDir() {
+			if name != "." && (strings.HasPrefix(base, ".") || base == "__MACOSX") {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !isMarkdown(base) {
+			return nil
+		}
+
+		files = append(files, MarkdownFile{
+			Path: name,
+			Open: func() (io.ReadCloser, error) { return fsys.Open(name) },
+		})
+		return nil
+	})
+	return files, err
+}
+
+// ZipMarkdownFiles lists the markdown files of a zip archive
+func ZipMarkdownFiles(r io.ReaderAt, size int64) ([]MarkdownFile, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return MarkdownFiles(zr)
+}
+
+func isMarkdown(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".md" || ext == ".markdown"
+}
+
+// NewMarkdownReader will create a Reader turning every markdown file into a record. The title,
+// tags and date come from the front matter, the title falls back to the first heading and then
+// to the file name. The body after the front matter becomes the content. The files dated by a
+// day without a time are a second apart in the order of files, so the posts of a day keep it.
+func NewMarkdownReader(files []MarkdownFile) Reader {
+	return &markdownReader{files: files, days: make(map[time.Time]int)}
+}
+
+type markdownReader struct {
+	files []MarkdownFile
+	next  int
+	// days counts the files read per date at midnight
+	days map[time.Time]int
+}
+
+func (r *markdownReader) Read() (Record, error) {
+	if r.next >= len(r.files) {
+		return Record{}, io.EOF
+	}
+	file := r.files[r.next]
+	r.next++
+
+	record := Record{Source: file.Path}
+	body, err := readMarkdown(file)
+	if err != nil {
+		return record, &InvalidRecordError{Err: err}
+	}
+
+	record, err = ParseMarkdown(file.Path, body)
+	if err != nil {
+		return record, &InvalidRecordError{Err: err}
+	}
+	if day := record.CreatedAt; !day.IsZero() && day.Equal(day.Truncate(24*time.Hour)) {
+		record.CreatedAt = day.Add(time.Duration(r.days[day]) * time.Second)
+		r.days[day]++
+	}
+	return record, nil
+}
+
+func readMarkdown(file MarkdownFile) ([]byte, error) {
+	f, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	body, err := io.ReadAll(io.LimitReader(f, maxMarkdownSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxMarkdownSize {
+		return nil, fmt.Errorf("the file is larger than %d bytes", maxMarkdownSize)
+	}
+	return body, nil
+}
+
+// ParseMarkdown maps the front matter and the body of a markdown document onto a record
+func ParseMarkdown(name string, document []byte) (Record, error) {
+	record := Record{Source: name}
+	text := strings.ReplaceAll(string(bytes.TrimPrefix(document, []byte("\ufeff"))), "\r\n", "\n")
+
+	var meta frontMatter
+	head, body, ok := splitFrontMatter(text)
+	if ok {
+		if err := yaml.Unmarshal([]byte(head), &meta); err != nil {
+			return record, fmt.Errorf("front matter: %w", err)
+		}
+	}
+
+	record.Title = strings.TrimSpace(meta.Title)
+	record.Content = strings.TrimSpace(body)
+	record.ContentFormat = domain.ContentFormatMarkdown
+	if record.Title == "" {
+		record.Title = firstHeading(record.Content)
+	}
+	if record.Title == "" {
+		record.Title = strings.TrimSuffix(path.Base(name), path.Ext(name))
+	}
+
+	if tag := strings.TrimSpace(meta.Tag); tag != "" {
+		record.Tags = append(record.Tags, tag)
+	}
+	record.Tags = append(record.Tags, meta.Tags...)
+
+	if meta.Date != "" {
+		date, err := parseDate(meta.Date)
+		if err != nil {
+			return record, err
+		}
+		record.CreatedAt = date
+	}
+	return record, nil
+}
+
+// splitFrontMatter separates the YAML block opened and closed by --- (or ...) lines from the body
+func splitFrontMatter(text string) (head, body string, ok bool) {
+	if !strings.HasPrefix(text, "---\n") {
+		return "", text, false
+	}
+
+	rest := text[len("---\n"):]
+	offset := 0
+	for _, line := range strings.SplitAfter(rest, "\n") {
+		if trimmed := strings.TrimRight(line, " \t\n"); trimmed == "---" || trimmed == "..." {
+			return rest[:offset], rest[offset+len(line):], true
+		}
+		offset += len(line)
+	}
+	// an unterminated block is part of the body
+	return "", text, false
+}
+
+func firstHeading(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, "# ") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "# "))
+		}
+	}
+	return ""
+}
+
+func parseDate(value string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, errors.New("date " + value + " is not a date, expected e.g. 2006-01-02 or RFC 3339")
+}
@@ -0,0 +1,203 @@
+package transfer_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"go-postgres-clean-arch/article/transfer"
+	"go-postgres-clean-arch/domain"
+)
+
+func TestParseMarkdown(t *testing.T) {
+	document := "\ufeff---\r\ntitle: Hello\r\ntags: [go, concurrency]\r\ndate: 2023-04-05\r\n---\r\n\r\n# Heading\r\n\r\nBody\r\n"
+
+	record, err := transfer.ParseMarkdown("posts/hello.md", []byte(document))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Title != "Hello" || record.Content != "# Heading\n\nBody" {
+		t.Fatalf("unexpected record %+v", record)
+	}
+	if len(record.Tags) != 2 || record.Tags[0] != "go" || record.Tags[1] != "concurrency" {
+		t.Fatalf("unexpected tags %v", record.Tags)
+	}
+	if !record.CreatedAt.Equal(time.Date(2023, 4, 5, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected date %v", record.CreatedAt)
+	}
+}
+
+func TestParseMarkdownFallbacks(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		title    string
+		tags     []string
+	}{
+		{name: "notes/first-heading.md", document: "intro\n\n# From heading\n\ntext", title: "From heading"},
+		{name: "notes/file-name.md", document: "no heading", title: "file-name"},
+		{name: "a.md", document: "---\ntag: go\ntags: sql, web\n---\nbody", title: "a", tags: []string{"go", "sql", "web"}},
+		{name: "b.md", document: "---\nnot closed\n", title: "b"},
+	}
+
+	for _, tt := range tests {
+		record, err := transfer.ParseMarkdown(tt.name, []byte(tt.document))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if record.Title != tt.title || len(record.Tags) != len(tt.tags) {
+			t.Fatalf("%s: unexpected record %+v", tt.name, record)
+		}
+		for i := range tt.tags {
+			if record.Tags[i] != tt.tags[i] {
+				t.Fatalf("%s: unexpected tags %v", tt.name, record.Tags)
+			}
+		}
+	}
+
+	if _, err := transfer.ParseMarkdown("bad.md", []byte("---\ndate: yesterday\n---\n")); err == nil {
+		t.Fatal("expected an error for an invalid date")
+	}
+	if _, err := transfer.ParseMarkdown("bad.md", []byte("---\ntitle: [unclosed\n---\n")); err == nil {
+		t.Fatal("expected an error for invalid yaml")
+	}
+}
+
+var tree = fstest.MapFS{
+	"posts/a.md":          {Data: []byte("---\ntitle: Alpha\ntags: [go]\ndate: 2020-01-01T10:00:00Z\n---\nalpha")},
+	"posts/b.markdown":    {Data: []byte("---\ntitle: Beta\ntags: [go, new]\n---\nbeta")},
+	"posts/broken.md":     {Data: []byte("---\ntitle: Broken\n---\n")},
+	"posts/image.png":     {Data: []byte("not markdown")},
+	".git/ignored.md":     {Data: []byte("---\ntitle: Ignored\n---\nx")},
+	"__MACOSX/ignored.md": {Data: []byte("---\ntitle: Ignored\n---\nx")},
+}
+
+func TestImportMarkdownIsIdempotent(t *testing.T) {
+	au, tu := newUsecases()
+	files, err := transfer.MarkdownFiles(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("expected 3 markdown files, got %+v", files)
+	}
+
+	importer := transfer.NewImporter(au, tu)
+	opts := transfer.ImportOptions{Upsert: true}
+	report, err := importer.Import(context.Background(), transfer.NewMarkdownReader(files), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Imported != 2 || report.Failed != 1 || report.Errors[0].Source != "posts/broken.md" {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	// the article takes its first tag, the other tags are reported rather than created
+	if len(report.CreatedTags) != 1 || report.CreatedTags[0] != "go" {
+		t.Fatalf("expected go to be created, got %v", report.CreatedTags)
+	}
+	if len(report.Warnings) != 1 || report.Warnings[0].Source != "posts/b.markdown" || !strings.Contains(report.Warnings[0].Message, `"new"`) {
+		t.Fatalf("expected a warning for the tag new, got %+v", report.Warnings)
+	}
+	if _, err = tu.FetchByName(context.Background(), "new"); err != domain.ErrNotFound {
+		t.Fatalf("expected the tag new not to be created, got %v", err)
+	}
+
+	alpha, err := au.GetByTitle(context.Background(), "Alpha")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !alpha.CreatedAt.Equal(time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)) || alpha.Tag.Name != "go" {
+		t.Fatalf("unexpected article %+v", alpha)
+	}
+
+	report, err = importer.Import(context.Background(), transfer.NewMarkdownReader(files), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Imported != 0 || report.Updated != 0 || report.Skipped != 2 || len(report.Warnings) != 0 {
+		t.Fatalf("re-import changed articles %+v", report)
+	}
+
+	edited := fstest.MapFS{"posts/a.md": {Data: []byte("---\ntitle: Alpha\ntags: [new]\n---\nalpha v2")}}
+	files, _ = transfer.MarkdownFiles(edited)
+	report, err = importer.Import(context.Background(), transfer.NewMarkdownReader(files), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Updated != 1 {
+		t.Fatalf("expected the edited article to be updated %+v", report)
+	}
+	alpha, _ = au.GetByTitle(context.Background(), "Alpha")
+	if alpha.Content != "alpha v2" || alpha.Tag.Name != "new" {
+		t.Fatalf("unexpected article %+v", alpha)
+	}
+}
+
+func TestZipMarkdownFiles(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range []string{"b.md", "a.md", "readme.txt"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte("---\ntitle: " + name + "\n---\nbody"))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := transfer.ZipMarkdownFiles(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].Path != "a.md" || files[1].Path != "b.md" {
+		t.Fatalf("unexpected files %+v", files)
+	}
+
+	record, err := transfer.NewMarkdownReader(files).Read()
+	if err != nil || record.Title != "a.md" {
+		t.Fatalf("unexpected record %+v %v", record, err)
+	}
+}
+
+func TestReexportSameDayMarkdown(t *testing.T) {
+	posts := fstest.MapFS{}
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		posts["posts/"+name+".md"] = &fstest.MapFile{Data: []byte("---\ntitle: Post " + name + "\ntags: [go]\ndate: 2023-04-05\n---\n" + name)}
+	}
+	files, err := transfer.MarkdownFiles(posts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	au, tu := newUsecases()
+	report, err := transfer.NewImporter(au, tu).Import(context.Background(), transfer.NewMarkdownReader(files), transfer.ImportOptions{})
+	if err != nil || report.Imported != 5 {
+		t.Fatalf("unexpected report %+v %v", report, err)
+	}
+
+	// the export pages of 2 end between posts of the same day
+	export := exportString(t, au, transfer.FormatNDJSON)
+	otherAu, otherTu := newUsecases()
+	report = importString(t, otherAu, otherTu, export, transfer.FormatNDJSON, transfer.ImportOptions{})
+	if report.Imported != 5 {
+		t.Fatalf("unexpected report %+v\n%s", report, export)
+	}
+
+	articles, _, err := otherAu.Fetch(context.Background(), "", 10)
+	if err != nil || len(articles) != 5 {
+		t.Fatalf("expected the 5 posts, got %d %v", len(articles), err)
+	}
+	for i, a := range articles {
+		if want := "Post " + string(rune('a'+i)); a.Title != want {
+			t.Fatalf("article %d is %q, want %q in the order of the files", i, a.Title, want)
+		}
+		if day := time.Date(2023, 4, 5, 0, 0, 0, 0, time.UTC); !a.CreatedAt.Truncate(24 * time.Hour).Equal(day) {
+			t.Fatalf("article %q moved to %v", a.Title, a.CreatedAt)
+		}
+	}
+}
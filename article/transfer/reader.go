@@ -0,0 +1,180 @@
+package transfer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-postgres-clean-arch/domain"
+)
+
+// Reader decodes the records of an import one at a time, it returns io.EOF after the last record
+type Reader interface {
+	Read() (Record, error)
+}
+
+// InvalidRecordError reports a record that could not be decoded, the records after it can still be read
+type InvalidRecordError struct {
+	Err error
+}
+
+func (e *InvalidRecordError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InvalidRecordError) Unwrap() error {
+	return e.Err
+}
+
+// NewReader will create the Reader of the given format on top of r. An empty format
+// detects JSON (a document starting with '[') or NDJSON (anything else).
+func NewReader(r io.Reader, format string) (Reader, error) {
+	br := bufio.NewReader(r)
+	if format == "" {
+		format = FormatNDJSON
+		if first, err := firstByte(br); err == nil && first == '[' {
+			format = FormatJSON
+		}
+	}
+
+	switch format {
+	case FormatJSON:
+		dec := json.NewDecoder(br)
+		if _, err := firstByte(br); err == io.EOF {
+			return &jsonReader{dec: dec, done: true}, nil
+		}
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if token != json.Delim('[') {
+			return nil, errors.New("json import expects an array of articles")
+		}
+		return &jsonReader{dec: dec}, nil
+	case FormatNDJSON:
+		return &ndjsonReader{dec: json.NewDecoder(br)}, nil
+	case FormatCSV:
+		return newCSVReader(br)
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// firstByte skips the leading white space and peeks the first byte of the document
+func firstByte(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		if !strings.ContainsRune(" \t\r\n", rune(b[0])) {
+			return b[0], nil
+		}
+		br.ReadByte() //nolint
+	}
+}
+
+// decodeRecord decodes the next JSON value, values of the wrong type are reported as invalid records
+func decodeRecord(dec *json.Decoder) (Record, error) {
+	var record Record
+	err := dec.Decode(&record)
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return record, &InvalidRecordError{Err: err}
+	}
+	return record, err
+}
+
+type ndjsonReader struct {
+	dec *json.Decoder
+}
+
+func (r *ndjsonReader) Read() (Record, error) {
+	return decodeRecord(r.dec)
+}
+
+type jsonReader struct {
+	dec  *json.Decoder
+	done bool
+}
+
+func (r *jsonReader) Read() (Record, error) {
+	if r.done || !r.dec.More() {
+		r.done = true
+		return Record{}, io.EOF
+	}
+	return decodeRecord(r.dec)
+}
+
+type csvReader struct {
+	csv     *csv.Reader
+	columns map[string]int
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	c := csv.NewReader(r)
+	header, err := c.Read()
+	if err == io.EOF {
+		return nil, errors.New("csv import expects a header row")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"title", "content"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("csv import expects a %s column", required)
+		}
+	}
+	return &csvReader{csv: c, columns: columns}, nil
+}
+
+func (r *csvReader) column(row []string, name string) string {
+	i, ok := r.columns[name]
+	if !ok || i >= len(row) {
+		return ""
+	}
+	return row[i]
+}
+
+func (r *csvReader) Read() (Record, error) {
+	row, err := r.csv.Read()
+	if errors.Is(err, csv.ErrFieldCount) {
+		return Record{Title: r.column(row, "title")}, &InvalidRecordError{Err: err}
+	}
+	if err != nil {
+		return Record{}, err
+	}
+
+	record := Record{
+		Title:         r.column(row, "title"),
+		Content:       r.column(row, "content"),
+		ContentFormat: r.column(row, "content_format"),
+	}
+	if tagID := r.column(row, "tag_id"); tagID != "" {
+		record.TagID, err = strconv.ParseInt(tagID, 10, 64)
+		if err != nil {
+			return record, &InvalidRecordError{Err: fmt.Errorf("tag_id %q is not a number", tagID)}
+		}
+	}
+	if createdAt := r.column(row, "created_at"); createdAt != "" {
+		record.CreatedAt, err = time.Parse(csvTimeFormat, createdAt)
+		if err != nil {
+			return record, &InvalidRecordError{Err: fmt.Errorf("created_at %q is not an RFC 3339 time", createdAt)}
+		}
+	}
+	if tagName := r.column(row, "tag_name"); tagName != "" {
+		record.Tag = &domain.Tag{Name: tagName}
+	}
+	return record, nil
+}
@@ -0,0 +1,199 @@
+package transfer_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	articleMemory "go-postgres-clean-arch/article/repository/memory"
+	"go-postgres-clean-arch/article/transfer"
+	articleUcase "go-postgres-clean-arch/article/usecase"
+	"go-postgres-clean-arch/domain"
+	tagMemory "go-postgres-clean-arch/tag/repository/memory"
+	tagUcase "go-postgres-clean-arch/tag/usecase"
+)
+
+func newUsecases() (domain.ArticleUsecase, domain.TagUseCase) {
+	tagRepo := tagMemory.NewMemoryTagRepository()
+	return articleUcase.NewArticleUsecase(articleMemory.NewMemoryArticleRepository(), tagRepo, time.Second),
+		tagUcase.NewTagUsecase(tagRepo, time.Second, nil)
+}
+
+func importString(t *testing.T, au domain.ArticleUsecase, tu domain.TagUseCase, body, format string, opts transfer.ImportOptions) transfer.Report {
+	t.Helper()
+	r, err := transfer.NewReader(strings.NewReader(body), format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := transfer.NewImporter(au, tu).Import(context.Background(), r, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+func exportString(t *testing.T, au domain.ArticleUsecase, format string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := transfer.NewWriter(&buf, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = transfer.Export(context.Background(), au, w, 2); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+const records = `{"title": "First", "content": "one", "tag": {"name": "go"}}
+{"title": "Second", "content": "two", "tag": {"name": "go"}}
+{"title": "Third", "content": "three, with \"quotes\"\nand lines", "tag": {"name": "sql"}}
+`
+
+func TestRoundTrip(t *testing.T) {
+	au, tu := newUsecases()
+	report := importString(t, au, tu, records, "", transfer.ImportOptions{})
+	if report.Imported != 3 || len(report.CreatedTags) != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	for _, format := range []string{transfer.FormatJSON, transfer.FormatNDJSON, transfer.FormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			export := exportString(t, au, format)
+
+			otherAu, otherTu := newUsecases()
+			report := importString(t, otherAu, otherTu, export, format, transfer.ImportOptions{})
+			if report.Imported != 3 || report.Failed != 0 {
+				t.Fatalf("unexpected report %+v\n%s", report, export)
+			}
+
+			third, err := otherAu.GetByTitle(context.Background(), "Third")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if third.Content != "three, with \"quotes\"\nand lines" || third.Tag.Name != "sql" {
+				t.Fatalf("unexpected article %+v", third)
+			}
+		})
+	}
+}
+
+func TestExportEmpty(t *testing.T) {
+	au, _ := newUsecases()
+
+	if out := exportString(t, au, transfer.FormatJSON); strings.TrimSpace(out) != "[]" {
+		t.Fatalf("expected an empty array, got %q", out)
+	}
+	out := exportString(t, au, transfer.FormatCSV)
+	header, err := csv.NewReader(strings.NewReader(out)).Read()
+	if err != nil || header[1] != "title" {
+		t.Fatalf("expected the csv header, got %q", out)
+	}
+}
+
+func TestImportReportsRowErrors(t *testing.T) {
+	au, tu := newUsecases()
+	body := `[
+		{"title": "Valid", "content": "ok", "tag": {"name": "go"}},
+		{"title": "", "content": "missing title", "tag": {"name": "go"}},
+		{"title": "Wrong type", "content": 42},
+		{"title": "No tag", "content": "tagless"},
+		{"title": "Unknown tag", "content": "x", "tag_id": 99},
+		{"title": "Valid", "content": "duplicate", "tag": {"name": "go"}}
+	]`
+
+	report := importString(t, au, tu, body, "", transfer.ImportOptions{})
+	if report.Imported != 1 || report.Skipped != 1 || report.Failed != 4 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	for i, row := range []int{2, 3, 4, 5} {
+		if report.Errors[i].Row != row {
+			t.Fatalf("expected error %d on row %d, got %+v", i, row, report.Errors[i])
+		}
+	}
+}
+
+func TestImportDryRun(t *testing.T) {
+	au, tu := newUsecases()
+	importString(t, au, tu, `{"title": "Existing", "content": "x", "tag": {"name": "go"}}`, "", transfer.ImportOptions{})
+
+	body := `{"title": "Existing", "content": "x", "tag": {"name": "go"}}
+{"title": "New", "content": "y", "tag": {"name": "new-tag"}}
+{"title": "Again", "content": "z", "tag": {"name": "new-tag"}}
+{"title": "", "content": "invalid", "tag": {"name": "go"}}
+`
+	report := importString(t, au, tu, body, transfer.FormatNDJSON, transfer.ImportOptions{DryRun: true})
+	if !report.DryRun || report.Imported != 2 || report.Skipped != 1 || report.Failed != 1 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if len(report.CreatedTags) != 1 || report.CreatedTags[0] != "new-tag" {
+		t.Fatalf("unexpected created tags %v", report.CreatedTags)
+	}
+
+	if _, err := au.GetByTitle(context.Background(), "New"); err != domain.ErrNotFound {
+		t.Fatalf("dry run stored an article: %v", err)
+	}
+	if _, err := tu.FetchByName(context.Background(), "new-tag"); err != domain.ErrNotFound {
+		t.Fatalf("dry run stored a tag: %v", err)
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	au, tu := newUsecases()
+	body := "title,content,tag_name\nFrom CSV,body,go\nShort row\nBad id,x,go\n"
+
+	report := importString(t, au, tu, body, transfer.FormatCSV, transfer.ImportOptions{})
+	if report.Imported != 2 || report.Failed != 1 || report.Errors[0].Row != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	if _, err := transfer.NewReader(strings.NewReader("name\nx\n"), transfer.FormatCSV); err == nil {
+		t.Fatal("expected an error for a csv without title and content columns")
+	}
+}
+
+func TestImportMalformedDocument(t *testing.T) {
+	au, tu := newUsecases()
+	r, err := transfer.NewReader(strings.NewReader(`{"title": "ok", "content": "x", "tag": {"name": "go"}}
+{"title": `), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := transfer.NewImporter(au, tu).Import(context.Background(), r, transfer.ImportOptions{})
+	if err == nil || report.Imported != 1 {
+		t.Fatalf("expected an error after the first row, got %v %+v", err, report)
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	if _, err := transfer.NewWriter(&bytes.Buffer{}, "xml"); err != transfer.ErrUnknownFormat {
+		t.Fatalf("expected %v, got %v", transfer.ErrUnknownFormat, err)
+	}
+	if _, err := transfer.NewReader(strings.NewReader(""), "xml"); err != transfer.ErrUnknownFormat {
+		t.Fatalf("expected %v, got %v", transfer.ErrUnknownFormat, err)
+	}
+	if transfer.FormatOf("text/csv; charset=utf-8") != transfer.FormatCSV {
+		t.Fatal("expected text/csv to map to csv")
+	}
+}
+
+func TestRoundTripTiedTimestamps(t *testing.T) {
+	au, tu := newUsecases()
+	var body strings.Builder
+	for _, title := range []string{"One", "Two", "Three", "Four", "Five"} {
+		body.WriteString(`{"title": "` + title + `", "content": "x", "tag": {"name": "go"}, "created_at": "2023-01-01T00:00:00Z"}` + "\n")
+	}
+	importString(t, au, tu, body.String(), transfer.FormatNDJSON, transfer.ImportOptions{})
+
+	// the pages of 2 end between articles created at the same time
+	export := exportString(t, au, transfer.FormatNDJSON)
+	otherAu, otherTu := newUsecases()
+	report := importString(t, otherAu, otherTu, export, transfer.FormatNDJSON, transfer.ImportOptions{})
+	if report.Imported != 5 || report.Failed != 0 {
+		t.Fatalf("unexpected report %+v\n%s", report, export)
+	}
+}
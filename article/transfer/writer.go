@@ -0,0 +1,175 @@
+package transfer
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"go-postgres-clean-arch/domain"
+)
+
+// Writer encodes articles one at a time in an export format
+type Writer interface {
+	Write(a domain.Article) error
+	// Flush sends the buffered articles to the underlying writer
+	Flush() error
+	// Close terminates the document and flushes it
+	Close() error
+}
+
+// NewWriter will create the Writer of the given format on top of w
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	buf := &flushWriter{Writer: bufio.NewWriter(w), out: w}
+	switch format {
+	case FormatJSON:
+		return &jsonWriter{buf: buf, enc: json.NewEncoder(buf)}, nil
+	case FormatNDJSON:
+		return &ndjsonWriter{buf: buf, enc: json.NewEncoder(buf)}, nil
+	case FormatCSV:
+		return &csvWriter{buf: buf, csv: csv.NewWriter(buf)}, nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// Export streams every article to w, fetching them in keyset pages of pageSize
+// so only one page is held in memory. It returns the number of exported articles.
+func Export(ctx context.Context, au domain.ArticleUsecase, w Writer, pageSize int64) (count int, err error) {
+	cursor := ""
+	for {
+		var articles []domain.Article
+		articles, cursor, err = au.Fetch(ctx, cursor, pageSize)
+		if err != nil {
+			return count, err
+		}
+
+		for _, a := range articles {
+			if err = w.Write(a); err != nil {
+				return count, err
+			}
+			count++
+		}
+		if err = w.Flush(); err != nil {
+			return count, err
+		}
+
+		if cursor == "" || len(articles) == 0 {
+			return count, w.Close()
+		}
+	}
+}
+
+// flushWriter buffers the output and pushes it through http.Flusher when the destination supports it
+type flushWriter struct {
+	*bufio.Writer
+	out io.Writer
+}
+
+func (f *flushWriter) Flush() error {
+	if err := f.Writer.Flush(); err != nil {
+		return err
+	}
+	if flusher, ok := f.out.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+type ndjsonWriter struct {
+	buf *flushWriter
+	enc *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(a domain.Article) error {
+	return w.enc.Encode(a)
+}
+
+func (w *ndjsonWriter) Flush() error {
+	return w.buf.Flush()
+}
+
+func (w *ndjsonWriter) Close() error {
+	return w.buf.Flush()
+}
+
+type jsonWriter struct {
+	buf   *flushWriter
+	enc   *json.Encoder
+	count int
+}
+
+func (w *jsonWriter) Write(a domain.Article) error {
+	separator := ","
+	if w.count == 0 {
+		separator = "["
+	}
+	if _, err := w.buf.WriteString(separator); err != nil {
+		return err
+	}
+	w.count++
+	return w.enc.Encode(a)
+}
+
+func (w *jsonWriter) Flush() error {
+	return w.buf.Flush()
+}
+
+func (w *jsonWriter) Close() error {
+	closing := "]\n"
+	if w.count == 0 {
+		closing = "[]\n"
+	}
+	if _, err := w.buf.WriteString(closing); err != nil {
+		return err
+	}
+	return w.buf.Flush()
+}
+
+type csvWriter struct {
+	buf    *flushWriter
+	csv    *csv.Writer
+	header bool
+}
+
+func (w *csvWriter) writeHeader() error {
+	if w.header {
+		return nil
+	}
+	w.header = true
+	return w.csv.Write(csvHeader)
+}
+
+func (w *csvWriter) Write(a domain.Article) error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	return w.csv.Write([]string{
+		strconv.FormatInt(a.ID, 10),
+		a.Title,
+		a.Content,
+		a.ContentFormat,
+		strconv.FormatInt(a.Tag.ID, 10),
+		a.Tag.Name,
+		a.CreatedAt.UTC().Format(csvTimeFormat),
+		a.UpdatedAt.UTC().Format(csvTimeFormat),
+	})
+}
+
+func (w *csvWriter) Flush() error {
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		return err
+	}
+	return w.buf.Flush()
+}
+
+func (w *csvWriter) Close() error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	return w.Flush()
+}
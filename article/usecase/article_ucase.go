@@ -3,6 +3,7 @@ package usecase
 import (
 	"context"
 	"go-postgres-clean-arch/domain"
+	"go-postgres-clean-arch/helper/slug"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -36,7 +37,10 @@ func (a *articleUsecase) fillTagDetails(c context.Context, data []domain.Article
 	mapTags := map[int64]domain.Tag{}
 
 	for _, article := range data { //nolint
-		mapTags[article.Tag.ID] = domain.Tag{}
+		// the articles of a deleted tag may be detached from any tag
+		if article.Tag.ID != 0 {
+			mapTags[article.Tag.ID] = domain.Tag{}
+		}
 	}
 	// Using goroutine to fetch the tag's detail
 	chanTag := make(chan domain.Tag)
@@ -82,7 +86,7 @@ func (a *articleUsecase) fillTagDetails(c context.Context, data []domain.Article
 
 func (a *articleUsecase) Fetch(c context.Context, cursor string, num int64) (res []domain.Article, nextCursor string, err error) {
 	if num == 0 {
-		num = 10
+		num = domain.DefaultPageSize
 	}
 
 	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
@@ -100,6 +104,102 @@ func (a *articleUsecase) Fetch(c context.Context, cursor string, num int64) (res
 	return
 }
 
+func (a *articleUsecase) FetchFiltered(c context.Context, filter domain.ArticleFilter, cursor string, num int64) (res []domain.Article, nextCursor string, err error) {
+	if num == 0 {
+		num = domain.DefaultPageSize
+	}
+	if !filter.Sort.Valid() {
+		return nil, "", domain.ErrBadParamInput
+	}
+
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	filter, ok, err := a.resolveFilter(ctx, filter)
+	if err != nil {
+		return nil, "", err
+	}
+	if !ok {
+		return []domain.Article{}, "", nil
+	}
+
+	res, nextCursor, err = a.articleRepo.FetchFiltered(ctx, filter, cursor, num)
+	if err != nil {
+		return nil, "", err
+	}
+
+	res, err = a.fillTagDetails(ctx, res)
+	if err != nil {
+		nextCursor = ""
+	}
+	return
+}
+
+func (a *articleUsecase) Count(c context.Context, filter domain.ArticleFilter) (int64, error) {
+	if !filter.Sort.Valid() {
+		return 0, domain.ErrBadParamInput
+	}
+
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	filter, ok, err := a.resolveFilter(ctx, filter)
+	if err != nil || !ok {
+		return 0, err
+	}
+	return a.articleRepo.Count(ctx, filter)
+}
+
+// resolveFilter turns the tag name and the descendants of the filter into tag ids, ok is false
+// when the filter matches no article
+func (a *articleUsecase) resolveFilter(ctx context.Context, filter domain.ArticleFilter) (_ domain.ArticleFilter, ok bool, err error) {
+	if filter.Tag != "" {
+		tag, err := a.tagRepo.FetchByName(ctx, filter.Tag)
+		if err == domain.ErrNotFound {
+			tag, err = a.tagRepo.FetchByAlias(ctx, filter.Tag)
+		}
+		// an unknown tag, or another one than the tag id, matches no article
+		if err == domain.ErrNotFound || (err == nil && filter.TagID != 0 && filter.TagID != tag.ID) {
+			return filter, false, nil
+		}
+		if err != nil {
+			return filter, false, err
+		}
+		filter.TagID = tag.ID
+	}
+
+	if filter.IncludeDescendants && filter.TagID != 0 {
+		tags, err := a.tagRepo.FetchTree(ctx, filter.TagID)
+		if err == domain.ErrNotFound {
+			return filter, false, nil
+		}
+		if err != nil {
+			return filter, false, err
+		}
+		// a hidden tag hides its branch, the articles below it are not listed with its ancestors
+		children := map[int64][]int64{}
+		for _, tag := range tags {
+			if !tag.Hidden && tag.ID != filter.TagID {
+				children[tag.ParentID] = append(children[tag.ParentID], tag.ID)
+			}
+		}
+		filter.TagIDs = make([]int64, 0, len(tags))
+		filter.TagIDs = append(filter.TagIDs, filter.TagID)
+		for i := 0; i < len(filter.TagIDs); i++ {
+			filter.TagIDs = append(filter.TagIDs, children[filter.TagIDs[i]]...)
+		}
+	}
+	return filter, true, nil
+}
+
+// tagOf returns the tag of an article, an article detached from its deleted tag has tag id 0 and no tag
+func (a *articleUsecase) tagOf(ctx context.Context, id int64) (domain.Tag, error) {
+	if id == 0 {
+		return domain.Tag{}, nil
+	}
+	return a.tagRepo.FetchByID(ctx, id)
+}
+
 func (a *articleUsecase) GetByID(c context.Context, id int64) (res domain.Article, err error) {
 	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
 	defer cancel()
@@ -109,7 +209,7 @@ func (a *articleUsecase) GetByID(c context.Context, id int64) (res domain.Articl
 		return
 	}
 
-	resTag, err := a.tagRepo.FetchByID(ctx, res.Tag.ID)
+	resTag, err := a.tagOf(ctx, res.Tag.ID)
 	if err != nil {
 		return domain.Article{}, err
 	}
@@ -125,6 +225,14 @@ func (a *articleUsecase) Update(c context.Context, ar *domain.UpdateArticleInput
 	if err != nil {
 		return err
 	}
+	// the empty fields are filled from the version the change applies to, the repository checks it again
+	if ar.Version != 0 && selectedArticle.Version != ar.Version {
+		return domain.ErrPreconditionFailed
+	}
+
+	if ar.Title == "" {
+		ar.Title = selectedArticle.Title
+	}
 
 	existedArticle, _ := a.GetByTitle(ctx, ar.Title)
 
@@ -132,17 +240,28 @@ func (a *articleUsecase) Update(c context.Context, ar *domain.UpdateArticleInput
 		return domain.ErrConflict
 	}
 
-	if ar.Title == "" {
-		ar.Title = selectedArticle.Title
-	}
 	if ar.Content == "" {
 		ar.Content = selectedArticle.Content
 	}
 	if ar.TagID == 0 {
 		ar.TagID = selectedArticle.Tag.ID
 	}
+	if ar.ContentFormat == "" {
+		ar.ContentFormat = selectedArticle.ContentFormat
+	}
 
-	_, err = a.tagRepo.FetchByID(ctx, ar.TagID)
+	_, err = a.tagOf(ctx, ar.TagID)
+	if err != nil {
+		return err
+	}
+
+	// a renamed article gets a new slug, the repository keeps the previous one as a redirect
+	switch {
+	case ar.Slug != "":
+		ar.Slug, err = a.requestedSlug(ctx, ar.Slug, ar.ID)
+	case ar.Title != selectedArticle.Title || selectedArticle.Slug == "":
+		ar.Slug, err = a.uniqueSlug(ctx, ar.Title, ar.ID)
+	}
 	if err != nil {
 		return err
 	}
@@ -159,7 +278,30 @@ func (a *articleUsecase) GetByTitle(c context.Context, title string) (res domain
 		return
 	}
 
-	resTag, err := a.tagRepo.FetchByID(ctx, res.Tag.ID)
+	resTag, err := a.tagOf(ctx, res.Tag.ID)
+	if err != nil {
+		return domain.Article{}, err
+	}
+
+	res.Tag = resTag
+	return
+}
+
+// GetBySlug finds the article by its current slug, or by a previous one so the caller can redirect
+// to the current slug
+func (a *articleUsecase) GetBySlug(c context.Context, s string) (res domain.Article, err error) {
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	res, err = a.articleRepo.GetBySlug(ctx, s)
+	if err == domain.ErrNotFound {
+		res, err = a.articleRepo.GetByOldSlug(ctx, s)
+	}
+	if err != nil {
+		return
+	}
+
+	resTag, err := a.tagOf(ctx, res.Tag.ID)
 	if err != nil {
 		return domain.Article{}, err
 	}
@@ -168,6 +310,50 @@ func (a *articleUsecase) GetByTitle(c context.Context, title string) (res domain
 	return
 }
 
+// slugTaken reports whether an article other than exceptID uses the slug, currently or as a redirect
+func (a *articleUsecase) slugTaken(ctx context.Context, s string, exceptID int64) (bool, error) {
+	for _, get := range []func(context.Context, string) (domain.Article, error){a.articleRepo.GetBySlug, a.articleRepo.GetByOldSlug} {
+		existing, err := get(ctx, s)
+		if err == domain.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if existing.ID != exceptID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// uniqueSlug derives a slug from the title that no other article uses
+func (a *articleUsecase) uniqueSlug(ctx context.Context, title string, exceptID int64) (string, error) {
+	base := slug.Make(title)
+	if base == "" {
+		base = "article"
+	}
+	return slug.Unique(base, func(s string) (bool, error) {
+		return a.slugTaken(ctx, s, exceptID)
+	})
+}
+
+// requestedSlug normalizes a slug given by the client, it is a conflict when another article uses it
+func (a *articleUsecase) requestedSlug(ctx context.Context, requested string, exceptID int64) (string, error) {
+	s := slug.Make(requested)
+	if s == "" {
+		return "", domain.ErrBadParamInput
+	}
+	taken, err := a.slugTaken(ctx, s, exceptID)
+	if err != nil {
+		return "", err
+	}
+	if taken {
+		return "", domain.ErrConflict
+	}
+	return s, nil
+}
+
 func (a *articleUsecase) Store(c context.Context, m *domain.CreateArticleInput) (err error) {
 	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
 	defer cancel()
@@ -182,21 +368,41 @@ func (a *articleUsecase) Store(c context.Context, m *domain.CreateArticleInput)
 		return err
 	}
 
-	m.CreatedAt = time.Now()
-	m.UpdatedAt = time.Now()
+	if m.ContentFormat == "" {
+		m.ContentFormat = domain.ContentFormatMarkdown
+	}
+
+	if m.Slug != "" {
+		m.Slug, err = a.requestedSlug(ctx, m.Slug, 0)
+	} else {
+		m.Slug, err = a.uniqueSlug(ctx, m.Title, 0)
+	}
+	if err != nil {
+		return err
+	}
+
+	// imports keep the original creation time of the article
+	now := time.Now()
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = now
+	}
+	m.UpdatedAt = now
 	err = a.articleRepo.Store(ctx, m)
 	return
 }
 
-func (a *articleUsecase) Delete(c context.Context, id int64) (err error) {
+func (a *articleUsecase) Delete(c context.Context, id int64, version int64) (err error) {
 	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
 	defer cancel()
 	existedArticle, err := a.articleRepo.GetByID(ctx, id)
 	if err != nil {
 		return
 	}
-	if existedArticle == (domain.Article{}) {
+	if existedArticle.ID == 0 {
 		return domain.ErrNotFound
 	}
-	return a.articleRepo.Delete(ctx, id)
+	if version != 0 && existedArticle.Version != version {
+		return domain.ErrPreconditionFailed
+	}
+	return a.articleRepo.Delete(ctx, id, version)
 }
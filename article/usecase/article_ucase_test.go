@@ -1 +1,170 @@
 package usecase
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	articleMemory "go-postgres-clean-arch/article/repository/memory"
+	"go-postgres-clean-arch/domain"
+	tagMemory "go-postgres-clean-arch/tag/repository/memory"
+)
+
+// newTestUsecase returns the usecase on memory repositories with the tag "go"
+func newTestUsecase(t *testing.T) (domain.ArticleUsecase, domain.Tag) {
+	t.Helper()
+	articles := articleMemory.NewMemoryArticleRepository()
+	tags := tagMemory.NewMemoryTagRepositoryWithArticles(articles)
+	tag := domain.Tag{Name: "go"}
+	if err := tags.Store(context.Background(), &tag); err != nil {
+		t.Fatal(err)
+	}
+	return NewArticleUsecase(articles, tags, time.Second), tag
+}
+
+func TestStoreConflictAndNotFound(t *testing.T) {
+	u, tag := newTestUsecase(t)
+	ctx := context.Background()
+
+	if err := u.Store(ctx, &domain.CreateArticleInput{Title: "no tag", Content: "c", TagID: 999}); err != domain.ErrNotFound {
+		t.Fatalf("expected ErrNotFound for an unknown tag, got %v", err)
+	}
+	a := &domain.CreateArticleInput{Title: "Hello", Content: "c", TagID: tag.ID}
+	if err := u.Store(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.Store(ctx, &domain.CreateArticleInput{Title: "Hello", Content: "other", TagID: tag.ID}); err != domain.ErrConflict {
+		t.Fatalf("expected ErrConflict for a title in use, got %v", err)
+	}
+
+	got, err := u.GetByID(ctx, a.ID)
+	if err != nil || got.Title != "Hello" || got.Tag.Name != "go" || got.ContentFormat != domain.ContentFormatMarkdown {
+		t.Fatalf("GetByID returned %+v %v", got, err)
+	}
+	if _, err = u.GetByID(ctx, 999); err != domain.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err = u.Update(ctx, &domain.UpdateArticleInput{ID: 999, Title: "missing"}); err != domain.ErrNotFound {
+		t.Fatalf("expected ErrNotFound for an unknown article, got %v", err)
+	}
+	if err = u.Update(ctx, &domain.UpdateArticleInput{ID: a.ID, TagID: 999}); err != domain.ErrNotFound {
+		t.Fatalf("expected ErrNotFound for an unknown tag, got %v", err)
+	}
+}
+
+func TestSlugsAreUnique(t *testing.T) {
+	u, tag := newTestUsecase(t)
+	ctx := context.Background()
+
+	first := &domain.CreateArticleInput{Title: "Hello World", Content: "c", TagID: tag.ID}
+	second := &domain.CreateArticleInput{Title: "Hello, world!", Content: "c", TagID: tag.ID}
+	for _, a := range []*domain.CreateArticleInput{first, second} {
+		if err := u.Store(ctx, a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if first.Slug != "hello-world" || second.Slug != "hello-world-2" {
+		t.Fatalf("unexpected slugs %q %q", first.Slug, second.Slug)
+	}
+
+	if err := u.Store(ctx, &domain.CreateArticleInput{Title: "third", Content: "c", TagID: tag.ID, Slug: "Hello World"}); err != domain.ErrConflict {
+		t.Fatalf("expected ErrConflict for a slug in use, got %v", err)
+	}
+	if err := u.Store(ctx, &domain.CreateArticleInput{Title: "third", Content: "c", TagID: tag.ID, Slug: "!!"}); err != domain.ErrBadParamInput {
+		t.Fatalf("expected ErrBadParamInput for an empty slug, got %v", err)
+	}
+
+	// a renamed article keeps its previous slug as a redirect no other article may take
+	if err := u.Update(ctx, &domain.UpdateArticleInput{ID: first.ID, Title: "Goodbye"}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := u.GetBySlug(ctx, "hello-world")
+	if err != nil || got.ID != first.ID || got.Slug != "goodbye" {
+		t.Fatalf("GetBySlug returned %+v %v, want the renamed article", got, err)
+	}
+	if err = u.Update(ctx, &domain.UpdateArticleInput{ID: second.ID, Slug: "hello-world"}); err != domain.ErrConflict {
+		t.Fatalf("expected ErrConflict for the previous slug of another article, got %v", err)
+	}
+	if err = u.Update(ctx, &domain.UpdateArticleInput{ID: second.ID, Title: "Goodbye"}); err != domain.ErrConflict {
+		t.Fatalf("expected ErrConflict for a title in use, got %v", err)
+	}
+}
+
+func TestUpdateAndDeleteCheckTheVersion(t *testing.T) {
+	u, tag := newTestUsecase(t)
+	ctx := context.Background()
+	a := &domain.CreateArticleInput{Title: "versioned", Content: "c", TagID: tag.ID}
+	if err := u.Store(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+
+	change := &domain.UpdateArticleInput{ID: a.ID, Content: "v2", Version: 1}
+	if err := u.Update(ctx, change); err != nil {
+		t.Fatal(err)
+	}
+	if change.Version != 2 || change.Title != "versioned" {
+		t.Fatalf("Update left %+v, want version 2 with the title filled", change)
+	}
+	if err := u.Update(ctx, &domain.UpdateArticleInput{ID: a.ID, Content: "stale", Version: 1}); err != domain.ErrPreconditionFailed {
+		t.Fatalf("expected ErrPreconditionFailed for a previous version, got %v", err)
+	}
+	// the version 0 applies the change to any version
+	if err := u.Update(ctx, &domain.UpdateArticleInput{ID: a.ID, Content: "v3"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := u.Delete(ctx, a.ID, 2); err != domain.ErrPreconditionFailed {
+		t.Fatalf("expected ErrPreconditionFailed for a previous version, got %v", err)
+	}
+	if err := u.Delete(ctx, a.ID, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.Delete(ctx, a.ID, 0); err != domain.ErrNotFound {
+		t.Fatalf("expected ErrNotFound for a deleted article, got %v", err)
+	}
+}
+
+func TestFetchFilteredSkipsHiddenDescendants(t *testing.T) {
+	articles := articleMemory.NewMemoryArticleRepository()
+	tags := tagMemory.NewMemoryTagRepositoryWithArticles(articles)
+	u := NewArticleUsecase(articles, tags, time.Second)
+	ctx := context.Background()
+
+	tag := func(name string, parentID int64, hidden bool) int64 {
+		t.Helper()
+		tag := &domain.Tag{Name: name, ParentID: parentID, Hidden: hidden}
+		if err := tags.Store(ctx, tag); err != nil {
+			t.Fatal(err)
+		}
+		if err := u.Store(ctx, &domain.CreateArticleInput{Title: "about " + name, Content: "c", TagID: tag.ID}); err != nil {
+			t.Fatal(err)
+		}
+		return tag.ID
+	}
+	programming := tag("programming", 0, false)
+	tag("go", programming, false)
+	internal := tag("internal", programming, true)
+	tag("drafts", internal, false)
+
+	titles := func(filter domain.ArticleFilter) string {
+		t.Helper()
+		filter.Sort = domain.SortTitle
+		res, _, err := u.FetchFiltered(ctx, filter, "", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var titles []string
+		for _, a := range res {
+			titles = append(titles, a.Title)
+		}
+		return fmt.Sprint(titles)
+	}
+	if got := titles(domain.ArticleFilter{TagID: programming, IncludeDescendants: true}); got != "[about go about programming]" {
+		t.Fatalf("the descendants of programming listed %s", got)
+	}
+	// the hidden tag itself still lists its branch when asked for
+	if got := titles(domain.ArticleFilter{TagID: internal, IncludeDescendants: true}); got != "[about drafts about internal]" {
+		t.Fatalf("the descendants of internal listed %s", got)
+	}
+}
@@ -0,0 +1,159 @@
+package client
+
+import (
+	"context"
+	"go-postgres-clean-arch/data/request"
+	"go-postgres-clean-arch/domain"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ArticleService calls the /api/articles endpoints, it mirrors domain.ArticleUsecase
+type ArticleService struct {
+	client *Client
+}
+
+// Fetch will fetch one page of articles, nextCursor is empty on the last page
+func (s *ArticleService) Fetch(ctx context.Context, cursor string, num int64) (res []domain.Article, nextCursor string, err error) {
+	header, err := s.client.do(ctx, http.MethodGet, "/api/articles", pageQuery(cursor, num), nil, &res)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return res, header.Get("X-Cursor"), nil
+}
+
+// FetchFiltered will fetch one page of the articles matching the filter, nextCursor is empty on the last page
+func (s *ArticleService) FetchFiltered(ctx context.Context, filter domain.ArticleFilter, cursor string, num int64) (res []domain.Article, nextCursor string, err error) {
+	return s.fetchFiltered(ctx, "/api/articles", filter, cursor, num)
+}
+
+// fetchFiltered lists the articles of the listing at path with the filter in the query
+func (s *ArticleService) fetchFiltered(ctx context.Context, path string, filter domain.ArticleFilter, cursor string, num int64) (res []domain.Article, nextCursor string, err error) {
+	query := pageQuery(cursor, num)
+	if filter.TagID != 0 {
+		query.Set("tag_id", strconv.FormatInt(filter.TagID, 10))
+	}
+	if filter.IncludeDescendants {
+		query.Set("include_descendants", "true")
+	}
+	params := map[string]string{"tag": filter.Tag, "q": filter.Query, "sort": string(filter.Sort)}
+	for name, value := range params {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	times := map[string]time.Time{"created_after": filter.CreatedAfter, "created_before": filter.CreatedBefore, "updated_since": filter.UpdatedSince}
+	for name, t := range times {
+		if !t.IsZero() {
+			query.Set(name, t.Format(time.RFC3339Nano))
+		}
+	}
+
+	header, err := s.client.do(ctx, http.MethodGet, path, query, nil, &res)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return res, header.Get("X-Cursor"), nil
+}
+
+// Iterate will walk every article, fetching num articles per request
+func (s *ArticleService) Iterate(ctx context.Context, num int64) *Iterator[domain.Article] {
+	return newIterator(ctx, func(ctx context.Context, cursor string) ([]domain.Article, string, error) {
+		return s.Fetch(ctx, cursor, num)
+	})
+}
+
+// GetByID will get the article by given id, its version is read from the ETag
+func (s *ArticleService) GetByID(ctx context.Context, id int64) (res domain.Article, err error) {
+	header, err := s.client.do(ctx, http.MethodGet, articlePath(id), nil, nil, &res)
+	if err != nil {
+		return domain.Article{}, err
+	}
+
+	res.Version = versionOf(header)
+	return res, nil
+}
+
+// GetBySlug will get the article by given slug, a previous slug of the article is followed to the current one
+func (s *ArticleService) GetBySlug(ctx context.Context, slug string) (res domain.Article, err error) {
+	header, err := s.client.do(ctx, http.MethodGet, "/api/articles/by-slug/"+url.PathEscape(slug), nil, nil, &res)
+	if err != nil {
+		return domain.Article{}, err
+	}
+
+	res.Version = versionOf(header)
+	return res, nil
+}
+
+// Store will create the article, like the usecase it sets the id, the slug and the timestamps of a.
+// The creation is retried with an Idempotency-Key, a retry does not create the article twice.
+func (s *ArticleService) Store(ctx context.Context, a *domain.CreateArticleInput) error {
+	in := request.CreateArticlesRequest{Title: a.Title, Slug: a.Slug, Content: a.Content, ContentFormat: a.ContentFormat, TagID: a.TagID}
+	key, err := idempotencyKey()
+	if err != nil {
+		return err
+	}
+	var res domain.Article
+	if _, err = s.client.doWithHeader(ctx, http.MethodPost, "/api/articles", nil, key, in, &res); err != nil {
+		return err
+	}
+
+	a.ID, a.Slug, a.ContentFormat, a.CreatedAt, a.UpdatedAt = res.ID, res.Slug, res.ContentFormat, res.CreatedAt, res.UpdatedAt
+	return nil
+}
+
+// Update will update the article identified by ar.ID, empty fields keep their current value. A change
+// applying to ar.Version of an article changed since fails with domain.ErrPreconditionFailed.
+func (s *ArticleService) Update(ctx context.Context, ar *domain.UpdateArticleInput) error {
+	in := request.UpdateArticlesRequest{Title: ar.Title, Slug: ar.Slug, Content: ar.Content, ContentFormat: ar.ContentFormat, TagID: ar.TagID}
+	var res domain.Article
+	header, err := s.client.doWithHeader(ctx, http.MethodPatch, articlePath(ar.ID), nil, ifMatch(ar.Version), in, &res)
+	if err != nil {
+		return err
+	}
+
+	ar.Slug, ar.UpdatedAt, ar.Version = res.Slug, res.UpdatedAt, versionOf(header)
+	return nil
+}
+
+// Delete will delete the article by given id, a version other than 0 fails with
+// domain.ErrPreconditionFailed when the article was changed since
+func (s *ArticleService) Delete(ctx context.Context, id int64, version int64) error {
+	_, err := s.client.doWithHeader(ctx, http.MethodDelete, articlePath(id), nil, ifMatch(version), nil, nil)
+	return err
+}
+
+func articlePath(id int64) string {
+	return "/api/articles/" + strconv.FormatInt(id, 10)
+}
+
+// ifMatch is the If-Match header of a change applying to the version of an article, none for version 0
+func ifMatch(version int64) http.Header {
+	if version == 0 {
+		return nil
+	}
+	return http.Header{"If-Match": {`"` + strconv.FormatInt(version, 10) + `"`}}
+}
+
+// versionOf reads the version of an article from the "<version>-<hash>" ETag of the response, 0 without one
+func versionOf(header http.Header) int64 {
+	v, _, _ := strings.Cut(strings.Trim(header.Get("ETag"), `"`), "-")
+	version, _ := strconv.ParseInt(v, 10, 64)
+	return version
+}
+
+func pageQuery(cursor string, num int64) url.Values {
+	query := url.Values{}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	if num > 0 {
+		query.Set("num", strconv.FormatInt(num, 10))
+	}
+	return query
+}
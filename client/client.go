@@ -0,0 +1,202 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMaxRetries = 3
+	defaultBackoff    = 100 * time.Millisecond
+	maxBackoff        = 5 * time.Second
+
+	// headerIdempotencyKey makes the retries of a creation answer the response of the first request
+	headerIdempotencyKey = "Idempotency-Key"
+)
+
+// Client is a typed client for the articles and tags API
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	maxRetries int
+	backoff    time.Duration
+
+	Articles *ArticleService
+	Tags     *TagService
+}
+
+// Option configures the Client
+type Option func(*Client)
+
+// WithHTTPClient will use the given http.Client to send the requests
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithRetries will set how many times an idempotent request is retried and the initial backoff between attempts
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.backoff = backoff
+	}
+}
+
+// NewClient will create a Client for the API served at baseURL, e.g. http://localhost:8080
+func NewClient(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		maxRetries: defaultMaxRetries,
+		backoff:    defaultBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.Articles = &ArticleService{client: c}
+	c.Tags = &TagService{client: c}
+	return c
+}
+
+// do will send the request, retrying idempotent methods on network errors and retryable statuses.
+// The response body is decoded into out when out is not nil.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) (header http.Header, err error) {
+	return c.doWithHeader(ctx, method, path, query, nil, in, out)
+}
+
+// doWithHeader will send the request like do, with the given headers added to the request. A request
+// with an Idempotency-Key header is retried whatever its method.
+func (c *Client) doWithHeader(ctx context.Context, method, path string, query url.Values, reqHeader http.Header, in, out interface{}) (header http.Header, err error) {
+	var body []byte
+	if in != nil {
+		body, err = json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	endpoint := c.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	attempts := 1
+	if isIdempotent(method) || reqHeader.Get(headerIdempotencyKey) != "" {
+		attempts += c.maxRetries
+	}
+
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, c.backoffFor(attempt)); err != nil {
+				return nil, err
+			}
+		}
+
+		var res *http.Response
+		res, err = c.send(ctx, method, endpoint, reqHeader, body)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			continue
+		}
+
+		header, err = decodeResponse(res, out)
+		if err == nil || !isRetryable(res.StatusCode) {
+			return header, err
+		}
+	}
+
+	return nil, err
+}
+
+func (c *Client) send(ctx context.Context, method, endpoint string, header http.Header, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return c.httpClient.Do(req)
+}
+
+// backoffFor returns an exponential backoff with jitter for the given attempt
+func (c *Client) backoffFor(attempt int) time.Duration {
+	d := c.backoff << uint(attempt-1)
+	if d <= 0 || d > maxBackoff {
+		d = maxBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func decodeResponse(res *http.Response, out interface{}) (http.Header, error) {
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, newError(res)
+	}
+
+	if out == nil || res.StatusCode == http.StatusNoContent {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return res.Header, nil
+	}
+
+	return res.Header, json.NewDecoder(res.Body).Decode(out)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// idempotencyKey returns a new Idempotency-Key header, the requests sent with it are retried like the
+// idempotent methods, the server answers the retries with the response of the first request
+func idempotencyKey() (http.Header, error) {
+	b := make([]byte, 16)
+	if _, err := crand.Read(b); err != nil {
+		return nil, err
+	}
+	return http.Header{headerIdempotencyKey: {hex.EncodeToString(b)}}, nil
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	default:
+		return false
+	}
+}
+
+func isRetryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
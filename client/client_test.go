@@ -0,0 +1,115 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-postgres-clean-arch/client"
+	"go-postgres-clean-arch/domain"
+)
+
+func TestErrorsMapToDomain(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"message":"your Item already exist"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"your requested Item is not found"}`))
+	}))
+	defer srv.Close()
+
+	c := client.NewClient(srv.URL)
+
+	_, err := c.Articles.GetByID(context.Background(), 1)
+	if !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	err = c.Tags.Store(context.Background(), &domain.Tag{Name: "go"})
+	if !errors.Is(err, domain.ErrConflict) {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+}
+
+func TestRetriesIdempotentRequests(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":1,"name":"go"}`))
+	}))
+	defer srv.Close()
+
+	c := client.NewClient(srv.URL, client.WithRetries(3, time.Millisecond))
+
+	tag, err := c.Tags.FetchByID(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag.Name != "go" || atomic.LoadInt32(&calls) != 3 {
+		t.Fatalf("unexpected tag %+v after %d calls", tag, calls)
+	}
+}
+
+func TestIterateFollowsCursor(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			w.Header().Set("X-Cursor", "next")
+			_, _ = w.Write([]byte(`[{"id":1},{"id":2}]`))
+		case "next":
+			_, _ = w.Write([]byte(`[{"id":3}]`))
+		}
+	}))
+	defer srv.Close()
+
+	c := client.NewClient(srv.URL)
+
+	var ids []int64
+	it := c.Articles.Iterate(context.Background(), 2)
+	for it.Next() {
+		ids = append(ids, it.Value().ID)
+	}
+	if it.Err() != nil {
+		t.Fatal(it.Err())
+	}
+	if len(ids) != 3 || ids[2] != 3 {
+		t.Fatalf("unexpected ids %v", ids)
+	}
+}
+
+func TestRetriesCreationsWithIdempotencyKey(t *testing.T) {
+	var calls int32
+	keys := map[string]bool{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys[r.Header.Get("Idempotency-Key")] = true
+		if atomic.AddInt32(&calls, 1) < 2 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":1,"name":"go"}`))
+	}))
+	defer srv.Close()
+
+	c := client.NewClient(srv.URL, client.WithRetries(3, time.Millisecond))
+
+	tag := domain.Tag{Name: "go"}
+	if err := c.Tags.Store(context.Background(), &tag); err != nil {
+		t.Fatal(err)
+	}
+	// the retry is sent with the key of the first attempt
+	if tag.ID != 1 || atomic.LoadInt32(&calls) != 2 || len(keys) != 1 || keys[""] {
+		t.Fatalf("unexpected tag %+v after %d calls with the keys %v", tag, calls, keys)
+	}
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-postgres-clean-arch/domain"
+	"io"
+	"net/http"
+)
+
+// Error is returned when the API answers with a non 2xx status.
+// It unwraps to the matching domain error, so errors.Is(err, domain.ErrNotFound) works.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("api responded %d: %s", e.StatusCode, e.Message)
+}
+
+// Unwrap maps the status code back to the domain error
+func (e *Error) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusNotFound:
+		return domain.ErrNotFound
+	case http.StatusConflict:
+		return domain.ErrConflict
+	case http.StatusPreconditionFailed:
+		return domain.ErrPreconditionFailed
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return domain.ErrBadParamInput
+	case http.StatusInternalServerError:
+		return domain.ErrInternalServerError
+	default:
+		return nil
+	}
+}
+
+func newError(res *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 1<<16))
+
+	apiErr := &Error{StatusCode: res.StatusCode, Message: http.StatusText(res.StatusCode)}
+
+	// the handlers answer either with {"message": "..."} or with a bare JSON string
+	var object struct {
+		Message string `json:"message"`
+	}
+	var text string
+	if err := json.Unmarshal(body, &object); err == nil && object.Message != "" {
+		apiErr.Message = object.Message
+	} else if err := json.Unmarshal(body, &text); err == nil && text != "" {
+		apiErr.Message = text
+	}
+
+	return apiErr
+}
@@ -0,0 +1,48 @@
+package client
+
+import "context"
+
+type pageFunc[T any] func(ctx context.Context, cursor string) (items []T, nextCursor string, err error)
+
+// Iterator walks every item of a cursor paginated listing, fetching the pages lazily
+type Iterator[T any] struct {
+	ctx     context.Context
+	fetch   pageFunc[T]
+	cursor  string
+	page    []T
+	current T
+	started bool
+	err     error
+}
+
+func newIterator[T any](ctx context.Context, fetch pageFunc[T]) *Iterator[T] {
+	return &Iterator[T]{ctx: ctx, fetch: fetch}
+}
+
+// Next advances the iterator, it returns false when there are no more items or an error happened
+func (it *Iterator[T]) Next() bool {
+	for len(it.page) == 0 {
+		if it.err != nil || (it.started && it.cursor == "") {
+			return false
+		}
+
+		it.page, it.cursor, it.err = it.fetch(it.ctx, it.cursor)
+		it.started = true
+		if it.err != nil {
+			return false
+		}
+	}
+
+	it.current, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Value returns the current item
+func (it *Iterator[T]) Value() T {
+	return it.current
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
@@ -0,0 +1,156 @@
+package client
+
+import (
+	"context"
+	"go-postgres-clean-arch/data/request"
+	"go-postgres-clean-arch/domain"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// TagService calls the /api/tags endpoints, it mirrors domain.TagUseCase
+type TagService struct {
+	client *Client
+}
+
+// Fetch will fetch one page of tags, nextCursor is empty on the last page
+func (s *TagService) Fetch(ctx context.Context, cursor string, num int64) (res []domain.Tag, nextCursor string, err error) {
+	header, err := s.client.do(ctx, http.MethodGet, "/api/tags", pageQuery(cursor, num), nil, &res)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return res, header.Get("X-Cursor"), nil
+}
+
+// FetchFiltered will fetch one page of tags in the order of the filter, the cursor is only valid for the same sort.
+// The API does not list the hidden tags, IncludeHidden is domain.ErrBadParamInput.
+func (s *TagService) FetchFiltered(ctx context.Context, filter domain.TagFilter, cursor string, num int64) (res []domain.Tag, nextCursor string, err error) {
+	if filter.IncludeHidden {
+		return nil, "", domain.ErrBadParamInput
+	}
+	query := pageQuery(cursor, num)
+	if filter.Sort != "" {
+		query.Set("sort", string(filter.Sort))
+	}
+
+	header, err := s.client.do(ctx, http.MethodGet, "/api/tags", query, nil, &res)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return res, header.Get("X-Cursor"), nil
+}
+
+// Iterate will walk every tag, fetching num tags per request
+func (s *TagService) Iterate(ctx context.Context, num int64) *Iterator[domain.Tag] {
+	return newIterator(ctx, func(ctx context.Context, cursor string) ([]domain.Tag, string, error) {
+		return s.Fetch(ctx, cursor, num)
+	})
+}
+
+// FetchArticles will fetch one page of the articles of the tag, filter.TagID and filter.Tag are ignored
+func (s *TagService) FetchArticles(ctx context.Context, id int64, filter domain.ArticleFilter, cursor string, num int64) (res []domain.Article, nextCursor string, err error) {
+	filter.TagID, filter.Tag = 0, ""
+	return s.client.Articles.fetchFiltered(ctx, tagPath(id)+"/articles", filter, cursor, num)
+}
+
+// FetchByID will get the tag by given id
+func (s *TagService) FetchByID(ctx context.Context, id int64) (res domain.Tag, err error) {
+	_, err = s.client.do(ctx, http.MethodGet, tagPath(id), nil, nil, &res)
+	return
+}
+
+// FetchBySlug will get the tag by given slug
+func (s *TagService) FetchBySlug(ctx context.Context, slug string) (res domain.Tag, err error) {
+	_, err = s.client.do(ctx, http.MethodGet, "/api/tags/by-slug/"+url.PathEscape(slug), nil, nil, &res)
+	return
+}
+
+// Suggest will complete the prefix to the tags whose name or alias starts with it
+func (s *TagService) Suggest(ctx context.Context, prefix string, num int64) (res []domain.Tag, err error) {
+	query := url.Values{"prefix": {prefix}}
+	if num != 0 {
+		query.Set("num", strconv.FormatInt(num, 10))
+	}
+	_, err = s.client.do(ctx, http.MethodGet, "/api/tags/suggest", query, nil, &res)
+	return
+}
+
+// FetchTree will get the taxonomy tree below the tag rootID, or the trees of every root tag when rootID is 0
+func (s *TagService) FetchTree(ctx context.Context, rootID int64) (res []domain.TagNode, err error) {
+	query := url.Values{}
+	if rootID != 0 {
+		query.Set("root_id", strconv.FormatInt(rootID, 10))
+	}
+	_, err = s.client.do(ctx, http.MethodGet, "/api/tags/tree", query, nil, &res)
+	return
+}
+
+// Store will create the tag, t is replaced by the stored tag. The creation is retried with an
+// Idempotency-Key, a retry does not create the tag twice.
+func (s *TagService) Store(ctx context.Context, t *domain.Tag) error {
+	in := request.CreateTagsRequest{Name: t.Name, ParentID: t.ParentID, Description: t.Description, Color: t.Color, Icon: t.Icon, Hidden: t.Hidden}
+	key, err := idempotencyKey()
+	if err != nil {
+		return err
+	}
+	_, err = s.client.doWithHeader(ctx, http.MethodPost, "/api/tags", nil, key, in, t)
+	return err
+}
+
+// Update will rename the tag identified by t.ID and move it to t.ParentID, t is replaced by the stored tag
+func (s *TagService) Update(ctx context.Context, t *domain.Tag) error {
+	in := request.UpdateTagsRequest{Name: t.Name, ParentID: t.ParentID, Description: t.Description, Color: t.Color, Icon: t.Icon, Hidden: t.Hidden}
+	_, err := s.client.do(ctx, http.MethodPatch, tagPath(t.ID), nil, in, t)
+	return err
+}
+
+// Delete will delete the tag by given id
+func (s *TagService) Delete(ctx context.Context, id int64) error {
+	_, err := s.client.do(ctx, http.MethodDelete, tagPath(id), nil, nil, nil)
+	return err
+}
+
+// DeleteWithPolicy will delete the tag by given id, reassignTo is the tag the articles move to with
+// domain.TagDeleteReassign. A restricted delete of a tag in use fails with domain.ErrConflict.
+func (s *TagService) DeleteWithPolicy(ctx context.Context, id int64, policy domain.TagDeletePolicy, reassignTo int64) error {
+	query := url.Values{}
+	if policy != "" {
+		query.Set("policy", string(policy))
+	}
+	if reassignTo != 0 {
+		query.Set("reassign_to", strconv.FormatInt(reassignTo, 10))
+	}
+	_, err := s.client.do(ctx, http.MethodDelete, tagPath(id), query, nil, nil)
+	return err
+}
+
+// Merge will fold the tag by given id into the tag into and return that tag
+func (s *TagService) Merge(ctx context.Context, id, into int64) (res domain.Tag, err error) {
+	_, err = s.client.do(ctx, http.MethodPost, tagPath(id)+"/merge", nil, request.MergeTagsRequest{Into: into}, &res)
+	return
+}
+
+// FetchAliases will list the aliases of the tag by given id
+func (s *TagService) FetchAliases(ctx context.Context, id int64) (res []domain.TagAlias, err error) {
+	_, err = s.client.do(ctx, http.MethodGet, tagPath(id)+"/aliases", nil, nil, &res)
+	return
+}
+
+// StoreAlias will add the alias a.Name to the tag a.TagID, a name already in use fails with domain.ErrConflict
+func (s *TagService) StoreAlias(ctx context.Context, a *domain.TagAlias) error {
+	_, err := s.client.do(ctx, http.MethodPost, tagPath(a.TagID)+"/aliases", nil, request.CreateTagAliasRequest{Name: a.Name}, nil)
+	return err
+}
+
+// DeleteAlias will remove the alias from the tag by given id
+func (s *TagService) DeleteAlias(ctx context.Context, id int64, alias string) error {
+	_, err := s.client.do(ctx, http.MethodDelete, tagPath(id)+"/aliases/"+url.PathEscape(alias), nil, nil, nil)
+	return err
+}
+
+func tagPath(id int64) string {
+	return "/api/tags/" + strconv.FormatInt(id, 10)
+}
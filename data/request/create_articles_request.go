@@ -0,0 +1,53 @@
+package request
+
+import "go-postgres-clean-arch/domain"
+
+// CreateArticlesRequest is the body of a new article, the slug is generated from the title when empty
+type CreateArticlesRequest struct {
+	Title         string `validate:"required" json:"title"`
+	Slug          string `validate:"omitempty,max=200" json:"slug"`
+	Content       string `validate:"required" json:"content"`
+	ContentFormat string `validate:"omitempty,oneof=markdown html plain" json:"content_format"`
+	TagID         int64  `json:"tag_id"`
+}
+
+// Input maps the request to the article to store, the usecase sets the timestamps
+func (r CreateArticlesRequest) Input() domain.CreateArticleInput {
+	return domain.CreateArticleInput{
+		Title:         r.Title,
+		Slug:          r.Slug,
+		Content:       r.Content,
+		ContentFormat: r.ContentFormat,
+		TagID:         r.TagID,
+	}
+}
+
+// CreateArticlesV2Request is the body of a new article of the v2 API, it lists the tags of the article.
+// An article keeps a single tag for now, so tag_ids holds exactly one id and is documented with
+// maxItems 1, a second id is a validation error rather than being dropped.
+type CreateArticlesV2Request struct {
+	Title         string  `validate:"required" json:"title"`
+	Slug          string  `validate:"omitempty,max=200" json:"slug"`
+	Content       string  `validate:"required" json:"content"`
+	ContentFormat string  `validate:"omitempty,oneof=markdown html plain" json:"content_format"`
+	TagIDs        []int64 `validate:"required,min=1,max=1" json:"tag_ids" description:"The tag of the article, an article has a single tag so the list holds exactly one id"`
+}
+
+// Input maps the request to the article to store, the usecase sets the timestamps
+func (r CreateArticlesV2Request) Input() domain.CreateArticleInput {
+	return CreateArticlesRequest{
+		Title:         r.Title,
+		Slug:          r.Slug,
+		Content:       r.Content,
+		ContentFormat: r.ContentFormat,
+		TagID:         firstID(r.TagIDs),
+	}.Input()
+}
+
+// firstID is the first of the ids, or 0 when there is none
+func firstID(ids []int64) int64 {
+	if len(ids) == 0 {
+		return 0
+	}
+	return ids[0]
+}
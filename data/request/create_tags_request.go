@@ -1,5 +1,25 @@
 package request
 
+import "go-postgres-clean-arch/domain"
+
+// CreateTagsRequest is the body of a new tag, the metadata fields are optional
 type CreateTagsRequest struct {
-	Name string `validate:"required,min=1,max=200" json:"name"`
+	Name        string `validate:"required,min=1,max=200" json:"name"`
+	ParentID    int64  `json:"parent_id"`
+	Description string `validate:"max=1000" json:"description"`
+	Color       string `validate:"omitempty,hexcolor" json:"color"`
+	Icon        string `validate:"max=64" json:"icon"`
+	Hidden      bool   `json:"hidden"`
+}
+
+// Tag maps the request to the tag to store
+func (r CreateTagsRequest) Tag() domain.Tag {
+	return domain.Tag{
+		Name:        r.Name,
+		ParentID:    r.ParentID,
+		Description: r.Description,
+		Color:       r.Color,
+		Icon:        r.Icon,
+		Hidden:      r.Hidden,
+	}
 }
@@ -0,0 +1,18 @@
+package request
+
+import "go-postgres-clean-arch/domain"
+
+// CreateTagAliasRequest is the body of a new alias, the tag comes from the path
+type CreateTagAliasRequest struct {
+	Name string `validate:"required,max=200" json:"name"`
+}
+
+// Alias maps the request to the alias of the tag
+func (r CreateTagAliasRequest) Alias(tagID int64) domain.TagAlias {
+	return domain.TagAlias{Name: r.Name, TagID: tagID}
+}
+
+// MergeTagsRequest names the tag the merged tag is folded into
+type MergeTagsRequest struct {
+	Into int64 `validate:"required" json:"into"`
+}
@@ -0,0 +1,49 @@
+package request
+
+import "go-postgres-clean-arch/domain"
+
+// UpdateArticlesRequest is the body of an article update, empty fields keep their value and the
+// ID comes from the path
+type UpdateArticlesRequest struct {
+	ID            int64  `validate:"required" json:"-"`
+	Title         string `json:"title"`
+	Slug          string `validate:"omitempty,max=200" json:"slug"`
+	Content       string `json:"content"`
+	ContentFormat string `validate:"omitempty,oneof=markdown html plain" json:"content_format"`
+	TagID         int64  `json:"tag_id"`
+}
+
+// Input maps the request to the change of the article, the usecase sets the timestamps
+func (r UpdateArticlesRequest) Input() domain.UpdateArticleInput {
+	return domain.UpdateArticleInput{
+		ID:            r.ID,
+		Title:         r.Title,
+		Slug:          r.Slug,
+		Content:       r.Content,
+		ContentFormat: r.ContentFormat,
+		TagID:         r.TagID,
+	}
+}
+
+// UpdateArticlesV2Request is the body of an article update of the v2 API, an empty tag_ids keeps the
+// tag of the article. An article keeps a single tag for now, so tag_ids holds at most one id.
+type UpdateArticlesV2Request struct {
+	ID            int64   `validate:"required" json:"-"`
+	Title         string  `json:"title"`
+	Slug          string  `validate:"omitempty,max=200" json:"slug"`
+	Content       string  `json:"content"`
+	ContentFormat string  `validate:"omitempty,oneof=markdown html plain" json:"content_format"`
+	TagIDs        []int64 `validate:"max=1" json:"tag_ids" description:"The new tag of the article, an article has a single tag so the list holds at most one id"`
+}
+
+// Input maps the request to the change of the article, the usecase sets the timestamps
+func (r UpdateArticlesV2Request) Input() domain.UpdateArticleInput {
+	return UpdateArticlesRequest{
+		ID:            r.ID,
+		Title:         r.Title,
+		Slug:          r.Slug,
+		Content:       r.Content,
+		ContentFormat: r.ContentFormat,
+		TagID:         firstID(r.TagIDs),
+	}.Input()
+}
@@ -1,6 +1,41 @@
 package request
 
+import "go-postgres-clean-arch/domain"
+
+// UpdateTagsRequest is the body of a tag update, the ID comes from the path
 type UpdateTagsRequest struct {
-	ID   int    `validate:"required"`
-	Name string `validate:"required,max=200,min=1" json:"name"`
+	ID          int64  `validate:"required" json:"-"`
+	Name        string `validate:"required,max=200,min=1" json:"name"`
+	ParentID    int64  `json:"parent_id"`
+	Description string `validate:"max=1000" json:"description"`
+	Color       string `validate:"omitempty,hexcolor" json:"color"`
+	Icon        string `validate:"max=64" json:"icon"`
+	Hidden      bool   `json:"hidden"`
+}
+
+// NewUpdateTagsRequest fills the request with the current tag, the fields missing from the
+// body bound onto it keep their value
+func NewUpdateTagsRequest(t domain.Tag) UpdateTagsRequest {
+	return UpdateTagsRequest{
+		ID:          t.ID,
+		Name:        t.Name,
+		ParentID:    t.ParentID,
+		Description: t.Description,
+		Color:       t.Color,
+		Icon:        t.Icon,
+		Hidden:      t.Hidden,
+	}
+}
+
+// Tag maps the request to the tag to update
+func (r UpdateTagsRequest) Tag() domain.Tag {
+	return domain.Tag{
+		ID:          r.ID,
+		Name:        r.Name,
+		ParentID:    r.ParentID,
+		Description: r.Description,
+		Color:       r.Color,
+		Icon:        r.Icon,
+		Hidden:      r.Hidden,
+	}
 }
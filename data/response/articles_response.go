@@ -0,0 +1,122 @@
+package response
+
+import (
+	"go-postgres-clean-arch/domain"
+	"time"
+)
+
+// ArticleResponse is an article as the API shows it, with the details of its tag
+type ArticleResponse struct {
+	ID            int64       `json:"id"`
+	Title         string      `json:"title"`
+	Slug          string      `json:"slug"`
+	Content       string      `json:"content"`
+	ContentFormat string      `json:"content_format"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+	CreatedAt     time.Time   `json:"created_at"`
+	Tag           TagResponse `json:"tag"`
+
+	// HTML, TOC and ReadingTime are only set when the rendering is requested, e.g. with ?render=html
+	HTML        string            `json:"html,omitempty"`
+	TOC         []HeadingResponse `json:"toc,omitempty"`
+	ReadingTime int               `json:"reading_time,omitempty"` // in minutes
+}
+
+// HeadingResponse is an entry of the table of contents of a rendered article
+type HeadingResponse struct {
+	Level int    `json:"level"`
+	ID    string `json:"id"`
+	Text  string `json:"text"`
+}
+
+// NewArticleResponse maps the article to its response
+func NewArticleResponse(a domain.Article) ArticleResponse {
+	res := ArticleResponse{
+		ID:            a.ID,
+		Title:         a.Title,
+		Slug:          a.Slug,
+		Content:       a.Content,
+		ContentFormat: a.ContentFormat,
+		UpdatedAt:     a.UpdatedAt,
+		CreatedAt:     a.CreatedAt,
+		Tag:           NewTagResponse(a.Tag),
+		HTML:          a.HTML,
+		ReadingTime:   a.ReadingTime,
+	}
+	for _, h := range a.TOC {
+		res.TOC = append(res.TOC, HeadingResponse{Level: h.Level, ID: h.ID, Text: h.Text})
+	}
+	return res
+}
+
+// NewArticleResponses maps the articles to their responses, no article is an empty list
+func NewArticleResponses(articles []domain.Article) []ArticleResponse {
+	res := make([]ArticleResponse, 0, len(articles))
+	for _, a := range articles {
+		res = append(res, NewArticleResponse(a))
+	}
+	return res
+}
+
+// ArticleV2Response is an article as the v2 API shows it, with the list of its tags
+type ArticleV2Response struct {
+	ID            int64         `json:"id"`
+	Title         string        `json:"title"`
+	Slug          string        `json:"slug"`
+	Content       string        `json:"content"`
+	ContentFormat string        `json:"content_format"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+	CreatedAt     time.Time     `json:"created_at"`
+	Tags          []TagResponse `json:"tags"`
+	// Version is the version the ETag of the article is derived from
+	Version int64 `json:"version"`
+
+	// HTML, TOC and ReadingTime are only set when the rendering is requested, e.g. with ?render=html
+	HTML        string            `json:"html,omitempty"`
+	TOC         []HeadingResponse `json:"toc,omitempty"`
+	ReadingTime int               `json:"reading_time,omitempty"` // in minutes
+}
+
+// NewArticleV2Response maps the article to its v2 response, an article detached from its tag has no tags
+func NewArticleV2Response(a domain.Article) ArticleV2Response {
+	v1 := NewArticleResponse(a)
+	res := ArticleV2Response{
+		ID:            v1.ID,
+		Title:         v1.Title,
+		Slug:          v1.Slug,
+		Content:       v1.Content,
+		ContentFormat: v1.ContentFormat,
+		UpdatedAt:     v1.UpdatedAt,
+		CreatedAt:     v1.CreatedAt,
+		Tags:          []TagResponse{},
+		Version:       a.Version,
+		HTML:          v1.HTML,
+		TOC:           v1.TOC,
+		ReadingTime:   v1.ReadingTime,
+	}
+	if a.Tag.ID != 0 {
+		res.Tags = append(res.Tags, v1.Tag)
+	}
+	return res
+}
+
+// NewArticleV2Responses maps the articles to their v2 responses, no article is an empty list
+func NewArticleV2Responses(articles []domain.Article) []ArticleV2Response {
+	res := make([]ArticleV2Response, 0, len(articles))
+	for _, a := range articles {
+		res = append(res, NewArticleV2Response(a))
+	}
+	return res
+}
+
+// ArticleRefResponse identifies an article without its content
+type ArticleRefResponse struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+	Slug  string `json:"slug"`
+}
+
+// NewArticleRefResponse maps the article to its reference
+func NewArticleRefResponse(a domain.Article) ArticleRefResponse {
+	return ArticleRefResponse{ID: a.ID, Title: a.Title, Slug: a.Slug}
+}
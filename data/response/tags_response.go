@@ -1 +1,99 @@
 package response
+
+import (
+	"go-postgres-clean-arch/domain"
+	"time"
+)
+
+// TagResponse is a tag as the API shows it
+type TagResponse struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Slug        string    `json:"slug"`
+	ParentID    int64     `json:"parent_id"`
+	Description string    `json:"description"`
+	Color       string    `json:"color"`
+	Icon        string    `json:"icon"`
+	Hidden      bool      `json:"hidden"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	// ArticleCount is the number of articles of the tag
+	ArticleCount int64 `json:"article_count"`
+}
+
+// NewTagResponse maps the tag to its response
+func NewTagResponse(t domain.Tag) TagResponse {
+	return TagResponse{
+		ID:           t.ID,
+		Name:         t.Name,
+		Slug:         t.Slug,
+		ParentID:     t.ParentID,
+		Description:  t.Description,
+		Color:        t.Color,
+		Icon:         t.Icon,
+		Hidden:       t.Hidden,
+		CreatedAt:    t.CreatedAt,
+		UpdatedAt:    t.UpdatedAt,
+		ArticleCount: t.ArticleCount,
+	}
+}
+
+// NewTagResponses maps the tags to their responses, no tag is an empty list
+func NewTagResponses(tags []domain.Tag) []TagResponse {
+	res := make([]TagResponse, 0, len(tags))
+	for _, t := range tags {
+		res = append(res, NewTagResponse(t))
+	}
+	return res
+}
+
+// TagNodeResponse is a tag of the taxonomy tree with its child tags
+type TagNodeResponse struct {
+	TagResponse
+	Children []TagNodeResponse `json:"children"`
+}
+
+// NewTagNodeResponses maps the trees to their responses
+func NewTagNodeResponses(nodes []domain.TagNode) []TagNodeResponse {
+	res := make([]TagNodeResponse, 0, len(nodes))
+	for _, n := range nodes {
+		res = append(res, TagNodeResponse{TagResponse: NewTagResponse(n.Tag), Children: NewTagNodeResponses(n.Children)})
+	}
+	return res
+}
+
+// TagAliasResponse is another name of the tag TagID
+type TagAliasResponse struct {
+	Name  string `json:"name"`
+	TagID int64  `json:"tag_id"`
+}
+
+// NewTagAliasResponse maps the alias to its response
+func NewTagAliasResponse(a domain.TagAlias) TagAliasResponse {
+	return TagAliasResponse{Name: a.Name, TagID: a.TagID}
+}
+
+// NewTagAliasResponses maps the aliases to their responses
+func NewTagAliasResponses(aliases []domain.TagAlias) []TagAliasResponse {
+	res := make([]TagAliasResponse, 0, len(aliases))
+	for _, a := range aliases {
+		res = append(res, NewTagAliasResponse(a))
+	}
+	return res
+}
+
+// TagInUseResponse is the conflict of a restricted delete, it lists the first articles still referencing the tag
+type TagInUseResponse struct {
+	Message      string               `json:"message"`
+	ArticleCount int64                `json:"article_count"`
+	Articles     []ArticleRefResponse `json:"articles"`
+}
+
+// NewTagInUseResponse maps the conflict to its response
+func NewTagInUseResponse(e *domain.TagInUseError) TagInUseResponse {
+	res := TagInUseResponse{Message: e.Error(), ArticleCount: e.ArticleCount, Articles: make([]ArticleRefResponse, 0, len(e.Articles))}
+	for _, a := range e.Articles {
+		res.Articles = append(res.Articles, NewArticleRefResponse(a))
+	}
+	return res
+}
@@ -1 +1,6 @@
 package response
+
+// ErrorResponse is the body of a failed request
+type ErrorResponse struct {
+	Message string `json:"message"`
+}
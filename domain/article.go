@@ -5,50 +5,147 @@ import (
 	"time"
 )
 
+// Formats of Article.Content
+const (
+	ContentFormatMarkdown = "markdown"
+	ContentFormatHTML     = "html"
+	ContentFormatPlain    = "plain"
+)
+
 // Article is representing the Article data struct
 type Article struct {
-	ID        int64     `json:"id"`
-	Title     string    `json:"title" validate:"required"`
-	Content   string    `json:"content" validate:"required"`
-	UpdatedAt time.Time `json:"updated_at"`
-	CreatedAt time.Time `json:"created_at"`
-	Tag       Tag       `json:"tag"`
+	ID            int64     `json:"id"`
+	Title         string    `json:"title" validate:"required"`
+	Slug          string    `json:"slug"`
+	Content       string    `json:"content" validate:"required"`
+	ContentFormat string    `json:"content_format"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	CreatedAt     time.Time `json:"created_at"`
+	Tag           Tag       `json:"tag"`
+	// Version counts the updates of the article, it starts at 1
+	Version int64 `json:"version"`
+
+	// HTML, TOC and ReadingTime are only set when the rendering is requested, e.g. with ?render=html
+	HTML        string    `json:"html,omitempty"`
+	TOC         []Heading `json:"toc,omitempty"`
+	ReadingTime int       `json:"reading_time,omitempty"` // in minutes
 }
 
+// Heading is an entry of the table of contents of a rendered article
+type Heading struct {
+	Level int    `json:"level"`
+	ID    string `json:"id"`
+	Text  string `json:"text"`
+}
+
+// CreateArticleInput is the article to store, the slug is generated from the title when empty
 type CreateArticleInput struct {
-	ID        int64     `json:"id"`
-	Title     string    `json:"title" validate:"required"`
-	Content   string    `json:"content" validate:"required"`
-	UpdatedAt time.Time `json:"updated_at"`
-	CreatedAt time.Time `json:"created_at"`
-	TagID     int64     `json:"tag_id"`
+	ID            int64     `json:"id"`
+	Title         string    `json:"title" validate:"required"`
+	Slug          string    `json:"slug" validate:"omitempty,max=200"`
+	Content       string    `json:"content" validate:"required"`
+	ContentFormat string    `json:"content_format" validate:"omitempty,oneof=markdown html plain"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	CreatedAt     time.Time `json:"created_at"`
+	TagID         int64     `json:"tag_id"`
 }
 
+// UpdateArticleInput is the change of an article, empty fields keep their value. The slug is
+// generated again when the title changes, the previous slug then redirects to the new one.
 type UpdateArticleInput struct {
-	ID        int64     `json:"id"`
-	Title     string    `json:"title"`
-	Content   string    `json:"content"`
-	UpdatedAt time.Time `json:"updated_at"`
-	CreatedAt time.Time `json:"created_at"`
-	TagID     int64     `json:"tag_id"`
+	ID            int64     `json:"id"`
+	Title         string    `json:"title"`
+	Slug          string    `json:"slug" validate:"omitempty,max=200"`
+	Content       string    `json:"content"`
+	ContentFormat string    `json:"content_format" validate:"omitempty,oneof=markdown html plain"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	CreatedAt     time.Time `json:"created_at"`
+	TagID         int64     `json:"tag_id"`
+	// Version is the version of the article the change applies to, 0 applies it to any version.
+	// It is set to the new version of the article once updated.
+	Version int64 `json:"version"`
+}
+
+// ArticleSort is the order of an article listing, the empty sort is SortCreatedAt
+type ArticleSort string
+
+// Sort orders of an article listing, a leading dash sorts in descending order
+const (
+	SortCreatedAt     ArticleSort = "created_at"
+	SortCreatedAtDesc ArticleSort = "-created_at"
+	SortUpdatedAt     ArticleSort = "updated_at"
+	SortUpdatedAtDesc ArticleSort = "-updated_at"
+	SortTitle         ArticleSort = "title"
+	SortTitleDesc     ArticleSort = "-title"
+)
+
+// ArticleSorts is the whitelist of the orders an article listing accepts
+var ArticleSorts = []ArticleSort{SortCreatedAt, SortCreatedAtDesc, SortUpdatedAt, SortUpdatedAtDesc, SortTitle, SortTitleDesc}
+
+// Valid reports whether the sort is empty or one of ArticleSorts
+func (s ArticleSort) Valid() bool {
+	if s == "" {
+		return true
+	}
+	for _, sort := range ArticleSorts {
+		if s == sort {
+			return true
+		}
+	}
+	return false
+}
+
+// ArticleFilter narrows an article listing, the zero value lists every article by creation time
+type ArticleFilter struct {
+	TagID int64
+	// Tag is the name of the tag, the usecase resolves it to TagID
+	Tag string
+	// IncludeDescendants also lists the articles of the visible tags below the tag, the usecase resolves
+	// them to TagIDs. A hidden tag leaves out its branch.
+	IncludeDescendants bool
+	// TagIDs replaces TagID when set, the articles of any of the tags match
+	TagIDs        []int64
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	UpdatedSince  time.Time
+	// Query is a case insensitive prefix of the title
+	Query string
+	Sort  ArticleSort
 }
 
 // ArticleUsecase represent the article's usecases
 type ArticleUsecase interface {
 	Fetch(ctx context.Context, cursor string, num int64) (articles []Article, nextCursor string, err error)
+	// FetchFiltered lists the articles matching the filter, the cursor is only valid for the same sort
+	FetchFiltered(ctx context.Context, filter ArticleFilter, cursor string, num int64) (articles []Article, nextCursor string, err error)
+	// Count returns the number of articles matching the filter across every page
+	Count(ctx context.Context, filter ArticleFilter) (int64, error)
 	GetByID(ctx context.Context, id int64) (Article, error)
 	GetByTitle(ctx context.Context, title string) (Article, error)
+	// GetBySlug also finds the article by one of its previous slugs, the article then has another slug
+	GetBySlug(ctx context.Context, slug string) (Article, error)
 	Store(context.Context, *CreateArticleInput) error
+	// Update fails with ErrPreconditionFailed when ar.Version is set and the article has another version
 	Update(ctx context.Context, ar *UpdateArticleInput) error
-	Delete(ctx context.Context, id int64) error
+	// Delete fails with ErrPreconditionFailed when version is not 0 and the article has another version
+	Delete(ctx context.Context, id int64, version int64) error
 }
 
 // ArticleRepository represent the article's repository contract
 type ArticleRepository interface {
 	Fetch(ctx context.Context, cursor string, num int64) (res []Article, nextCursor string, err error)
+	FetchFiltered(ctx context.Context, filter ArticleFilter, cursor string, num int64) (res []Article, nextCursor string, err error)
+	// Count returns the number of articles matching the filter, Tag and IncludeDescendants are resolved by the usecase
+	Count(ctx context.Context, filter ArticleFilter) (int64, error)
 	GetByID(ctx context.Context, id int64) (Article, error)
 	GetByTitle(ctx context.Context, title string) (Article, error)
+	GetBySlug(ctx context.Context, slug string) (Article, error)
+	// GetByOldSlug returns the article a slug belonged to before the article was given another one
+	GetByOldSlug(ctx context.Context, slug string) (Article, error)
 	Store(ctx context.Context, a *CreateArticleInput) error
+	// Update increments the version of the article, it fails with ErrPreconditionFailed when ar.Version
+	// is set and the article has another version
 	Update(ctx context.Context, ar *UpdateArticleInput) error
-	Delete(ctx context.Context, id int64) error
+	// Delete fails with ErrPreconditionFailed when version is not 0 and the article has another version
+	Delete(ctx context.Context, id int64, version int64) error
 }
@@ -11,4 +11,8 @@ var (
 	ErrConflict = errors.New("your Item already exist")
 	// ErrBadParamInput will throw if the given request-body or params is not valid
 	ErrBadParamInput = errors.New("given Param is not valid")
+	// ErrPreconditionFailed will throw if the item was changed since the version the action applies to
+	ErrPreconditionFailed = errors.New("your Item was changed by another request")
+	// ErrIdempotencyKeyReused will throw if the Idempotency-Key was already used for another request
+	ErrIdempotencyKeyReused = errors.New("your Idempotency-Key was used for another request")
 )
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// IdempotentRequest is a request sent with an Idempotency-Key header, with the response it was answered
+// with. A request sent again with the key is answered the same response instead of being processed again.
+type IdempotentRequest struct {
+	Key string `json:"key"`
+	// Fingerprint identifies the method, the path and the body of the request
+	Fingerprint string `json:"fingerprint"`
+	// Status is the status of the response, 0 while the request is processed
+	Status int `json:"status"`
+	// Header holds the headers of the response replayed with it, e.g. Content-Type and ETag
+	Header map[string]string `json:"header"`
+	Body   []byte            `json:"body"`
+	// CreatedAt is when the key was first used, the key can be used for another request from ExpiresAt
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// IdempotencyUsecase represent the usecases of the requests sent with an Idempotency-Key header
+type IdempotencyUsecase interface {
+	// Begin reserves the key for the request with the fingerprint, it returns the request answered
+	// before with the key to replay its response. It fails with ErrIdempotencyKeyReused when the key
+	// was used for another request, and with ErrConflict while the first request is processed.
+	Begin(ctx context.Context, key, fingerprint string) (replay *IdempotentRequest, err error)
+	// Complete stores the response of the request reserved by Begin
+	Complete(ctx context.Context, r *IdempotentRequest) error
+	// Release frees the key of a request that failed, the request can be sent again with the key
+	Release(ctx context.Context, key string) error
+	// Purge deletes the expired requests and returns how many were deleted
+	Purge(ctx context.Context) (int64, error)
+}
+
+// IdempotencyRepository represent the repository contract of the requests sent with an Idempotency-Key header
+type IdempotencyRepository interface {
+	// Reserve stores the request before it is processed, replacing a request of the key expired at
+	// r.CreatedAt. It fails with ErrConflict when the key is used by a request that did not expire.
+	Reserve(ctx context.Context, r *IdempotentRequest) error
+	GetByKey(ctx context.Context, key string) (IdempotentRequest, error)
+	// Complete stores r.Status, r.Header and r.Body, it fails with ErrNotFound when the key was released
+	Complete(ctx context.Context, r *IdempotentRequest) error
+	Delete(ctx context.Context, key string) error
+	// DeleteExpired deletes the requests expired at now and returns how many were deleted
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
@@ -0,0 +1,4 @@
+package domain
+
+// DefaultPageSize is the number of items of a listing page when the request does not give one
+const DefaultPageSize = 10
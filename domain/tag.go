@@ -2,35 +2,166 @@ package domain
 
 import (
 	"context"
+	"regexp"
 	"time"
 )
 
 // Tag is representing the Tag data struct
 type Tag struct {
-	ID        int64     `json:"id"`
-	Name      string    `json:"name" validate:"required"`
+	ID   int64  `json:"id"`
+	Name string `json:"name" validate:"required"`
+	Slug string `json:"slug"`
+	// ParentID is the parent in the taxonomy tree, 0 for a root tag
+	ParentID    int64  `json:"parent_id"`
+	Description string `json:"description"`
+	// Color is the hex color of the tag chip, e.g. #1e90ff, empty for the default color
+	Color string `json:"color"`
+	Icon  string `json:"icon"`
+	// Hidden tags are left out of the tag listings, the tree and the suggestions
+	Hidden    bool      `json:"hidden"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	// ArticleCount is the number of articles of the tag, it is read only and left zero by the single
+	// tag fetches of the repository
+	ArticleCount int64 `json:"article_count,omitempty"`
+}
+
+// hexColor matches a hex color of 3 or 6 digits, the format of the hexcolor validation
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// ValidColor reports whether the color of the tag is empty or a hex color, e.g. #1e90ff
+func (t Tag) ValidColor() bool {
+	return t.Color == "" || hexColor.MatchString(t.Color)
+}
+
+// TagNode is a tag of the taxonomy tree with its child tags
+type TagNode struct {
+	Tag
+	Children []TagNode `json:"children"`
+}
+
+// TagAlias is another name of the tag TagID, e.g. the name of a tag merged into it. The aliases
+// and the names of the tags are unique by their key, folded to lower case without accents.
+type TagAlias struct {
+	Name  string `json:"name" validate:"required,max=200"`
+	TagID int64  `json:"tag_id"`
+}
+
+// TagSort is the order of a tag listing, the empty sort orders by creation time
+type TagSort string
+
+// TagSortPopular orders the tags by their number of articles, the most used first
+const TagSortPopular TagSort = "popular"
+
+// TagFilter narrows a tag listing, the zero value lists every visible tag by creation time
+type TagFilter struct {
+	Sort TagSort
+	// IncludeHidden also lists the hidden tags, it is never set from a request of the API
+	IncludeHidden bool
+}
+
+// TagDeletePolicy decides what happens to the articles of a deleted tag
+type TagDeletePolicy string
+
+// Delete policies of a tag, the default is TagDeleteRestrict
+const (
+	// TagDeleteRestrict refuses to delete a tag that articles still reference
+	TagDeleteRestrict TagDeletePolicy = "restrict"
+	// TagDeleteReassign moves the articles to another tag
+	TagDeleteReassign TagDeletePolicy = "reassign"
+	// TagDeleteCascadeDetach leaves the articles without a tag, their tag_id becomes 0
+	TagDeleteCascadeDetach TagDeletePolicy = "cascade-detach"
+)
+
+// TagDeletePolicies is the whitelist of the delete policies
+var TagDeletePolicies = []TagDeletePolicy{TagDeleteRestrict, TagDeleteReassign, TagDeleteCascadeDetach}
+
+// TagInUseError is the conflict of a restricted delete, Articles are the first of the
+// ArticleCount articles referencing the tag with only their id, title and slug
+type TagInUseError struct {
+	ArticleCount int64
+	Articles     []Article
+}
+
+func (e *TagInUseError) Error() string {
+	return ErrConflict.Error()
+}
+
+// Unwrap lets errors.Is match the conflict
+func (e *TagInUseError) Unwrap() error {
+	return ErrConflict
 }
 
 // TagUseCase represent the tag's usecases
 type TagUseCase interface {
 	Fetch(ctx context.Context, cursor string, num int64) (tags []Tag, nextCursor string, err error) // naked return
+	// FetchFiltered lists the tags in the order of the filter, the cursor is only valid for the same sort
+	FetchFiltered(ctx context.Context, filter TagFilter, cursor string, num int64) (tags []Tag, nextCursor string, err error)
+	// Count returns the number of tags the filter lists across every page
+	Count(ctx context.Context, filter TagFilter) (int64, error)
+	// FetchByID and FetchBySlug count the articles of the tag, FetchByName leaves its ArticleCount zero
 	FetchByID(ctx context.Context, id int64) (Tag, error)
+	// FetchByName finds the tag by its name or one of its aliases, ignoring the case and the accents
 	FetchByName(ctx context.Context, name string) (Tag, error)
+	FetchBySlug(ctx context.Context, slug string) (Tag, error)
+	// Suggest completes the prefix of a visible tag name or alias, the exact matches first and then the most used tags
+	Suggest(ctx context.Context, prefix string, num int64) ([]Tag, error)
+	// FetchTree returns the tree below the tag rootID, or the trees of every root tag when rootID is 0.
+	// A hidden tag is left out with its descendants, a hidden rootID is ErrNotFound.
+	FetchTree(ctx context.Context, rootID int64) ([]TagNode, error)
+	// Store fails with ErrBadParamInput when the color is not a hex color
 	Store(ctx context.Context, t *Tag) error
+	// Update moves the tag to t.ParentID, a parent below the tag itself or a color that is not a
+	// hex color is ErrBadParamInput
 	Update(ctx context.Context, t *Tag) error
+	// Delete refuses to delete a tag that articles still reference, it is DeleteWithPolicy with TagDeleteRestrict
 	Delete(ctx context.Context, id int64) error
+	// DeleteWithPolicy deletes the tag, reassignTo is the tag the articles move to with TagDeleteReassign
+	DeleteWithPolicy(ctx context.Context, id int64, policy TagDeletePolicy, reassignTo int64) error
+	// Merge folds the tag into the tag into, the articles move and the name of the tag becomes an alias of into
+	Merge(ctx context.Context, id, into int64) (Tag, error)
+	FetchAliases(ctx context.Context, tagID int64) ([]TagAlias, error)
+	// StoreAlias adds an alias to the tag a.TagID, a name or an alias already in use is ErrConflict
+	StoreAlias(ctx context.Context, a *TagAlias) error
+	DeleteAlias(ctx context.Context, tagID int64, alias string) error
 }
 
 // Tag represent the tag's repository contract
 type TagRepository interface {
 	Fetch(ctx context.Context, cursor string, num int64) (tags []Tag, nextCursor string, err error) // naked return
+	FetchFiltered(ctx context.Context, filter TagFilter, cursor string, num int64) (tags []Tag, nextCursor string, err error)
+	// Count returns the number of tags the filter lists, the hidden tags only with IncludeHidden
+	Count(ctx context.Context, filter TagFilter) (int64, error)
+	// FetchByID, FetchByName, FetchBySlug and FetchByAlias leave the ArticleCount of the tag zero,
+	// ArticleCount counts the articles of a single tag
 	FetchByID(ctx context.Context, id int64) (Tag, error)
+	// FetchByName matches the name by its key, e.g. "Golang" finds the tag "golang"
 	FetchByName(ctx context.Context, name string) (Tag, error)
+	FetchBySlug(ctx context.Context, slug string) (Tag, error)
+	// ArticleCount returns the number of articles of the tag
+	ArticleCount(ctx context.Context, id int64) (int64, error)
+	// Suggest returns the visible tags whose name key or alias key starts with the key of the prefix, ranked
+	// by the exact name, the exact alias, the name prefix and the alias prefix, then by article count and name
+	Suggest(ctx context.Context, prefix string, num int64) ([]Tag, error)
+	// FetchTree returns the tag rootID with its descendants, or every tag below the root tags when
+	// rootID is 0, found with a recursive query
+	FetchTree(ctx context.Context, rootID int64) ([]Tag, error)
+	// FetchByAlias returns the tag of the alias, matched by its key
+	FetchByAlias(ctx context.Context, alias string) (Tag, error)
+	FetchAliases(ctx context.Context, tagID int64) ([]TagAlias, error)
+	// StoreAlias fails with ErrConflict when the key of the alias is taken by another alias
+	StoreAlias(ctx context.Context, a *TagAlias) error
+	// DeleteAlias fails with ErrNotFound when the tag has no such alias
+	DeleteAlias(ctx context.Context, tagID int64, alias string) error
 	Store(ctx context.Context, t *Tag) error
 	Update(ctx context.Context, t *Tag) error
+	// Delete fails with a *TagInUseError while articles still reference the tag, the child tags of
+	// a deleted tag move to its parent
 	Delete(ctx context.Context, id int64) error
+	// Reassign deletes the tag and moves its articles to the tag to in one transaction, to 0 leaves
+	// them without a tag, the moved articles get a new version and updated_at. With keepAlias the name
+	// and the aliases of the tag become aliases of to and its child tags move to to.
+	Reassign(ctx context.Context, id, to int64, keepAlias bool) error
 }
 
 // // CreateTagRequest is representing the create request data input
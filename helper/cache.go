@@ -0,0 +1,76 @@
+package helper
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo"
+)
+
+const headerCacheControl = "Cache-Control"
+
+// CachePolicy is the Cache-Control header of the GET responses, e.g. no-cache so the clients revalidate
+// with the ETag, or public, max-age=60 so shared caches keep the responses for a minute
+type CachePolicy struct {
+	// Default applies to the routes without a policy of their own, empty sends no header
+	Default string `mapstructure:"default"`
+	// Routes are the policies of the routes, by path
+	Routes []RouteCachePolicy `mapstructure:"routes"`
+}
+
+// RouteCachePolicy is the Cache-Control header of a route. The path is the route as registered, e.g.
+// /articles/:articleId, and applies to every version of the routes under /api.
+type RouteCachePolicy struct {
+	Path         string `mapstructure:"path"`
+	CacheControl string `mapstructure:"cache_control"`
+}
+
+// CacheControl returns the Cache-Control header of the route registered with path
+func (p CachePolicy) CacheControl(path string) string {
+	if rest, ok := unversionedPath(path); ok {
+		path = rest
+	}
+	for _, route := range p.Routes {
+		if route.Path == path {
+			return route.CacheControl
+		}
+	}
+	return p.Default
+}
+
+// Middleware will set the Cache-Control header of the policy on the successful, redirected and
+// 304 Not Modified responses to GET, the errors are left to the default caching of the clients
+func (p CachePolicy) Middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if c.Request().Method != http.MethodGet {
+			return next(c)
+		}
+		value := p.CacheControl(c.Path())
+		if value == "" {
+			return next(c)
+		}
+
+		res := c.Response()
+		res.Writer = &cacheControlWriter{ResponseWriter: res.Writer, value: value}
+		return next(c)
+	}
+}
+
+// cacheControlWriter sets the Cache-Control header once the status of the response is known
+type cacheControlWriter struct {
+	http.ResponseWriter
+	value string
+}
+
+func (w *cacheControlWriter) WriteHeader(code int) {
+	if code < http.StatusBadRequest && w.Header().Get(headerCacheControl) == "" {
+		w.Header().Set(headerCacheControl, w.value)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Flush keeps the streamed responses, e.g. the export, flushing through the writer
+func (w *cacheControlWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
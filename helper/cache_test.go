@@ -0,0 +1,56 @@
+package helper_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-postgres-clean-arch/helper"
+
+	"github.com/labstack/echo"
+)
+
+func TestCachePolicy(t *testing.T) {
+	policy := helper.CachePolicy{
+		Default: "no-cache",
+		Routes: []helper.RouteCachePolicy{
+			{Path: "/articles/:articleId", CacheControl: "public, max-age=60"},
+			{Path: "/sitemap.xml", CacheControl: "public, max-age=3600"},
+		},
+	}
+	e := echo.New()
+	e.Use(policy.Middleware)
+	ok := func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	}
+	for _, prefix := range []string{"/api", "/api/v1", "/api/v2"} {
+		e.GET(prefix+"/articles", ok)
+		e.GET(prefix+"/articles/:articleId", func(c echo.Context) error {
+			if c.Param("articleId") == "0" {
+				return c.NoContent(http.StatusNotFound)
+			}
+			return c.NoContent(http.StatusNotModified)
+		})
+		e.DELETE(prefix+"/articles/:articleId", ok)
+	}
+	e.GET("/sitemap.xml", ok)
+
+	tests := []struct {
+		method, target, want string
+	}{
+		{http.MethodGet, "/api/articles/1", "public, max-age=60"},
+		{http.MethodGet, "/api/v2/articles/1", "public, max-age=60"},
+		{http.MethodGet, "/api/v1/articles", "no-cache"},
+		{http.MethodGet, "/sitemap.xml", "public, max-age=3600"},
+		// the errors and the changes are not cached
+		{http.MethodGet, "/api/v2/articles/0", ""},
+		{http.MethodDelete, "/api/v2/articles/1", ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+		if got := rec.Header().Get("Cache-Control"); got != tt.want {
+			t.Errorf("%s %s answered Cache-Control %q, want %q", tt.method, tt.target, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+// Package fold builds the keys the tag names are compared on.
+package fold
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Key folds the name to lower case without accents and with single spaces, e.g. "  Crème  Brûlée"
+// becomes "creme brulee". Two names with the same key are the same name.
+func Key(name string) string {
+	var b strings.Builder
+	space := false
+	// the decomposition splits the accents from their base letter so they can be dropped
+	for _, r := range norm.NFKD.String(strings.ToLower(name)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// a combining accent
+		case unicode.IsSpace(r):
+			space = true
+		default:
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// LikePrefix escapes the key for a LIKE pattern matching the keys starting with it, '!' is the
+// escape character
+func LikePrefix(key string) string {
+	r := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+	return r.Replace(key) + "%"
+}
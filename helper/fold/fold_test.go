@@ -0,0 +1,30 @@
+package fold_test
+
+import (
+	"testing"
+
+	"go-postgres-clean-arch/helper/fold"
+)
+
+func TestKey(t *testing.T) {
+	tests := map[string]string{
+		"Golang":              "golang",
+		"GOLANG":              "golang",
+		"  Crème  Brûlée  ":   "creme brulee",
+		"Ｇｏ":                  "go",
+		"Привет":              "привет",
+		"\tmachine\nlearning": "machine learning",
+		"":                    "",
+	}
+	for name, want := range tests {
+		if got := fold.Key(name); got != want {
+			t.Errorf("Key(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestLikePrefix(t *testing.T) {
+	if got := fold.LikePrefix("50%_off!"); got != "50!%!_off!!%" {
+		t.Fatalf("unexpected pattern %q", got)
+	}
+}
@@ -0,0 +1,74 @@
+// Package mysqltest provides the MySQL database the repository tests run against.
+package mysqltest
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"go-postgres-clean-arch/migration"
+
+	sqle "github.com/dolthub/go-mysql-server"
+	"github.com/dolthub/go-mysql-server/memory"
+	"github.com/dolthub/go-mysql-server/server"
+	gmsql "github.com/dolthub/go-mysql-server/sql"
+	"github.com/dolthub/vitess/go/mysql"
+	_ "github.com/go-sql-driver/mysql"
+)
+
+const dbName = "article"
+
+// Open connects to the database given in MYSQL_TEST_DSN, or to an in-process
+// go-mysql-server stand-in when it is not set.
+func Open(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("MYSQL_TEST_DSN")
+	if dsn == "" {
+		dsn = startStandIn(t)
+	}
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// OpenMigrated opens the database like Open and applies the embedded mysql migrations, the
+// repository tests run against the schema the application creates
+func OpenMigrated(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db := Open(t)
+	if _, err := migration.Up(context.Background(), db, "mysql"); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// startStandIn serves an empty in-memory database over the MySQL protocol and returns its DSN
+func startStandIn(t *testing.T) string {
+	t.Helper()
+
+	pro := memory.NewDBProvider(memory.NewDatabase(dbName))
+	engine := sqle.NewDefault(pro)
+
+	sessionBuilder := func(ctx context.Context, conn *mysql.Conn, addr string) (gmsql.Session, error) {
+		client := gmsql.Client{Address: addr, User: "root", Capabilities: conn.Capabilities}
+		return memory.NewSession(gmsql.NewBaseSessionWithClientServer(addr, client, conn.ConnectionID), pro), nil
+	}
+
+	srv, err := server.NewServer(server.Config{Protocol: "tcp", Address: "127.0.0.1:0"}, engine, sessionBuilder, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = srv.Start()
+	}()
+	t.Cleanup(func() { srv.Close() })
+
+	return "root@tcp(" + srv.Listener.Addr().String() + ")/" + dbName + "?parseTime=true&loc=UTC"
+}
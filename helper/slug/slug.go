@@ -0,0 +1,91 @@
+// Package slug builds the URL slugs of the articles and tags.
+package slug
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxLength bounds the length of a slug, the suffix added by Unique included
+const MaxLength = 200
+
+// transliterations spells the letters that do not decompose to an ASCII base letter
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d", 'ł': "l", 'þ': "th", 'ı': "i",
+	// cyrillic
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'ґ': "g", 'д': "d", 'е': "e", 'ё': "e", 'є': "ye", 'ж': "zh",
+	'з': "z", 'и': "i", 'і': "i", 'ї': "yi", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh",
+	'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	// greek
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th", 'ι': "i", 'κ': "k",
+	'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t",
+	'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o",
+}
+
+// Make transliterates the text to lower case ASCII words joined by dashes, e.g. "Crème Brûlée!"
+// becomes "creme-brulee". The letters it can not transliterate are dropped, so the slug may be empty.
+func Make(text string) string {
+	var b strings.Builder
+	dash := false
+	write := func(s string) {
+		if s == "" {
+			return
+		}
+		if dash && b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		dash = false
+		b.WriteString(s)
+	}
+
+	// the decomposition splits the accents from their base letter so they can be dropped
+	for _, r := range norm.NFKD.String(strings.ToLower(text)) {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			write(string(r))
+		case unicode.Is(unicode.Mn, r):
+			// a combining accent
+		default:
+			// the cyrillic signs are transliterated to nothing and keep the word together
+			if s, ok := transliterations[r]; ok {
+				write(s)
+			} else {
+				dash = true
+			}
+		}
+	}
+	return truncate(b.String(), MaxLength)
+}
+
+// Unique returns base, or base followed by the first free -2, -3... suffix, taken reports whether
+// a slug is already used
+func Unique(base string, taken func(slug string) (bool, error)) (string, error) {
+	candidate := base
+	for n := 2; ; n++ {
+		used, err := taken(candidate)
+		if err != nil {
+			return "", err
+		}
+		if !used {
+			return candidate, nil
+		}
+		suffix := "-" + strconv.Itoa(n)
+		candidate = truncate(base, MaxLength-len(suffix)) + suffix
+	}
+}
+
+// truncate cuts the slug to max bytes, on a dash when there is one
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	s = s[:max]
+	if i := strings.LastIndexByte(s, '-'); i > 0 {
+		s = s[:i]
+	}
+	return strings.TrimRight(s, "-")
+}
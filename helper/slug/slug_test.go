@@ -0,0 +1,53 @@
+package slug_test
+
+import (
+	"strings"
+	"testing"
+
+	"go-postgres-clean-arch/helper/slug"
+)
+
+func TestMake(t *testing.T) {
+	tests := map[string]string{
+		"Hello, World!":           "hello-world",
+		"  Crème Brûlée  ":        "creme-brulee",
+		"Straße & Ærø":            "strasse-aero",
+		"Łódź 2023":               "lodz-2023",
+		"Привет, мир":             "privet-mir",
+		"Объект":                  "obekt",
+		"Αθήνα":                   "athina",
+		"ﬁve ①":                   "five-1",
+		"Go_is--fun...":           "go-is-fun",
+		"日本語":                     "",
+		"C++ & Go: a comparison?": "c-go-a-comparison",
+	}
+	for text, want := range tests {
+		if got := slug.Make(text); got != want {
+			t.Errorf("Make(%q) = %q, want %q", text, got, want)
+		}
+	}
+
+	long := slug.Make(strings.Repeat("word ", 100))
+	if len(long) > slug.MaxLength || strings.HasSuffix(long, "-") {
+		t.Fatalf("unexpected long slug %q", long)
+	}
+}
+
+func TestUnique(t *testing.T) {
+	taken := map[string]bool{"go": true, "go-2": true}
+	got, err := slug.Unique("go", func(s string) (bool, error) { return taken[s], nil })
+	if err != nil || got != "go-3" {
+		t.Fatalf("expected go-3, got %q %v", got, err)
+	}
+
+	got, _ = slug.Unique("free", func(s string) (bool, error) { return taken[s], nil })
+	if got != "free" {
+		t.Fatalf("expected free, got %q", got)
+	}
+
+	base := strings.Repeat("a", slug.MaxLength)
+	got, _ = slug.Unique(base, func(s string) (bool, error) { return s == base, nil })
+	if len(got) > slug.MaxLength || !strings.HasSuffix(got, "-2") {
+		t.Fatalf("unexpected slug %q", got)
+	}
+}
@@ -0,0 +1,103 @@
+package helper
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+// APIVersion is the version of the routes of the API, the changes breaking the clients of a version
+// go to the next one
+type APIVersion int
+
+const (
+	// APIv1 is frozen, it is mounted under /api and /api/v1 and announces its deprecation
+	APIv1 APIVersion = 1
+	// APIv2 answers every response in the envelope and lists the tags of an article
+	APIv2 APIVersion = 2
+)
+
+// APIVersions are the versions the handlers mount their routes for, the latest last
+var APIVersions = []APIVersion{APIv1, APIv2}
+
+// V1Deprecation and V1Sunset are the dates the Deprecation and Sunset headers of the v1 routes announce
+var (
+	V1Deprecation = time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC)
+	V1Sunset      = time.Date(2027, time.May, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// Prefixes are the paths the routes of the version are mounted under
+func (v APIVersion) Prefixes() []string {
+	if v == APIv1 {
+		return []string{"/api", v.Prefix()}
+	}
+	return []string{v.Prefix()}
+}
+
+// Prefix is the path of the version, e.g. /api/v2
+func (v APIVersion) Prefix() string {
+	return "/api/v" + strconv.Itoa(int(v))
+}
+
+// Middleware returns the middlewares of the routes of the version
+func (v APIVersion) Middleware() []echo.MiddlewareFunc {
+	if v == APIv1 {
+		return []echo.MiddlewareFunc{NegotiateEnvelope, Deprecated(V1Deprecation, V1Sunset, APIv2)}
+	}
+	return []echo.MiddlewareFunc{ForceEnvelope}
+}
+
+// Deprecated will announce the deprecation of the routes with the Deprecation header of RFC 9745
+// and the Sunset header of RFC 8594, the Link header points to the same route of the successor version
+func Deprecated(deprecation, sunset time.Time, successor APIVersion) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			header := c.Response().Header()
+			header.Set("Deprecation", "@"+strconv.FormatInt(deprecation.Unix(), 10))
+			header.Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			if path, ok := successorPath(c.Request().URL.Path, successor); ok {
+				header.Add("Link", "<"+path+`>; rel="successor-version"`)
+			}
+			return next(c)
+		}
+	}
+}
+
+// successorPath is the path of the route under the successor version, for a path of an older version
+func successorPath(path string, successor APIVersion) (string, bool) {
+	rest, ok := unversionedPath(path)
+	if !ok {
+		return "", false
+	}
+	return successor.Prefix() + rest, true
+}
+
+// unversionedPath is the path under /api without its version, e.g. /tags for /api/v1/tags.
+// It is false for a path outside of /api.
+func unversionedPath(path string) (string, bool) {
+	rest := strings.TrimPrefix(path, "/api")
+	if rest == path {
+		return "", false
+	}
+	// a versioned path, e.g. /api/v1/tags
+	if strings.HasPrefix(rest, "/v") {
+		i := strings.IndexByte(rest[1:], '/')
+		if i < 0 {
+			return "", true
+		}
+		rest = rest[i+1:]
+	}
+	return rest, true
+}
+
+// OperationID suffixes the OpenAPI operation id of a route mounted under prefix with the version,
+// the routes under /api keep the bare id
+func (v APIVersion) OperationID(prefix, id string) string {
+	if prefix == "/api" {
+		return id
+	}
+	return id + "V" + strconv.Itoa(int(v))
+}
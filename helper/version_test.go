@@ -0,0 +1,39 @@
+package helper_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-postgres-clean-arch/helper"
+
+	"github.com/labstack/echo"
+)
+
+func TestDeprecated(t *testing.T) {
+	deprecation := time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2027, time.May, 1, 0, 0, 0, 0, time.UTC)
+	e := echo.New()
+	e.GET("/*", func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	}, helper.Deprecated(deprecation, sunset, helper.APIv2))
+
+	tests := map[string]string{
+		"/api/tags/1":        `</api/v2/tags/1>; rel="successor-version"`,
+		"/api/v1/tags?num=1": `</api/v2/tags>; rel="successor-version"`,
+		"/api/v1":            `</api/v2>; rel="successor-version"`,
+		"/sitemap.xml":       "",
+	}
+	for target, link := range tests {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		header := rec.Header()
+		if header.Get("Deprecation") != "@1793491200" || header.Get("Sunset") != "Sat, 01 May 2027 00:00:00 GMT" {
+			t.Errorf("%s answered the headers %v", target, header)
+		}
+		if got := header.Get("Link"); got != link {
+			t.Errorf("%s answered the link %q, want %q", target, got, link)
+		}
+	}
+}
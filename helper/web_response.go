@@ -1,7 +1,196 @@
 package helper
 
+import (
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/labstack/echo"
+)
+
+// EnvelopeProfile is the profile of the Accept header negotiating the envelope, e.g.
+// Accept: application/json; profile="envelope"
+const EnvelopeProfile = "envelope"
+
+// EnvelopeVersion is the API version answered with the envelope, e.g. Accept: application/json; version=2
+const EnvelopeVersion = "2"
+
+const (
+	envelopeContentType = echo.MIMEApplicationJSONCharsetUTF8 + `; profile="` + EnvelopeProfile + `"`
+	envelopeKey         = "helper.envelope"
+	pageKey             = "helper.page"
+)
+
+// Response is the envelope of the JSON responses, Meta and Links are only set on the pages of a listing
 type Response struct {
 	Code   int         `json:"code"`
 	Status string      `json:"status"`
 	Data   interface{} `json:"data,omitempty"`
+	Meta   *Meta       `json:"meta,omitempty"`
+	Links  *Links      `json:"links,omitempty"`
+}
+
+// Meta is the position of a page in its listing
+type Meta struct {
+	// Cursor is the cursor the page was requested with, empty on the first page
+	Cursor string `json:"cursor"`
+	// NextCursor is empty on the last page
+	NextCursor string `json:"next_cursor"`
+	PageSize   int64  `json:"page_size"`
+	// Total is the number of items of every page of the listing
+	Total int64 `json:"total"`
+}
+
+// Links are the URLs of a page and of the pages around it. The cursors only move forward, the Next
+// link carries the cursor of its page in prevCursorParam so the page it leads to links back to it.
+// Prev is empty on the first page and on a page requested without that parameter, e.g. through Prev.
+type Links struct {
+	Self  string `json:"self"`
+	First string `json:"first"`
+	Prev  string `json:"prev,omitempty"`
+	Next  string `json:"next,omitempty"`
+}
+
+// prevCursorParam is the query parameter of the Next link holding the cursor of the page before it
+const prevCursorParam = "prev_cursor"
+
+// Envelope is a Response with a Data of type T, e.g. to document the body of an enveloped route
+type Envelope[T any] struct {
+	Code   int    `json:"code"`
+	Status string `json:"status"`
+	Data   T      `json:"data"`
+	Meta   *Meta  `json:"meta,omitempty"`
+	Links  *Links `json:"links,omitempty"`
+}
+
+// Page describes the page of a listing for the Meta and the Links of the envelope
+type Page struct {
+	Cursor     string
+	NextCursor string
+	Size       int64
+	// Total counts the items of the listing, it is only called when the response is enveloped
+	Total func() (int64, error)
+}
+
+// NegotiateEnvelope will wrap the JSON answered by the handlers in a Response when the Accept header
+// asks for the EnvelopeProfile or the EnvelopeVersion, the other responses are left as they are
+func NegotiateEnvelope(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Response().Header().Add(echo.HeaderVary, echo.HeaderAccept)
+		if !WantsEnvelope(c.Request().Header.Get(echo.HeaderAccept)) {
+			return next(c)
+		}
+		c.Set(envelopeKey, true)
+		return next(&envelopeContext{Context: c})
+	}
+}
+
+// ForceEnvelope will wrap the JSON answered by the handlers in a Response whatever the Accept header
+func ForceEnvelope(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Set(envelopeKey, true)
+		return next(&envelopeContext{Context: c})
+	}
+}
+
+// WantsEnvelope reports whether one of the JSON media types of the Accept header asks for the envelope
+func WantsEnvelope(accept string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil || (mediaType != echo.MIMEApplicationJSON && mediaType != "*/*") {
+			continue
+		}
+		if params["version"] == EnvelopeVersion {
+			return true
+		}
+		// the profile is a list of profiles separated by spaces
+		for _, profile := range strings.Fields(params["profile"]) {
+			if profile == EnvelopeProfile {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Enveloped reports whether the response to the request is wrapped in the envelope
+func Enveloped(c echo.Context) bool {
+	enveloped, _ := c.Get(envelopeKey).(bool)
+	return enveloped
+}
+
+// SetPage will describe the page the handler answers to the envelope, it returns the error of
+// p.Total. Without the envelope it does nothing.
+func SetPage(c echo.Context, p Page) error {
+	if !Enveloped(c) {
+		return nil
+	}
+
+	meta := Meta{Cursor: p.Cursor, NextCursor: p.NextCursor, PageSize: p.Size}
+	if p.Total != nil {
+		total, err := p.Total()
+		if err != nil {
+			return err
+		}
+		meta.Total = total
+	}
+	c.Set(pageKey, meta)
+	return nil
+}
+
+// PageMeta returns the meta of the page set by SetPage, it is false without the envelope
+func PageMeta(c echo.Context) (Meta, bool) {
+	meta, ok := c.Get(pageKey).(Meta)
+	return meta, ok
+}
+
+// envelopeContext answers the JSON of the handler in a Response
+type envelopeContext struct {
+	echo.Context
+}
+
+func (c *envelopeContext) JSON(code int, i interface{}) error {
+	res := Response{Code: code, Status: "Ok", Data: i}
+	if code >= http.StatusBadRequest {
+		res.Status = "Error"
+	}
+
+	if meta, ok := c.Get(pageKey).(Meta); ok && code < http.StatusBadRequest {
+		res.Meta = &meta
+		res.Links = pageLinks(c.Request().URL, meta.NextCursor)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, envelopeContentType)
+	return c.Context.JSON(code, res)
+}
+
+// pageLinks builds the links of the page from the URL it was requested with
+func pageLinks(u *url.URL, nextCursor string) *Links {
+	current := u.Query()
+	link := func(cursor string, prev *string) string {
+		query := u.Query()
+		query.Del("cursor")
+		query.Del(prevCursorParam)
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		if prev != nil {
+			query.Set(prevCursorParam, *prev)
+		}
+		if len(query) == 0 {
+			return u.Path
+		}
+		return u.Path + "?" + query.Encode()
+	}
+
+	cursor := current.Get("cursor")
+	links := &Links{Self: u.RequestURI(), First: link("", nil)}
+	if _, ok := current[prevCursorParam]; ok && cursor != "" {
+		links.Prev = link(current.Get(prevCursorParam), nil)
+	}
+	if nextCursor != "" {
+		links.Next = link(nextCursor, &cursor)
+	}
+	return links
 }
@@ -0,0 +1,100 @@
+package helper_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-postgres-clean-arch/helper"
+
+	"github.com/labstack/echo"
+)
+
+func TestWantsEnvelope(t *testing.T) {
+	tests := map[string]bool{
+		"":                                     false,
+		"application/json":                     false,
+		`application/json; profile="envelope"`: true,
+		`application/json;profile="https://example.com/p envelope"`: true,
+		"application/json; version=2":                               true,
+		"application/json; version=1":                               false,
+		`text/html, */*; profile=envelope`:                          true,
+		`application/xml; profile="envelope"`:                       false,
+	}
+	for accept, want := range tests {
+		if got := helper.WantsEnvelope(accept); got != want {
+			t.Errorf("WantsEnvelope(%q) = %v, want %v", accept, got, want)
+		}
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	e := echo.New()
+	e.GET("/items", func(c echo.Context) error {
+		err := helper.SetPage(c, helper.Page{
+			Cursor:     c.QueryParam("cursor"),
+			NextCursor: "next+1",
+			Size:       2,
+			Total:      func() (int64, error) { return 5, nil },
+		})
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, []string{"a", "b"})
+	}, helper.NegotiateEnvelope)
+	e.GET("/failing", func(c echo.Context) error {
+		if err := helper.SetPage(c, helper.Page{Total: func() (int64, error) { return 0, errors.New("count") }}); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+		}
+		return c.JSON(http.StatusOK, []string{})
+	}, helper.NegotiateEnvelope)
+
+	serve := func(target, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set(echo.HeaderAccept, accept)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("/items?q=x&cursor=c1", echo.MIMEApplicationJSON)
+	if rec.Body.String() != "[\"a\",\"b\"]\n" || rec.Header().Get(echo.HeaderVary) != echo.HeaderAccept {
+		t.Fatalf("unexpected response without the envelope %v %s", rec.Header(), rec.Body.String())
+	}
+
+	rec = serve("/items?q=x&cursor=c1", `application/json; profile="envelope"`)
+	var res struct {
+		helper.Response
+		Data []string `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+	if res.Code != http.StatusOK || res.Status != "Ok" || len(res.Data) != 2 {
+		t.Fatalf("unexpected envelope %+v", res)
+	}
+	if *res.Meta != (helper.Meta{Cursor: "c1", NextCursor: "next+1", PageSize: 2, Total: 5}) {
+		t.Fatalf("unexpected meta %+v", res.Meta)
+	}
+	want := helper.Links{Self: "/items?q=x&cursor=c1", First: "/items?q=x", Next: "/items?cursor=next%2B1&prev_cursor=c1&q=x"}
+	if *res.Links != want {
+		t.Fatalf("unexpected links %+v, want %+v", res.Links, want)
+	}
+
+	// the page of the Next link links back to the page before it
+	rec = serve(want.Next, "application/json; version=2")
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil || res.Links.Prev != "/items?cursor=c1&q=x" {
+		t.Fatalf("unexpected links %+v", res.Links)
+	}
+	rec = serve("/items?cursor=next&prev_cursor=", "application/json; version=2")
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil || res.Links.Prev != "/items" {
+		t.Fatalf("unexpected links %+v", res.Links)
+	}
+
+	rec = serve("/failing", "application/json; version=2")
+	if rec.Code != http.StatusInternalServerError || rec.Body.String() != `{"code":500,"status":"Error","data":{"message":"count"}}`+"\n" {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+}
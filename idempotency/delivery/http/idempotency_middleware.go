@@ -0,0 +1,156 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"go-postgres-clean-arch/data/response"
+	"go-postgres-clean-arch/domain"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// HeaderIdempotencyKey is the header of the key a client sends again with the retries of a request
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// HeaderIdempotentReplayed is set on the responses replayed for a retry
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+	// maxKeyLength is the length of the idempotency_key column
+	maxKeyLength = 255
+)
+
+// replayedHeaders are the headers of a response stored to be replayed with it
+var replayedHeaders = []string{echo.HeaderContentType, echo.HeaderLocation, "ETag", echo.HeaderLastModified}
+
+// IdempotencyMiddleware represent the middleware answering the retries of a request sent with the same
+// Idempotency-Key header with the response of the first request, instead of processing them again
+type IdempotencyMiddleware struct {
+	IUsecase domain.IdempotencyUsecase
+}
+
+// NewIdempotencyMiddleware will initialize the middleware storing the requests in the usecase
+func NewIdempotencyMiddleware(u domain.IdempotencyUsecase) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{IUsecase: u}
+}
+
+// Idempotent will replay the response of the request first sent with the Idempotency-Key header. The keys
+// are scoped by the route and the client, the same key sent to another route or by another client is a
+// new request. A key used for another request is refused with 422, and with 409 while the first request is
+// processed. The requests without the header are processed as they are.
+func (m *IdempotencyMiddleware) Idempotent(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		key := c.Request().Header.Get(HeaderIdempotencyKey)
+		if key == "" {
+			return next(c)
+		}
+		if len(key) > maxKeyLength {
+			return c.JSON(http.StatusBadRequest, HeaderIdempotencyKey+" is longer than 255 characters")
+		}
+		key = scopeKey(c, key)
+
+		fingerprint, err := fingerprintRequest(c.Request())
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+
+		replay, err := m.IUsecase.Begin(c.Request().Context(), key, fingerprint)
+		switch err {
+		case nil:
+		case domain.ErrIdempotencyKeyReused:
+			// the handlers answer the other unprocessable requests with the bare message too
+			return c.JSON(http.StatusUnprocessableEntity, err.Error())
+		case domain.ErrConflict:
+			return c.JSON(http.StatusConflict, response.ErrorResponse{Message: "the first request with this " + HeaderIdempotencyKey + " is still processed"})
+		default:
+			logrus.Error(err)
+			return c.JSON(http.StatusInternalServerError, response.ErrorResponse{Message: domain.ErrInternalServerError.Error()})
+		}
+
+		if replay != nil {
+			header := c.Response().Header()
+			for name, value := range replay.Header {
+				header.Set(name, value)
+			}
+			header.Set(HeaderIdempotentReplayed, "true")
+			return c.Blob(replay.Status, replay.Header[echo.HeaderContentType], replay.Body)
+		}
+
+		res := c.Response()
+		rec := &responseRecorder{ResponseWriter: res.Writer}
+		res.Writer = rec
+		// a panic of the handler releases the key too, then goes on to the recovery of the server
+		defer func() {
+			if r := recover(); r != nil {
+				m.release(key)
+				panic(r)
+			}
+		}()
+		err = next(c)
+
+		if err != nil || res.Status >= http.StatusInternalServerError {
+			// the failure may be temporary, a retry processes the request again
+			m.release(key)
+			return err
+		}
+
+		stored := &domain.IdempotentRequest{Key: key, Status: res.Status, Header: map[string]string{}, Body: rec.body.Bytes()}
+		for _, name := range replayedHeaders {
+			if value := res.Header().Get(name); value != "" {
+				stored.Header[name] = value
+			}
+		}
+		// the response is stored once sent, even when the client went away meanwhile
+		if errComplete := m.IUsecase.Complete(context.Background(), stored); errComplete != nil {
+			logrus.Error(errComplete)
+		}
+		return nil
+	}
+}
+
+// release frees the key for a retry, even when the client went away meanwhile
+func (m *IdempotencyMiddleware) release(key string) {
+	if err := m.IUsecase.Release(context.Background(), key); err != nil {
+		logrus.Error(err)
+	}
+}
+
+// scopeKey returns the key stored for the request, hashed with the method, the route and the
+// Authorization header identifying the client when there is one, so it fits the idempotency_key column
+func scopeKey(c echo.Context, key string) string {
+	h := sha256.New()
+	io.WriteString(h, c.Request().Method+" "+c.Path()+"\n")
+	io.WriteString(h, c.Request().Header.Get(echo.HeaderAuthorization)+"\n")
+	io.WriteString(h, key)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// fingerprintRequest identifies the method, the URI, the Accept header and the body of the request, the
+// body is left to be read again
+func fingerprintRequest(r *http.Request) (string, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	io.WriteString(h, r.Header.Get(echo.HeaderAccept)+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// responseRecorder keeps a copy of the body written to the response
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,149 @@
+package http_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go-postgres-clean-arch/helper"
+	idempotencyHttp "go-postgres-clean-arch/idempotency/delivery/http"
+	"go-postgres-clean-arch/idempotency/repository/memory"
+	"go-postgres-clean-arch/idempotency/usecase"
+
+	"github.com/labstack/echo"
+)
+
+func TestIdempotent(t *testing.T) {
+	u := usecase.NewIdempotencyUsecase(memory.NewMemoryIdempotencyRepository(), time.Hour, time.Second)
+	m := idempotencyHttp.NewIdempotencyMiddleware(u)
+
+	created := 0
+	store := func(c echo.Context) error {
+		if strings.Contains(c.Request().URL.RawQuery, "fail") {
+			return c.JSON(http.StatusInternalServerError, "down")
+		}
+		created++
+		c.Response().Header().Set("ETag", `"`+strconv.Itoa(created)+`"`)
+		return c.JSON(http.StatusCreated, map[string]int{"id": created})
+	}
+	e := echo.New()
+	e.POST("/api/articles", store, m.Idempotent)
+	e.Group("/api/v2", helper.ForceEnvelope).POST("/articles", store, m.Idempotent)
+
+	postWith := func(target, key, body string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		for name := range header {
+			req.Header.Set(name, header.Get(name))
+		}
+		if key != "" {
+			req.Header.Set(idempotencyHttp.HeaderIdempotencyKey, key)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	post := func(target, key, body string) *httptest.ResponseRecorder {
+		return postWith(target, key, body, nil)
+	}
+
+	first := post("/api/articles", "k1", `{"title": "a"}`)
+	if first.Code != http.StatusCreated || first.Header().Get(idempotencyHttp.HeaderIdempotentReplayed) != "" {
+		t.Fatalf("unexpected response %d %v", first.Code, first.Header())
+	}
+
+	// a retry is answered the first response without creating again
+	retry := post("/api/articles", "k1", `{"title": "a"}`)
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() || created != 1 {
+		t.Fatalf("the retry answered %d %s after %d creations", retry.Code, retry.Body.String(), created)
+	}
+	header := retry.Header()
+	if header.Get(idempotencyHttp.HeaderIdempotentReplayed) != "true" || header.Get("ETag") != `"1"` || header.Get(echo.HeaderContentType) != first.Header().Get(echo.HeaderContentType) {
+		t.Fatalf("the retry answered the headers %v", header)
+	}
+
+	for name, rec := range map[string]*httptest.ResponseRecorder{
+		"another body":   post("/api/articles", "k1", `{"title": "b"}`),
+		"another accept": postWith("/api/articles", "k1", `{"title": "a"}`, http.Header{echo.HeaderAccept: {"text/plain"}}),
+	} {
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s answered %d %s", name, rec.Code, rec.Body.String())
+		}
+	}
+
+	// the keys are scoped by the route and the client, the same key is a new request for them
+	for name, rec := range map[string]*httptest.ResponseRecorder{
+		"another route":  post("/api/v2/articles", "k1", `{"title": "a"}`),
+		"another client": postWith("/api/articles", "k1", `{"title": "a"}`, http.Header{echo.HeaderAuthorization: {"Bearer other"}}),
+	} {
+		if rec.Code != http.StatusCreated || rec.Header().Get(idempotencyHttp.HeaderIdempotentReplayed) != "" {
+			t.Errorf("%s answered %d %s", name, rec.Code, rec.Body.String())
+		}
+	}
+	if created != 3 {
+		t.Fatalf("the other route and client made %d creations", created)
+	}
+	if rec := post("/api/v2/articles", "k1", `{"title": "b"}`); !strings.HasPrefix(rec.Body.String(), `{"code":422,"status":"Error"`) {
+		t.Errorf("the v2 refusal is not enveloped: %s", rec.Body.String())
+	}
+
+	if rec := post("/api/articles", "", `{"title": "a"}`); rec.Code != http.StatusCreated || created != 4 {
+		t.Fatalf("the request without a key answered %d after %d creations", rec.Code, created)
+	}
+	if rec := post("/api/articles", strings.Repeat("k", 256), `{"title": "a"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("a long key answered %d", rec.Code)
+	}
+
+	// a failed request is processed again with its key
+	if rec := post("/api/articles?fail", "k2", `{"title": "c"}`); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("unexpected response %d", rec.Code)
+	}
+	if rec := post("/api/articles?fail", "k2", `{"title": "c"}`); rec.Code != http.StatusInternalServerError || rec.Header().Get(idempotencyHttp.HeaderIdempotentReplayed) != "" {
+		t.Fatalf("the failure was replayed %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestIdempotentReleasesTheKeyOfAPanic(t *testing.T) {
+	u := usecase.NewIdempotencyUsecase(memory.NewMemoryIdempotencyRepository(), time.Hour, time.Second)
+	m := idempotencyHttp.NewIdempotencyMiddleware(u)
+
+	panics := true
+	e := echo.New()
+	// recover answers the panics with 500 like the recovery of the server
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = c.NoContent(http.StatusInternalServerError)
+				}
+			}()
+			return next(c)
+		}
+	})
+	e.POST("/api/articles", func(c echo.Context) error {
+		if panics {
+			panic("broken handler")
+		}
+		return c.JSON(http.StatusCreated, map[string]int{"id": 1})
+	}, m.Idempotent)
+
+	post := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/articles", strings.NewReader(`{"title": "a"}`))
+		req.Header.Set(idempotencyHttp.HeaderIdempotencyKey, "k1")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post(); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("the panic answered %d", rec.Code)
+	}
+	// the retry is processed again rather than refused while the key stays reserved
+	panics = false
+	if rec := post(); rec.Code != http.StatusCreated || rec.Header().Get(idempotencyHttp.HeaderIdempotentReplayed) != "" {
+		t.Fatalf("the retry answered %d %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go-postgres-clean-arch/domain"
+)
+
+// EncodeHeader will encode the headers of a response to the JSON object the header column keeps
+func EncodeHeader(header map[string]string) (string, error) {
+	if header == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(header)
+	return string(b), err
+}
+
+// DecodeHeader will decode the headers of a response from the header column
+func DecodeHeader(s string) (header map[string]string, err error) {
+	if s == "" {
+		return map[string]string{}, nil
+	}
+	err = json.Unmarshal([]byte(s), &header)
+	return
+}
+
+// CheckAffected maps an update of no request to domain.ErrNotFound
+func CheckAffected(res sql.Result) error {
+	affect, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affect == 0 {
+		return domain.ErrNotFound
+	}
+	if affect != 1 {
+		return fmt.Errorf("weird  Behavior. Total Affected: %d", affect)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"context"
+	"go-postgres-clean-arch/domain"
+	"sync"
+	"time"
+)
+
+type memoryIdempotencyRepo struct {
+	mu       sync.Mutex
+	requests map[string]domain.IdempotentRequest
+}
+
+// NewMemoryIdempotencyRepository will create a thread-safe in-memory object that represent the idempotency.Repository interface
+func NewMemoryIdempotencyRepository() domain.IdempotencyRepository {
+	return &memoryIdempotencyRepo{requests: map[string]domain.IdempotentRequest{}}
+}
+
+func (m *memoryIdempotencyRepo) Reserve(ctx context.Context, r *domain.IdempotentRequest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// the key of an expired request can be used again
+	if stored, ok := m.requests[r.Key]; ok && stored.ExpiresAt.After(r.CreatedAt) {
+		return domain.ErrConflict
+	}
+	m.requests[r.Key] = domain.IdempotentRequest{
+		Key:         r.Key,
+		Fingerprint: r.Fingerprint,
+		Header:      map[string]string{},
+		CreatedAt:   r.CreatedAt,
+		ExpiresAt:   r.ExpiresAt,
+	}
+	return nil
+}
+
+func (m *memoryIdempotencyRepo) GetByKey(ctx context.Context, key string) (domain.IdempotentRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, ok := m.requests[key]
+	if !ok {
+		return domain.IdempotentRequest{}, domain.ErrNotFound
+	}
+	return copyRequest(r), nil
+}
+
+func (m *memoryIdempotencyRepo) Complete(ctx context.Context, r *domain.IdempotentRequest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.requests[r.Key]
+	if !ok {
+		return domain.ErrNotFound
+	}
+	stored.Status, stored.Header, stored.Body = r.Status, r.Header, r.Body
+	m.requests[r.Key] = copyRequest(stored)
+	return nil
+}
+
+func (m *memoryIdempotencyRepo) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.requests, key)
+	return nil
+}
+
+func (m *memoryIdempotencyRepo) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted int64
+	for key, r := range m.requests {
+		if !r.ExpiresAt.After(now) {
+			delete(m.requests, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// copyRequest keeps the stored header and body from being changed through a returned request
+func copyRequest(r domain.IdempotentRequest) domain.IdempotentRequest {
+	header := make(map[string]string, len(r.Header))
+	for name, value := range r.Header {
+		header[name] = value
+	}
+	r.Header = header
+	if r.Body != nil {
+		r.Body = append([]byte(nil), r.Body...)
+	}
+	return r
+}
@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"go-postgres-clean-arch/domain"
+	"go-postgres-clean-arch/idempotency/repository/memory"
+	"go-postgres-clean-arch/idempotency/repository/repositorytest"
+)
+
+func TestMemoryIdempotencyRepositoryContract(t *testing.T) {
+	repositorytest.RunIdempotencyContract(t, func(t *testing.T) domain.IdempotencyRepository {
+		return memory.NewMemoryIdempotencyRepository()
+	})
+}
@@ -0,0 +1,91 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go-postgres-clean-arch/domain"
+	"go-postgres-clean-arch/idempotency/repository"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// errDupEntry is the mysql error number raised when a unique key is violated
+const errDupEntry = 1062
+
+type mysqlIdempotencyRepository struct {
+	Conn *sql.DB
+}
+
+// NewMysqlIdempotencyRepository will create an object that represent the idempotency.Repository interface
+func NewMysqlIdempotencyRepository(conn *sql.DB) domain.IdempotencyRepository {
+	return &mysqlIdempotencyRepository{conn}
+}
+
+func (m *mysqlIdempotencyRepository) Reserve(ctx context.Context, r *domain.IdempotentRequest) (err error) {
+	// the key of an expired request can be used again
+	_, err = m.Conn.ExecContext(ctx, "DELETE FROM idempotency_request WHERE idempotency_key = ? AND expires_at <= ?", r.Key, r.CreatedAt)
+	if err != nil {
+		return
+	}
+
+	query := `INSERT INTO idempotency_request (idempotency_key, fingerprint, status, header, created_at, expires_at)
+				VALUES (?, ?, 0, '{}', ?, ?)`
+	_, err = m.Conn.ExecContext(ctx, query, r.Key, r.Fingerprint, r.CreatedAt, r.ExpiresAt)
+	return translateError(err)
+}
+
+func (m *mysqlIdempotencyRepository) GetByKey(ctx context.Context, key string) (res domain.IdempotentRequest, err error) {
+	query := `SELECT idempotency_key, fingerprint, status, header, body, created_at, expires_at
+				FROM idempotency_request
+				WHERE idempotency_key = ?`
+
+	var header string
+	err = m.Conn.QueryRowContext(ctx, query, key).Scan(&res.Key, &res.Fingerprint, &res.Status, &header, &res.Body, &res.CreatedAt, &res.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return res, domain.ErrNotFound
+	}
+	if err != nil {
+		return
+	}
+
+	res.Header, err = repository.DecodeHeader(header)
+	return
+}
+
+func (m *mysqlIdempotencyRepository) Complete(ctx context.Context, r *domain.IdempotentRequest) error {
+	header, err := repository.EncodeHeader(r.Header)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE idempotency_request SET status = ?, header = ?, body = ? WHERE idempotency_key = ?`
+	res, err := m.Conn.ExecContext(ctx, query, r.Status, header, r.Body, r.Key)
+	if err != nil {
+		return err
+	}
+	return repository.CheckAffected(res)
+}
+
+func (m *mysqlIdempotencyRepository) Delete(ctx context.Context, key string) error {
+	_, err := m.Conn.ExecContext(ctx, "DELETE FROM idempotency_request WHERE idempotency_key = ?", key)
+	return err
+}
+
+func (m *mysqlIdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	res, err := m.Conn.ExecContext(ctx, "DELETE FROM idempotency_request WHERE expires_at <= ?", now)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// translateError maps the mysql constraint violations to the domain errors
+func translateError(err error) error {
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) && myErr.Number == errDupEntry {
+		return domain.ErrConflict
+	}
+	return err
+}
@@ -0,0 +1,21 @@
+package mysql_test
+
+import (
+	"testing"
+
+	"go-postgres-clean-arch/domain"
+	"go-postgres-clean-arch/helper/mysqltest"
+	"go-postgres-clean-arch/idempotency/repository/mysql"
+	"go-postgres-clean-arch/idempotency/repository/repositorytest"
+)
+
+func TestMysqlIdempotencyRepositoryContract(t *testing.T) {
+	db := mysqltest.OpenMigrated(t)
+
+	repositorytest.RunIdempotencyContract(t, func(t *testing.T) domain.IdempotencyRepository {
+		if _, err := db.Exec(`TRUNCATE TABLE idempotency_request`); err != nil {
+			t.Fatal(err)
+		}
+		return mysql.NewMysqlIdempotencyRepository(db)
+	})
+}
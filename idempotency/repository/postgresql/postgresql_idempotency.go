@@ -0,0 +1,91 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go-postgres-clean-arch/domain"
+	"go-postgres-clean-arch/idempotency/repository"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// uniqueViolation is the postgresql error code raised when a unique constraint is violated
+const uniqueViolation = "23505"
+
+type postgresqlIdempotencyRepository struct {
+	Conn *sql.DB
+}
+
+// NewPostgresqlIdempotencyRepository will create an object that represent the idempotency.Repository interface
+func NewPostgresqlIdempotencyRepository(conn *sql.DB) domain.IdempotencyRepository {
+	return &postgresqlIdempotencyRepository{conn}
+}
+
+func (m *postgresqlIdempotencyRepository) Reserve(ctx context.Context, r *domain.IdempotentRequest) (err error) {
+	// the key of an expired request can be used again
+	_, err = m.Conn.ExecContext(ctx, "DELETE FROM idempotency_request WHERE idempotency_key = $1 AND expires_at <= $2", r.Key, r.CreatedAt)
+	if err != nil {
+		return
+	}
+
+	query := `INSERT INTO idempotency_request (idempotency_key, fingerprint, status, header, created_at, expires_at)
+				VALUES ($1, $2, 0, '{}', $3, $4)`
+	_, err = m.Conn.ExecContext(ctx, query, r.Key, r.Fingerprint, r.CreatedAt, r.ExpiresAt)
+	return translateError(err)
+}
+
+func (m *postgresqlIdempotencyRepository) GetByKey(ctx context.Context, key string) (res domain.IdempotentRequest, err error) {
+	query := `SELECT idempotency_key, fingerprint, status, header, body, created_at, expires_at
+				FROM idempotency_request
+				WHERE idempotency_key = $1`
+
+	var header string
+	err = m.Conn.QueryRowContext(ctx, query, key).Scan(&res.Key, &res.Fingerprint, &res.Status, &header, &res.Body, &res.CreatedAt, &res.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return res, domain.ErrNotFound
+	}
+	if err != nil {
+		return
+	}
+
+	res.Header, err = repository.DecodeHeader(header)
+	return
+}
+
+func (m *postgresqlIdempotencyRepository) Complete(ctx context.Context, r *domain.IdempotentRequest) error {
+	header, err := repository.EncodeHeader(r.Header)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE idempotency_request SET status = $1, header = $2, body = $3 WHERE idempotency_key = $4`
+	res, err := m.Conn.ExecContext(ctx, query, r.Status, header, r.Body, r.Key)
+	if err != nil {
+		return err
+	}
+	return repository.CheckAffected(res)
+}
+
+func (m *postgresqlIdempotencyRepository) Delete(ctx context.Context, key string) error {
+	_, err := m.Conn.ExecContext(ctx, "DELETE FROM idempotency_request WHERE idempotency_key = $1", key)
+	return err
+}
+
+func (m *postgresqlIdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	res, err := m.Conn.ExecContext(ctx, "DELETE FROM idempotency_request WHERE expires_at <= $1", now)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// translateError maps the postgresql constraint violations to the domain errors
+func translateError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return domain.ErrConflict
+	}
+	return err
+}
@@ -0,0 +1,42 @@
+package postgresql_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"go-postgres-clean-arch/domain"
+	"go-postgres-clean-arch/idempotency/repository/postgresql"
+	"go-postgres-clean-arch/idempotency/repository/repositorytest"
+	"go-postgres-clean-arch/migration"
+
+	_ "github.com/lib/pq"
+)
+
+// testDSN returns the database given in POSTGRES_TEST_DSN, the test is skipped when it is not set
+func testDSN(t *testing.T) string {
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_TEST_DSN is not set")
+	}
+	return dsn
+}
+
+func TestPostgresqlIdempotencyRepositoryContract(t *testing.T) {
+	db, err := sql.Open("postgres", testDSN(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err = migration.Up(context.Background(), db, "postgres"); err != nil {
+		t.Fatal(err)
+	}
+
+	repositorytest.RunIdempotencyContract(t, func(t *testing.T) domain.IdempotencyRepository {
+		if _, err := db.Exec(`TRUNCATE TABLE idempotency_request`); err != nil {
+			t.Fatal(err)
+		}
+		return postgresql.NewPostgresqlIdempotencyRepository(db)
+	})
+}
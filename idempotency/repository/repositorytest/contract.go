@@ -0,0 +1,128 @@
+// Package repositorytest holds the contract every domain.IdempotencyRepository implementation must satisfy.
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go-postgres-clean-arch/domain"
+)
+
+// RunIdempotencyContract runs the shared contract against the repositories built by newRepo,
+// newRepo is called once per subtest and must return a repository backed by an empty store.
+func RunIdempotencyContract(t *testing.T, newRepo func(t *testing.T) domain.IdempotencyRepository) {
+	base := time.Date(2023, 11, 30, 15, 20, 33, 682000, time.UTC)
+	ctx := context.Background()
+
+	reserve := func(t *testing.T, repo domain.IdempotencyRepository, key, fingerprint string, createdAt time.Time) error {
+		t.Helper()
+		return repo.Reserve(ctx, &domain.IdempotentRequest{
+			Key:         key,
+			Fingerprint: fingerprint,
+			CreatedAt:   createdAt,
+			ExpiresAt:   createdAt.Add(time.Hour),
+		})
+	}
+
+	t.Run("ReserveAndComplete", func(t *testing.T) {
+		repo := newRepo(t)
+		if err := reserve(t, repo, "k1", "f1", base); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := repo.GetByKey(ctx, "k1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Fingerprint != "f1" || got.Status != 0 || len(got.Body) != 0 || !got.CreatedAt.Equal(base) || !got.ExpiresAt.Equal(base.Add(time.Hour)) {
+			t.Fatalf("unexpected reserved request %+v", got)
+		}
+
+		completed := &domain.IdempotentRequest{
+			Key:    "k1",
+			Status: 201,
+			Header: map[string]string{"Content-Type": "application/json", "ETag": `"1"`},
+			Body:   []byte(`{"id":1}`),
+		}
+		if err = repo.Complete(ctx, completed); err != nil {
+			t.Fatal(err)
+		}
+		got, err = repo.GetByKey(ctx, "k1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Fingerprint != "f1" || got.Status != 201 || string(got.Body) != `{"id":1}` || !reflect.DeepEqual(got.Header, completed.Header) {
+			t.Fatalf("unexpected completed request %+v", got)
+		}
+	})
+
+	t.Run("ReserveConflictsUntilExpired", func(t *testing.T) {
+		repo := newRepo(t)
+		if err := reserve(t, repo, "k1", "f1", base); err != nil {
+			t.Fatal(err)
+		}
+		if err := reserve(t, repo, "k1", "f2", base.Add(time.Minute)); !errors.Is(err, domain.ErrConflict) {
+			t.Fatalf("expected ErrConflict for a used key, got %v", err)
+		}
+		if err := reserve(t, repo, "k2", "f1", base.Add(time.Minute)); err != nil {
+			t.Fatalf("another key conflicted: %v", err)
+		}
+
+		// once expired the key is used for another request
+		if err := reserve(t, repo, "k1", "f2", base.Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		got, err := repo.GetByKey(ctx, "k1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Fingerprint != "f2" || got.Status != 0 {
+			t.Fatalf("unexpected request %+v", got)
+		}
+	})
+
+	t.Run("DeleteReleasesKey", func(t *testing.T) {
+		repo := newRepo(t)
+		if err := reserve(t, repo, "k1", "f1", base); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.Delete(ctx, "k1"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.GetByKey(ctx, "k1"); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound, got %v", err)
+		}
+		if err := repo.Complete(ctx, &domain.IdempotentRequest{Key: "k1", Status: 201}); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound completing a released key, got %v", err)
+		}
+		if err := reserve(t, repo, "k1", "f2", base); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("DeleteExpired", func(t *testing.T) {
+		repo := newRepo(t)
+		for i, key := range []string{"k1", "k2", "k3"} {
+			if err := reserve(t, repo, key, "f", base.Add(time.Duration(i)*time.Hour)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		deleted, err := repo.DeleteExpired(ctx, base.Add(2*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if deleted != 2 {
+			t.Fatalf("deleted %d requests, want 2", deleted)
+		}
+		if _, err = repo.GetByKey(ctx, "k2"); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound for an expired request, got %v", err)
+		}
+		if _, err = repo.GetByKey(ctx, "k3"); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go-postgres-clean-arch/domain"
+	"go-postgres-clean-arch/idempotency/repository"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// timestampFormat is fixed width so the timestamps compare in chronological order as text
+const timestampFormat = "2006-01-02T15:04:05.000000Z"
+
+type sqliteIdempotencyRepository struct {
+	Conn *sql.DB
+}
+
+// NewSqliteIdempotencyRepository will create an object that represent the idempotency.Repository interface
+func NewSqliteIdempotencyRepository(conn *sql.DB) domain.IdempotencyRepository {
+	return &sqliteIdempotencyRepository{conn}
+}
+
+func (m *sqliteIdempotencyRepository) Reserve(ctx context.Context, r *domain.IdempotentRequest) (err error) {
+	// the key of an expired request can be used again
+	_, err = m.Conn.ExecContext(ctx, "DELETE FROM idempotency_request WHERE idempotency_key = ? AND expires_at <= ?", r.Key, formatTimestamp(r.CreatedAt))
+	if err != nil {
+		return
+	}
+
+	query := `INSERT INTO idempotency_request (idempotency_key, fingerprint, status, header, created_at, expires_at)
+				VALUES (?, ?, 0, '{}', ?, ?)`
+	_, err = m.Conn.ExecContext(ctx, query, r.Key, r.Fingerprint, formatTimestamp(r.CreatedAt), formatTimestamp(r.ExpiresAt))
+	return translateError(err)
+}
+
+func (m *sqliteIdempotencyRepository) GetByKey(ctx context.Context, key string) (res domain.IdempotentRequest, err error) {
+	query := `SELECT idempotency_key, fingerprint, status, header, body, created_at, expires_at
+				FROM idempotency_request
+				WHERE idempotency_key = ?`
+
+	var header, createdAt, expiresAt string
+	err = m.Conn.QueryRowContext(ctx, query, key).Scan(&res.Key, &res.Fingerprint, &res.Status, &header, &res.Body, &createdAt, &expiresAt)
+	if err == sql.ErrNoRows {
+		return res, domain.ErrNotFound
+	}
+	if err != nil {
+		return
+	}
+
+	if res.CreatedAt, err = parseTimestamp(createdAt); err != nil {
+		return
+	}
+	if res.ExpiresAt, err = parseTimestamp(expiresAt); err != nil {
+		return
+	}
+	res.Header, err = repository.DecodeHeader(header)
+	return
+}
+
+func (m *sqliteIdempotencyRepository) Complete(ctx context.Context, r *domain.IdempotentRequest) error {
+	header, err := repository.EncodeHeader(r.Header)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE idempotency_request SET status = ?, header = ?, body = ? WHERE idempotency_key = ?`
+	res, err := m.Conn.ExecContext(ctx, query, r.Status, header, r.Body, r.Key)
+	if err != nil {
+		return err
+	}
+	return repository.CheckAffected(res)
+}
+
+func (m *sqliteIdempotencyRepository) Delete(ctx context.Context, key string) error {
+	_, err := m.Conn.ExecContext(ctx, "DELETE FROM idempotency_request WHERE idempotency_key = ?", key)
+	return err
+}
+
+func (m *sqliteIdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	res, err := m.Conn.ExecContext(ctx, "DELETE FROM idempotency_request WHERE expires_at <= ?", formatTimestamp(now))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// translateError maps the sqlite constraint violations to the domain errors
+func translateError(err error) error {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && (sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY) {
+		return domain.ErrConflict
+	}
+	return err
+}
+
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format(timestampFormat)
+}
+
+func parseTimestamp(s string) (time.Time, error) {
+	return time.Parse(timestampFormat, s)
+}
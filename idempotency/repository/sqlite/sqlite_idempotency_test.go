@@ -0,0 +1,36 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"go-postgres-clean-arch/domain"
+	"go-postgres-clean-arch/idempotency/repository/repositorytest"
+	"go-postgres-clean-arch/idempotency/repository/sqlite"
+	"go-postgres-clean-arch/migration"
+
+	_ "modernc.org/sqlite"
+)
+
+// openTestDB opens a fresh in-memory database with the migrations applied
+func openTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection to :memory: is a different database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	if _, err = migration.Up(context.Background(), db, "sqlite"); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestSqliteIdempotencyRepositoryContract(t *testing.T) {
+	repositorytest.RunIdempotencyContract(t, func(t *testing.T) domain.IdempotencyRepository {
+		return sqlite.NewSqliteIdempotencyRepository(openTestDB(t))
+	})
+}
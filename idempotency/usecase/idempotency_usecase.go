@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	"context"
+	"go-postgres-clean-arch/domain"
+	"time"
+)
+
+// DefaultTTL is how long the responses are replayed when no TTL is configured
+const DefaultTTL = 24 * time.Hour
+
+type idempotencyUsecase struct {
+	idempotencyRepo domain.IdempotencyRepository
+	ttl             time.Duration
+	contextTimeout  time.Duration
+	// now is the clock of the expirations, replaced in the tests
+	now func() time.Time
+}
+
+// NewIdempotencyUsecase will create a new idempotencyUsecase object representation of domain.IdempotencyUsecase
+// interface, the responses are replayed for ttl
+func NewIdempotencyUsecase(r domain.IdempotencyRepository, ttl time.Duration, timeout time.Duration) domain.IdempotencyUsecase {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &idempotencyUsecase{
+		idempotencyRepo: r,
+		ttl:             ttl,
+		contextTimeout:  timeout,
+		now:             time.Now,
+	}
+}
+
+// Begin implements domain.IdempotencyUsecase.
+func (u *idempotencyUsecase) Begin(c context.Context, key, fingerprint string) (*domain.IdempotentRequest, error) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	now := u.now()
+	err := u.idempotencyRepo.Reserve(ctx, &domain.IdempotentRequest{
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(u.ttl),
+	})
+	if err != domain.ErrConflict {
+		return nil, err
+	}
+
+	stored, err := u.idempotencyRepo.GetByKey(ctx, key)
+	if err == domain.ErrNotFound {
+		// the first request was released meanwhile, it is still being retried
+		return nil, domain.ErrConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stored.Fingerprint != fingerprint {
+		return nil, domain.ErrIdempotencyKeyReused
+	}
+	if stored.Status == 0 {
+		return nil, domain.ErrConflict
+	}
+	return &stored, nil
+}
+
+// Complete implements domain.IdempotencyUsecase.
+func (u *idempotencyUsecase) Complete(c context.Context, r *domain.IdempotentRequest) error {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	return u.idempotencyRepo.Complete(ctx, r)
+}
+
+// Release implements domain.IdempotencyUsecase.
+func (u *idempotencyUsecase) Release(c context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	return u.idempotencyRepo.Delete(ctx, key)
+}
+
+// Purge implements domain.IdempotencyUsecase.
+func (u *idempotencyUsecase) Purge(c context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	return u.idempotencyRepo.DeleteExpired(ctx, u.now())
+}
@@ -0,0 +1,53 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-postgres-clean-arch/domain"
+	"go-postgres-clean-arch/idempotency/repository/memory"
+)
+
+func TestBegin(t *testing.T) {
+	now := time.Date(2026, time.October, 19, 10, 0, 0, 0, time.UTC)
+	u := NewIdempotencyUsecase(memory.NewMemoryIdempotencyRepository(), time.Hour, time.Second).(*idempotencyUsecase)
+	u.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if replay, err := u.Begin(ctx, "k1", "f1"); err != nil || replay != nil {
+		t.Fatalf("the first request got %v, %v", replay, err)
+	}
+	// the first request is still processed
+	if _, err := u.Begin(ctx, "k1", "f1"); err != domain.ErrConflict {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+
+	err := u.Complete(ctx, &domain.IdempotentRequest{Key: "k1", Status: 201, Body: []byte("created")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay, err := u.Begin(ctx, "k1", "f1")
+	if err != nil || replay == nil || replay.Status != 201 || string(replay.Body) != "created" {
+		t.Fatalf("the retry got %+v, %v", replay, err)
+	}
+	if _, err = u.Begin(ctx, "k1", "f2"); err != domain.ErrIdempotencyKeyReused {
+		t.Fatalf("expected ErrIdempotencyKeyReused, got %v", err)
+	}
+
+	// a released key is processed again
+	if err = u.Release(ctx, "k1"); err != nil {
+		t.Fatal(err)
+	}
+	if replay, err = u.Begin(ctx, "k1", "f2"); err != nil || replay != nil {
+		t.Fatalf("the released key got %v, %v", replay, err)
+	}
+
+	now = now.Add(time.Hour)
+	if deleted, err := u.Purge(ctx); err != nil || deleted != 1 {
+		t.Fatalf("purged %d, %v", deleted, err)
+	}
+	if replay, err = u.Begin(ctx, "k1", "f1"); err != nil || replay != nil {
+		t.Fatalf("the expired key got %v, %v", replay, err)
+	}
+}
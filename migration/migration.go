@@ -0,0 +1,161 @@
+// Package migration applies the versioned schema migrations embedded for every database dialect.
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+//go:embed postgres/*.sql mysql/*.sql sqlite/*.sql
+var files embed.FS
+
+// hooks run after the statements of a migration, in its transaction, to migrate the data the
+// statements can not
+var hooks = map[string]func(ctx context.Context, tx *sql.Tx, dialect string) error{
+	"0003_add_slugs":         backfillSlugs,
+	"0007_add_tag_name_keys": backfillNameKeys,
+}
+
+const createVersionTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version VARCHAR(255) NOT NULL PRIMARY KEY,
+	applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+// Up applies the pending migrations of the dialect in file name order, each file in its own transaction.
+// It returns the versions applied by this call.
+func Up(ctx context.Context, db *sql.DB, dialect string) (applied []string, err error) {
+	versions, err := Versions(dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = db.ExecContext(ctx, createVersionTable); err != nil {
+		return nil, err
+	}
+
+	done, err := appliedVersions(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	insert := "INSERT INTO schema_migrations (version) VALUES (?)"
+	if dialect == "postgres" {
+		insert = "INSERT INTO schema_migrations (version) VALUES ($1)"
+	}
+
+	for _, version := range versions {
+		if done[version] {
+			continue
+		}
+
+		body, err := fs.ReadFile(files, path.Join(dialect, version+".sql"))
+		if err != nil {
+			return applied, err
+		}
+
+		if err = apply(ctx, db, dialect, string(body), insert, version); err != nil {
+			return applied, fmt.Errorf("migration %s: %w", version, err)
+		}
+		applied = append(applied, version)
+	}
+
+	return applied, nil
+}
+
+// Versions lists the migrations embedded for the dialect in the order they are applied
+func Versions(dialect string) ([]string, error) {
+	names, err := fs.Glob(files, path.Join(dialect, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no migrations for dialect %q", dialect)
+	}
+
+	versions := make([]string, 0, len(names))
+	for _, name := range names {
+		versions = append(versions, strings.TrimSuffix(path.Base(name), ".sql"))
+	}
+	sort.Strings(versions)
+	return versions, nil
+}
+
+func appliedVersions(ctx context.Context, db *sql.DB) (map[string]bool, error) {
+	rows, err := db.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := map[string]bool{}
+	for rows.Next() {
+		var version string
+		if err = rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		done[version] = true
+	}
+	return done, rows.Err()
+}
+
+// apply runs a migration file and records its version, mysql commits DDL implicitly
+// so there a failing file may be left partially applied
+func apply(ctx context.Context, db *sql.DB, dialect, body, insert, version string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint
+
+	for _, statement := range statements(body) {
+		if _, err = tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	if hook, ok := hooks[version]; ok {
+		if err = hook(ctx, tx, dialect); err != nil {
+			return err
+		}
+	}
+	if _, err = tx.ExecContext(ctx, insert, version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// statements splits a migration file on the semicolons ending a line, the mysql driver
+// refuses to execute several statements at once
+func statements(body string) []string {
+	var res []string
+	var current strings.Builder
+	for _, line := range strings.SplitAfter(body, "\n") {
+		current.WriteString(line)
+		if !strings.HasSuffix(strings.TrimSpace(line), ";") {
+			continue
+		}
+		if statement := strings.TrimSpace(current.String()); !onlyComments(statement) {
+			res = append(res, statement)
+		}
+		current.Reset()
+	}
+	if statement := strings.TrimSpace(current.String()); !onlyComments(statement) {
+		res = append(res, statement)
+	}
+	return res
+}
+
+func onlyComments(statement string) bool {
+	for _, line := range strings.Split(statement, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"reflect"
+	"testing"
+
+	"go-postgres-clean-arch/helper/mysqltest"
+	"go-postgres-clean-arch/migration"
+
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+func TestUpSqlite(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	testUp(t, db, "sqlite")
+}
+
+func TestUpMysql(t *testing.T) {
+	testUp(t, mysqltest.Open(t), "mysql")
+}
+
+func TestUpPostgres(t *testing.T) {
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_TEST_DSN is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	// start from an empty schema so every version is applied
+	if _, err = db.Exec(`DROP TABLE IF EXISTS schema_migrations, tag_alias, article_slug_history, article, tag`); err != nil {
+		t.Fatal(err)
+	}
+
+	testUp(t, db, "postgres")
+}
+
+func testUp(t *testing.T, db *sql.DB, dialect string) {
+	ctx := context.Background()
+	versions, err := migration.Versions(dialect)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := migration.Up(ctx, db, dialect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(applied, versions) {
+		t.Fatalf("applied %v, want %v", applied, versions)
+	}
+
+	applied, err = migration.Up(ctx, db, dialect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 0 {
+		t.Fatalf("second run applied %v, want nothing", applied)
+	}
+
+	for _, table := range []string{"tag", "article"} {
+		var n int
+		if err = db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&n); err != nil {
+			t.Fatalf("table %s: %v", table, err)
+		}
+	}
+}
+
+func TestVersionsUnknownDialect(t *testing.T) {
+	if _, err := migration.Versions("oracle"); err == nil {
+		t.Fatal("expected an error for a dialect without migrations")
+	}
+}
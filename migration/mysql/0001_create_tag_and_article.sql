@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS tag (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  name VARCHAR(200) NOT NULL UNIQUE,
+  created_at DATETIME(6) NOT NULL,
+  updated_at DATETIME(6) NOT NULL,
+  INDEX tag_created_at_idx (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS article (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  title VARCHAR(255) NOT NULL UNIQUE,
+  content TEXT NOT NULL,
+  tag_id BIGINT NOT NULL,
+  updated_at DATETIME(6) NOT NULL,
+  created_at DATETIME(6) NOT NULL,
+  INDEX article_created_at_idx (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1 @@
+ALTER TABLE article ADD COLUMN content_format VARCHAR(16) NOT NULL DEFAULT 'markdown' AFTER content;
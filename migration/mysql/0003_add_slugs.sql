@@ -0,0 +1,11 @@
+-- the slugs of the existing rows are filled by the backfill hook of this migration
+ALTER TABLE tag ADD COLUMN slug VARCHAR(255) NULL, ADD UNIQUE INDEX tag_slug_idx (slug);
+
+ALTER TABLE article ADD COLUMN slug VARCHAR(255) NULL, ADD UNIQUE INDEX article_slug_idx (slug);
+
+CREATE TABLE IF NOT EXISTS article_slug_history (
+  slug VARCHAR(255) NOT NULL PRIMARY KEY,
+  article_id BIGINT NOT NULL,
+  created_at DATETIME(6) NOT NULL,
+  INDEX article_slug_history_article_idx (article_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,2 @@
+-- the articles of a tag are listed and counted by tag
+ALTER TABLE article ADD INDEX article_tag_id_idx (tag_id, created_at);
@@ -0,0 +1,6 @@
+-- the names of the merged tags resolve to the tag they were merged into
+CREATE TABLE IF NOT EXISTS tag_alias (
+  name VARCHAR(200) NOT NULL PRIMARY KEY,
+  tag_id BIGINT NOT NULL,
+  INDEX tag_alias_tag_idx (tag_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
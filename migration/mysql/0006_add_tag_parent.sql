@@ -0,0 +1,2 @@
+-- the root tags have no parent
+ALTER TABLE tag ADD COLUMN parent_id BIGINT NULL, ADD INDEX tag_parent_idx (parent_id);
@@ -0,0 +1,6 @@
+-- the optional metadata the front end renders the tags with, the hidden tags are left out of the listings
+ALTER TABLE tag
+  ADD COLUMN description VARCHAR(1000) NOT NULL DEFAULT '',
+  ADD COLUMN color VARCHAR(7) NOT NULL DEFAULT '',
+  ADD COLUMN icon VARCHAR(64) NOT NULL DEFAULT '',
+  ADD COLUMN hidden BOOLEAN NOT NULL DEFAULT FALSE;
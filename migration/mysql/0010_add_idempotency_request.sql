@@ -0,0 +1,11 @@
+-- the requests sent with an Idempotency-Key header and their responses, replayed until they expire
+CREATE TABLE IF NOT EXISTS idempotency_request (
+  idempotency_key VARCHAR(255) NOT NULL PRIMARY KEY,
+  fingerprint VARCHAR(64) NOT NULL,
+  status INT NOT NULL DEFAULT 0,
+  header TEXT NOT NULL,
+  body LONGBLOB,
+  created_at DATETIME(6) NOT NULL,
+  expires_at DATETIME(6) NOT NULL,
+  INDEX idempotency_request_expires_at_idx (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,113 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"go-postgres-clean-arch/helper/fold"
+)
+
+// backfillNameKeys gives a name key to the tags and aliases stored before the key columns existed.
+// The tags whose names share a key are merged into the oldest of them, like a merge through the API
+// but without keeping an alias since the name resolves to the kept tag anyway, and the aliases whose
+// key is taken are dropped.
+func backfillNameKeys(ctx context.Context, tx *sql.Tx, dialect string) error {
+	bind := func(query string) string {
+		if dialect != "postgres" {
+			return query
+		}
+		for n := 1; strings.Contains(query, "?"); n++ {
+			query = strings.Replace(query, "?", fmt.Sprintf("$%d", n), 1)
+		}
+		return query
+	}
+
+	type tagRow struct {
+		id       int64
+		parentID sql.NullInt64
+		key      string
+	}
+	var tags []tagRow
+	// every row is read before the updates, the mysql driver can not interleave them on a transaction
+	rows, err := tx.QueryContext(ctx, "SELECT id, name, parent_id FROM tag ORDER BY id")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var r tagRow
+		var name string
+		if err = rows.Scan(&r.id, &name, &r.parentID); err != nil {
+			rows.Close()
+			return err
+		}
+		r.key = fold.Key(name)
+		tags = append(tags, r)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	taken := map[string]bool{}
+	kept := map[string]int64{}
+	for _, r := range tags {
+		into, ok := kept[r.key]
+		if !ok {
+			kept[r.key] = r.id
+			taken[r.key] = true
+			continue
+		}
+		for _, query := range []struct {
+			statement string
+			args      []interface{}
+		}{
+			{"UPDATE article SET tag_id = ? WHERE tag_id = ?", []interface{}{into, r.id}},
+			{"UPDATE tag_alias SET tag_id = ? WHERE tag_id = ?", []interface{}{into, r.id}},
+			// the children move up to the parent of the merged tag, as when it is deleted
+			{"UPDATE tag SET parent_id = ? WHERE parent_id = ?", []interface{}{r.parentID, r.id}},
+			{"DELETE FROM tag WHERE id = ?", []interface{}{r.id}},
+		} {
+			if _, err = tx.ExecContext(ctx, bind(query.statement), query.args...); err != nil {
+				return err
+			}
+		}
+	}
+	for key, id := range kept {
+		if _, err = tx.ExecContext(ctx, bind("UPDATE tag SET name_key = ? WHERE id = ?"), key, id); err != nil {
+			return err
+		}
+	}
+
+	var aliases []string
+	rows, err = tx.QueryContext(ctx, "SELECT name FROM tag_alias ORDER BY name")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		aliases = append(aliases, name)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, name := range aliases {
+		key := fold.Key(name)
+		statement, args := "UPDATE tag_alias SET name_key = ? WHERE name = ?", []interface{}{key, name}
+		if taken[key] {
+			statement, args = "DELETE FROM tag_alias WHERE name = ?", []interface{}{name}
+		}
+		taken[key] = true
+		if _, err = tx.ExecContext(ctx, bind(statement), args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
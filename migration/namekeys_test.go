@@ -0,0 +1,69 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestBackfillNameKeys(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	if _, err = Up(ctx, db, "sqlite"); err != nil {
+		t.Fatal(err)
+	}
+
+	// rows stored before the key columns existed, Golang and golang are the same tag
+	for _, statement := range []string{
+		`INSERT INTO tag (name, slug, created_at, updated_at) VALUES ('Golang', 'golang', '', ''), ('Café', 'cafe', '', '')`,
+		`INSERT INTO tag (name, slug, parent_id, created_at, updated_at) VALUES ('golang', 'golang-2', 2, '', ''), ('generics', 'generics', 3, '', '')`,
+		`INSERT INTO article (title, content, tag_id, created_at, updated_at) VALUES ('a', 'x', 1, '', ''), ('b', 'x', 3, '', '')`,
+		`INSERT INTO tag_alias (name, tag_id) VALUES ('go', 3), ('GOLANG', 2), ('Cafe', 1)`,
+	} {
+		if _, err = db.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = backfillNameKeys(ctx, tx, "sqlite"); err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	for query, want := range map[string][]string{
+		"SELECT id || ' ' || name_key || ' ' || COALESCE(parent_id, '') FROM tag ORDER BY id": {"1 golang ", "2 cafe ", "4 generics 2"},
+		"SELECT name || ' ' || name_key || ' ' || tag_id FROM tag_alias ORDER BY name":        {"go go 1"},
+		"SELECT title || ' ' || tag_id FROM article ORDER BY id":                              {"a 1", "b 1"},
+	} {
+		rows, err := db.Query(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for rows.Next() {
+			var s string
+			if err = rows.Scan(&s); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, s)
+		}
+		rows.Close()
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: got %v, want %v", query, got, want)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS tag (
+  id BIGSERIAL PRIMARY KEY,
+  name VARCHAR(200) NOT NULL UNIQUE,
+  created_at TIMESTAMPTZ NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS tag_created_at_idx ON tag (created_at);
+
+CREATE TABLE IF NOT EXISTS article (
+  id BIGSERIAL PRIMARY KEY,
+  title VARCHAR(255) NOT NULL UNIQUE,
+  content TEXT NOT NULL,
+  tag_id BIGINT NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS article_created_at_idx ON article (created_at);
@@ -0,0 +1,2 @@
+-- the articles of a tag are listed and counted by tag
+CREATE INDEX IF NOT EXISTS article_tag_id_idx ON article (tag_id, created_at);
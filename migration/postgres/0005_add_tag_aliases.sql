@@ -0,0 +1,7 @@
+-- the names of the merged tags resolve to the tag they were merged into
+CREATE TABLE IF NOT EXISTS tag_alias (
+  name VARCHAR(200) PRIMARY KEY,
+  tag_id BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS tag_alias_tag_idx ON tag_alias (tag_id);
@@ -0,0 +1,3 @@
+-- the root tags have no parent
+ALTER TABLE tag ADD COLUMN IF NOT EXISTS parent_id BIGINT;
+CREATE INDEX IF NOT EXISTS tag_parent_idx ON tag (parent_id);
@@ -0,0 +1,9 @@
+-- the names are unique by their key, the name folded to lower case without accents, the hook of
+-- this migration fills the keys and merges the existing tags whose names share a key
+ALTER TABLE tag ADD COLUMN IF NOT EXISTS name_key VARCHAR(200);
+CREATE UNIQUE INDEX IF NOT EXISTS tag_name_key_idx ON tag (name_key);
+CREATE INDEX IF NOT EXISTS tag_name_key_prefix_idx ON tag (name_key varchar_pattern_ops);
+
+ALTER TABLE tag_alias ADD COLUMN IF NOT EXISTS name_key VARCHAR(200);
+CREATE UNIQUE INDEX IF NOT EXISTS tag_alias_name_key_idx ON tag_alias (name_key);
+CREATE INDEX IF NOT EXISTS tag_alias_name_key_prefix_idx ON tag_alias (name_key varchar_pattern_ops);
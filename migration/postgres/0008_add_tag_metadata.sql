@@ -0,0 +1,5 @@
+-- the optional metadata the front end renders the tags with, the hidden tags are left out of the listings
+ALTER TABLE tag ADD COLUMN IF NOT EXISTS description VARCHAR(1000) NOT NULL DEFAULT '';
+ALTER TABLE tag ADD COLUMN IF NOT EXISTS color VARCHAR(7) NOT NULL DEFAULT '';
+ALTER TABLE tag ADD COLUMN IF NOT EXISTS icon VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE tag ADD COLUMN IF NOT EXISTS hidden BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,2 @@
+-- the version counts the updates of an article, a change applying to a previous version is refused
+ALTER TABLE article ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
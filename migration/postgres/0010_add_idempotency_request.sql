@@ -0,0 +1,12 @@
+-- the requests sent with an Idempotency-Key header and their responses, replayed until they expire
+CREATE TABLE IF NOT EXISTS idempotency_request (
+  idempotency_key VARCHAR(255) PRIMARY KEY,
+  fingerprint VARCHAR(64) NOT NULL,
+  status INTEGER NOT NULL DEFAULT 0,
+  header TEXT NOT NULL DEFAULT '{}',
+  body BYTEA,
+  created_at TIMESTAMPTZ NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_request_expires_at_idx ON idempotency_request (expires_at);
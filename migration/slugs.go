@@ -0,0 +1,67 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"go-postgres-clean-arch/helper/slug"
+)
+
+// backfillSlugs gives a slug to the tags and articles stored before the slug columns existed
+func backfillSlugs(ctx context.Context, tx *sql.Tx, dialect string) error {
+	if err := backfillTable(ctx, tx, dialect, "tag", "name", "tag"); err != nil {
+		return err
+	}
+	return backfillTable(ctx, tx, dialect, "article", "title", "article")
+}
+
+func backfillTable(ctx context.Context, tx *sql.Tx, dialect, table, column, fallback string) error {
+	taken := map[string]bool{}
+	type row struct {
+		id   int64
+		text string
+	}
+	var missing []row
+
+	// every row is read before the updates, the mysql driver can not interleave them on a transaction
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT id, %s, slug FROM %s ORDER BY id", column, table))
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var r row
+		var current sql.NullString
+		if err = rows.Scan(&r.id, &r.text, &current); err != nil {
+			rows.Close()
+			return err
+		}
+		if current.Valid && current.String != "" {
+			taken[current.String] = true
+		} else {
+			missing = append(missing, r)
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	update := fmt.Sprintf("UPDATE %s SET slug = ? WHERE id = ?", table)
+	if dialect == "postgres" {
+		update = strings.Replace(strings.Replace(update, "?", "$1", 1), "?", "$2", 1)
+	}
+	for _, r := range missing {
+		base := slug.Make(r.text)
+		if base == "" {
+			base = fallback
+		}
+		s, _ := slug.Unique(base, func(s string) (bool, error) { return taken[s], nil })
+		taken[s] = true
+		if _, err = tx.ExecContext(ctx, update, s, r.id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
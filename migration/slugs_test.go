@@ -0,0 +1,68 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestBackfillSlugs(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	if _, err = Up(ctx, db, "sqlite"); err != nil {
+		t.Fatal(err)
+	}
+
+	// rows stored before the slug columns existed
+	for _, statement := range []string{
+		`INSERT INTO tag (name, slug, created_at, updated_at) VALUES ('Café', 'cafe', '', '')`,
+		`INSERT INTO tag (name, created_at, updated_at) VALUES ('cafe!', '', ''), ('日本', '', '')`,
+		`INSERT INTO article (title, content, tag_id, created_at, updated_at) VALUES ('Hello World', 'x', 1, '', ''), ('hello, world', 'x', 1, '', '')`,
+	} {
+		if _, err = db.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = backfillSlugs(ctx, tx, "sqlite"); err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	for table, want := range map[string][]string{"tag": {"cafe", "cafe-2", "tag"}, "article": {"hello-world", "hello-world-2"}} {
+		rows, err := db.Query("SELECT slug FROM " + table + " ORDER BY id")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for rows.Next() {
+			var s string
+			if err = rows.Scan(&s); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, s)
+		}
+		rows.Close()
+		if len(got) != len(want) {
+			t.Fatalf("%s: got slugs %v, want %v", table, got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("%s: got slugs %v, want %v", table, got, want)
+			}
+		}
+	}
+}
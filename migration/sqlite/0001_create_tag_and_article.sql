@@ -0,0 +1,20 @@
+-- timestamps are stored as fixed width UTC text so they compare in chronological order
+CREATE TABLE IF NOT EXISTS tag (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL UNIQUE,
+  created_at TEXT NOT NULL,
+  updated_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS tag_created_at_idx ON tag (created_at);
+
+CREATE TABLE IF NOT EXISTS article (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  title TEXT NOT NULL UNIQUE,
+  content TEXT NOT NULL,
+  tag_id INTEGER NOT NULL,
+  updated_at TEXT NOT NULL,
+  created_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS article_created_at_idx ON article (created_at);
@@ -0,0 +1 @@
+ALTER TABLE article ADD COLUMN content_format TEXT NOT NULL DEFAULT 'markdown';
@@ -0,0 +1,14 @@
+-- the slugs of the existing rows are filled by the backfill hook of this migration
+ALTER TABLE tag ADD COLUMN slug TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS tag_slug_idx ON tag (slug);
+
+ALTER TABLE article ADD COLUMN slug TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS article_slug_idx ON article (slug);
+
+CREATE TABLE IF NOT EXISTS article_slug_history (
+  slug TEXT PRIMARY KEY,
+  article_id INTEGER NOT NULL,
+  created_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS article_slug_history_article_idx ON article_slug_history (article_id);
@@ -0,0 +1,7 @@
+-- the names of the merged tags resolve to the tag they were merged into
+CREATE TABLE IF NOT EXISTS tag_alias (
+  name TEXT PRIMARY KEY,
+  tag_id INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS tag_alias_tag_idx ON tag_alias (tag_id);
@@ -0,0 +1,3 @@
+-- the root tags have no parent
+ALTER TABLE tag ADD COLUMN parent_id INTEGER;
+CREATE INDEX IF NOT EXISTS tag_parent_idx ON tag (parent_id);
@@ -0,0 +1,7 @@
+-- the names are unique by their key, the name folded to lower case without accents, the hook of
+-- this migration fills the keys and merges the existing tags whose names share a key
+ALTER TABLE tag ADD COLUMN name_key TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS tag_name_key_idx ON tag (name_key);
+
+ALTER TABLE tag_alias ADD COLUMN name_key TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS tag_alias_name_key_idx ON tag_alias (name_key);
@@ -0,0 +1,5 @@
+-- the optional metadata the front end renders the tags with, the hidden tags are left out of the listings
+ALTER TABLE tag ADD COLUMN description TEXT NOT NULL DEFAULT '';
+ALTER TABLE tag ADD COLUMN color TEXT NOT NULL DEFAULT '';
+ALTER TABLE tag ADD COLUMN icon TEXT NOT NULL DEFAULT '';
+ALTER TABLE tag ADD COLUMN hidden INTEGER NOT NULL DEFAULT 0;
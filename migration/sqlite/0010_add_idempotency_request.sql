@@ -0,0 +1,12 @@
+-- the requests sent with an Idempotency-Key header and their responses, replayed until they expire
+CREATE TABLE IF NOT EXISTS idempotency_request (
+  idempotency_key TEXT PRIMARY KEY,
+  fingerprint TEXT NOT NULL,
+  status INTEGER NOT NULL DEFAULT 0,
+  header TEXT NOT NULL DEFAULT '{}',
+  body BLOB,
+  created_at TEXT NOT NULL,
+  expires_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_request_expires_at_idx ON idempotency_request (expires_at);
@@ -0,0 +1,36 @@
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/labstack/echo"
+)
+
+//go:embed swagger-ui.html
+var swaggerUI string
+
+// Handler represent the httphandler for the API documentation
+type Handler struct {
+	Document *Document
+}
+
+// NewOpenAPIHandler will initialize the /openapi.json and /docs endpoints
+func NewOpenAPIHandler(e *echo.Echo, doc *Document) {
+	handler := &Handler{
+		Document: doc,
+	}
+
+	e.GET("/openapi.json", handler.Spec)
+	e.GET("/docs", handler.SwaggerUI)
+}
+
+// Spec will serve the OpenAPI document
+func (h *Handler) Spec(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.Document)
+}
+
+// SwaggerUI will serve the Swagger UI page pointing at the OpenAPI document
+func (h *Handler) SwaggerUI(c echo.Context) error {
+	return c.HTML(http.StatusOK, swaggerUI)
+}
@@ -0,0 +1,273 @@
+package openapi
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo"
+)
+
+const (
+	version         = "3.0.3"
+	jsonContentType = "application/json"
+)
+
+// Route describes a single route registered on echo, it is the source the specification is generated from
+type Route struct {
+	Method      string
+	Path        string // echo style path, e.g. /api/articles/:articleId
+	OperationID string
+	Summary     string
+	Tag         string
+	Query       []Param
+	Headers     []Param
+	Body        interface{} // zero value of the request body type
+	BodyType    string      // content type of the request body, default to application/json
+	Replies     []Reply
+	Deprecated  bool
+}
+
+// Param describes a query, header or path parameter of a route
+type Param struct {
+	Name        string
+	Description string
+	Type        interface{} // zero value of the parameter type, default to string
+	Required    bool
+	Enum        []interface{}
+}
+
+// Reply describes one of the possible responses of a route
+type Reply struct {
+	Status      int
+	Description string
+	Body        interface{} // zero value of the response body type, nil for an empty body
+	ContentType string      // default to application/json
+	Headers     []Param
+}
+
+// Document is representing the OpenAPI 3 document
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Info is representing the metadata of the API
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// PathItem holds the operations of a single path keyed by the lowercase http method
+type PathItem map[string]*Operation
+
+// Operation is representing a single API operation on a path
+type Operation struct {
+	Tags        []string             `json:"tags,omitempty"`
+	Summary     string               `json:"summary,omitempty"`
+	OperationID string               `json:"operationId,omitempty"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+	Deprecated  bool                 `json:"deprecated,omitempty"`
+}
+
+// Parameter is representing a path, query or header parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody is representing the request body of an operation
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response is representing a single response of an operation
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// Header is representing a response header
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// MediaType holds the schema of a given content type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds the reusable schemas of the document
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// NewDocument will generate the OpenAPI document from the given routes
+func NewDocument(title, apiVersion string, routes ...Route) *Document {
+	doc := &Document{
+		OpenAPI:    version,
+		Info:       Info{Title: title, Version: apiVersion},
+		Paths:      map[string]PathItem{},
+		Components: Components{Schemas: map[string]*Schema{}},
+	}
+	gen := newGenerator(doc.Components.Schemas)
+
+	for _, r := range routes {
+		path := SpecPath(r.Path)
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = PathItem{}
+			doc.Paths[path] = item
+		}
+		item[strings.ToLower(r.Method)] = gen.operation(r)
+	}
+
+	return doc
+}
+
+// SpecPath converts an echo path (/api/tags/:tagId) into an OpenAPI path (/api/tags/{tagId})
+func SpecPath(echoPath string) string {
+	segments := strings.Split(echoPath, "/")
+	for i, s := range segments {
+		if strings.HasPrefix(s, ":") {
+			segments[i] = "{" + s[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// Drift compares the routes registered on echo with the documented routes.
+// It returns the registered routes missing from the documentation and the
+// documented routes that are not registered, both formatted as "METHOD path".
+func Drift(e *echo.Echo, routes []Route) (undocumented, stale []string) {
+	registered := map[string]bool{}
+	for _, r := range e.Routes() {
+		// echo registers a catch-all NotFound route for every group
+		if strings.Contains(r.Name, "(*Group).Use") {
+			continue
+		}
+		registered[r.Method+" "+r.Path] = true
+	}
+
+	documented := map[string]bool{}
+	for _, r := range routes {
+		key := strings.ToUpper(r.Method) + " " + r.Path
+		documented[key] = true
+		if !registered[key] {
+			stale = append(stale, key)
+		}
+	}
+
+	for key := range registered {
+		if !documented[key] {
+			undocumented = append(undocumented, key)
+		}
+	}
+
+	sort.Strings(undocumented)
+	sort.Strings(stale)
+	return
+}
+
+func (g *generator) operation(r Route) *Operation {
+	op := &Operation{
+		Summary:     r.Summary,
+		OperationID: r.OperationID,
+		Responses:   map[string]*Response{},
+		Deprecated:  r.Deprecated,
+	}
+	if r.Tag != "" {
+		op.Tags = []string{r.Tag}
+	}
+
+	for _, s := range strings.Split(r.Path, "/") {
+		if !strings.HasPrefix(s, ":") {
+			continue
+		}
+		name := s[1:]
+		schema := &Schema{Type: "string"}
+		if strings.HasSuffix(name, "Id") || strings.HasSuffix(name, "ID") {
+			schema = &Schema{Type: "integer", Format: "int64"}
+		}
+		op.Parameters = append(op.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: schema})
+	}
+	for _, p := range r.Query {
+		op.Parameters = append(op.Parameters, g.parameter(p, "query"))
+	}
+	for _, p := range r.Headers {
+		op.Parameters = append(op.Parameters, g.parameter(p, "header"))
+	}
+
+	if r.Body != nil {
+		contentType := r.BodyType
+		if contentType == "" {
+			contentType = jsonContentType
+		}
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{contentType: {Schema: g.schemaOf(r.Body)}},
+		}
+	}
+
+	for _, reply := range r.Replies {
+		description := reply.Description
+		if description == "" {
+			description = http.StatusText(reply.Status)
+		}
+		res := &Response{Description: description}
+		if reply.Body != nil {
+			contentType := reply.ContentType
+			if contentType == "" {
+				contentType = jsonContentType
+			}
+			res.Content = map[string]MediaType{contentType: {Schema: g.schemaOf(reply.Body)}}
+		}
+		for _, h := range reply.Headers {
+			if res.Headers == nil {
+				res.Headers = map[string]Header{}
+			}
+			res.Headers[h.Name] = Header{Description: h.Description, Schema: g.paramSchema(h)}
+		}
+		op.Responses[statusKey(reply.Status)] = res
+	}
+
+	return op
+}
+
+func (g *generator) parameter(p Param, in string) Parameter {
+	return Parameter{
+		Name:        p.Name,
+		In:          in,
+		Description: p.Description,
+		Required:    p.Required,
+		Schema:      g.paramSchema(p),
+	}
+}
+
+func (g *generator) paramSchema(p Param) *Schema {
+	schema := &Schema{Type: "string"}
+	if p.Type != nil {
+		schema = g.schemaOf(p.Type)
+	}
+	schema.Enum = p.Enum
+	return schema
+}
+
+func statusKey(status int) string {
+	if status == 0 {
+		return "default"
+	}
+	return strconv.Itoa(status)
+}
@@ -0,0 +1,281 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is representing the OpenAPI schema object
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinLength            *int64             `json:"minLength,omitempty"`
+	MaxLength            *int64             `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinItems             *int64             `json:"minItems,omitempty"`
+	MaxItems             *int64             `json:"maxItems,omitempty"`
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	rawJSONType   = reflect.TypeOf(json.RawMessage{})
+	componentPath = "#/components/schemas/"
+)
+
+// generator turns go types into schemas, registering every named struct as a component
+type generator struct {
+	components map[string]*Schema
+}
+
+func newGenerator(components map[string]*Schema) *generator {
+	return &generator{components: components}
+}
+
+func (g *generator) schemaOf(v interface{}) *Schema {
+	return g.schema(reflect.TypeOf(v))
+}
+
+func (g *generator) schema(t reflect.Type) *Schema {
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	if t == rawJSONType {
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		s := g.schema(t.Elem())
+		if s.Ref != "" {
+			return s
+		}
+		s.Nullable = true
+		return s
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		name := componentName(t.Name())
+		// register the name before walking the fields so recursive types terminate,
+		// types sharing a name across packages (e.g. ResponseError) share one component
+		if _, ok := g.components[name]; !ok {
+			g.components[name] = &Schema{}
+			*g.components[name] = *g.object(t)
+		}
+		return &Schema{Ref: componentPath + name}
+	default:
+		return &Schema{}
+	}
+}
+
+func (g *generator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	g.fields(t, s)
+	return s
+}
+
+func (g *generator) fields(t reflect.Type, s *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+
+		name, opts := parseTag(f.Tag.Get("json"))
+		if name == "-" && opts == "" {
+			continue
+		}
+
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.fields(ft, s)
+				continue
+			}
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		prop := g.schema(ft)
+		if applyValidation(prop, ft, f.Tag.Get("validate")) {
+			s.Required = append(s.Required, name)
+		}
+		if description := f.Tag.Get("description"); description != "" {
+			prop.Description = description
+		}
+		s.Properties[name] = prop
+	}
+}
+
+// applyValidation maps the validator tags onto the schema, it reports whether the field is required
+func applyValidation(s *Schema, t reflect.Type, tag string) (required bool) {
+	if tag == "" || tag == "-" {
+		return false
+	}
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	for _, rule := range strings.Split(tag, ",") {
+		key, value := rule, ""
+		if idx := strings.Index(rule, "="); idx >= 0 {
+			key, value = rule[:idx], rule[idx+1:]
+		}
+
+		switch key {
+		case "dive":
+			// the remaining rules apply to the elements
+			return required
+		case "required":
+			required = true
+		case "email":
+			s.Format = "email"
+		case "url", "uri":
+			s.Format = "uri"
+		case "hexcolor":
+			s.Pattern = "^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$"
+		case "oneof":
+			for _, v := range strings.Fields(value) {
+				s.Enum = append(s.Enum, v)
+			}
+		case "min", "max", "len", "gte", "lte":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			applyBound(s, t, key, n)
+		}
+	}
+
+	return required
+}
+
+func applyBound(s *Schema, t reflect.Type, key string, n float64) {
+	lower := key == "min" || key == "gte" || key == "len"
+	upper := key == "max" || key == "lte" || key == "len"
+
+	switch t.Kind() {
+	case reflect.String:
+		if lower {
+			s.MinLength = int64Ptr(int64(n))
+		}
+		if upper {
+			s.MaxLength = int64Ptr(int64(n))
+		}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if lower {
+			s.MinItems = int64Ptr(int64(n))
+		}
+		if upper {
+			s.MaxItems = int64Ptr(int64(n))
+		}
+	default:
+		if lower {
+			s.Minimum = float64Ptr(n)
+		}
+		if upper {
+			s.Maximum = float64Ptr(n)
+		}
+	}
+}
+
+func parseTag(tag string) (name, opts string) {
+	if idx := strings.Index(tag, ","); idx >= 0 {
+		return tag[:idx], tag[idx+1:]
+	}
+	return tag, ""
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
+func float64Ptr(f float64) *float64 {
+	return &f
+}
+
+// componentName turns the name of a go type into the name of its component, the type arguments of a
+// generic type are appended without their package, e.g. Envelope[[]pkg.Article] is EnvelopeArticleList
+func componentName(name string) string {
+	open := strings.IndexByte(name, '[')
+	if open < 0 {
+		return name
+	}
+
+	var b strings.Builder
+	b.WriteString(name[:open])
+	for _, arg := range typeArgs(name[open+1 : len(name)-1]) {
+		list := 0
+		for strings.HasPrefix(arg, "[]") {
+			arg, list = arg[2:], list+1
+		}
+		// the package path ends at the last dot before the type arguments of the argument
+		pkg := arg
+		if i := strings.IndexByte(arg, '['); i >= 0 {
+			pkg = arg[:i]
+		}
+		if dot := strings.LastIndexByte(pkg, '.'); dot >= 0 {
+			arg = arg[dot+1:]
+		}
+		b.WriteString(componentName(arg))
+		b.WriteString(strings.Repeat("List", list))
+	}
+	return b.String()
+}
+
+// typeArgs splits the type arguments of a generic type name, the commas of nested arguments are kept
+func typeArgs(args string) []string {
+	var res []string
+	depth, start := 0, 0
+	for i, r := range args {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				res = append(res, args[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(res, args[start:])
+}
@@ -0,0 +1,243 @@
+package http
+
+import (
+	"go-postgres-clean-arch/data/request"
+	"go-postgres-clean-arch/data/response"
+	"go-postgres-clean-arch/domain"
+	"go-postgres-clean-arch/helper"
+	"go-postgres-clean-arch/openapi"
+	"net/http"
+)
+
+// OpenAPIRoutes describes the routes registered by NewTagHandler
+func OpenAPIRoutes() []openapi.Route {
+	var routes []openapi.Route
+	for _, version := range helper.APIVersions {
+		for _, prefix := range version.Prefixes() {
+			routes = append(routes, apiRoutes(version, prefix)...)
+		}
+	}
+	return routes
+}
+
+// apiRoutes describes the routes of the version of the API mounted under prefix
+func apiRoutes(version helper.APIVersion, prefix string) []openapi.Route {
+	const tag = "tags"
+	// the routes of v2 answer every JSON body in the envelope
+	var tagBody, tagsBody, treeBody, aliasBody, aliasesBody, inUseBody, errorBody, messageBody interface{}
+	if version == helper.APIv1 {
+		tagBody, tagsBody, treeBody = response.TagResponse{}, []response.TagResponse{}, []response.TagNodeResponse{}
+		aliasBody, aliasesBody = response.TagAliasResponse{}, []response.TagAliasResponse{}
+		inUseBody, errorBody, messageBody = response.TagInUseResponse{}, ResponseError{}, ""
+	} else {
+		tagBody, tagsBody = helper.Envelope[response.TagResponse]{}, helper.Envelope[[]response.TagResponse]{}
+		treeBody = helper.Envelope[[]response.TagNodeResponse]{}
+		aliasBody, aliasesBody = helper.Envelope[response.TagAliasResponse]{}, helper.Envelope[[]response.TagAliasResponse]{}
+		inUseBody, errorBody, messageBody = helper.Envelope[response.TagInUseResponse]{}, helper.Envelope[ResponseError]{}, helper.Envelope[string]{}
+	}
+	errorReply := func(status int) openapi.Reply {
+		return openapi.Reply{Status: status, Body: errorBody}
+	}
+	invalidReply := openapi.Reply{Status: http.StatusBadRequest, Description: "Validation error message", Body: messageBody}
+	unprocessableReply := openapi.Reply{Status: http.StatusUnprocessableEntity, Description: "Malformed request body", Body: messageBody}
+	idempotencyKeyHeader := openapi.Param{Name: "Idempotency-Key", Description: "Key sent again with the retries of the request, a retry is answered the response of the first request"}
+	replayedHeader := openapi.Param{Name: "Idempotent-Replayed", Description: "Set to true on the response replayed for a retry"}
+	storeUnprocessableReply := openapi.Reply{Status: http.StatusUnprocessableEntity, Description: "Malformed request body, or an Idempotency-Key used for another request", Body: messageBody}
+	storeConflictReply := openapi.Reply{Status: http.StatusConflict, Description: "The item already exists, or the first request with the Idempotency-Key is still processed", Body: errorBody}
+	policies := make([]interface{}, 0, len(domain.TagDeletePolicies))
+	for _, policy := range domain.TagDeletePolicies {
+		policies = append(policies, string(policy))
+	}
+
+	routes := []openapi.Route{
+		{
+			Method:      http.MethodGet,
+			Path:        prefix + "/tags",
+			OperationID: version.OperationID(prefix, "fetchTags"),
+			Summary:     "List tags ordered by creation time or by number of articles",
+			Tag:         tag,
+			Query: []openapi.Param{
+				{Name: "num", Description: "Page size, default to 10", Type: int64(0)},
+				{Name: "cursor", Description: "Cursor returned by the previous page in X-Cursor, only valid for the same sort"},
+				{Name: "prev_cursor", Description: "Cursor of the page before, set by the next link of the envelope so the page links back to it"},
+				{Name: "sort", Description: "popular lists the tags with the most articles first", Enum: []interface{}{string(domain.TagSortPopular)}},
+			},
+			Replies: []openapi.Reply{
+				{
+					Status:  http.StatusOK,
+					Body:    tagsBody,
+					Headers: []openapi.Param{{Name: "X-Cursor", Description: "Cursor of the next page, empty on the last page"}},
+				},
+				errorReply(http.StatusBadRequest),
+				errorReply(http.StatusInternalServerError),
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        prefix + "/tags/:tagId",
+			OperationID: version.OperationID(prefix, "getTag"),
+			Summary:     "Get a tag by id",
+			Tag:         tag,
+			Replies: []openapi.Reply{
+				{Status: http.StatusOK, Body: tagBody},
+				errorReply(http.StatusNotFound),
+				errorReply(http.StatusInternalServerError),
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        prefix + "/tags/by-slug/:slug",
+			OperationID: version.OperationID(prefix, "getTagBySlug"),
+			Summary:     "Get a tag by slug",
+			Tag:         tag,
+			Replies: []openapi.Reply{
+				{Status: http.StatusOK, Body: tagBody},
+				errorReply(http.StatusNotFound),
+				errorReply(http.StatusInternalServerError),
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        prefix + "/tags/tree",
+			OperationID: version.OperationID(prefix, "fetchTagTree"),
+			Summary:     "Get the taxonomy tree of the tags without the hidden tags and their children, the children are ordered by name",
+			Tag:         tag,
+			Query: []openapi.Param{
+				{Name: "root_id", Description: "Only the tree below this tag, default to the trees of every root tag", Type: int64(0)},
+			},
+			Replies: []openapi.Reply{
+				{Status: http.StatusOK, Body: treeBody},
+				errorReply(http.StatusBadRequest),
+				errorReply(http.StatusNotFound),
+				errorReply(http.StatusInternalServerError),
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        prefix + "/tags/suggest",
+			OperationID: version.OperationID(prefix, "suggestTags"),
+			Summary:     "Complete a prefix to the visible tags whose name or alias starts with it, ignoring the case and the accents",
+			Tag:         tag,
+			Query: []openapi.Param{
+				{Name: "prefix", Description: "Start of the tag name", Required: true},
+				{Name: "num", Description: "Number of suggestions, default to 10", Type: int64(0)},
+			},
+			Replies: []openapi.Reply{
+				{Status: http.StatusOK, Description: "Exact matches first, then the most used tags", Body: tagsBody},
+				errorReply(http.StatusBadRequest),
+				errorReply(http.StatusInternalServerError),
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        prefix + "/tags",
+			OperationID: version.OperationID(prefix, "storeTag"),
+			Summary:     "Create a tag",
+			Tag:         tag,
+			Headers:     []openapi.Param{idempotencyKeyHeader},
+			Body:        request.CreateTagsRequest{},
+			Replies: []openapi.Reply{
+				{Status: http.StatusCreated, Body: tagBody, Headers: []openapi.Param{replayedHeader}},
+				invalidReply,
+				storeConflictReply,
+				storeUnprocessableReply,
+				errorReply(http.StatusInternalServerError),
+			},
+		},
+		{
+			Method:      http.MethodPatch,
+			Path:        prefix + "/tags/:tagId",
+			OperationID: version.OperationID(prefix, "updateTag"),
+			Summary:     "Rename a tag, change its metadata or move it in the taxonomy tree, the fields missing from the body are kept",
+			Tag:         tag,
+			Body:        request.UpdateTagsRequest{},
+			Replies: []openapi.Reply{
+				{Status: http.StatusOK, Body: tagBody},
+				invalidReply,
+				errorReply(http.StatusNotFound),
+				errorReply(http.StatusConflict),
+				unprocessableReply,
+				errorReply(http.StatusInternalServerError),
+			},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        prefix + "/tags/:tagId",
+			OperationID: version.OperationID(prefix, "deleteTag"),
+			Summary:     "Delete a tag, by default only while no article references it",
+			Tag:         tag,
+			Query: []openapi.Param{
+				{Name: "policy", Description: "What happens to the articles of the tag, default to restrict", Enum: policies},
+				{Name: "reassign_to", Description: "Tag the articles move to with the reassign policy", Type: int64(0)},
+			},
+			Replies: []openapi.Reply{
+				{Status: http.StatusNoContent},
+				errorReply(http.StatusBadRequest),
+				errorReply(http.StatusNotFound),
+				{Status: http.StatusConflict, Description: "Articles still reference the tag", Body: inUseBody},
+				errorReply(http.StatusInternalServerError),
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        prefix + "/tags/:tagId/merge",
+			OperationID: version.OperationID(prefix, "mergeTag"),
+			Summary:     "Fold a tag into another, its articles move and its name becomes an alias of the other tag",
+			Tag:         tag,
+			Body:        request.MergeTagsRequest{},
+			Replies: []openapi.Reply{
+				{Status: http.StatusOK, Body: tagBody},
+				invalidReply,
+				errorReply(http.StatusNotFound),
+				unprocessableReply,
+				errorReply(http.StatusInternalServerError),
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        prefix + "/tags/:tagId/aliases",
+			OperationID: version.OperationID(prefix, "fetchTagAliases"),
+			Summary:     "List the other names resolving to a tag",
+			Tag:         tag,
+			Replies: []openapi.Reply{
+				{Status: http.StatusOK, Body: aliasesBody},
+				errorReply(http.StatusNotFound),
+				errorReply(http.StatusInternalServerError),
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        prefix + "/tags/:tagId/aliases",
+			OperationID: version.OperationID(prefix, "storeTagAlias"),
+			Summary:     "Add another name resolving to a tag",
+			Tag:         tag,
+			Body:        request.CreateTagAliasRequest{},
+			Replies: []openapi.Reply{
+				{Status: http.StatusCreated, Body: aliasBody},
+				invalidReply,
+				errorReply(http.StatusNotFound),
+				{Status: http.StatusConflict, Description: "The name or an alias of a tag already matches the alias", Body: errorBody},
+				unprocessableReply,
+				errorReply(http.StatusInternalServerError),
+			},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        prefix + "/tags/:tagId/aliases/:alias",
+			OperationID: version.OperationID(prefix, "deleteTagAlias"),
+			Summary:     "Remove an alias from a tag",
+			Tag:         tag,
+			Replies: []openapi.Reply{
+				{Status: http.StatusNoContent},
+				errorReply(http.StatusNotFound),
+				errorReply(http.StatusInternalServerError),
+			},
+		},
+	}
+
+	// v1 is frozen, its routes announce their sunset
+	for i := range routes {
+		routes[i].Deprecated = version == helper.APIv1
+	}
+	return routes
+}
@@ -1,8 +1,12 @@
 package http
 
 import (
+	"errors"
 	"fmt"
+	"go-postgres-clean-arch/data/request"
+	"go-postgres-clean-arch/data/response"
 	"go-postgres-clean-arch/domain"
+	"go-postgres-clean-arch/helper"
 	"net/http"
 	"strconv"
 
@@ -11,47 +15,74 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-type ResponseError struct {
-	Message string `json:"message"`
-}
-
-type TagResponse struct {
-	Name string `json:"name"`
-}
+// ResponseError is the body of a failed request
+type ResponseError = response.ErrorResponse
 
 type TagHandler struct {
 	TUsecase domain.TagUseCase
 }
 
-// NewTagHandler will initialize the tags/ resources endpoint
-func NewTagHandler(e *echo.Echo, tu domain.TagUseCase) {
+// NewTagHandler will initialize the tags/ resources endpoint of every API version, the store
+// middlewares only wrap the creation of the tags, e.g. to make it idempotent
+func NewTagHandler(e *echo.Echo, tu domain.TagUseCase, store ...echo.MiddlewareFunc) {
 	handler := &TagHandler{
 		TUsecase: tu,
 	}
 
-	baseRouter := e.Group("/api")
-	tagsRouter := baseRouter.Group("/tags")
-	tagsRouter.GET("", handler.FetchTag)
-	tagsRouter.GET("/:tagId", handler.GetByID)
-	tagsRouter.POST("", handler.Store)
-	tagsRouter.PATCH("/:tagId", handler.Update)
-	tagsRouter.DELETE("/:tagId", handler.Delete)
+	// the tags answer the same body in every version, only the middlewares differ
+	for _, version := range helper.APIVersions {
+		for _, prefix := range version.Prefixes() {
+			baseRouter := e.Group(prefix, version.Middleware()...)
+			tagsRouter := baseRouter.Group("/tags")
+			tagsRouter.GET("", handler.FetchTag)
+			tagsRouter.GET("/:tagId", handler.GetByID)
+			tagsRouter.GET("/by-slug/:slug", handler.GetBySlug)
+			tagsRouter.GET("/tree", handler.FetchTree)
+			tagsRouter.GET("/suggest", handler.Suggest)
+			tagsRouter.POST("", handler.Store, store...)
+			tagsRouter.PATCH("/:tagId", handler.Update)
+			tagsRouter.DELETE("/:tagId", handler.Delete)
+			tagsRouter.POST("/:tagId/merge", handler.Merge)
+			tagsRouter.GET("/:tagId/aliases", handler.FetchAliases)
+			tagsRouter.POST("/:tagId/aliases", handler.StoreAlias)
+			tagsRouter.DELETE("/:tagId/aliases/:alias", handler.DeleteAlias)
+		}
+	}
 }
 
-// FetchTag will fetch the tag based on given params
+// FetchTag will fetch the tag based on given params, the hidden tags are never listed. The API
+// has no access control, they are only listed to the operators by the tags command.
 func (t *TagHandler) FetchTag(c echo.Context) error {
 	numS := c.QueryParam("num")
 	num, _ := strconv.Atoi(numS)
 	cursor := c.QueryParam("cursor")
 	ctx := c.Request().Context()
 
-	listTag, nextCursor, err := t.TUsecase.Fetch(ctx, cursor, int64(num))
+	filter := domain.TagFilter{Sort: domain.TagSort(c.QueryParam("sort"))}
+	if filter.Sort != "" && filter.Sort != domain.TagSortPopular {
+		return c.JSON(http.StatusBadRequest, ResponseError{Message: fmt.Sprintf("sort must be %q", domain.TagSortPopular)})
+	}
+
+	if num == 0 {
+		num = domain.DefaultPageSize
+	}
+	listTag, nextCursor, err := t.TUsecase.FetchFiltered(ctx, filter, cursor, int64(num))
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	err = helper.SetPage(c, helper.Page{
+		Cursor:     cursor,
+		NextCursor: nextCursor,
+		Size:       int64(num),
+		Total:      func() (int64, error) { return t.TUsecase.Count(ctx, filter) },
+	})
 	if err != nil {
 		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
 	}
 
 	c.Response().Header().Set(`X-Cursor`, nextCursor)
-	return c.JSON(http.StatusOK, listTag)
+	return c.JSON(http.StatusOK, response.NewTagResponses(listTag))
 }
 
 // GetByID will get tag by given id
@@ -71,32 +102,74 @@ func (t *TagHandler) GetByID(c echo.Context) error {
 		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
 	}
 
-	return c.JSON(http.StatusOK, tag)
+	return c.JSON(http.StatusOK, response.NewTagResponse(tag))
+}
+
+// GetBySlug will get tag by given slug
+func (t *TagHandler) GetBySlug(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	tag, err := t.TUsecase.FetchBySlug(ctx, c.Param("slug"))
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, response.NewTagResponse(tag))
+}
+
+// FetchTree will get the taxonomy tree, below the tag of the root_id param or from every root tag
+func (t *TagHandler) FetchTree(c echo.Context) error {
+	var rootID int64
+	if v := c.QueryParam("root_id"); v != "" {
+		var err error
+		if rootID, err = strconv.ParseInt(v, 10, 64); err != nil || rootID <= 0 {
+			return c.JSON(http.StatusBadRequest, ResponseError{Message: "root_id must be the id of a tag"})
+		}
+	}
+
+	ctx := c.Request().Context()
+	tree, err := t.TUsecase.FetchTree(ctx, rootID)
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, response.NewTagNodeResponses(tree))
+}
+
+// Suggest will complete the prefix param to the names of the tags, a tag also matches by its aliases
+func (t *TagHandler) Suggest(c echo.Context) error {
+	num, _ := strconv.Atoi(c.QueryParam("num"))
+	ctx := c.Request().Context()
+
+	tags, err := t.TUsecase.Suggest(ctx, c.QueryParam("prefix"), int64(num))
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, response.NewTagResponses(tags))
 }
 
 // Store will store the tag by given request body
 func (t *TagHandler) Store(c echo.Context) (err error) {
-	var tag domain.Tag
-	err = c.Bind(&tag)
+	var req request.CreateTagsRequest
+	err = c.Bind(&req)
 	if err != nil {
 		return c.JSON(http.StatusUnprocessableEntity, err.Error())
 	}
 
 	var ok bool
-	if ok, err = isRequestValid(&tag); !ok {
+	if ok, err = isRequestValid(&req); !ok {
 		return c.JSON(http.StatusBadRequest, err.Error())
 	}
 
+	tag := req.Tag()
 	ctx := c.Request().Context()
 	err = t.TUsecase.Store(ctx, &tag)
 	if err != nil {
 		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
 	}
 
-	var tagResponse TagResponse
-	tagResponse.Name = tag.Name
-
-	return c.JSON(http.StatusCreated, tagResponse)
+	return c.JSON(http.StatusCreated, response.NewTagResponse(tag))
 }
 
 // Update will update the tag by request body based on param id
@@ -108,35 +181,40 @@ func (t *TagHandler) Update(c echo.Context) (err error) {
 
 	id := int64(idP)
 	ctx := c.Request().Context()
-	_, err = t.TUsecase.FetchByID(ctx, id)
+	selectedTag, err := t.TUsecase.FetchByID(ctx, id)
 	if err != nil {
 		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
 	}
 
-	var tag domain.Tag
-	err = c.Bind(&tag)
-	tag.ID = id
+	// the fields missing from the body keep their value, e.g. a body without parent_id keeps the
+	// tag in place while parent_id 0 moves it to the roots
+	req := request.NewUpdateTagsRequest(selectedTag)
+	err = c.Bind(&req)
 	if err != nil {
 		return c.JSON(http.StatusUnprocessableEntity, err.Error())
 	}
+	req.ID = id
 
 	var ok bool
-	if ok, err = isRequestValid(&tag); !ok {
+	if ok, err = isRequestValid(&req); !ok {
 		return c.JSON(http.StatusBadRequest, err.Error())
 	}
-
-	var tagResponse TagResponse
-	tagResponse.Name = tag.Name
-
+	tag := req.Tag()
 	err = t.TUsecase.Update(ctx, &tag)
 	if err != nil {
 		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
 	}
 
-	return c.JSON(http.StatusOK, tagResponse)
+	// the stored tag carries the timestamps and the article count the request does not
+	tag, err = t.TUsecase.FetchByID(ctx, id)
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, response.NewTagResponse(tag))
 }
 
-// Delete will delete tag by given param
+// Delete will delete tag by given param, the policy param decides what happens to the articles of the tag
 func (t *TagHandler) Delete(c echo.Context) error {
 	idP, err := strconv.Atoi(c.Param("tagId"))
 	if err != nil {
@@ -146,7 +224,19 @@ func (t *TagHandler) Delete(c echo.Context) error {
 	id := int64(idP)
 	ctx := c.Request().Context()
 
-	err = t.TUsecase.Delete(ctx, id)
+	policy := domain.TagDeletePolicy(c.QueryParam("policy"))
+	var reassignTo int64
+	if policy == domain.TagDeleteReassign {
+		if reassignTo, err = strconv.ParseInt(c.QueryParam("reassign_to"), 10, 64); err != nil || reassignTo <= 0 {
+			return c.JSON(http.StatusBadRequest, ResponseError{Message: "reassign_to must be the id of a tag"})
+		}
+	}
+
+	err = t.TUsecase.DeleteWithPolicy(ctx, id, policy, reassignTo)
+	var inUse *domain.TagInUseError
+	if errors.As(err, &inUse) {
+		return c.JSON(http.StatusConflict, response.NewTagInUseResponse(inUse))
+	}
 	if err != nil {
 		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
 	}
@@ -154,7 +244,86 @@ func (t *TagHandler) Delete(c echo.Context) error {
 	return c.NoContent(http.StatusNoContent)
 }
 
-func isRequestValid(m *domain.Tag) (bool, error) {
+// Merge will fold the tag into the tag of the request body, the name of the tag resolves to that tag afterwards
+func (t *TagHandler) Merge(c echo.Context) (err error) {
+	idP, err := strconv.Atoi(c.Param("tagId"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, domain.ErrNotFound.Error())
+	}
+
+	var req request.MergeTagsRequest
+	if err = c.Bind(&req); err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, err.Error())
+	}
+	if err = validator.New().Struct(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	ctx := c.Request().Context()
+	tag, err := t.TUsecase.Merge(ctx, int64(idP), req.Into)
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, response.NewTagResponse(tag))
+}
+
+// FetchAliases will list the aliases of the tag
+func (t *TagHandler) FetchAliases(c echo.Context) error {
+	idP, err := strconv.Atoi(c.Param("tagId"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, domain.ErrNotFound.Error())
+	}
+
+	ctx := c.Request().Context()
+	aliases, err := t.TUsecase.FetchAliases(ctx, int64(idP))
+	if err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, response.NewTagAliasResponses(aliases))
+}
+
+// StoreAlias will add the alias of the request body to the tag
+func (t *TagHandler) StoreAlias(c echo.Context) (err error) {
+	idP, err := strconv.Atoi(c.Param("tagId"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, domain.ErrNotFound.Error())
+	}
+
+	var req request.CreateTagAliasRequest
+	if err = c.Bind(&req); err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, err.Error())
+	}
+	if err = validator.New().Struct(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	alias := req.Alias(int64(idP))
+	ctx := c.Request().Context()
+	if err = t.TUsecase.StoreAlias(ctx, &alias); err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	return c.JSON(http.StatusCreated, response.NewTagAliasResponse(alias))
+}
+
+// DeleteAlias will remove the alias of the param from the tag
+func (t *TagHandler) DeleteAlias(c echo.Context) error {
+	idP, err := strconv.Atoi(c.Param("tagId"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, domain.ErrNotFound.Error())
+	}
+
+	ctx := c.Request().Context()
+	if err = t.TUsecase.DeleteAlias(ctx, int64(idP), c.Param("alias")); err != nil {
+		return c.JSON(getStatusCode(err), ResponseError{Message: err.Error()})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func isRequestValid(m interface{}) (bool, error) {
 	validate := validator.New()
 	err := validate.Struct(m)
 	if err != nil {
@@ -176,6 +345,8 @@ func getStatusCode(err error) int {
 		return http.StatusNotFound
 	case domain.ErrConflict:
 		return http.StatusConflict
+	case domain.ErrBadParamInput:
+		return http.StatusBadRequest
 	default:
 		return http.StatusInternalServerError
 	}
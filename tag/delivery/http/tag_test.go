@@ -1 +1,332 @@
-package http
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	articleMemory "go-postgres-clean-arch/article/repository/memory"
+	"go-postgres-clean-arch/data/response"
+	"go-postgres-clean-arch/domain"
+	"go-postgres-clean-arch/helper"
+	"go-postgres-clean-arch/openapi"
+	tagHttp "go-postgres-clean-arch/tag/delivery/http"
+	tagMemory "go-postgres-clean-arch/tag/repository/memory"
+	tagUcase "go-postgres-clean-arch/tag/usecase"
+
+	"github.com/labstack/echo"
+)
+
+func TestOpenAPIRoutesMatchHandler(t *testing.T) {
+	e := echo.New()
+	tagHttp.NewTagHandler(e, nil)
+
+	undocumented, stale := openapi.Drift(e, tagHttp.OpenAPIRoutes())
+	for _, r := range undocumented {
+		t.Errorf("route %s is registered but missing from OpenAPIRoutes", r)
+	}
+	for _, r := range stale {
+		t.Errorf("route %s is documented but not registered", r)
+	}
+}
+
+func newTagServer(t *testing.T) (*echo.Echo, domain.ArticleRepository) {
+	articles := articleMemory.NewMemoryArticleRepository()
+	tu := tagUcase.NewTagUsecase(tagMemory.NewMemoryTagRepositoryWithArticles(articles), time.Second, nil)
+	ctx := context.Background()
+	for _, name := range []string{"golang", "go", "empty"} {
+		if err := tu.Store(ctx, &domain.Tag{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, tagID := range []int64{1, 1, 2} {
+		a := &domain.CreateArticleInput{Title: fmt.Sprintf("article %d", i), Content: "c", TagID: tagID, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+		if err := articles.Store(ctx, a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	e := echo.New()
+	tagHttp.NewTagHandler(e, tu)
+	return e, articles
+}
+
+func TestDeletePolicies(t *testing.T) {
+	e, articles := newTagServer(t)
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve(http.MethodDelete, "/api/tags/1", "")
+	var inUse response.TagInUseResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &inUse); err != nil || rec.Code != http.StatusConflict {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+	if inUse.ArticleCount != 2 || len(inUse.Articles) != 2 {
+		t.Fatalf("unexpected conflict %+v", inUse)
+	}
+
+	for target, status := range map[string]int{
+		"/api/tags/1?policy=reassign":               http.StatusBadRequest,
+		"/api/tags/1?policy=reassign&reassign_to=9": http.StatusBadRequest,
+		"/api/tags/1?policy=orphan":                 http.StatusBadRequest,
+		"/api/tags/9?policy=cascade-detach":         http.StatusNotFound,
+		"/api/tags/3":                               http.StatusNoContent,
+	} {
+		if rec := serve(http.MethodDelete, target, ""); rec.Code != status {
+			t.Errorf("%s answered %d, want %d", target, rec.Code, status)
+		}
+	}
+
+	if rec = serve(http.MethodPost, "/api/tags/1/merge", `{"into": 2}`); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"article_count":3`) {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+	// the name of the merged tag is taken by its alias
+	if rec = serve(http.MethodPost, "/api/tags", `{"name": "golang"}`); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for the alias of a merged tag, got %d", rec.Code)
+	}
+
+	if rec = serve(http.MethodDelete, "/api/tags/2?policy=cascade-detach", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+	res, _, err := articles.Fetch(context.Background(), "", 10)
+	if err != nil || len(res) != 3 || res[0].Tag.ID != 0 {
+		t.Fatalf("Fetch returned %+v %v, want detached articles", res, err)
+	}
+}
+
+func TestTagTree(t *testing.T) {
+	e, _ := newTagServer(t)
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// golang > go > empty
+	if rec := serve(http.MethodPost, "/api/tags", `{"name": "orphan", "parent_id": 9}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown parent, got %d", rec.Code)
+	}
+	for _, move := range [][2]string{{"/api/tags/2", `{"name": "go", "parent_id": 1}`}, {"/api/tags/3", `{"name": "empty", "parent_id": 2}`}} {
+		if rec := serve(http.MethodPatch, move[0], move[1]); rec.Code != http.StatusOK {
+			t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+		}
+	}
+	// a body without parent_id keeps the parent
+	if rec := serve(http.MethodPatch, "/api/tags/3", `{"name": "still empty"}`); rec.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+
+	for _, body := range []string{`{"name": "golang", "parent_id": 3}`, `{"name": "golang", "parent_id": 1}`} {
+		if rec := serve(http.MethodPatch, "/api/tags/1", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s answered %d, want 400 for a cycle", body, rec.Code)
+		}
+	}
+
+	rec := serve(http.MethodGet, "/api/tags/tree", "")
+	var tree []domain.TagNode
+	if err := json.Unmarshal(rec.Body.Bytes(), &tree); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+	if len(tree) != 1 || tree[0].Name != "golang" || len(tree[0].Children) != 1 || len(tree[0].Children[0].Children) != 1 || tree[0].Children[0].Children[0].Name != "still empty" {
+		t.Fatalf("unexpected tree %+v", tree)
+	}
+
+	rec = serve(http.MethodGet, "/api/tags/tree?root_id=2", "")
+	if err := json.Unmarshal(rec.Body.Bytes(), &tree); err != nil || len(tree) != 1 || tree[0].Name != "go" {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+	for target, status := range map[string]int{"/api/tags/tree?root_id=9": http.StatusNotFound, "/api/tags/tree?root_id=x": http.StatusBadRequest} {
+		if rec := serve(http.MethodGet, target, ""); rec.Code != status {
+			t.Errorf("%s answered %d, want %d", target, rec.Code, status)
+		}
+	}
+}
+
+func TestTagSynonyms(t *testing.T) {
+	e, _ := newTagServer(t)
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// the names compare without case and accents, against the names and the aliases
+	for _, c := range []struct {
+		method, target, body string
+		status               int
+	}{
+		{http.MethodPost, "/api/tags", `{"name": "GoLang"}`, http.StatusConflict},
+		{http.MethodPost, "/api/tags/1/aliases", `{"name": "Gopher"}`, http.StatusCreated},
+		{http.MethodPost, "/api/tags/2/aliases", `{"name": "gophér"}`, http.StatusConflict},
+		{http.MethodPost, "/api/tags/2/aliases", `{"name": "GO"}`, http.StatusConflict},
+		{http.MethodPost, "/api/tags/2/aliases", `{"name": ""}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/tags/9/aliases", `{"name": "nine"}`, http.StatusNotFound},
+		{http.MethodPost, "/api/tags", `{"name": "GOPHER"}`, http.StatusConflict},
+		{http.MethodGet, "/api/tags/suggest", "", http.StatusBadRequest},
+	} {
+		if rec := serve(c.method, c.target, c.body); rec.Code != c.status {
+			t.Errorf("%s %s %s answered %d, want %d", c.method, c.target, c.body, rec.Code, c.status)
+		}
+	}
+
+	rec := serve(http.MethodGet, "/api/tags/suggest?prefix=GO", "")
+	var tags []domain.Tag
+	if err := json.Unmarshal(rec.Body.Bytes(), &tags); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+	if len(tags) != 2 || tags[0].Name != "go" || tags[1].Name != "golang" {
+		t.Fatalf("unexpected suggestions %+v", tags)
+	}
+
+	rec = serve(http.MethodGet, "/api/tags/1/aliases", "")
+	var aliases []domain.TagAlias
+	if err := json.Unmarshal(rec.Body.Bytes(), &aliases); err != nil || len(aliases) != 1 || aliases[0].Name != "Gopher" {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+	for _, status := range []int{http.StatusNoContent, http.StatusNotFound} {
+		if rec := serve(http.MethodDelete, "/api/tags/1/aliases/gopher", ""); rec.Code != status {
+			t.Fatalf("delete alias answered %d, want %d", rec.Code, status)
+		}
+	}
+}
+
+func TestTagMetadata(t *testing.T) {
+	e, _ := newTagServer(t)
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	names := func(target string) []string {
+		t.Helper()
+		rec := serve(http.MethodGet, target, "")
+		var tags []domain.Tag
+		if err := json.Unmarshal(rec.Body.Bytes(), &tags); err != nil || rec.Code != http.StatusOK {
+			t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+		}
+		var res []string
+		for _, tag := range tags {
+			res = append(res, tag.Name)
+		}
+		return res
+	}
+
+	for _, body := range []string{`{"name": "red", "color": "red"}`, `{"name": "red", "color": "#ff00001"}`, `{"name": "red", "icon": "` + strings.Repeat("i", 65) + `"}`} {
+		if rec := serve(http.MethodPost, "/api/tags", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s answered %d, want 400", body, rec.Code)
+		}
+	}
+	if rec := serve(http.MethodPost, "/api/tags", `{"name": "red", "color": "#F00", "description": "Alerts", "icon": "bell"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+
+	// a body with only hidden keeps the name and the other metadata
+	if rec := serve(http.MethodPatch, "/api/tags/4", `{"hidden": true}`); rec.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+	rec := serve(http.MethodGet, "/api/tags/4", "")
+	var tag domain.Tag
+	if err := json.Unmarshal(rec.Body.Bytes(), &tag); err != nil || tag.Name != "red" || tag.Color != "#F00" || tag.Description != "Alerts" || tag.Icon != "bell" || !tag.Hidden {
+		t.Fatalf("unexpected tag %d %s", rec.Code, rec.Body.String())
+	}
+
+	if got := names("/api/tags"); len(got) != 3 {
+		t.Errorf("listed %v, want the visible tags", got)
+	}
+	// the api has no access control, the hidden tags can't be asked for
+	if got := names("/api/tags?sort=popular&include_hidden=true"); len(got) != 3 {
+		t.Errorf("listed %v, want the visible tags", got)
+	}
+	if got := names("/api/tags/suggest?prefix=re"); len(got) != 0 {
+		t.Errorf("suggested %v, want no hidden tag", got)
+	}
+	for target, status := range map[string]int{"/api/tags/tree?root_id=4": http.StatusNotFound, "/api/tags/tree?root_id=1": http.StatusOK} {
+		if rec := serve(http.MethodGet, target, ""); rec.Code != status {
+			t.Errorf("%s answered %d, want %d", target, rec.Code, status)
+		}
+	}
+}
+
+func TestFetchTagEnvelope(t *testing.T) {
+	e, _ := newTagServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/tags?sort=popular&num=2", nil)
+	req.Header.Set(echo.HeaderAccept, `application/json; profile="envelope"`)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	var res struct {
+		helper.Response
+		Data []domain.Tag `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil || rec.Code != http.StatusOK || res.Meta == nil || res.Links == nil {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+	if len(res.Data) != 2 || res.Data[0].Name != "golang" || res.Meta.Total != 3 || res.Meta.PageSize != 2 {
+		t.Fatalf("unexpected page %+v %+v", res.Data, res.Meta)
+	}
+	if res.Links.Next != "/api/tags?cursor="+url.QueryEscape(res.Meta.NextCursor)+"&num=2&prev_cursor=&sort=popular" || res.Links.Prev != "" {
+		t.Fatalf("unexpected links %+v", res.Links)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, res.Links.Next, nil)
+	req.Header.Set(echo.HeaderAccept, `application/json; profile="envelope"`)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil || len(res.Data) != 1 || res.Links.Prev != "/api/tags?num=2&sort=popular" {
+		t.Fatalf("unexpected page %d %+v %+v", rec.Code, res.Data, res.Links)
+	}
+
+	// a single tag is enveloped without the meta and the links of a listing
+	req = httptest.NewRequest(http.MethodGet, "/api/tags/1", nil)
+	req.Header.Set(echo.HeaderAccept, "application/json; version=2")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Body.String(), `{"code":200,"status":"Ok","data":{"id":1,`) || strings.Contains(rec.Body.String(), `"meta"`) {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestTagAPIVersions(t *testing.T) {
+	e, _ := newTagServer(t)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/tags/1", nil))
+	var tag response.TagResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &tag); err != nil || rec.Code != http.StatusOK || tag.Name != "golang" {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Deprecation") == "" || rec.Header().Get("Sunset") == "" {
+		t.Fatalf("v1 does not announce its sunset %v", rec.Header())
+	}
+
+	// v2 answers the envelope without asking for it
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v2/tags?num=2", nil))
+	var res struct {
+		helper.Response
+		Data []response.TagResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil || rec.Code != http.StatusOK || len(res.Data) != 2 || res.Meta.Total != 3 {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Deprecation") != "" || res.Links.Next == "" {
+		t.Fatalf("unexpected response %v %+v", rec.Header(), res.Links)
+	}
+}
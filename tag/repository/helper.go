@@ -1,12 +1,15 @@
 package repository
 
 import (
+	"database/sql"
 	"encoding/base64"
+	"encoding/json"
+	"go-postgres-clean-arch/domain"
 	"time"
 )
 
 const (
-	timeFormat = "2006-01-02T15:04:05.999Z07:00" // reduce precision from RFC3339Nano as date format
+	timeFormat = "2006-01-02T15:04:05.999999Z07:00" // reduce precision from RFC3339Nano to the microseconds the database keeps
 )
 
 // DecodeCursor will decode cursor from user for mysql
@@ -30,3 +33,86 @@ func EncodeCursor(t time.Time) string {
 
 	return base64.StdEncoding.EncodeToString([]byte(timeString))
 }
+
+// NullString stores an empty string as NULL, e.g. a missing slug the unique index must not compare
+func NullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// PopularCursor is the position after the last tag of a page sorted by domain.TagSortPopular,
+// it holds the number of articles of the tag and its id to break the ties
+type PopularCursor struct {
+	Count int64 `json:"c"`
+	ID    int64 `json:"id"`
+}
+
+// EncodePopularCursor will encode the cursor after the tag for the user
+func EncodePopularCursor(t domain.Tag) string {
+	byt, _ := json.Marshal(PopularCursor{Count: t.ArticleCount, ID: t.ID})
+	return base64.StdEncoding.EncodeToString(byt)
+}
+
+// DecodePopularCursor will decode the cursor from the user
+func DecodePopularCursor(cursor string) (c PopularCursor, err error) {
+	byt, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil {
+		return c, domain.ErrBadParamInput
+	}
+	if err = json.Unmarshal(byt, &c); err != nil || c.ID == 0 {
+		return PopularCursor{}, domain.ErrBadParamInput
+	}
+	return c, nil
+}
+
+// InUseLimit is the number of referencing articles a restricted delete lists
+const InUseLimit = 20
+
+// InUseError scans the id, title and slug of the articles of rows into the conflict of a
+// restricted delete, count is the number of articles referencing the tag
+func InUseError(rows *sql.Rows, count int64) error {
+	defer rows.Close()
+
+	inUse := &domain.TagInUseError{ArticleCount: count, Articles: make([]domain.Article, 0)}
+	for rows.Next() {
+		a := domain.Article{}
+		slug := sql.NullString{}
+		if err := rows.Scan(&a.ID, &a.Title, &slug); err != nil {
+			return err
+		}
+		a.Slug = slug.String
+		inUse.Articles = append(inUse.Articles, a)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return inUse
+}
+
+// NullID stores the id 0 as NULL, e.g. the missing parent of a root tag
+func NullID(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id != 0}
+}
+
+// ScanAliases scans the name and tag id of the aliases of rows
+func ScanAliases(rows *sql.Rows) ([]domain.TagAlias, error) {
+	defer rows.Close()
+
+	res := make([]domain.TagAlias, 0)
+	for rows.Next() {
+		a := domain.TagAlias{}
+		if err := rows.Scan(&a.Name, &a.TagID); err != nil {
+			return nil, err
+		}
+		res = append(res, a)
+	}
+	return res, rows.Err()
+}
+
+// VisibleCondition is the SQL condition on the tag table of the tags a listing shows, the hidden
+// tags only with includeHidden
+func VisibleCondition(includeHidden bool) string {
+	if includeHidden {
+		return "1 = 1"
+	}
+	return "NOT hidden"
+}
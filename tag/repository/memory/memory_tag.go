@@ -0,0 +1,618 @@
+package memory
+
+import (
+	"context"
+	"go-postgres-clean-arch/domain"
+	"go-postgres-clean-arch/helper/fold"
+	"go-postgres-clean-arch/tag/repository"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryTagRepo struct {
+	mu     sync.RWMutex
+	lastID int64
+	tags   map[int64]domain.Tag
+	// aliases maps the name keys of the other names of the tags to the aliases, e.g. the names of
+	// the merged tags
+	aliases map[string]domain.TagAlias
+	// articles are counted for the article_count of the tags, the counts are zero without them
+	articles domain.ArticleRepository
+}
+
+// NewMemoryTagRepository will create a thread-safe in-memory object that represent the tag.Repository interface
+func NewMemoryTagRepository() domain.TagRepository {
+	return NewMemoryTagRepositoryWithArticles(nil)
+}
+
+// NewMemoryTagRepositoryWithArticles will create the in-memory tag.Repository counting the articles of
+// the tags in the articles repository, the memory has no article table to join
+func NewMemoryTagRepositoryWithArticles(articles domain.ArticleRepository) domain.TagRepository {
+	return &memoryTagRepo{
+		tags:     map[int64]domain.Tag{},
+		aliases:  map[string]domain.TagAlias{},
+		articles: articles,
+	}
+}
+
+// countsPageSize is the number of articles read per page when counting the articles of the tags
+const countsPageSize = 500
+
+// counts returns the number of articles of every tag
+func (m *memoryTagRepo) counts(ctx context.Context) (map[int64]int64, error) {
+	counts := map[int64]int64{}
+	if m.articles == nil {
+		return counts, nil
+	}
+
+	cursor := ""
+	for {
+		articles, nextCursor, err := m.articles.Fetch(ctx, cursor, countsPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range articles {
+			counts[a.Tag.ID]++
+		}
+		if nextCursor == "" {
+			return counts, nil
+		}
+		cursor = nextCursor
+	}
+}
+
+// withCount sets the article count of the tag
+func withCount(t domain.Tag, counts map[int64]int64) domain.Tag {
+	t.ArticleCount = counts[t.ID]
+	return t
+}
+
+// sorted returns the tags ordered by created_at, the caller must hold the lock
+func (m *memoryTagRepo) sorted() []domain.Tag {
+	list := make([]domain.Tag, 0, len(m.tags))
+	for _, t := range m.tags {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].ID < list[j].ID
+		}
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list
+}
+
+// Fetch implements domain.TagRepository.
+func (m *memoryTagRepo) Fetch(ctx context.Context, cursor string, num int64) (res []domain.Tag, nextCursor string, err error) {
+	return m.fetchCreated(ctx, cursor, num, true)
+}
+
+// fetchCreated lists the tags by creation time, the hidden tags only with includeHidden
+func (m *memoryTagRepo) fetchCreated(ctx context.Context, cursor string, num int64, includeHidden bool) (res []domain.Tag, nextCursor string, err error) {
+	var decodedCursor time.Time
+	if cursor != "" {
+		decodedCursor, err = repository.DecodeCursor(cursor)
+		if err != nil {
+			return nil, "", domain.ErrBadParamInput
+		}
+	}
+
+	counts, err := m.counts(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	res = make([]domain.Tag, 0)
+	for _, t := range m.sorted() {
+		if int64(len(res)) == num {
+			break
+		}
+		if (cursor != "" && !t.CreatedAt.After(decodedCursor)) || (t.Hidden && !includeHidden) {
+			continue
+		}
+		res = append(res, withCount(t, counts))
+	}
+
+	if len(res) == int(num) {
+		nextCursor = repository.EncodeCursor(res[len(res)-1].CreatedAt)
+	}
+
+	return
+}
+
+// FetchFiltered implements domain.TagRepository.
+func (m *memoryTagRepo) FetchFiltered(ctx context.Context, filter domain.TagFilter, cursor string, num int64) (res []domain.Tag, nextCursor string, err error) {
+	switch filter.Sort {
+	case "":
+		return m.fetchCreated(ctx, cursor, num, filter.IncludeHidden)
+	case domain.TagSortPopular:
+	default:
+		return nil, "", domain.ErrBadParamInput
+	}
+
+	var after repository.PopularCursor
+	if cursor != "" {
+		if after, err = repository.DecodePopularCursor(cursor); err != nil {
+			return nil, "", err
+		}
+	}
+
+	counts, err := m.counts(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]domain.Tag, 0, len(m.tags))
+	for _, t := range m.tags {
+		if !t.Hidden || filter.IncludeHidden {
+			list = append(list, withCount(t, counts))
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].ArticleCount != list[j].ArticleCount {
+			return list[i].ArticleCount > list[j].ArticleCount
+		}
+		return list[i].ID < list[j].ID
+	})
+
+	res = make([]domain.Tag, 0)
+	for _, t := range list {
+		if int64(len(res)) == num {
+			break
+		}
+		if cursor != "" && (t.ArticleCount > after.Count || (t.ArticleCount == after.Count && t.ID <= after.ID)) {
+			continue
+		}
+		res = append(res, t)
+	}
+
+	if len(res) == int(num) {
+		nextCursor = repository.EncodePopularCursor(res[len(res)-1])
+	}
+
+	return
+}
+
+// Count implements domain.TagRepository.
+func (m *memoryTagRepo) Count(ctx context.Context, filter domain.TagFilter) (total int64, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, t := range m.tags {
+		if filter.IncludeHidden || !t.Hidden {
+			total++
+		}
+	}
+	return total, nil
+}
+
+// FetchByID implements domain.TagRepository.
+func (m *memoryTagRepo) FetchByID(ctx context.Context, id int64) (domain.Tag, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	t, ok := m.tags[id]
+	if !ok {
+		return domain.Tag{}, domain.ErrNotFound
+	}
+	return t, nil
+}
+
+// FetchByName implements domain.TagRepository.
+func (m *memoryTagRepo) FetchByName(ctx context.Context, name string) (domain.Tag, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	key := fold.Key(name)
+	for _, t := range m.tags {
+		if fold.Key(t.Name) == key {
+			return t, nil
+		}
+	}
+	return domain.Tag{}, domain.ErrNotFound
+}
+
+// FetchBySlug implements domain.TagRepository.
+func (m *memoryTagRepo) FetchBySlug(ctx context.Context, slug string) (domain.Tag, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, t := range m.tags {
+		if slug != "" && t.Slug == slug {
+			return t, nil
+		}
+	}
+	return domain.Tag{}, domain.ErrNotFound
+}
+
+// ArticleCount implements domain.TagRepository.
+func (m *memoryTagRepo) ArticleCount(ctx context.Context, id int64) (int64, error) {
+	counts, err := m.counts(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return counts[id], nil
+}
+
+// FetchTree implements domain.TagRepository.
+func (m *memoryTagRepo) FetchTree(ctx context.Context, rootID int64) ([]domain.Tag, error) {
+	counts, err := m.counts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	visited := map[int64]bool{}
+	var queue []int64
+	if rootID != 0 {
+		if _, ok := m.tags[rootID]; !ok {
+			return nil, domain.ErrNotFound
+		}
+		queue = append(queue, rootID)
+	} else {
+		for id, t := range m.tags {
+			if t.ParentID == 0 {
+				queue = append(queue, id)
+			}
+		}
+	}
+
+	res := make([]domain.Tag, 0)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+		res = append(res, withCount(m.tags[id], counts))
+		for childID, child := range m.tags {
+			if child.ParentID == id {
+				queue = append(queue, childID)
+			}
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res, nil
+}
+
+// FetchByAlias implements domain.TagRepository.
+func (m *memoryTagRepo) FetchByAlias(ctx context.Context, alias string) (domain.Tag, error) {
+	m.mu.RLock()
+	a, ok := m.aliases[fold.Key(alias)]
+	m.mu.RUnlock()
+	if !ok {
+		return domain.Tag{}, domain.ErrNotFound
+	}
+
+	return m.FetchByID(ctx, a.TagID)
+}
+
+// Suggest implements domain.TagRepository.
+func (m *memoryTagRepo) Suggest(ctx context.Context, prefix string, num int64) ([]domain.Tag, error) {
+	counts, err := m.counts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// the rank of a tag is its best match: 0 the name, 1 an alias, 2 a name prefix, 3 an alias prefix
+	key := fold.Key(prefix)
+	ranks := map[int64]int{}
+	match := func(id int64, nameKey string, rank int) {
+		switch {
+		case nameKey == key:
+		case strings.HasPrefix(nameKey, key):
+			rank += 2
+		default:
+			return
+		}
+		if current, ok := ranks[id]; !ok || rank < current {
+			ranks[id] = rank
+		}
+	}
+	for id, t := range m.tags {
+		match(id, fold.Key(t.Name), 0)
+	}
+	for aliasKey, a := range m.aliases {
+		match(a.TagID, aliasKey, 1)
+	}
+
+	res := make([]domain.Tag, 0, len(ranks))
+	for id := range ranks {
+		if t := m.tags[id]; !t.Hidden {
+			res = append(res, withCount(t, counts))
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if ranks[res[i].ID] != ranks[res[j].ID] {
+			return ranks[res[i].ID] < ranks[res[j].ID]
+		}
+		if res[i].ArticleCount != res[j].ArticleCount {
+			return res[i].ArticleCount > res[j].ArticleCount
+		}
+		return res[i].Name < res[j].Name
+	})
+	if int64(len(res)) > num {
+		res = res[:num]
+	}
+	return res, nil
+}
+
+// FetchAliases implements domain.TagRepository.
+func (m *memoryTagRepo) FetchAliases(ctx context.Context, tagID int64) ([]domain.TagAlias, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	res := make([]domain.TagAlias, 0)
+	for _, a := range m.aliases {
+		if a.TagID == tagID {
+			res = append(res, a)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res, nil
+}
+
+// StoreAlias implements domain.TagRepository.
+func (m *memoryTagRepo) StoreAlias(ctx context.Context, a *domain.TagAlias) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := fold.Key(a.Name)
+	if _, ok := m.aliases[key]; ok {
+		return domain.ErrConflict
+	}
+	m.aliases[key] = *a
+	return nil
+}
+
+// DeleteAlias implements domain.TagRepository.
+func (m *memoryTagRepo) DeleteAlias(ctx context.Context, tagID int64, alias string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := fold.Key(alias)
+	if a, ok := m.aliases[key]; !ok || a.TagID != tagID {
+		return domain.ErrNotFound
+	}
+	delete(m.aliases, key)
+	return nil
+}
+
+// Store implements domain.TagRepository.
+func (m *memoryTagRepo) Store(ctx context.Context, t *domain.Tag) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.nameTaken(t.Name, 0) || m.slugTaken(t.Slug, 0) {
+		return domain.ErrConflict
+	}
+
+	m.lastID++
+	t.ID = m.lastID
+	stored := *t
+	stored.ArticleCount = 0
+	stored.CreatedAt = roundTime(t.CreatedAt)
+	stored.UpdatedAt = roundTime(t.UpdatedAt)
+	m.tags[t.ID] = stored
+	return nil
+}
+
+// Update implements domain.TagRepository.
+func (m *memoryTagRepo) Update(ctx context.Context, t *domain.Tag) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.tags[t.ID]
+	if !ok {
+		return domain.ErrNotFound
+	}
+	if m.nameTaken(t.Name, t.ID) || m.slugTaken(t.Slug, t.ID) {
+		return domain.ErrConflict
+	}
+
+	existing.Name = t.Name
+	existing.ParentID = t.ParentID
+	existing.Description = t.Description
+	existing.Color = t.Color
+	existing.Icon = t.Icon
+	existing.Hidden = t.Hidden
+	// an empty slug keeps the current one
+	if t.Slug != "" {
+		existing.Slug = t.Slug
+	}
+	existing.UpdatedAt = roundTime(t.UpdatedAt)
+	m.tags[t.ID] = existing
+	return nil
+}
+
+// Delete implements domain.TagRepository.
+func (m *memoryTagRepo) Delete(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.tags[id]; !ok {
+		return domain.ErrNotFound
+	}
+
+	articles, err := m.tagArticles(ctx, id)
+	if err != nil {
+		return err
+	}
+	if len(articles) > 0 {
+		inUse := &domain.TagInUseError{ArticleCount: int64(len(articles)), Articles: make([]domain.Article, 0)}
+		for _, a := range articles {
+			if len(inUse.Articles) == repository.InUseLimit {
+				break
+			}
+			inUse.Articles = append(inUse.Articles, domain.Article{ID: a.ID, Title: a.Title, Slug: a.Slug})
+		}
+		return inUse
+	}
+
+	m.remove(id)
+	return nil
+}
+
+// Reassign implements domain.TagRepository. The memory has no transaction, the tags stay locked
+// while the articles move but a failing article update leaves the moved articles on the tag to.
+func (m *memoryTagRepo) Reassign(ctx context.Context, id, to int64, keepAlias bool) error {
+	if to == id || (keepAlias && to == 0) {
+		return domain.ErrBadParamInput
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tag, ok := m.tags[id]
+	if !ok {
+		return domain.ErrNotFound
+	}
+	if _, ok = m.tags[to]; to != 0 && !ok {
+		return domain.ErrNotFound
+	}
+
+	articles, err := m.tagArticles(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, a := range articles {
+		err = m.articles.Update(ctx, &domain.UpdateArticleInput{
+			ID:            a.ID,
+			Title:         a.Title,
+			Slug:          a.Slug,
+			Content:       a.Content,
+			ContentFormat: a.ContentFormat,
+			UpdatedAt:     time.Now(),
+			CreatedAt:     a.CreatedAt,
+			TagID:         to,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if keepAlias {
+		for key, a := range m.aliases {
+			if a.TagID == id {
+				a.TagID = to
+				m.aliases[key] = a
+			}
+		}
+		m.adopt(id, to)
+	}
+	m.remove(id)
+	if keepAlias {
+		m.aliases[fold.Key(tag.Name)] = domain.TagAlias{Name: tag.Name, TagID: to}
+	}
+	return nil
+}
+
+// tagArticles returns every article of the tag, the caller must hold the lock
+func (m *memoryTagRepo) tagArticles(ctx context.Context, id int64) ([]domain.Article, error) {
+	res := make([]domain.Article, 0)
+	if m.articles == nil {
+		return res, nil
+	}
+
+	cursor := ""
+	for {
+		articles, nextCursor, err := m.articles.FetchFiltered(ctx, domain.ArticleFilter{TagID: id}, cursor, countsPageSize)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, articles...)
+		if nextCursor == "" {
+			return res, nil
+		}
+		cursor = nextCursor
+	}
+}
+
+// adopt moves the child tags of the tag id to the tag to, a descendant to first takes the place
+// of the tag. The caller must hold the lock.
+func (m *memoryTagRepo) adopt(id, to int64) {
+	// the visited tags are not walked again, a cycle in the data cannot loop forever
+	visited := make(map[int64]bool)
+	for parentID := m.tags[to].ParentID; parentID != 0 && !visited[parentID]; parentID = m.tags[parentID].ParentID {
+		visited[parentID] = true
+		if parentID == id {
+			target := m.tags[to]
+			target.ParentID = m.tags[id].ParentID
+			m.tags[to] = target
+			break
+		}
+	}
+
+	for childID, child := range m.tags {
+		if child.ParentID == id {
+			child.ParentID = to
+			m.tags[childID] = child
+		}
+	}
+}
+
+// remove deletes the tag and its aliases, the child tags move up to the parent of the tag.
+// The caller must hold the lock.
+func (m *memoryTagRepo) remove(id int64) {
+	parentID := m.tags[id].ParentID
+	for childID, child := range m.tags {
+		if child.ParentID == id {
+			child.ParentID = parentID
+			m.tags[childID] = child
+		}
+	}
+	delete(m.tags, id)
+	for key, a := range m.aliases {
+		if a.TagID == id {
+			delete(m.aliases, key)
+		}
+	}
+}
+
+// nameTaken reports whether another tag than exceptID already uses the name key, the caller must hold the lock
+func (m *memoryTagRepo) nameTaken(name string, exceptID int64) bool {
+	key := fold.Key(name)
+	for id, t := range m.tags {
+		if id != exceptID && fold.Key(t.Name) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// slugTaken reports whether another tag than exceptID already uses the slug, the caller must hold the lock
+func (m *memoryTagRepo) slugTaken(slug string, exceptID int64) bool {
+	if slug == "" {
+		return false
+	}
+	for id, t := range m.tags {
+		if id != exceptID && t.Slug == slug {
+			return true
+		}
+	}
+	return false
+}
+
+// roundTime keeps the microsecond precision a database column would
+func roundTime(t time.Time) time.Time {
+	return t.Round(time.Microsecond)
+}
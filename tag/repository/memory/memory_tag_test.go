@@ -0,0 +1,17 @@
+package memory_test
+
+import (
+	"testing"
+
+	articleMemory "go-postgres-clean-arch/article/repository/memory"
+	"go-postgres-clean-arch/domain"
+	"go-postgres-clean-arch/tag/repository/memory"
+	"go-postgres-clean-arch/tag/repository/repositorytest"
+)
+
+func TestMemoryTagRepositoryContract(t *testing.T) {
+	repositorytest.RunTagContract(t, func(t *testing.T) (domain.TagRepository, domain.ArticleRepository) {
+		articles := articleMemory.NewMemoryArticleRepository()
+		return memory.NewMemoryTagRepositoryWithArticles(articles), articles
+	})
+}
@@ -0,0 +1,24 @@
+package mysql_test
+
+import (
+	"testing"
+
+	articleMysql "go-postgres-clean-arch/article/repository/mysql"
+	"go-postgres-clean-arch/domain"
+	"go-postgres-clean-arch/helper/mysqltest"
+	"go-postgres-clean-arch/tag/repository/mysql"
+	"go-postgres-clean-arch/tag/repository/repositorytest"
+)
+
+func TestMysqlTagRepositoryContract(t *testing.T) {
+	db := mysqltest.OpenMigrated(t)
+
+	repositorytest.RunTagContract(t, func(t *testing.T) (domain.TagRepository, domain.ArticleRepository) {
+		for _, table := range []string{"tag", "article", "tag_alias"} {
+			if _, err := db.Exec(`TRUNCATE TABLE ` + table); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return mysql.NewMysqlTagRepository(db), articleMysql.NewMysqlArticleRepository(db)
+	})
+}
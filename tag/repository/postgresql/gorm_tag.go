@@ -0,0 +1,457 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go-postgres-clean-arch/domain"
+	"go-postgres-clean-arch/helper/fold"
+	"go-postgres-clean-arch/tag/repository"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// gormTag is the GORM model of the tag table
+type gormTag struct {
+	ID          int64 `gorm:"primaryKey"`
+	Name        string
+	NameKey     string
+	Slug        sql.NullString
+	ParentID    sql.NullInt64
+	Description string
+	Color       string
+	Icon        string
+	Hidden      bool
+	CreatedAt   time.Time `gorm:"autoCreateTime:false"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime:false"`
+	// ArticleCount is only read, from the article_count column of countedTagColumns
+	ArticleCount int64 `gorm:"->"`
+}
+
+func (gormTag) TableName() string {
+	return "tag"
+}
+
+func (g gormTag) toDomain() domain.Tag {
+	return domain.Tag{
+		ID:           g.ID,
+		Name:         g.Name,
+		Slug:         g.Slug.String,
+		ParentID:     g.ParentID.Int64,
+		Description:  g.Description,
+		Color:        g.Color,
+		Icon:         g.Icon,
+		Hidden:       g.Hidden,
+		CreatedAt:    g.CreatedAt,
+		UpdatedAt:    g.UpdatedAt,
+		ArticleCount: g.ArticleCount,
+	}
+}
+
+type gormTagRepo struct {
+	Db *gorm.DB
+}
+
+// NewGormTagRepository will create a GORM based object that represent the tag.Repository interface.
+// The db must be opened with TranslateError enabled so unique violations surface as domain.ErrConflict.
+func NewGormTagRepository(db *gorm.DB) domain.TagRepository {
+	return &gormTagRepo{Db: db}
+}
+
+// Fetch implements domain.TagRepository.
+func (p *gormTagRepo) Fetch(ctx context.Context, cursor string, num int64) (res []domain.Tag, nextCursor string, err error) {
+	return p.fetchCreated(ctx, cursor, num, true)
+}
+
+// fetchCreated lists the tags by creation time, the hidden tags only with includeHidden
+func (p *gormTagRepo) fetchCreated(ctx context.Context, cursor string, num int64, includeHidden bool) (res []domain.Tag, nextCursor string, err error) {
+	query := p.Db.WithContext(ctx).Select(countedTagColumns).Joins(articleCounts).
+		Where(repository.VisibleCondition(includeHidden)).Order("created_at").Limit(int(num))
+
+	if cursor != "" {
+		decodedCursor, err := repository.DecodeCursor(cursor)
+		if err != nil {
+			return nil, "", domain.ErrBadParamInput
+		}
+		query = query.Where("created_at > ?", decodedCursor)
+	}
+
+	var rows []gormTag
+	if err = query.Find(&rows).Error; err != nil {
+		return nil, "", err
+	}
+
+	res = make([]domain.Tag, 0, len(rows))
+	for _, row := range rows {
+		res = append(res, row.toDomain())
+	}
+
+	if len(res) == int(num) {
+		nextCursor = repository.EncodeCursor(res[len(res)-1].CreatedAt)
+	}
+
+	return
+}
+
+// FetchFiltered implements domain.TagRepository.
+func (p *gormTagRepo) FetchFiltered(ctx context.Context, filter domain.TagFilter, cursor string, num int64) (res []domain.Tag, nextCursor string, err error) {
+	switch filter.Sort {
+	case "":
+		return p.fetchCreated(ctx, cursor, num, filter.IncludeHidden)
+	case domain.TagSortPopular:
+	default:
+		return nil, "", domain.ErrBadParamInput
+	}
+
+	query := `SELECT id, name, slug, parent_id, description, color, icon, hidden, created_at, updated_at, article_count
+				FROM (SELECT ` + countedTagColumns + ` FROM tag ` + articleCounts + `
+					WHERE ` + repository.VisibleCondition(filter.IncludeHidden) + `) AS tags`
+	args := []interface{}{}
+	if cursor != "" {
+		c, err := repository.DecodePopularCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		query += ` WHERE article_count < ? OR (article_count = ? AND id > ?)`
+		args = append(args, c.Count, c.Count, c.ID)
+	}
+	query += ` ORDER BY article_count DESC, id LIMIT ?`
+	args = append(args, num)
+
+	var rows []gormTag
+	if err = p.Db.WithContext(ctx).Raw(query, args...).Scan(&rows).Error; err != nil {
+		return nil, "", err
+	}
+
+	res = make([]domain.Tag, 0, len(rows))
+	for _, row := range rows {
+		res = append(res, row.toDomain())
+	}
+
+	if len(res) == int(num) {
+		nextCursor = repository.EncodePopularCursor(res[len(res)-1])
+	}
+
+	return
+}
+
+// Count implements domain.TagRepository.
+func (p *gormTagRepo) Count(ctx context.Context, filter domain.TagFilter) (total int64, err error) {
+	err = p.Db.WithContext(ctx).Model(&gormTag{}).Where(repository.VisibleCondition(filter.IncludeHidden)).Count(&total).Error
+	return
+}
+
+// FetchByID implements domain.TagRepository.
+func (p *gormTagRepo) FetchByID(ctx context.Context, id int64) (domain.Tag, error) {
+	var row gormTag
+	err := p.Db.WithContext(ctx).Select(tagColumns).Where("id = ?", id).Take(&row).Error
+	if err != nil {
+		return domain.Tag{}, translateGormError(err)
+	}
+
+	return row.toDomain(), nil
+}
+
+// FetchByName implements domain.TagRepository.
+func (p *gormTagRepo) FetchByName(ctx context.Context, name string) (domain.Tag, error) {
+	var row gormTag
+	err := p.Db.WithContext(ctx).Select(tagColumns).Where("name_key = ?", fold.Key(name)).Take(&row).Error
+	if err != nil {
+		return domain.Tag{}, translateGormError(err)
+	}
+
+	return row.toDomain(), nil
+}
+
+// FetchBySlug implements domain.TagRepository.
+func (p *gormTagRepo) FetchBySlug(ctx context.Context, slug string) (domain.Tag, error) {
+	var row gormTag
+	err := p.Db.WithContext(ctx).Select(tagColumns).Where("slug = ?", slug).Take(&row).Error
+	if err != nil {
+		return domain.Tag{}, translateGormError(err)
+	}
+
+	return row.toDomain(), nil
+}
+
+// ArticleCount implements domain.TagRepository.
+func (p *gormTagRepo) ArticleCount(ctx context.Context, id int64) (count int64, err error) {
+	err = p.Db.WithContext(ctx).Raw("SELECT COUNT(*) FROM article WHERE tag_id = ?", id).Scan(&count).Error
+	return
+}
+
+// FetchTree implements domain.TagRepository.
+func (p *gormTagRepo) FetchTree(ctx context.Context, rootID int64) ([]domain.Tag, error) {
+	anchor, args := "parent_id IS NULL", []interface{}{}
+	if rootID != 0 {
+		anchor, args = "id = ?", append(args, rootID)
+	}
+	// UNION drops the tags already visited, a cycle in the data cannot recurse forever
+	query := `WITH RECURSIVE subtree (id) AS (
+					SELECT id FROM tag WHERE ` + anchor + `
+					UNION
+					SELECT tag.id FROM tag JOIN subtree ON tag.parent_id = subtree.id
+				)
+				SELECT ` + countedTagColumns + `
+				FROM tag ` + articleCounts + `
+				WHERE id IN (SELECT id FROM subtree)
+				ORDER BY name`
+
+	var rows []gormTag
+	if err := p.Db.WithContext(ctx).Raw(query, args...).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	if rootID != 0 && len(rows) == 0 {
+		return nil, domain.ErrNotFound
+	}
+
+	res := make([]domain.Tag, 0, len(rows))
+	for _, row := range rows {
+		res = append(res, row.toDomain())
+	}
+	return res, nil
+}
+
+// FetchByAlias implements domain.TagRepository.
+func (p *gormTagRepo) FetchByAlias(ctx context.Context, alias string) (domain.Tag, error) {
+	var row gormTag
+	err := p.Db.WithContext(ctx).Select(tagColumns).Where("id = (SELECT tag_id FROM tag_alias WHERE name_key = ?)", fold.Key(alias)).Take(&row).Error
+	if err != nil {
+		return domain.Tag{}, translateGormError(err)
+	}
+
+	return row.toDomain(), nil
+}
+
+// Suggest implements domain.TagRepository.
+func (p *gormTagRepo) Suggest(ctx context.Context, prefix string, num int64) ([]domain.Tag, error) {
+	query := `SELECT id, name, slug, parent_id, description, color, icon, hidden, created_at, updated_at, article_count
+				FROM (SELECT ` + countedTagColumns + `,
+						CASE WHEN name_key = @key THEN 0
+							WHEN id IN (SELECT tag_id FROM tag_alias WHERE name_key = @key) THEN 1
+							WHEN name_key LIKE @like ESCAPE '!' THEN 2
+							ELSE 3 END AS match_rank
+					FROM tag ` + articleCounts + `
+					WHERE NOT hidden AND (name_key LIKE @like ESCAPE '!'
+						OR id IN (SELECT tag_id FROM tag_alias WHERE name_key LIKE @like ESCAPE '!'))) AS tags
+				ORDER BY match_rank, article_count DESC, name
+				LIMIT @num`
+
+	key := fold.Key(prefix)
+	var rows []gormTag
+	err := p.Db.WithContext(ctx).Raw(query, sql.Named("key", key), sql.Named("like", fold.LikePrefix(key)), sql.Named("num", num)).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]domain.Tag, 0, len(rows))
+	for _, row := range rows {
+		res = append(res, row.toDomain())
+	}
+	return res, nil
+}
+
+// FetchAliases implements domain.TagRepository.
+func (p *gormTagRepo) FetchAliases(ctx context.Context, tagID int64) ([]domain.TagAlias, error) {
+	rows, err := p.Db.WithContext(ctx).Raw("SELECT name, tag_id FROM tag_alias WHERE tag_id = ? ORDER BY name", tagID).Rows()
+	if err != nil {
+		return nil, err
+	}
+	return repository.ScanAliases(rows)
+}
+
+// StoreAlias implements domain.TagRepository.
+func (p *gormTagRepo) StoreAlias(ctx context.Context, a *domain.TagAlias) error {
+	err := p.Db.WithContext(ctx).Exec("INSERT INTO tag_alias (name, name_key, tag_id) VALUES (?, ?, ?)", a.Name, fold.Key(a.Name), a.TagID).Error
+	if err != nil {
+		return translateGormError(err)
+	}
+	return nil
+}
+
+// DeleteAlias implements domain.TagRepository.
+func (p *gormTagRepo) DeleteAlias(ctx context.Context, tagID int64, alias string) error {
+	result := p.Db.WithContext(ctx).Exec("DELETE FROM tag_alias WHERE tag_id = ? AND name_key = ?", tagID, fold.Key(alias))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// Store implements domain.TagRepository.
+func (p *gormTagRepo) Store(ctx context.Context, t *domain.Tag) error {
+	row := gormTag{
+		Name:        t.Name,
+		NameKey:     fold.Key(t.Name),
+		Slug:        repository.NullString(t.Slug),
+		ParentID:    repository.NullID(t.ParentID),
+		Description: t.Description,
+		Color:       t.Color,
+		Icon:        t.Icon,
+		Hidden:      t.Hidden,
+		CreatedAt:   t.CreatedAt,
+		UpdatedAt:   t.UpdatedAt,
+	}
+	if err := p.Db.WithContext(ctx).Create(&row).Error; err != nil {
+		return translateGormError(err)
+	}
+
+	t.ID = row.ID
+	return nil
+}
+
+// Update implements domain.TagRepository.
+func (p *gormTagRepo) Update(ctx context.Context, t *domain.Tag) error {
+	values := map[string]interface{}{
+		"name":        t.Name,
+		"name_key":    fold.Key(t.Name),
+		"parent_id":   repository.NullID(t.ParentID),
+		"description": t.Description,
+		"color":       t.Color,
+		"icon":        t.Icon,
+		"hidden":      t.Hidden,
+		"updated_at":  t.UpdatedAt,
+	}
+	// an empty slug keeps the current one
+	if t.Slug != "" {
+		values["slug"] = t.Slug
+	}
+	result := p.Db.WithContext(ctx).Model(&gormTag{}).Where("id = ?", t.ID).Updates(values)
+	if result.Error != nil {
+		return translateGormError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
+
+// Delete implements domain.TagRepository.
+func (p *gormTagRepo) Delete(ctx context.Context, id int64) error {
+	return p.delete(ctx, id, nil, false)
+}
+
+// Reassign implements domain.TagRepository.
+func (p *gormTagRepo) Reassign(ctx context.Context, id, to int64, keepAlias bool) error {
+	if to == id || (keepAlias && to == 0) {
+		return domain.ErrBadParamInput
+	}
+	return p.delete(ctx, id, &to, keepAlias)
+}
+
+// delete deletes the tag in a transaction, the articles move to the tag to or restrict the delete when to is nil
+func (p *gormTagRepo) delete(ctx context.Context, id int64, to *int64, keepAlias bool) error {
+	return p.Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current gormTag
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("name", "parent_id").Where("id = ?", id).Take(&current).Error
+		if err != nil {
+			return translateGormError(err)
+		}
+
+		if to == nil {
+			if err = gormInUse(tx, id); err != nil {
+				return err
+			}
+		} else {
+			if *to != 0 {
+				// the tag the articles move to must outlive the transaction
+				var target gormTag
+				err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ?", *to).Take(&target).Error
+				if err != nil {
+					return translateGormError(err)
+				}
+			}
+			if err = tx.Exec("UPDATE article SET tag_id = ?, version = version + 1, updated_at = ? WHERE tag_id = ?",
+				*to, time.Now(), id).Error; err != nil {
+				return err
+			}
+		}
+
+		if keepAlias {
+			if err = tx.Exec("UPDATE tag_alias SET tag_id = ? WHERE tag_id = ?", *to, id).Error; err != nil {
+				return err
+			}
+			if err = tx.Exec("DELETE FROM tag_alias WHERE name_key = ?", fold.Key(current.Name)).Error; err != nil {
+				return err
+			}
+			if err = tx.Exec("INSERT INTO tag_alias (name, name_key, tag_id) VALUES (?, ?, ?)", current.Name, fold.Key(current.Name), *to).Error; err != nil {
+				return err
+			}
+		} else if err = tx.Exec("DELETE FROM tag_alias WHERE tag_id = ?", id).Error; err != nil {
+			return err
+		}
+
+		// the child tags move up to the parent of the tag, or to the tag it merges into
+		newParentID := current.ParentID
+		if keepAlias {
+			// a tag merged into one of its descendants leaves the descendant in its place
+			below, err := gormIsBelow(tx, *to, id)
+			if err != nil {
+				return err
+			}
+			if below {
+				if err = tx.Exec("UPDATE tag SET parent_id = ? WHERE id = ?", current.ParentID, *to).Error; err != nil {
+					return err
+				}
+			}
+			newParentID = sql.NullInt64{Int64: *to, Valid: true}
+		}
+		if err = tx.Exec("UPDATE tag SET parent_id = ? WHERE parent_id = ?", newParentID, id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&gormTag{}, id).Error
+	})
+}
+
+// gormIsBelow reports whether the tag id is a descendant of the tag ancestor
+func gormIsBelow(tx *gorm.DB, id, ancestor int64) (bool, error) {
+	// the visited tags are not walked again, a cycle in the data cannot loop forever
+	visited := make(map[int64]bool)
+	for id != 0 && !visited[id] {
+		visited[id] = true
+		var tag gormTag
+		if err := tx.Select("parent_id").Where("id = ?", id).Take(&tag).Error; err != nil {
+			return false, translateGormError(err)
+		}
+		if tag.ParentID.Valid && tag.ParentID.Int64 == ancestor {
+			return true, nil
+		}
+		id = tag.ParentID.Int64
+	}
+	return false, nil
+}
+
+// gormInUse returns a *domain.TagInUseError when articles still reference the tag
+func gormInUse(tx *gorm.DB, id int64) error {
+	var count int64
+	if err := tx.Raw("SELECT COUNT(*) FROM article WHERE tag_id = ?", id).Scan(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return nil
+	}
+
+	rows, err := tx.Raw("SELECT id, title, slug FROM article WHERE tag_id = ? ORDER BY id LIMIT ?", id, repository.InUseLimit).Rows()
+	if err != nil {
+		return err
+	}
+	return repository.InUseError(rows, count)
+}
+
+// translateGormError maps the GORM errors to the domain errors
+func translateGormError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return domain.ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return domain.ErrConflict
+	default:
+		return err
+	}
+}
@@ -3,22 +3,38 @@ package postgresql
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"go-postgres-clean-arch/domain"
+	"go-postgres-clean-arch/helper/fold"
 	"go-postgres-clean-arch/tag/repository"
+	"time"
 
+	"github.com/lib/pq"
 	"github.com/sirupsen/logrus"
-	"gorm.io/gorm"
 )
 
+// uniqueViolation is the postgresql error code raised when a unique constraint is violated
+const uniqueViolation = "23505"
+
+// tagColumns selects a tag without counting its articles, the article_count is zero
+const tagColumns = `id, name, slug, parent_id, description, color, icon, hidden, created_at, updated_at, 0 AS article_count`
+
+// countedTagColumns selects a tag with its number of articles, the query joins articleCounts
+const countedTagColumns = `id, name, slug, parent_id, description, color, icon, hidden, created_at, updated_at,
+	COALESCE(article_counts.article_count, 0) AS article_count`
+
+// articleCounts joins the number of articles of the tags, counted in one grouped scan of the article table
+const articleCounts = `LEFT JOIN (SELECT tag_id, COUNT(*) AS article_count FROM article GROUP BY tag_id) AS article_counts
+	ON article_counts.tag_id = tag.id`
+
 type postgresqlTagRepo struct {
 	Conn *sql.DB
-	Db   *gorm.DB
 }
 
-// PostgresqlTagRepository will create an object that represent the tag.Repository interface
-func NewPostgresqlTagRepository(Conn *sql.DB, Db *gorm.DB) domain.TagRepository {
-	return &postgresqlTagRepo{Conn: Conn, Db: Db}
+// NewPostgresqlTagRepository will create an object that represent the tag.Repository interface
+func NewPostgresqlTagRepository(Conn *sql.DB) domain.TagRepository {
+	return &postgresqlTagRepo{Conn: Conn}
 }
 
 func (p *postgresqlTagRepo) fetch(ctx context.Context, query string, args ...interface{}) (result []domain.Tag, err error) {
@@ -38,17 +54,28 @@ func (p *postgresqlTagRepo) fetch(ctx context.Context, query string, args ...int
 	result = make([]domain.Tag, 0)
 	for rows.Next() {
 		t := domain.Tag{}
+		slug := sql.NullString{}
+		parentID := sql.NullInt64{}
 		err = rows.Scan(
 			&t.ID,
 			&t.Name,
+			&slug,
+			&parentID,
+			&t.Description,
+			&t.Color,
+			&t.Icon,
+			&t.Hidden,
 			&t.CreatedAt,
 			&t.UpdatedAt,
+			&t.ArticleCount,
 		)
 
 		if err != nil {
 			logrus.Error(err)
 			return nil, err
 		}
+		t.Slug = slug.String
+		t.ParentID = parentID.Int64
 		result = append(result, t)
 	}
 
@@ -57,11 +84,17 @@ func (p *postgresqlTagRepo) fetch(ctx context.Context, query string, args ...int
 
 // Fetch implements domain.TagRepository.
 func (p *postgresqlTagRepo) Fetch(ctx context.Context, cursor string, num int64) (res []domain.Tag, nextCursor string, err error) {
+	return p.fetchCreated(ctx, cursor, num, true)
+}
+
+// fetchCreated lists the tags by creation time, the hidden tags only with includeHidden
+func (p *postgresqlTagRepo) fetchCreated(ctx context.Context, cursor string, num int64, includeHidden bool) (res []domain.Tag, nextCursor string, err error) {
+	visible := repository.VisibleCondition(includeHidden)
 	var query string
 	if cursor != "" {
-		query = `SELECT id,name,created_at,updated_at
-					FROM tag
-					WHERE created_at > $1
+		query = `SELECT ` + countedTagColumns + `
+					FROM tag ` + articleCounts + `
+					WHERE ` + visible + ` AND created_at > $1
 					ORDER BY created_at
 					LIMIT $2`
 
@@ -69,7 +102,6 @@ func (p *postgresqlTagRepo) Fetch(ctx context.Context, cursor string, num int64)
 		// Example encodeCursor and cursor: MjAyMy0xMS0zMFQxNToyMDozMy42ODJa
 
 		decodedCursor, err := repository.DecodeCursor(cursor)
-		fmt.Println("decodedCursor: " + decodedCursor.String())
 		if err != nil && cursor != "" {
 			return nil, "", domain.ErrBadParamInput
 		}
@@ -80,16 +112,14 @@ func (p *postgresqlTagRepo) Fetch(ctx context.Context, cursor string, num int64)
 
 		if len(res) == int(num) {
 			nextCursor = repository.EncodeCursor(res[len(res)-1].CreatedAt)
-			if err != nil && cursor != "" {
-				return nil, "", domain.ErrBadParamInput
-			}
 		}
 
 		return res, nextCursor, nil
 	}
 
-	query = `SELECT id,name,created_at,updated_at
-			FROM tag
+	query = `SELECT ` + countedTagColumns + `
+			FROM tag ` + articleCounts + `
+			WHERE ` + visible + `
 			ORDER BY created_at
 			LIMIT $1`
 
@@ -99,12 +129,71 @@ func (p *postgresqlTagRepo) Fetch(ctx context.Context, cursor string, num int64)
 		return nil, "", err
 	}
 
+	if len(res) == int(num) {
+		nextCursor = repository.EncodeCursor(res[len(res)-1].CreatedAt)
+	}
+
+	return
+}
+
+// FetchFiltered implements domain.TagRepository.
+func (p *postgresqlTagRepo) FetchFiltered(ctx context.Context, filter domain.TagFilter, cursor string, num int64) (res []domain.Tag, nextCursor string, err error) {
+	switch filter.Sort {
+	case "":
+		return p.fetchCreated(ctx, cursor, num, filter.IncludeHidden)
+	case domain.TagSortPopular:
+		return p.fetchPopular(ctx, cursor, num, filter.IncludeHidden)
+	default:
+		return nil, "", domain.ErrBadParamInput
+	}
+}
+
+// fetchPopular lists the tags with the most articles first, the id breaks the ties
+func (p *postgresqlTagRepo) fetchPopular(ctx context.Context, cursor string, num int64, includeHidden bool) (res []domain.Tag, nextCursor string, err error) {
+	visible := repository.VisibleCondition(includeHidden)
+	if cursor != "" {
+		c, err := repository.DecodePopularCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+
+		query := `SELECT id, name, slug, parent_id, description, color, icon, hidden, created_at, updated_at, article_count
+					FROM (SELECT ` + countedTagColumns + ` FROM tag ` + articleCounts + ` WHERE ` + visible + `) AS tags
+					WHERE article_count < $1 OR (article_count = $1 AND id > $2)
+					ORDER BY article_count DESC, id
+					LIMIT $3`
+		res, err = p.fetch(ctx, query, c.Count, c.ID, num)
+		if err != nil {
+			return nil, "", err
+		}
+	} else {
+		query := `SELECT id, name, slug, parent_id, description, color, icon, hidden, created_at, updated_at, article_count
+					FROM (SELECT ` + countedTagColumns + ` FROM tag ` + articleCounts + ` WHERE ` + visible + `) AS tags
+					ORDER BY article_count DESC, id
+					LIMIT $1`
+		res, err = p.fetch(ctx, query, num)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	if len(res) == int(num) {
+		nextCursor = repository.EncodePopularCursor(res[len(res)-1])
+	}
+
+	return
+}
+
+// Count implements domain.TagRepository.
+func (p *postgresqlTagRepo) Count(ctx context.Context, filter domain.TagFilter) (total int64, err error) {
+	query := `SELECT COUNT(*) FROM tag WHERE ` + repository.VisibleCondition(filter.IncludeHidden)
+	err = p.Conn.QueryRowContext(ctx, query).Scan(&total)
 	return
 }
 
 // FetchByID implements domain.TagRepository.
 func (p *postgresqlTagRepo) FetchByID(ctx context.Context, id int64) (res domain.Tag, err error) {
-	query := `SELECT id,name,created_at,updated_at 
+	query := `SELECT ` + tagColumns + ` 
 				FROM tag 
 				WHERE id = $1`
 
@@ -124,11 +213,11 @@ func (p *postgresqlTagRepo) FetchByID(ctx context.Context, id int64) (res domain
 
 // FetchByName implements domain.TagRepository.
 func (p *postgresqlTagRepo) FetchByName(ctx context.Context, name string) (res domain.Tag, err error) {
-	query := `SELECT id, name, created_at, updated_at 
+	query := `SELECT ` + tagColumns + ` 
 				FROM tag 
-				WHERE name = $1`
+				WHERE name_key = $1`
 
-	list, err := p.fetch(ctx, query, name)
+	list, err := p.fetch(ctx, query, fold.Key(name))
 
 	if err != nil {
 		return
@@ -143,70 +232,288 @@ func (p *postgresqlTagRepo) FetchByName(ctx context.Context, name string) (res d
 	return
 }
 
-// Store implements domain.TagRepository.
-func (p *postgresqlTagRepo) Store(ctx context.Context, t *domain.Tag) (err error) {
-	query := `INSERT INTO tag (name, created_at, updated_at) 
-				VALUES ($1, $2, $3)
-				RETURNING id`
-	stmt, err := p.Conn.PrepareContext(ctx, query)
+// FetchBySlug implements domain.TagRepository.
+func (p *postgresqlTagRepo) FetchBySlug(ctx context.Context, slug string) (res domain.Tag, err error) {
+	query := `SELECT ` + tagColumns + `
+				FROM tag
+				WHERE slug = $1`
+
+	list, err := p.fetch(ctx, query, slug)
 	if err != nil {
 		return
 	}
 
-	err = stmt.QueryRowContext(ctx, t.Name, t.CreatedAt, t.UpdatedAt).Err()
+	if len(list) == 0 {
+		return res, domain.ErrNotFound
+	}
+	return list[0], nil
+}
+
+// ArticleCount implements domain.TagRepository.
+func (p *postgresqlTagRepo) ArticleCount(ctx context.Context, id int64) (count int64, err error) {
+	err = p.Conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM article WHERE tag_id = $1", id).Scan(&count)
+	return
+}
+
+// FetchTree implements domain.TagRepository.
+func (p *postgresqlTagRepo) FetchTree(ctx context.Context, rootID int64) (res []domain.Tag, err error) {
+	anchor, args := "parent_id IS NULL", []interface{}{}
+	if rootID != 0 {
+		anchor, args = "id = $1", append(args, rootID)
+	}
+	// UNION drops the tags already visited, a cycle in the data cannot recurse forever
+	query := `WITH RECURSIVE subtree (id) AS (
+					SELECT id FROM tag WHERE ` + anchor + `
+					UNION
+					SELECT tag.id FROM tag JOIN subtree ON tag.parent_id = subtree.id
+				)
+				SELECT ` + countedTagColumns + `
+				FROM tag ` + articleCounts + `
+				WHERE id IN (SELECT id FROM subtree)
+				ORDER BY name`
+
+	res, err = p.fetch(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if rootID != 0 && len(res) == 0 {
+		return nil, domain.ErrNotFound
+	}
+	return res, nil
+}
+
+// FetchByAlias implements domain.TagRepository.
+func (p *postgresqlTagRepo) FetchByAlias(ctx context.Context, alias string) (res domain.Tag, err error) {
+	query := `SELECT ` + tagColumns + `
+				FROM tag
+				WHERE id = (SELECT tag_id FROM tag_alias WHERE name_key = $1)`
+
+	list, err := p.fetch(ctx, query, fold.Key(alias))
 	if err != nil {
 		return
 	}
+
+	if len(list) == 0 {
+		return res, domain.ErrNotFound
+	}
+	return list[0], nil
+}
+
+// Suggest implements domain.TagRepository.
+func (p *postgresqlTagRepo) Suggest(ctx context.Context, prefix string, num int64) (res []domain.Tag, err error) {
+	// the prefix patterns use the varchar_pattern_ops indexes of the name keys
+	query := `SELECT id, name, slug, parent_id, description, color, icon, hidden, created_at, updated_at, article_count
+				FROM (SELECT ` + countedTagColumns + `,
+						CASE WHEN name_key = $1 THEN 0
+							WHEN id IN (SELECT tag_id FROM tag_alias WHERE name_key = $1) THEN 1
+							WHEN name_key LIKE $2 ESCAPE '!' THEN 2
+							ELSE 3 END AS match_rank
+					FROM tag ` + articleCounts + `
+					WHERE NOT hidden AND (name_key LIKE $2 ESCAPE '!'
+						OR id IN (SELECT tag_id FROM tag_alias WHERE name_key LIKE $2 ESCAPE '!'))) AS tags
+				ORDER BY match_rank, article_count DESC, name
+				LIMIT $3`
+
+	key := fold.Key(prefix)
+	return p.fetch(ctx, query, key, fold.LikePrefix(key), num)
+}
+
+// FetchAliases implements domain.TagRepository.
+func (p *postgresqlTagRepo) FetchAliases(ctx context.Context, tagID int64) (res []domain.TagAlias, err error) {
+	rows, err := p.Conn.QueryContext(ctx, "SELECT name, tag_id FROM tag_alias WHERE tag_id = $1 ORDER BY name", tagID)
+	if err != nil {
+		return nil, err
+	}
+	return repository.ScanAliases(rows)
+}
+
+// StoreAlias implements domain.TagRepository.
+func (p *postgresqlTagRepo) StoreAlias(ctx context.Context, a *domain.TagAlias) error {
+	_, err := p.Conn.ExecContext(ctx, "INSERT INTO tag_alias (name, name_key, tag_id) VALUES ($1, $2, $3)", a.Name, fold.Key(a.Name), a.TagID)
+	return translateError(err)
+}
+
+// DeleteAlias implements domain.TagRepository.
+func (p *postgresqlTagRepo) DeleteAlias(ctx context.Context, tagID int64, alias string) error {
+	res, err := p.Conn.ExecContext(ctx, "DELETE FROM tag_alias WHERE tag_id = $1 AND name_key = $2", tagID, fold.Key(alias))
+	if err != nil {
+		return err
+	}
+	affect, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affect == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// Store implements domain.TagRepository.
+func (p *postgresqlTagRepo) Store(ctx context.Context, t *domain.Tag) (err error) {
+	query := `INSERT INTO tag (name, name_key, slug, parent_id, description, color, icon, hidden, created_at, updated_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+				RETURNING id`
+	stmt, err := p.Conn.PrepareContext(ctx, query)
 	if err != nil {
 		return
 	}
+
+	err = stmt.QueryRowContext(ctx, t.Name, fold.Key(t.Name), repository.NullString(t.Slug), repository.NullID(t.ParentID), t.Description, t.Color, t.Icon, t.Hidden, t.CreatedAt, t.UpdatedAt).Scan(&t.ID)
+	if err != nil {
+		return translateError(err)
+	}
 	return
 }
 
 // Delete implements domain.TagRepository.
-func (p *postgresqlTagRepo) Delete(ctx context.Context, id int64) (err error) {
-	query := "DELETE FROM tag WHERE id = $1"
+func (p *postgresqlTagRepo) Delete(ctx context.Context, id int64) error {
+	return p.delete(ctx, id, nil, false)
+}
 
-	stmt, err := p.Conn.PrepareContext(ctx, query)
+// Reassign implements domain.TagRepository.
+func (p *postgresqlTagRepo) Reassign(ctx context.Context, id, to int64, keepAlias bool) error {
+	if to == id || (keepAlias && to == 0) {
+		return domain.ErrBadParamInput
+	}
+	return p.delete(ctx, id, &to, keepAlias)
+}
+
+// delete deletes the tag in a transaction, the articles move to the tag to or restrict the delete when to is nil
+func (p *postgresqlTagRepo) delete(ctx context.Context, id int64, to *int64, keepAlias bool) (err error) {
+	tx, err := p.Conn.BeginTx(ctx, nil)
 	if err != nil {
 		return
 	}
+	defer tx.Rollback() //nolint
 
-	res, err := stmt.ExecContext(ctx, id)
+	var name string
+	var parentID sql.NullInt64
+	err = tx.QueryRowContext(ctx, "SELECT name, parent_id FROM tag WHERE id = $1 FOR UPDATE", id).Scan(&name, &parentID)
+	if err == sql.ErrNoRows {
+		return domain.ErrNotFound
+	}
 	if err != nil {
 		return
 	}
 
-	rowsAfected, err := res.RowsAffected()
-	if err != nil {
+	if to == nil {
+		if err = inUse(ctx, tx, id); err != nil {
+			return
+		}
+	} else {
+		if *to != 0 {
+			// the tag the articles move to must outlive the transaction
+			err = tx.QueryRowContext(ctx, "SELECT id FROM tag WHERE id = $1 FOR UPDATE", *to).Scan(to)
+			if err == sql.ErrNoRows {
+				return domain.ErrNotFound
+			}
+			if err != nil {
+				return
+			}
+		}
+		if _, err = tx.ExecContext(ctx, "UPDATE article SET tag_id = $1, version = version + 1, updated_at = $2 WHERE tag_id = $3",
+			*to, time.Now(), id); err != nil {
+			return
+		}
+	}
+
+	if keepAlias {
+		if _, err = tx.ExecContext(ctx, "UPDATE tag_alias SET tag_id = $1 WHERE tag_id = $2", *to, id); err != nil {
+			return
+		}
+		if _, err = tx.ExecContext(ctx, "DELETE FROM tag_alias WHERE name_key = $1", fold.Key(name)); err != nil {
+			return
+		}
+		if _, err = tx.ExecContext(ctx, "INSERT INTO tag_alias (name, name_key, tag_id) VALUES ($1, $2, $3)", name, fold.Key(name), *to); err != nil {
+			return
+		}
+	} else if _, err = tx.ExecContext(ctx, "DELETE FROM tag_alias WHERE tag_id = $1", id); err != nil {
 		return
 	}
 
-	if rowsAfected != 1 {
-		err = fmt.Errorf("weird  Behavior. Total Affected: %d", rowsAfected)
+	// the child tags move up to the parent of the tag, or to the tag it merges into
+	newParentID := parentID
+	if keepAlias {
+		// a tag merged into one of its descendants leaves the descendant in its place
+		var below bool
+		if below, err = isBelow(ctx, tx, *to, id); err != nil {
+			return
+		}
+		if below {
+			if _, err = tx.ExecContext(ctx, "UPDATE tag SET parent_id = $1 WHERE id = $2", parentID, *to); err != nil {
+				return
+			}
+		}
+		newParentID = sql.NullInt64{Int64: *to, Valid: true}
+	}
+	if _, err = tx.ExecContext(ctx, "UPDATE tag SET parent_id = $1 WHERE parent_id = $2", newParentID, id); err != nil {
+		return
+	}
+	if _, err = tx.ExecContext(ctx, "DELETE FROM tag WHERE id = $1", id); err != nil {
 		return
 	}
 
-	return
+	return tx.Commit()
+}
+
+// isBelow reports whether the tag id is a descendant of the tag ancestor
+func isBelow(ctx context.Context, tx *sql.Tx, id, ancestor int64) (bool, error) {
+	// the visited tags are not walked again, a cycle in the data cannot loop forever
+	visited := make(map[int64]bool)
+	for id != 0 && !visited[id] {
+		visited[id] = true
+		var parentID sql.NullInt64
+		if err := tx.QueryRowContext(ctx, "SELECT parent_id FROM tag WHERE id = $1", id).Scan(&parentID); err != nil {
+			return false, err
+		}
+		if parentID.Valid && parentID.Int64 == ancestor {
+			return true, nil
+		}
+		id = parentID.Int64
+	}
+	return false, nil
+}
+
+// inUse returns a *domain.TagInUseError when articles still reference the tag
+func inUse(ctx context.Context, tx *sql.Tx, id int64) error {
+	var count int64
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM article WHERE tag_id = $1", id).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return nil
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT id, title, slug FROM article WHERE tag_id = $1 ORDER BY id LIMIT $2", id, repository.InUseLimit)
+	if err != nil {
+		return err
+	}
+	return repository.InUseError(rows, count)
 }
 
 // Update implements domain.TagRepository.
 func (p *postgresqlTagRepo) Update(ctx context.Context, t *domain.Tag) (err error) {
-	query := `UPDATE tag SET name=$1, updated_at=$2 WHERE id = $3;`
+	// an empty slug keeps the current one
+	query := `UPDATE tag SET name=$1, name_key=$2, slug=COALESCE($3, slug), parent_id=$4,
+				description=$5, color=$6, icon=$7, hidden=$8, updated_at=$9 WHERE id = $10;`
 
 	stmt, err := p.Conn.PrepareContext(ctx, query)
 	if err != nil {
 		return
 	}
 
-	res, err := stmt.ExecContext(ctx, t.Name, t.UpdatedAt, t.ID)
+	res, err := stmt.ExecContext(ctx, t.Name, fold.Key(t.Name), repository.NullString(t.Slug), repository.NullID(t.ParentID), t.Description, t.Color, t.Icon, t.Hidden, t.UpdatedAt, t.ID)
 	if err != nil {
-		return
+		return translateError(err)
 	}
 	affect, err := res.RowsAffected()
 	if err != nil {
 		return
 	}
+	if affect == 0 {
+		return domain.ErrNotFound
+	}
 	if affect != 1 {
 		err = fmt.Errorf("weird  Behavior. Total Affected: %d", affect)
 		return
@@ -215,64 +522,11 @@ func (p *postgresqlTagRepo) Update(ctx context.Context, t *domain.Tag) (err erro
 	return
 }
 
-// OLD IMPLEMENTATION
-// // Delete implements domain.TagRepository.
-// func (p *postgresqlTagRepository) Delete(tagId int) error {
-// 	var tag domain.Tag
-// 	result := p.Db.Where("id = ?", tagId).Delete(&tag)
-// 	// helper.ErrorPanic(result.Error)
-
-// 	if result.Error != nil {
-// 		return errors.New("failed to delete tag")
-// 	}
-// 	return nil
-
-// }
-
-// // Fetch implements domain.TagRepository.
-// func (p *postgresqlTagRepository) Fetch() ([]domain.Tag, error) {
-// 	var tags []domain.Tag
-// 	result := p.Db.Find(&tags)
-// 	// helper.ErrorPanic(result.Error)
-
-// 	if result.Error != nil {
-// 		return tags, errors.New("failed to get tags")
-// 	}
-// 	return tags, nil
-
-// }
-
-// // FetchByID implements domain.TagRepository.
-// func (p *postgresqlTagRepository) FetchByID(tagId int) (domain.Tag, error) {
-// 	var tag domain.Tag
-// 	result := p.Db.Find(&tag, tagId)
-
-// 	if result.Error != nil {
-// 		return tag, errors.New("tag not found")
-// 	}
-// 	return tag, nil
-// }
-
-// // Store implements domain.TagRepository.
-// func (p *postgresqlTagRepository) Store(t *domain.Tag) error {
-// 	result := p.Db.Create(t)
-
-// 	if result.Error != nil {
-// 		return errors.New("failed to create tag")
-// 	}
-// 	return nil
-// }
-
-// // Update implements domain.TagRepository.
-// func (p *postgresqlTagRepository) Update(t *domain.Tag) error {
-// 	var updateTag = domain.UpdateTagRequest{
-// 		ID:   t.ID,
-// 		Name: t.Name,
-// 	}
-// 	result := p.Db.Model(&t).Updates(updateTag)
-
-// 	if result.Error != nil {
-// 		return errors.New("failed to update tag")
-// 	}
-// 	return nil
-// }
+// translateError maps the postgresql constraint violations to the domain errors
+func translateError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return domain.ErrConflict
+	}
+	return err
+}
@@ -1 +1,67 @@
-package postgresql
+package postgresql_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	articlePostgresql "go-postgres-clean-arch/article/repository/postgresql"
+	"go-postgres-clean-arch/domain"
+	"go-postgres-clean-arch/migration"
+	"go-postgres-clean-arch/tag/repository/postgresql"
+	"go-postgres-clean-arch/tag/repository/repositorytest"
+
+	_ "github.com/lib/pq"
+	gormPostgres "gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// testDSN returns the database given in POSTGRES_TEST_DSN, the test is skipped when it is not set
+func testDSN(t *testing.T) string {
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_TEST_DSN is not set")
+	}
+	return dsn
+}
+
+// openTestDB connects to the test database and applies the migrations
+func openTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("postgres", testDSN(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err = migration.Up(context.Background(), db, "postgres"); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestPostgresqlTagRepositoryContract(t *testing.T) {
+	db := openTestDB(t)
+
+	repositorytest.RunTagContract(t, func(t *testing.T) (domain.TagRepository, domain.ArticleRepository) {
+		if _, err := db.Exec(`TRUNCATE tag, article, tag_alias RESTART IDENTITY`); err != nil {
+			t.Fatal(err)
+		}
+		return postgresql.NewPostgresqlTagRepository(db), articlePostgresql.NewPostgresqlArticleRepository(db)
+	})
+}
+
+func TestGormTagRepositoryContract(t *testing.T) {
+	db := openTestDB(t)
+	gormDB, err := gorm.Open(gormPostgres.Open(testDSN(t)), &gorm.Config{TranslateError: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repositorytest.RunTagContract(t, func(t *testing.T) (domain.TagRepository, domain.ArticleRepository) {
+		if _, err := db.Exec(`TRUNCATE tag, article, tag_alias RESTART IDENTITY`); err != nil {
+			t.Fatal(err)
+		}
+		return postgresql.NewGormTagRepository(gormDB), articlePostgresql.NewGormArticleRepository(gormDB)
+	})
+}
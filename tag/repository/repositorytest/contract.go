@@ -0,0 +1,642 @@
+// Package repositorytest holds the contract every domain.TagRepository implementation must satisfy.
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-postgres-clean-arch/domain"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RunTagContract runs the shared contract against the repositories built by newRepo,
+// newRepo is called once per subtest and must return a repository backed by an empty store,
+// with the article repository of the same store the article counts are checked against.
+func RunTagContract(t *testing.T, newRepo func(t *testing.T) (domain.TagRepository, domain.ArticleRepository)) {
+	base := time.Date(2023, 11, 30, 15, 20, 33, 682000, time.UTC)
+
+	store := func(t *testing.T, repo domain.TagRepository, name string, createdAt time.Time) *domain.Tag {
+		t.Helper()
+		tag := &domain.Tag{Name: name, Slug: name, CreatedAt: createdAt, UpdatedAt: createdAt}
+		if err := repo.Store(context.Background(), tag); err != nil {
+			t.Fatalf("Store(%q): %v", name, err)
+		}
+		return tag
+	}
+
+	t.Run("StoreAssignsIDAndFetchByID", func(t *testing.T) {
+		repo, _ := newRepo(t)
+		tag := store(t, repo, "go", base)
+		if tag.ID == 0 {
+			t.Fatal("Store did not assign an id")
+		}
+
+		got, err := repo.FetchByID(context.Background(), tag.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ID != tag.ID || got.Name != "go" || got.Slug != "go" {
+			t.Fatalf("FetchByID returned %+v, stored %+v", got, tag)
+		}
+		if !got.CreatedAt.Equal(base) || !got.UpdatedAt.Equal(base) {
+			t.Fatalf("timestamps not kept: %v %v", got.CreatedAt, got.UpdatedAt)
+		}
+	})
+
+	t.Run("FetchByName", func(t *testing.T) {
+		repo, _ := newRepo(t)
+		tag := store(t, repo, "postgres", base)
+
+		got, err := repo.FetchByName(context.Background(), "postgres")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ID != tag.ID {
+			t.Fatalf("FetchByName returned id %d, want %d", got.ID, tag.ID)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		repo, _ := newRepo(t)
+		ctx := context.Background()
+
+		if _, err := repo.FetchByID(ctx, 404); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("FetchByID: expected ErrNotFound, got %v", err)
+		}
+		if _, err := repo.FetchByName(ctx, "missing"); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("FetchByName: expected ErrNotFound, got %v", err)
+		}
+		if _, err := repo.FetchBySlug(ctx, "missing"); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("FetchBySlug: expected ErrNotFound, got %v", err)
+		}
+		if err := repo.Update(ctx, &domain.Tag{ID: 404, Name: "missing", UpdatedAt: base}); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("Update: expected ErrNotFound, got %v", err)
+		}
+		if err := repo.Delete(ctx, 404); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("Delete: expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("Conflict", func(t *testing.T) {
+		repo, _ := newRepo(t)
+		ctx := context.Background()
+		store(t, repo, "taken", base)
+		other := store(t, repo, "other", base.Add(time.Second))
+
+		if err := repo.Store(ctx, &domain.Tag{Name: "taken", CreatedAt: base, UpdatedAt: base}); !errors.Is(err, domain.ErrConflict) {
+			t.Errorf("Store: expected ErrConflict, got %v", err)
+		}
+		if err := repo.Update(ctx, &domain.Tag{ID: other.ID, Name: "taken", UpdatedAt: base}); !errors.Is(err, domain.ErrConflict) {
+			t.Errorf("Update: expected ErrConflict, got %v", err)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		repo, _ := newRepo(t)
+		ctx := context.Background()
+		tag := store(t, repo, "before", base)
+
+		updatedAt := base.Add(time.Hour)
+		if err := repo.Update(ctx, &domain.Tag{ID: tag.ID, Name: "after", UpdatedAt: updatedAt}); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := repo.FetchByID(ctx, tag.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Name != "after" || !got.UpdatedAt.Equal(updatedAt) || !got.CreatedAt.Equal(base) {
+			t.Fatalf("Update not applied: %+v", got)
+		}
+	})
+
+	t.Run("Slugs", func(t *testing.T) {
+		repo, _ := newRepo(t)
+		ctx := context.Background()
+		tag := store(t, repo, "go", base)
+		store(t, repo, "rust", base.Add(time.Second))
+
+		got, err := repo.FetchBySlug(ctx, "go")
+		if err != nil || got.ID != tag.ID {
+			t.Fatalf("FetchBySlug returned %+v %v", got, err)
+		}
+		if err := repo.Store(ctx, &domain.Tag{Name: "other", Slug: "go", CreatedAt: base, UpdatedAt: base}); !errors.Is(err, domain.ErrConflict) {
+			t.Errorf("Store: expected ErrConflict, got %v", err)
+		}
+		if err := repo.Update(ctx, &domain.Tag{ID: tag.ID, Name: "go", Slug: "rust", UpdatedAt: base}); !errors.Is(err, domain.ErrConflict) {
+			t.Errorf("Update: expected ErrConflict, got %v", err)
+		}
+
+		// an update without slug keeps the current one
+		if err := repo.Update(ctx, &domain.Tag{ID: tag.ID, Name: "golang", UpdatedAt: base}); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ = repo.FetchByID(ctx, tag.ID); got.Slug != "go" {
+			t.Fatalf("slug not kept: %q", got.Slug)
+		}
+
+		if err := repo.Update(ctx, &domain.Tag{ID: tag.ID, Name: "golang", Slug: "golang", UpdatedAt: base}); err != nil {
+			t.Fatal(err)
+		}
+		if got, err = repo.FetchBySlug(ctx, "golang"); err != nil || got.ID != tag.ID {
+			t.Fatalf("FetchBySlug returned %+v %v", got, err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		repo, _ := newRepo(t)
+		ctx := context.Background()
+		tag := store(t, repo, "doomed", base)
+
+		if err := repo.Delete(ctx, tag.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.FetchByID(ctx, tag.ID); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound after Delete, got %v", err)
+		}
+	})
+
+	t.Run("DeleteRestrict", func(t *testing.T) {
+		repo, articles := newRepo(t)
+		ctx := context.Background()
+		tag := store(t, repo, "used", base)
+		a := &domain.CreateArticleInput{Title: "referencing", Content: "c", TagID: tag.ID, CreatedAt: base, UpdatedAt: base}
+		if err := articles.Store(ctx, a); err != nil {
+			t.Fatal(err)
+		}
+
+		err := repo.Delete(ctx, tag.ID)
+		var inUse *domain.TagInUseError
+		if !errors.As(err, &inUse) || !errors.Is(err, domain.ErrConflict) {
+			t.Fatalf("expected a TagInUseError, got %v", err)
+		}
+		if inUse.ArticleCount != 1 || len(inUse.Articles) != 1 || inUse.Articles[0].ID != a.ID || inUse.Articles[0].Title != "referencing" {
+			t.Fatalf("unexpected conflict %+v", inUse)
+		}
+		if _, err = repo.FetchByID(ctx, tag.ID); err != nil {
+			t.Fatalf("the restricted tag is gone: %v", err)
+		}
+		if err = repo.Delete(ctx, 999); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound for an unknown tag, got %v", err)
+		}
+	})
+
+	t.Run("Reassign", func(t *testing.T) {
+		repo, articles := newRepo(t)
+		ctx := context.Background()
+		from := store(t, repo, "from", base)
+		to := store(t, repo, "to", base.Add(time.Second))
+		for i := 0; i < 2; i++ {
+			a := &domain.CreateArticleInput{Title: fmt.Sprintf("moved %d", i), Content: "c", TagID: from.ID, CreatedAt: base, UpdatedAt: base}
+			if err := articles.Store(ctx, a); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := repo.Reassign(ctx, from.ID, 999, false); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound for an unknown target, got %v", err)
+		}
+		if err := repo.Reassign(ctx, from.ID, from.ID, false); !errors.Is(err, domain.ErrBadParamInput) {
+			t.Fatalf("expected ErrBadParamInput for the tag itself, got %v", err)
+		}
+		if err := repo.Reassign(ctx, from.ID, to.ID, false); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.FetchByID(ctx, from.ID); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound after Reassign, got %v", err)
+		}
+		count, err := repo.ArticleCount(ctx, to.ID)
+		if err != nil || count != 2 {
+			t.Fatalf("ArticleCount returned %d %v, want 2 articles", count, err)
+		}
+		if _, err = repo.FetchByAlias(ctx, "from"); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("a reassigned tag left an alias: %v", err)
+		}
+		// the moved articles change version so their cached copies go stale
+		moved, _, err := articles.FetchFiltered(ctx, domain.ArticleFilter{TagID: to.ID}, "", 10)
+		if err != nil || len(moved) != 2 {
+			t.Fatalf("FetchFiltered returned %+v %v, want the moved articles", moved, err)
+		}
+		for _, m := range moved {
+			if m.Version != 2 || !m.UpdatedAt.After(base) {
+				t.Fatalf("moved article %d has version %d updated at %v, want version 2 updated after %v", m.ID, m.Version, m.UpdatedAt, base)
+			}
+		}
+
+		// detaching leaves the articles with tag id 0
+		if err = repo.Reassign(ctx, to.ID, 0, false); err != nil {
+			t.Fatal(err)
+		}
+		res, _, err := articles.FetchFiltered(ctx, domain.ArticleFilter{}, "", 10)
+		if err != nil || len(res) != 2 || res[0].Tag.ID != 0 || res[1].Tag.ID != 0 {
+			t.Fatalf("FetchFiltered returned %+v %v, want detached articles", res, err)
+		}
+	})
+
+	t.Run("MergeKeepsAlias", func(t *testing.T) {
+		repo, articles := newRepo(t)
+		ctx := context.Background()
+		golang := store(t, repo, "golang", base)
+		gopher := store(t, repo, "gopher", base.Add(time.Second))
+		goTag := store(t, repo, "go", base.Add(2*time.Second))
+		a := &domain.CreateArticleInput{Title: "merged", Content: "c", TagID: golang.ID, CreatedAt: base, UpdatedAt: base}
+		if err := articles.Store(ctx, a); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := repo.Reassign(ctx, golang.ID, 0, true); !errors.Is(err, domain.ErrBadParamInput) {
+			t.Fatalf("expected ErrBadParamInput for an alias of no tag, got %v", err)
+		}
+		if err := repo.Reassign(ctx, golang.ID, gopher.ID, true); err != nil {
+			t.Fatal(err)
+		}
+		// the aliases of a merged tag follow it
+		if err := repo.Reassign(ctx, gopher.ID, goTag.ID, true); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, alias := range []string{"golang", "gopher"} {
+			got, err := repo.FetchByAlias(ctx, alias)
+			if err != nil || got.ID != goTag.ID {
+				t.Fatalf("FetchByAlias(%q) returned %+v %v", alias, got, err)
+			}
+		}
+		if count, err := repo.ArticleCount(ctx, goTag.ID); err != nil || count != 1 {
+			t.Fatalf("ArticleCount returned %d %v, want the article of the merged tag", count, err)
+		}
+		if _, err := repo.FetchByAlias(ctx, "go"); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound for a name that is no alias, got %v", err)
+		}
+
+		// deleting the tag drops its aliases
+		if err := repo.Reassign(ctx, goTag.ID, 0, false); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.FetchByAlias(ctx, "golang"); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound for the alias of a deleted tag, got %v", err)
+		}
+	})
+
+	t.Run("NameKeys", func(t *testing.T) {
+		repo, _ := newRepo(t)
+		ctx := context.Background()
+		dessert := store(t, repo, "Crème Brûlée", base)
+		other := store(t, repo, "pie", base.Add(time.Second))
+
+		for _, name := range []string{"creme brulee", "CRÈME  brûlée"} {
+			got, err := repo.FetchByName(ctx, name)
+			if err != nil || got.ID != dessert.ID {
+				t.Fatalf("FetchByName(%q) returned %+v %v", name, got, err)
+			}
+		}
+		if err := repo.Store(ctx, &domain.Tag{Name: "creme brulee", CreatedAt: base, UpdatedAt: base}); !errors.Is(err, domain.ErrConflict) {
+			t.Fatalf("Store: expected ErrConflict for the same key, got %v", err)
+		}
+		if err := repo.Update(ctx, &domain.Tag{ID: other.ID, Name: "CREME BRULEE", UpdatedAt: base}); !errors.Is(err, domain.ErrConflict) {
+			t.Fatalf("Update: expected ErrConflict for the same key, got %v", err)
+		}
+
+		if err := repo.StoreAlias(ctx, &domain.TagAlias{Name: "Flan", TagID: dessert.ID}); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.StoreAlias(ctx, &domain.TagAlias{Name: "FLAN", TagID: other.ID}); !errors.Is(err, domain.ErrConflict) {
+			t.Fatalf("StoreAlias: expected ErrConflict for the same key, got %v", err)
+		}
+		if got, err := repo.FetchByAlias(ctx, "flan"); err != nil || got.ID != dessert.ID {
+			t.Fatalf("FetchByAlias returned %+v %v", got, err)
+		}
+		aliases, err := repo.FetchAliases(ctx, dessert.ID)
+		if err != nil || len(aliases) != 1 || aliases[0] != (domain.TagAlias{Name: "Flan", TagID: dessert.ID}) {
+			t.Fatalf("FetchAliases returned %+v %v", aliases, err)
+		}
+
+		if err := repo.DeleteAlias(ctx, other.ID, "flan"); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("DeleteAlias: expected ErrNotFound for the alias of another tag, got %v", err)
+		}
+		if err := repo.DeleteAlias(ctx, dessert.ID, "FLAN"); err != nil {
+			t.Fatal(err)
+		}
+		if aliases, err = repo.FetchAliases(ctx, dessert.ID); err != nil || len(aliases) != 0 {
+			t.Fatalf("FetchAliases returned %+v %v after the delete", aliases, err)
+		}
+	})
+
+	t.Run("Metadata", func(t *testing.T) {
+		repo, _ := newRepo(t)
+		ctx := context.Background()
+		chip := &domain.Tag{Name: "go", Description: "The Go language", Color: "#00add8", Icon: "gopher", CreatedAt: base, UpdatedAt: base}
+		if err := repo.Store(ctx, chip); err != nil {
+			t.Fatal(err)
+		}
+		hidden := &domain.Tag{Name: "golang-internal", Hidden: true, CreatedAt: base.Add(time.Second), UpdatedAt: base.Add(time.Second)}
+		if err := repo.Store(ctx, hidden); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := repo.FetchByID(ctx, chip.ID)
+		if err != nil || got.Description != chip.Description || got.Color != chip.Color || got.Icon != chip.Icon || got.Hidden {
+			t.Fatalf("FetchByID returned %+v %v", got, err)
+		}
+		if got, err = repo.FetchByID(ctx, hidden.ID); err != nil || !got.Hidden {
+			t.Fatalf("FetchByID returned %+v %v for the hidden tag", got, err)
+		}
+
+		listed := func(filter domain.TagFilter) int {
+			t.Helper()
+			res, _, err := repo.FetchFiltered(ctx, filter, "", 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return len(res)
+		}
+		for _, sort := range []domain.TagSort{"", domain.TagSortPopular} {
+			if n := listed(domain.TagFilter{Sort: sort}); n != 1 {
+				t.Errorf("sort %q listed %d tags, want the visible one", sort, n)
+			}
+			if n := listed(domain.TagFilter{Sort: sort, IncludeHidden: true}); n != 2 {
+				t.Errorf("sort %q listed %d tags with the hidden ones, want 2", sort, n)
+			}
+		}
+		if res, _, err := repo.Fetch(ctx, "", 10); err != nil || len(res) != 2 {
+			t.Errorf("Fetch returned %d tags %v, want every tag", len(res), err)
+		}
+		if total, err := repo.Count(ctx, domain.TagFilter{}); err != nil || total != 1 {
+			t.Errorf("Count returned %d %v, want the visible tag", total, err)
+		}
+		if total, err := repo.Count(ctx, domain.TagFilter{IncludeHidden: true}); err != nil || total != 2 {
+			t.Errorf("Count returned %d %v with the hidden tags, want 2", total, err)
+		}
+		if res, err := repo.Suggest(ctx, "go", 10); err != nil || len(res) != 1 || res[0].ID != chip.ID {
+			t.Errorf("Suggest returned %+v %v, want the visible tag", res, err)
+		}
+
+		chip.Description, chip.Color, chip.Icon, chip.Hidden = "", "", "", true
+		chip.UpdatedAt = base.Add(time.Hour)
+		if err = repo.Update(ctx, chip); err != nil {
+			t.Fatal(err)
+		}
+		if got, err = repo.FetchByID(ctx, chip.ID); err != nil || got.Description != "" || got.Color != "" || got.Icon != "" || !got.Hidden {
+			t.Fatalf("FetchByID returned %+v %v after the update", got, err)
+		}
+	})
+
+	t.Run("Suggest", func(t *testing.T) {
+		repo, articles := newRepo(t)
+		ctx := context.Background()
+		golang := store(t, repo, "golang", base)
+		goTag := store(t, repo, "Go", base.Add(time.Second))
+		gopher := store(t, repo, "gopher", base.Add(2*time.Second))
+		rust := store(t, repo, "rust", base.Add(3*time.Second))
+		for i, tagID := range []int64{golang.ID, golang.ID, gopher.ID} {
+			a := &domain.CreateArticleInput{Title: fmt.Sprintf("article %d", i), Content: "c", TagID: tagID, CreatedAt: base, UpdatedAt: base}
+			if err := articles.Store(ctx, a); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := repo.StoreAlias(ctx, &domain.TagAlias{Name: "gorust", TagID: rust.ID}); err != nil {
+			t.Fatal(err)
+		}
+
+		names := func(prefix string, num int64) string {
+			t.Helper()
+			tags, err := repo.Suggest(ctx, prefix, num)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var res []string
+			for _, tag := range tags {
+				res = append(res, tag.Name)
+			}
+			return strings.Join(res, " ")
+		}
+
+		// the exact name, then the name prefixes by article count, then the alias prefixes
+		for prefix, want := range map[string]string{"go": "Go golang gopher rust", "GÖL": "golang", "gorust": "rust", "g_": "", "java": ""} {
+			if got := names(prefix, 10); got != want {
+				t.Errorf("Suggest(%q) = %q, want %q", prefix, got, want)
+			}
+		}
+		if got := names("go", 2); got != goTag.Name+" "+golang.Name {
+			t.Errorf("Suggest limited to 2 = %q", got)
+		}
+	})
+
+	t.Run("Tree", func(t *testing.T) {
+		repo, articles := newRepo(t)
+		ctx := context.Background()
+		programming := store(t, repo, "programming", base)
+		goTag := &domain.Tag{Name: "go", ParentID: programming.ID, CreatedAt: base, UpdatedAt: base}
+		if err := repo.Store(ctx, goTag); err != nil {
+			t.Fatal(err)
+		}
+		concurrency := &domain.Tag{Name: "concurrency", ParentID: goTag.ID, CreatedAt: base, UpdatedAt: base}
+		if err := repo.Store(ctx, concurrency); err != nil {
+			t.Fatal(err)
+		}
+		store(t, repo, "cooking", base)
+		a := &domain.CreateArticleInput{Title: "channels", Content: "c", TagID: concurrency.ID, CreatedAt: base, UpdatedAt: base}
+		if err := articles.Store(ctx, a); err != nil {
+			t.Fatal(err)
+		}
+
+		names := func(tags []domain.Tag) string {
+			var res []string
+			for _, tag := range tags {
+				res = append(res, fmt.Sprintf("%s<%d", tag.Name, tag.ParentID))
+			}
+			return strings.Join(res, ",")
+		}
+		tree, err := repo.FetchTree(ctx, goTag.ID)
+		if err != nil || names(tree) != fmt.Sprintf("concurrency<%d,go<%d", goTag.ID, programming.ID) {
+			t.Fatalf("FetchTree returned %s %v", names(tree), err)
+		}
+		if tree[0].ArticleCount != 1 {
+			t.Fatalf("FetchTree counted %d articles", tree[0].ArticleCount)
+		}
+		if tree, err = repo.FetchTree(ctx, 0); err != nil || len(tree) != 4 {
+			t.Fatalf("FetchTree of the roots returned %s %v", names(tree), err)
+		}
+		if _, err = repo.FetchTree(ctx, 999); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound for an unknown root, got %v", err)
+		}
+
+		// moving a tag to the roots and deleting its parent
+		goTag.ParentID = 0
+		goTag.UpdatedAt = base.Add(time.Second)
+		if err = repo.Update(ctx, goTag); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := repo.FetchByID(ctx, goTag.ID); err != nil || got.ParentID != 0 {
+			t.Fatalf("FetchByID returned %+v %v, want a root tag", got, err)
+		}
+		if err = repo.Reassign(ctx, goTag.ID, 0, false); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := repo.FetchByID(ctx, concurrency.ID); err != nil || got.ParentID != 0 {
+			t.Fatalf("FetchByID returned %+v %v, want the child of a deleted root to be a root", got, err)
+		}
+	})
+
+	t.Run("MergeAdoptsChildren", func(t *testing.T) {
+		repo, _ := newRepo(t)
+		ctx := context.Background()
+		child := func(name string, parentID int64) *domain.Tag {
+			t.Helper()
+			tag := &domain.Tag{Name: name, ParentID: parentID, CreatedAt: base, UpdatedAt: base}
+			if err := repo.Store(ctx, tag); err != nil {
+				t.Fatal(err)
+			}
+			return tag
+		}
+		parentOf := func(tag *domain.Tag) int64 {
+			t.Helper()
+			got, err := repo.FetchByID(ctx, tag.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return got.ParentID
+		}
+		programming := store(t, repo, "programming", base)
+		golang := child("golang", programming.ID)
+		generics := child("generics", golang.ID)
+		languages := store(t, repo, "languages", base)
+		goTag := child("go", languages.ID)
+
+		// the children of a merged tag follow it, a delete moves them up to the parent instead
+		if err := repo.Reassign(ctx, golang.ID, goTag.ID, true); err != nil {
+			t.Fatal(err)
+		}
+		if got := parentOf(generics); got != goTag.ID {
+			t.Fatalf("the child of a merged tag has parent %d, want %d", got, goTag.ID)
+		}
+
+		// a tag merged into its descendant leaves the descendant in its place
+		modules := child("modules", languages.ID)
+		if err := repo.Reassign(ctx, languages.ID, generics.ID, true); err != nil {
+			t.Fatal(err)
+		}
+		for _, tag := range []*domain.Tag{goTag, modules} {
+			if got := parentOf(tag); got != generics.ID {
+				t.Fatalf("the child %q of a merged tag has parent %d, want %d", tag.Name, got, generics.ID)
+			}
+		}
+		if got := parentOf(generics); got != 0 {
+			t.Fatalf("the tag merged into has parent %d, want the root of the merged tag", got)
+		}
+	})
+
+	t.Run("FetchCursor", func(t *testing.T) {
+		repo, _ := newRepo(t)
+		ctx := context.Background()
+		for i := 0; i < 5; i++ {
+			store(t, repo, fmt.Sprintf("tag %d", i), base.Add(time.Duration(i)*time.Minute))
+		}
+
+		var names []string
+		cursor := ""
+		for page := 0; ; page++ {
+			if page > 5 {
+				t.Fatal("pagination does not terminate")
+			}
+			res, next, err := repo.Fetch(ctx, cursor, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, tag := range res {
+				names = append(names, tag.Name)
+			}
+			if next == "" {
+				if len(res) == 2 {
+					t.Fatalf("full page %d returned no cursor", page)
+				}
+				break
+			}
+			cursor = next
+		}
+
+		want := "[tag 0 tag 1 tag 2 tag 3 tag 4]"
+		if fmt.Sprint(names) != want {
+			t.Fatalf("Fetch walked %v, want %s", names, want)
+		}
+	})
+
+	t.Run("ArticleCounts", func(t *testing.T) {
+		repo, articles := newRepo(t)
+		ctx := context.Background()
+		tags := []*domain.Tag{
+			store(t, repo, "once", base),
+			store(t, repo, "unused", base.Add(time.Second)),
+			store(t, repo, "twice", base.Add(2*time.Second)),
+			store(t, repo, "also once", base.Add(3*time.Second)),
+		}
+		for i, tagID := range []int64{tags[0].ID, tags[2].ID, tags[2].ID, tags[3].ID} {
+			a := &domain.CreateArticleInput{Title: fmt.Sprintf("article %d", i), Content: "c", TagID: tagID, CreatedAt: base, UpdatedAt: base}
+			if err := articles.Store(ctx, a); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// the single tag fetches leave the count to ArticleCount
+		got, err := repo.FetchByID(ctx, tags[2].ID)
+		if err != nil || got.ArticleCount != 0 {
+			t.Fatalf("FetchByID returned %+v %v, want no count", got, err)
+		}
+		count, err := repo.ArticleCount(ctx, tags[2].ID)
+		if err != nil || count != 2 {
+			t.Fatalf("ArticleCount returned %d %v, want 2 articles", count, err)
+		}
+		if count, err = repo.ArticleCount(ctx, tags[1].ID); err != nil || count != 0 {
+			t.Fatalf("ArticleCount returned %d %v for an unused tag", count, err)
+		}
+		res, _, err := repo.Fetch(ctx, "", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var counts []int64
+		for _, tag := range res {
+			counts = append(counts, tag.ArticleCount)
+		}
+		if fmt.Sprint(counts) != "[1 0 2 1]" {
+			t.Fatalf("Fetch counted %v", counts)
+		}
+
+		// the ties keep the id order
+		var names []string
+		cursor := ""
+		for page := 0; ; page++ {
+			if page > 4 {
+				t.Fatal("pagination does not terminate")
+			}
+			res, next, err := repo.FetchFiltered(ctx, domain.TagFilter{Sort: domain.TagSortPopular}, cursor, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, tag := range res {
+				names = append(names, tag.Name)
+			}
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+		if fmt.Sprint(names) != "[twice once also once unused]" {
+			t.Fatalf("FetchFiltered popular walked %v", names)
+		}
+
+		if _, _, err := repo.FetchFiltered(ctx, domain.TagFilter{Sort: domain.TagSortPopular}, "not a cursor", 3); !errors.Is(err, domain.ErrBadParamInput) {
+			t.Errorf("expected ErrBadParamInput for an invalid cursor, got %v", err)
+		}
+		if _, _, err := repo.FetchFiltered(ctx, domain.TagFilter{Sort: "name"}, "", 3); !errors.Is(err, domain.ErrBadParamInput) {
+			t.Errorf("expected ErrBadParamInput for an unknown sort, got %v", err)
+		}
+	})
+
+	t.Run("FetchInvalidCursor", func(t *testing.T) {
+		repo, _ := newRepo(t)
+		if _, _, err := repo.Fetch(context.Background(), "not a cursor", 10); !errors.Is(err, domain.ErrBadParamInput) {
+			t.Fatalf("expected ErrBadParamInput, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,531 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-postgres-clean-arch/domain"
+	"go-postgres-clean-arch/helper/fold"
+	"go-postgres-clean-arch/tag/repository"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// tagColumns selects a tag without counting its articles, the article_count is zero
+const tagColumns = `id, name, slug, parent_id, description, color, icon, hidden, created_at, updated_at, 0 AS article_count`
+
+// countedTagColumns selects a tag with its number of articles, the query joins articleCounts
+const countedTagColumns = `id, name, slug, parent_id, description, color, icon, hidden, created_at, updated_at,
+	COALESCE(article_counts.article_count, 0) AS article_count`
+
+// articleCounts joins the number of articles of the tags, counted in one grouped scan of the article table
+const articleCounts = `LEFT JOIN (SELECT tag_id, COUNT(*) AS article_count FROM article GROUP BY tag_id) AS article_counts
+	ON article_counts.tag_id = tag.id`
+
+// timestampFormat is fixed width so the text columns compare in chronological order
+const timestampFormat = "2006-01-02T15:04:05.000000Z"
+
+type sqliteTagRepo struct {
+	Conn *sql.DB
+}
+
+// NewSqliteTagRepository will create an object that represent the tag.Repository interface.
+// The schema is created by the sqlite migrations of the migration package.
+func NewSqliteTagRepository(conn *sql.DB) domain.TagRepository {
+	return &sqliteTagRepo{Conn: conn}
+}
+
+func (p *sqliteTagRepo) fetch(ctx context.Context, query string, args ...interface{}) (result []domain.Tag, err error) {
+	rows, err := p.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(errRow)
+		}
+	}()
+
+	result = make([]domain.Tag, 0)
+	for rows.Next() {
+		t := domain.Tag{}
+		var createdAt, updatedAt string
+		slug := sql.NullString{}
+		parentID := sql.NullInt64{}
+		err = rows.Scan(
+			&t.ID,
+			&t.Name,
+			&slug,
+			&parentID,
+			&t.Description,
+			&t.Color,
+			&t.Icon,
+			&t.Hidden,
+			&createdAt,
+			&updatedAt,
+			&t.ArticleCount,
+		)
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+
+		if t.CreatedAt, err = parseTimestamp(createdAt); err != nil {
+			return nil, err
+		}
+		if t.UpdatedAt, err = parseTimestamp(updatedAt); err != nil {
+			return nil, err
+		}
+		t.Slug = slug.String
+		t.ParentID = parentID.Int64
+		result = append(result, t)
+	}
+
+	return result, rows.Err()
+}
+
+// Fetch implements domain.TagRepository.
+func (p *sqliteTagRepo) Fetch(ctx context.Context, cursor string, num int64) (res []domain.Tag, nextCursor string, err error) {
+	return p.fetchCreated(ctx, cursor, num, true)
+}
+
+// fetchCreated lists the tags by creation time, the hidden tags only with includeHidden
+func (p *sqliteTagRepo) fetchCreated(ctx context.Context, cursor string, num int64, includeHidden bool) (res []domain.Tag, nextCursor string, err error) {
+	visible := repository.VisibleCondition(includeHidden)
+	if cursor != "" {
+		query := `SELECT ` + countedTagColumns + `
+					FROM tag ` + articleCounts + `
+					WHERE ` + visible + ` AND created_at > ?
+					ORDER BY created_at
+					LIMIT ?`
+
+		decodedCursor, err := repository.DecodeCursor(cursor)
+		if err != nil {
+			return nil, "", domain.ErrBadParamInput
+		}
+
+		res, err = p.fetch(ctx, query, formatTimestamp(decodedCursor), num)
+		if err != nil {
+			return nil, "", err
+		}
+	} else {
+		query := `SELECT ` + countedTagColumns + `
+					FROM tag ` + articleCounts + `
+					WHERE ` + visible + `
+					ORDER BY created_at
+					LIMIT ?`
+
+		res, err = p.fetch(ctx, query, num)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	if len(res) == int(num) {
+		nextCursor = repository.EncodeCursor(res[len(res)-1].CreatedAt)
+	}
+
+	return
+}
+
+// FetchFiltered implements domain.TagRepository.
+func (p *sqliteTagRepo) FetchFiltered(ctx context.Context, filter domain.TagFilter, cursor string, num int64) (res []domain.Tag, nextCursor string, err error) {
+	switch filter.Sort {
+	case "":
+		return p.fetchCreated(ctx, cursor, num, filter.IncludeHidden)
+	case domain.TagSortPopular:
+		return p.fetchPopular(ctx, cursor, num, filter.IncludeHidden)
+	default:
+		return nil, "", domain.ErrBadParamInput
+	}
+}
+
+// fetchPopular lists the tags with the most articles first, the id breaks the ties
+func (p *sqliteTagRepo) fetchPopular(ctx context.Context, cursor string, num int64, includeHidden bool) (res []domain.Tag, nextCursor string, err error) {
+	visible := repository.VisibleCondition(includeHidden)
+	if cursor != "" {
+		c, err := repository.DecodePopularCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+
+		query := `SELECT id, name, slug, parent_id, description, color, icon, hidden, created_at, updated_at, article_count
+					FROM (SELECT ` + countedTagColumns + ` FROM tag ` + articleCounts + ` WHERE ` + visible + `) AS tags
+					WHERE article_count < ? OR (article_count = ? AND id > ?)
+					ORDER BY article_count DESC, id
+					LIMIT ?`
+		res, err = p.fetch(ctx, query, c.Count, c.Count, c.ID, num)
+		if err != nil {
+			return nil, "", err
+		}
+	} else {
+		query := `SELECT id, name, slug, parent_id, description, color, icon, hidden, created_at, updated_at, article_count
+					FROM (SELECT ` + countedTagColumns + ` FROM tag ` + articleCounts + ` WHERE ` + visible + `) AS tags
+					ORDER BY article_count DESC, id
+					LIMIT ?`
+		res, err = p.fetch(ctx, query, num)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	if len(res) == int(num) {
+		nextCursor = repository.EncodePopularCursor(res[len(res)-1])
+	}
+
+	return
+}
+
+// Count implements domain.TagRepository.
+func (p *sqliteTagRepo) Count(ctx context.Context, filter domain.TagFilter) (total int64, err error) {
+	query := `SELECT COUNT(*) FROM tag WHERE ` + repository.VisibleCondition(filter.IncludeHidden)
+	err = p.Conn.QueryRowContext(ctx, query).Scan(&total)
+	return
+}
+
+// FetchByID implements domain.TagRepository.
+func (p *sqliteTagRepo) FetchByID(ctx context.Context, id int64) (res domain.Tag, err error) {
+	query := `SELECT ` + tagColumns + `
+				FROM tag
+				WHERE id = ?`
+
+	list, err := p.fetch(ctx, query, id)
+	if err != nil {
+		return domain.Tag{}, err
+	}
+
+	if len(list) > 0 {
+		res = list[0]
+	} else {
+		return res, domain.ErrNotFound
+	}
+
+	return
+}
+
+// FetchByName implements domain.TagRepository.
+func (p *sqliteTagRepo) FetchByName(ctx context.Context, name string) (res domain.Tag, err error) {
+	query := `SELECT ` + tagColumns + `
+				FROM tag
+				WHERE name_key = ?`
+
+	list, err := p.fetch(ctx, query, fold.Key(name))
+	if err != nil {
+		return
+	}
+
+	if len(list) > 0 {
+		res = list[0]
+	} else {
+		return res, domain.ErrNotFound
+	}
+
+	return
+}
+
+// FetchBySlug implements domain.TagRepository.
+func (p *sqliteTagRepo) FetchBySlug(ctx context.Context, slug string) (res domain.Tag, err error) {
+	query := `SELECT ` + tagColumns + `
+				FROM tag
+				WHERE slug = ?`
+
+	list, err := p.fetch(ctx, query, slug)
+	if err != nil {
+		return
+	}
+
+	if len(list) == 0 {
+		return res, domain.ErrNotFound
+	}
+	return list[0], nil
+}
+
+// ArticleCount implements domain.TagRepository.
+func (p *sqliteTagRepo) ArticleCount(ctx context.Context, id int64) (count int64, err error) {
+	err = p.Conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM article WHERE tag_id = ?", id).Scan(&count)
+	return
+}
+
+// FetchTree implements domain.TagRepository.
+func (p *sqliteTagRepo) FetchTree(ctx context.Context, rootID int64) (res []domain.Tag, err error) {
+	anchor, args := "parent_id IS NULL", []interface{}{}
+	if rootID != 0 {
+		anchor, args = "id = ?", append(args, rootID)
+	}
+	// UNION drops the tags already visited, a cycle in the data cannot recurse forever
+	query := `WITH RECURSIVE subtree (id) AS (
+					SELECT id FROM tag WHERE ` + anchor + `
+					UNION
+					SELECT tag.id FROM tag JOIN subtree ON tag.parent_id = subtree.id
+				)
+				SELECT ` + countedTagColumns + `
+				FROM tag ` + articleCounts + `
+				WHERE id IN (SELECT id FROM subtree)
+				ORDER BY name`
+
+	res, err = p.fetch(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if rootID != 0 && len(res) == 0 {
+		return nil, domain.ErrNotFound
+	}
+	return res, nil
+}
+
+// FetchByAlias implements domain.TagRepository.
+func (p *sqliteTagRepo) FetchByAlias(ctx context.Context, alias string) (res domain.Tag, err error) {
+	query := `SELECT ` + tagColumns + `
+				FROM tag
+				WHERE id = (SELECT tag_id FROM tag_alias WHERE name_key = ?)`
+
+	list, err := p.fetch(ctx, query, fold.Key(alias))
+	if err != nil {
+		return
+	}
+
+	if len(list) == 0 {
+		return res, domain.ErrNotFound
+	}
+	return list[0], nil
+}
+
+// Suggest implements domain.TagRepository.
+func (p *sqliteTagRepo) Suggest(ctx context.Context, prefix string, num int64) (res []domain.Tag, err error) {
+	query := `SELECT id, name, slug, parent_id, description, color, icon, hidden, created_at, updated_at, article_count
+				FROM (SELECT ` + countedTagColumns + `,
+						CASE WHEN name_key = ? THEN 0
+							WHEN id IN (SELECT tag_id FROM tag_alias WHERE name_key = ?) THEN 1
+							WHEN name_key LIKE ? ESCAPE '!' THEN 2
+							ELSE 3 END AS match_rank
+					FROM tag ` + articleCounts + `
+					WHERE NOT hidden AND (name_key LIKE ? ESCAPE '!'
+						OR id IN (SELECT tag_id FROM tag_alias WHERE name_key LIKE ? ESCAPE '!'))) AS tags
+				ORDER BY match_rank, article_count DESC, name
+				LIMIT ?`
+
+	key := fold.Key(prefix)
+	like := fold.LikePrefix(key)
+	return p.fetch(ctx, query, key, key, like, like, like, num)
+}
+
+// FetchAliases implements domain.TagRepository.
+func (p *sqliteTagRepo) FetchAliases(ctx context.Context, tagID int64) (res []domain.TagAlias, err error) {
+	rows, err := p.Conn.QueryContext(ctx, "SELECT name, tag_id FROM tag_alias WHERE tag_id = ? ORDER BY name", tagID)
+	if err != nil {
+		return nil, err
+	}
+	return repository.ScanAliases(rows)
+}
+
+// StoreAlias implements domain.TagRepository.
+func (p *sqliteTagRepo) StoreAlias(ctx context.Context, a *domain.TagAlias) error {
+	_, err := p.Conn.ExecContext(ctx, "INSERT INTO tag_alias (name, name_key, tag_id) VALUES (?, ?, ?)", a.Name, fold.Key(a.Name), a.TagID)
+	return translateError(err)
+}
+
+// DeleteAlias implements domain.TagRepository.
+func (p *sqliteTagRepo) DeleteAlias(ctx context.Context, tagID int64, alias string) error {
+	res, err := p.Conn.ExecContext(ctx, "DELETE FROM tag_alias WHERE tag_id = ? AND name_key = ?", tagID, fold.Key(alias))
+	if err != nil {
+		return err
+	}
+	affect, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affect == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// Store implements domain.TagRepository.
+func (p *sqliteTagRepo) Store(ctx context.Context, t *domain.Tag) (err error) {
+	query := `INSERT INTO tag (name, name_key, slug, parent_id, description, color, icon, hidden, created_at, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	res, err := p.Conn.ExecContext(ctx, query, t.Name, fold.Key(t.Name), repository.NullString(t.Slug), repository.NullID(t.ParentID), t.Description, t.Color, t.Icon, t.Hidden, formatTimestamp(t.CreatedAt), formatTimestamp(t.UpdatedAt))
+	if err != nil {
+		return translateError(err)
+	}
+
+	t.ID, err = res.LastInsertId()
+	return
+}
+
+// Delete implements domain.TagRepository.
+func (p *sqliteTagRepo) Delete(ctx context.Context, id int64) error {
+	return p.delete(ctx, id, nil, false)
+}
+
+// Reassign implements domain.TagRepository.
+func (p *sqliteTagRepo) Reassign(ctx context.Context, id, to int64, keepAlias bool) error {
+	if to == id || (keepAlias && to == 0) {
+		return domain.ErrBadParamInput
+	}
+	return p.delete(ctx, id, &to, keepAlias)
+}
+
+// delete deletes the tag in a transaction, the articles move to the tag to or restrict the delete when to is nil
+func (p *sqliteTagRepo) delete(ctx context.Context, id int64, to *int64, keepAlias bool) (err error) {
+	tx, err := p.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback() //nolint
+
+	// the sqlite transaction locks the whole database, the rows need no lock
+	var name string
+	var parentID sql.NullInt64
+	err = tx.QueryRowContext(ctx, "SELECT name, parent_id FROM tag WHERE id = ?", id).Scan(&name, &parentID)
+	if err == sql.ErrNoRows {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		return
+	}
+
+	if to == nil {
+		if err = inUse(ctx, tx, id); err != nil {
+			return
+		}
+	} else {
+		if *to != 0 {
+			// the tag the articles move to must outlive the transaction
+			err = tx.QueryRowContext(ctx, "SELECT id FROM tag WHERE id = ?", *to).Scan(to)
+			if err == sql.ErrNoRows {
+				return domain.ErrNotFound
+			}
+			if err != nil {
+				return
+			}
+		}
+		if _, err = tx.ExecContext(ctx, "UPDATE article SET tag_id = ?, version = version + 1, updated_at = ? WHERE tag_id = ?",
+			*to, formatTimestamp(time.Now()), id); err != nil {
+			return
+		}
+	}
+
+	if keepAlias {
+		if _, err = tx.ExecContext(ctx, "UPDATE tag_alias SET tag_id = ? WHERE tag_id = ?", *to, id); err != nil {
+			return
+		}
+		if _, err = tx.ExecContext(ctx, "DELETE FROM tag_alias WHERE name_key = ?", fold.Key(name)); err != nil {
+			return
+		}
+		if _, err = tx.ExecContext(ctx, "INSERT INTO tag_alias (name, name_key, tag_id) VALUES (?, ?, ?)", name, fold.Key(name), *to); err != nil {
+			return
+		}
+	} else if _, err = tx.ExecContext(ctx, "DELETE FROM tag_alias WHERE tag_id = ?", id); err != nil {
+		return
+	}
+
+	// the child tags move up to the parent of the tag, or to the tag it merges into
+	newParentID := parentID
+	if keepAlias {
+		// a tag merged into one of its descendants leaves the descendant in its place
+		var below bool
+		if below, err = isBelow(ctx, tx, *to, id); err != nil {
+			return
+		}
+		if below {
+			if _, err = tx.ExecContext(ctx, "UPDATE tag SET parent_id = ? WHERE id = ?", parentID, *to); err != nil {
+				return
+			}
+		}
+		newParentID = sql.NullInt64{Int64: *to, Valid: true}
+	}
+	if _, err = tx.ExecContext(ctx, "UPDATE tag SET parent_id = ? WHERE parent_id = ?", newParentID, id); err != nil {
+		return
+	}
+	if _, err = tx.ExecContext(ctx, "DELETE FROM tag WHERE id = ?", id); err != nil {
+		return
+	}
+
+	return tx.Commit()
+}
+
+// isBelow reports whether the tag id is a descendant of the tag ancestor
+func isBelow(ctx context.Context, tx *sql.Tx, id, ancestor int64) (bool, error) {
+	// the visited tags are not walked again, a cycle in the data cannot loop forever
+	visited := make(map[int64]bool)
+	for id != 0 && !visited[id] {
+		visited[id] = true
+		var parentID sql.NullInt64
+		if err := tx.QueryRowContext(ctx, "SELECT parent_id FROM tag WHERE id = ?", id).Scan(&parentID); err != nil {
+			return false, err
+		}
+		if parentID.Valid && parentID.Int64 == ancestor {
+			return true, nil
+		}
+		id = parentID.Int64
+	}
+	return false, nil
+}
+
+// inUse returns a *domain.TagInUseError when articles still reference the tag
+func inUse(ctx context.Context, tx *sql.Tx, id int64) error {
+	var count int64
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM article WHERE tag_id = ?", id).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return nil
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT id, title, slug FROM article WHERE tag_id = ? ORDER BY id LIMIT ?", id, repository.InUseLimit)
+	if err != nil {
+		return err
+	}
+	return repository.InUseError(rows, count)
+}
+
+// Update implements domain.TagRepository.
+func (p *sqliteTagRepo) Update(ctx context.Context, t *domain.Tag) (err error) {
+	// an empty slug keeps the current one
+	query := `UPDATE tag SET name = ?, name_key = ?, slug = COALESCE(?, slug), parent_id = ?,
+				description = ?, color = ?, icon = ?, hidden = ?, updated_at = ? WHERE id = ?`
+
+	res, err := p.Conn.ExecContext(ctx, query, t.Name, fold.Key(t.Name), repository.NullString(t.Slug), repository.NullID(t.ParentID), t.Description, t.Color, t.Icon, t.Hidden, formatTimestamp(t.UpdatedAt), t.ID)
+	if err != nil {
+		return translateError(err)
+	}
+	affect, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if affect == 0 {
+		return domain.ErrNotFound
+	}
+	if affect != 1 {
+		err = fmt.Errorf("weird  Behavior. Total Affected: %d", affect)
+		return
+	}
+
+	return
+}
+
+// translateError maps the sqlite constraint violations to the domain errors
+func translateError(err error) error {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return domain.ErrConflict
+	}
+	return err
+}
+
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format(timestampFormat)
+}
+
+func parseTimestamp(s string) (time.Time, error) {
+	return time.Parse(timestampFormat, s)
+}
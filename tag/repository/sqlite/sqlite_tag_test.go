@@ -0,0 +1,38 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	articleSqlite "go-postgres-clean-arch/article/repository/sqlite"
+	"go-postgres-clean-arch/domain"
+	"go-postgres-clean-arch/migration"
+	"go-postgres-clean-arch/tag/repository/repositorytest"
+	"go-postgres-clean-arch/tag/repository/sqlite"
+
+	_ "modernc.org/sqlite"
+)
+
+// openTestDB opens a fresh in-memory database with the migrations applied
+func openTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection to :memory: is a different database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	if _, err = migration.Up(context.Background(), db, "sqlite"); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestSqliteTagRepositoryContract(t *testing.T) {
+	repositorytest.RunTagContract(t, func(t *testing.T) (domain.TagRepository, domain.ArticleRepository) {
+		db := openTestDB(t)
+		return sqlite.NewSqliteTagRepository(db), articleSqlite.NewSqliteArticleRepository(db)
+	})
+}
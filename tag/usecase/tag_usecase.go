@@ -3,6 +3,8 @@ package usecase
 import (
 	"context"
 	"go-postgres-clean-arch/domain"
+	"go-postgres-clean-arch/helper/fold"
+	"go-postgres-clean-arch/helper/slug"
 	"time"
 
 	"github.com/go-playground/validator"
@@ -26,7 +28,7 @@ func NewTagUsecase(t domain.TagRepository, timeout time.Duration, v *validator.V
 // Fetch implements domain.TagUseCase.
 func (t *tagUsecase) Fetch(c context.Context, cursor string, num int64) (res []domain.Tag, nextCursor string, err error) {
 	if num == 0 {
-		num = 10
+		num = domain.DefaultPageSize
 	}
 
 	ctx, cancel := context.WithTimeout(c, t.contextTimeout)
@@ -40,6 +42,31 @@ func (t *tagUsecase) Fetch(c context.Context, cursor string, num int64) (res []d
 	return
 }
 
+// FetchFiltered implements domain.TagUseCase.
+func (t *tagUsecase) FetchFiltered(c context.Context, filter domain.TagFilter, cursor string, num int64) (res []domain.Tag, nextCursor string, err error) {
+	if num == 0 {
+		num = domain.DefaultPageSize
+	}
+
+	ctx, cancel := context.WithTimeout(c, t.contextTimeout)
+	defer cancel()
+
+	res, nextCursor, err = t.tagRepo.FetchFiltered(ctx, filter, cursor, num)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return
+}
+
+// Count implements domain.TagUseCase.
+func (t *tagUsecase) Count(c context.Context, filter domain.TagFilter) (int64, error) {
+	ctx, cancel := context.WithTimeout(c, t.contextTimeout)
+	defer cancel()
+
+	return t.tagRepo.Count(ctx, filter)
+}
+
 // FetchByID implements domain.TagUseCase.
 func (t *tagUsecase) FetchByID(c context.Context, id int64) (res domain.Tag, err error) {
 	ctx, cancel := context.WithTimeout(c, t.contextTimeout)
@@ -50,14 +77,17 @@ func (t *tagUsecase) FetchByID(c context.Context, id int64) (res domain.Tag, err
 		return
 	}
 
-	return
+	return t.withArticleCount(ctx, res)
 }
 
-// FetchByName implements domain.TagUseCase.
+// FetchByName implements domain.TagUseCase. An alias, e.g. the name of a merged tag, finds its tag.
 func (t *tagUsecase) FetchByName(c context.Context, name string) (res domain.Tag, err error) {
 	ctx, cancel := context.WithTimeout(c, t.contextTimeout)
 	defer cancel()
 	res, err = t.tagRepo.FetchByName(ctx, name)
+	if err == domain.ErrNotFound {
+		return t.tagRepo.FetchByAlias(ctx, name)
+	}
 	if err != nil {
 		return
 	}
@@ -65,17 +95,161 @@ func (t *tagUsecase) FetchByName(c context.Context, name string) (res domain.Tag
 	return
 }
 
+// FetchBySlug implements domain.TagUseCase.
+func (t *tagUsecase) FetchBySlug(c context.Context, slug string) (res domain.Tag, err error) {
+	ctx, cancel := context.WithTimeout(c, t.contextTimeout)
+	defer cancel()
+
+	res, err = t.tagRepo.FetchBySlug(ctx, slug)
+	if err != nil {
+		return
+	}
+
+	return t.withArticleCount(ctx, res)
+}
+
+// Suggest implements domain.TagUseCase.
+func (t *tagUsecase) Suggest(c context.Context, prefix string, num int64) ([]domain.Tag, error) {
+	if num == 0 {
+		num = 10
+	}
+	// an empty prefix would complete to every tag
+	if fold.Key(prefix) == "" {
+		return nil, domain.ErrBadParamInput
+	}
+
+	ctx, cancel := context.WithTimeout(c, t.contextTimeout)
+	defer cancel()
+
+	return t.tagRepo.Suggest(ctx, prefix, num)
+}
+
+// FetchTree implements domain.TagUseCase.
+func (t *tagUsecase) FetchTree(c context.Context, rootID int64) ([]domain.TagNode, error) {
+	ctx, cancel := context.WithTimeout(c, t.contextTimeout)
+	defer cancel()
+
+	tags, err := t.tagRepo.FetchTree(ctx, rootID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the tags are ordered by name, so are the children of every node. A hidden tag hides its
+	// branch of the tree, its children are no more reachable.
+	children := map[int64][]domain.Tag{}
+	for _, tag := range tags {
+		if tag.ID == rootID && tag.Hidden {
+			return nil, domain.ErrNotFound
+		}
+		if !tag.Hidden {
+			children[tag.ParentID] = append(children[tag.ParentID], tag)
+		}
+	}
+	var build func(tag domain.Tag) domain.TagNode
+	build = func(tag domain.Tag) domain.TagNode {
+		node := domain.TagNode{Tag: tag, Children: make([]domain.TagNode, 0, len(children[tag.ID]))}
+		for _, child := range children[tag.ID] {
+			node.Children = append(node.Children, build(child))
+		}
+		return node
+	}
+
+	res := make([]domain.TagNode, 0)
+	for _, tag := range tags {
+		if tag.Hidden {
+			continue
+		}
+		if (rootID == 0 && tag.ParentID == 0) || tag.ID == rootID {
+			res = append(res, build(tag))
+		}
+	}
+	return res, nil
+}
+
+// withArticleCount sets the number of articles of the tag, the single tag fetches of the repository
+// leave it zero
+func (t *tagUsecase) withArticleCount(ctx context.Context, tag domain.Tag) (domain.Tag, error) {
+	count, err := t.tagRepo.ArticleCount(ctx, tag.ID)
+	if err != nil {
+		return domain.Tag{}, err
+	}
+	tag.ArticleCount = count
+	return tag, nil
+}
+
+// checkParent checks the parent of the tag id exists and is not below the tag, a tag cannot be
+// its own ancestor
+func (t *tagUsecase) checkParent(ctx context.Context, id, parentID int64) error {
+	if parentID == 0 {
+		return nil
+	}
+	if parentID == id {
+		return domain.ErrBadParamInput
+	}
+	if _, err := t.tagRepo.FetchByID(ctx, parentID); err != nil {
+		if err == domain.ErrNotFound {
+			return domain.ErrBadParamInput
+		}
+		return err
+	}
+	if id == 0 {
+		return nil
+	}
+
+	subtree, err := t.tagRepo.FetchTree(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, tag := range subtree {
+		if tag.ID == parentID {
+			return domain.ErrBadParamInput
+		}
+	}
+	return nil
+}
+
+// uniqueSlug derives a slug from the name that no other tag than exceptID uses
+func (t *tagUsecase) uniqueSlug(ctx context.Context, name string, exceptID int64) (string, error) {
+	base := slug.Make(name)
+	if base == "" {
+		base = "tag"
+	}
+	return slug.Unique(base, func(s string) (bool, error) {
+		existing, err := t.tagRepo.FetchBySlug(ctx, s)
+		if err == domain.ErrNotFound {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return existing.ID != exceptID, nil
+	})
+}
+
 // Store implements domain.TagUseCase.
 func (t *tagUsecase) Store(c context.Context, tag *domain.Tag) (err error) {
+	if !tag.ValidColor() {
+		return domain.ErrBadParamInput
+	}
+
 	ctx, cancel := context.WithTimeout(c, t.contextTimeout)
 	defer cancel()
 
+	// the name may also be the alias of a merged tag
 	existedTag, _ := t.FetchByName(ctx, tag.Name)
 
-	if existedTag.Name == tag.Name {
+	if existedTag.ID != 0 {
 		return domain.ErrConflict
 	}
 
+	if err = t.checkParent(ctx, 0, tag.ParentID); err != nil {
+		return
+	}
+
+	if tag.Slug, err = t.uniqueSlug(ctx, tag.Name, 0); err != nil {
+		return
+	}
+
 	tag.CreatedAt = time.Now()
 	tag.UpdatedAt = time.Now()
 	err = t.tagRepo.Store(ctx, tag)
@@ -84,6 +258,10 @@ func (t *tagUsecase) Store(c context.Context, tag *domain.Tag) (err error) {
 
 // Update implements domain.TagUseCase.
 func (t *tagUsecase) Update(c context.Context, tag *domain.Tag) (err error) {
+	if !tag.ValidColor() {
+		return domain.ErrBadParamInput
+	}
+
 	ctx, cancel := context.WithTimeout(c, t.contextTimeout)
 	defer cancel()
 
@@ -92,28 +270,133 @@ func (t *tagUsecase) Update(c context.Context, tag *domain.Tag) (err error) {
 		return err
 	}
 
+	// the name may also be the alias of a merged tag
 	existedTag, _ := t.FetchByName(ctx, tag.Name)
 
-	if existedTag.Name != selectedTag.Name && existedTag.Name == tag.Name {
+	if existedTag.ID != 0 && existedTag.ID != tag.ID {
 		return domain.ErrConflict
 	}
 
+	if tag.ParentID != selectedTag.ParentID {
+		if err = t.checkParent(ctx, tag.ID, tag.ParentID); err != nil {
+			return err
+		}
+	}
+
+	// the slug follows the name, a tag keeps its slug while the name is unchanged
+	tag.Slug = selectedTag.Slug
+	if tag.Name != selectedTag.Name || tag.Slug == "" {
+		if tag.Slug, err = t.uniqueSlug(ctx, tag.Name, tag.ID); err != nil {
+			return err
+		}
+	}
+
 	tag.UpdatedAt = time.Now()
-	return t.tagRepo.Update(ctx, tag)
+	if err = t.tagRepo.Update(ctx, tag); err != nil {
+		return err
+	}
+
+	// a tag renamed to one of its aliases does not need the alias anymore
+	if err = t.tagRepo.DeleteAlias(ctx, tag.ID, tag.Name); err != nil && err != domain.ErrNotFound {
+		return err
+	}
+	return nil
 }
 
 // Delete implements domain.TagUseCase.
 func (t *tagUsecase) Delete(c context.Context, id int64) (err error) {
+	return t.DeleteWithPolicy(c, id, domain.TagDeleteRestrict, 0)
+}
+
+// DeleteWithPolicy implements domain.TagUseCase.
+func (t *tagUsecase) DeleteWithPolicy(c context.Context, id int64, policy domain.TagDeletePolicy, reassignTo int64) (err error) {
 	ctx, cancel := context.WithTimeout(c, t.contextTimeout)
 	defer cancel()
-	existedTag, err := t.tagRepo.FetchByID(ctx, id)
-	if err != nil {
+
+	switch policy {
+	case "", domain.TagDeleteRestrict:
+		return t.tagRepo.Delete(ctx, id)
+	case domain.TagDeleteReassign:
+		if err = t.existingTarget(ctx, id, reassignTo); err != nil {
+			return
+		}
+		return t.tagRepo.Reassign(ctx, id, reassignTo, false)
+	case domain.TagDeleteCascadeDetach:
+		return t.tagRepo.Reassign(ctx, id, 0, false)
+	default:
+		return domain.ErrBadParamInput
+	}
+}
+
+// Merge implements domain.TagUseCase.
+func (t *tagUsecase) Merge(c context.Context, id, into int64) (res domain.Tag, err error) {
+	ctx, cancel := context.WithTimeout(c, t.contextTimeout)
+	defer cancel()
+
+	if err = t.existingTarget(ctx, id, into); err != nil {
+		return
+	}
+	if err = t.tagRepo.Reassign(ctx, id, into, true); err != nil {
 		return
 	}
-	if existedTag == (domain.Tag{}) {
-		return domain.ErrNotFound
+
+	if res, err = t.tagRepo.FetchByID(ctx, into); err != nil {
+		return
+	}
+	return t.withArticleCount(ctx, res)
+}
+
+// FetchAliases implements domain.TagUseCase.
+func (t *tagUsecase) FetchAliases(c context.Context, tagID int64) (res []domain.TagAlias, err error) {
+	ctx, cancel := context.WithTimeout(c, t.contextTimeout)
+	defer cancel()
+
+	if _, err = t.tagRepo.FetchByID(ctx, tagID); err != nil {
+		return
+	}
+	return t.tagRepo.FetchAliases(ctx, tagID)
+}
+
+// StoreAlias implements domain.TagUseCase.
+func (t *tagUsecase) StoreAlias(c context.Context, a *domain.TagAlias) (err error) {
+	ctx, cancel := context.WithTimeout(c, t.contextTimeout)
+	defer cancel()
+
+	if fold.Key(a.Name) == "" {
+		return domain.ErrBadParamInput
+	}
+	if _, err = t.tagRepo.FetchByID(ctx, a.TagID); err != nil {
+		return
+	}
+
+	// the alias must not be the name or an alias of any tag, its own tag included
+	existedTag, _ := t.FetchByName(ctx, a.Name)
+	if existedTag.ID != 0 {
+		return domain.ErrConflict
+	}
+
+	return t.tagRepo.StoreAlias(ctx, a)
+}
+
+// DeleteAlias implements domain.TagUseCase.
+func (t *tagUsecase) DeleteAlias(c context.Context, tagID int64, alias string) error {
+	ctx, cancel := context.WithTimeout(c, t.contextTimeout)
+	defer cancel()
+
+	return t.tagRepo.DeleteAlias(ctx, tagID, alias)
+}
+
+// existingTarget checks the tag the articles of the tag id move to, an unknown tag is a bad param
+// and not the missing tag id
+func (t *tagUsecase) existingTarget(ctx context.Context, id, to int64) error {
+	if to <= 0 || to == id {
+		return domain.ErrBadParamInput
+	}
+	_, err := t.tagRepo.FetchByID(ctx, to)
+	if err == domain.ErrNotFound {
+		return domain.ErrBadParamInput
 	}
-	return t.tagRepo.Delete(ctx, id)
+	return err
 }
 
 // OLD IMPLEMENTATION
@@ -1 +1,201 @@
 package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	articleMemory "go-postgres-clean-arch/article/repository/memory"
+	"go-postgres-clean-arch/domain"
+	tagMemory "go-postgres-clean-arch/tag/repository/memory"
+)
+
+// newTestUsecase returns the usecase on a memory tag repository counting the articles of the
+// returned memory article repository
+func newTestUsecase() (domain.TagUseCase, domain.ArticleRepository) {
+	articles := articleMemory.NewMemoryArticleRepository()
+	return NewTagUsecase(tagMemory.NewMemoryTagRepositoryWithArticles(articles), time.Second, nil), articles
+}
+
+// storeTag stores the tag through the usecase
+func storeTag(t *testing.T, u domain.TagUseCase, name string, parentID int64) *domain.Tag {
+	t.Helper()
+	tag := &domain.Tag{Name: name, ParentID: parentID}
+	if err := u.Store(context.Background(), tag); err != nil {
+		t.Fatalf("Store(%q): %v", name, err)
+	}
+	return tag
+}
+
+// storeArticle stores an article of the tag in the repository
+func storeArticle(t *testing.T, articles domain.ArticleRepository, title string, tagID int64) *domain.CreateArticleInput {
+	t.Helper()
+	now := time.Now()
+	a := &domain.CreateArticleInput{Title: title, Content: "c", TagID: tagID, CreatedAt: now, UpdatedAt: now}
+	if err := articles.Store(context.Background(), a); err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestStoreAndUpdateConflictAndNotFound(t *testing.T) {
+	u, _ := newTestUsecase()
+	ctx := context.Background()
+	goTag := storeTag(t, u, "Go", 0)
+	storeTag(t, u, "Rust", 0)
+
+	// the names are compared by their key
+	if err := u.Store(ctx, &domain.Tag{Name: "go"}); err != domain.ErrConflict {
+		t.Fatalf("expected ErrConflict for a name in use, got %v", err)
+	}
+	if err := u.Store(ctx, &domain.Tag{Name: "child", ParentID: 999}); err != domain.ErrBadParamInput {
+		t.Fatalf("expected ErrBadParamInput for an unknown parent, got %v", err)
+	}
+	if _, err := u.FetchByID(ctx, 999); err != domain.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err := u.Update(ctx, &domain.Tag{ID: 999, Name: "missing"}); err != domain.ErrNotFound {
+		t.Fatalf("expected ErrNotFound for an unknown tag, got %v", err)
+	}
+	if err := u.Update(ctx, &domain.Tag{ID: goTag.ID, Name: "RUST"}); err != domain.ErrConflict {
+		t.Fatalf("expected ErrConflict for the name of another tag, got %v", err)
+	}
+	// a tag keeps its own name with another case
+	if err := u.Update(ctx, &domain.Tag{ID: goTag.ID, Name: "GO"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a tag can not move below itself
+	child := storeTag(t, u, "generics", goTag.ID)
+	if err := u.Update(ctx, &domain.Tag{ID: goTag.ID, Name: "GO", ParentID: child.ID}); err != domain.ErrBadParamInput {
+		t.Fatalf("expected ErrBadParamInput for a parent below the tag, got %v", err)
+	}
+}
+
+func TestTagSlugsAreUnique(t *testing.T) {
+	u, _ := newTestUsecase()
+	ctx := context.Background()
+
+	first := storeTag(t, u, "Go Lang", 0)
+	second := storeTag(t, u, "go-lang", 0)
+	if first.Slug != "go-lang" || second.Slug != "go-lang-2" {
+		t.Fatalf("unexpected slugs %q %q", first.Slug, second.Slug)
+	}
+
+	// the slug follows the name, it is kept while the name is unchanged
+	second.Description = "described"
+	if err := u.Update(ctx, second); err != nil || second.Slug != "go-lang-2" {
+		t.Fatalf("Update left the slug %q %v", second.Slug, err)
+	}
+	second.Name = "Golang"
+	if err := u.Update(ctx, second); err != nil || second.Slug != "golang" {
+		t.Fatalf("Update left the slug %q %v", second.Slug, err)
+	}
+	got, err := u.FetchBySlug(ctx, "golang")
+	if err != nil || got.ID != second.ID {
+		t.Fatalf("FetchBySlug returned %+v %v", got, err)
+	}
+}
+
+func TestDeletePolicies(t *testing.T) {
+	u, articles := newTestUsecase()
+	ctx := context.Background()
+	from := storeTag(t, u, "from", 0)
+	to := storeTag(t, u, "to", 0)
+	a := storeArticle(t, articles, "moved", from.ID)
+
+	var inUse *domain.TagInUseError
+	if err := u.Delete(ctx, from.ID); !errors.As(err, &inUse) || inUse.ArticleCount != 1 || inUse.Articles[0].ID != a.ID {
+		t.Fatalf("expected a *TagInUseError with the article, got %v", err)
+	}
+	for _, reassignTo := range []int64{0, from.ID, 999} {
+		if err := u.DeleteWithPolicy(ctx, from.ID, domain.TagDeleteReassign, reassignTo); err != domain.ErrBadParamInput {
+			t.Fatalf("expected ErrBadParamInput for the reassign target %d, got %v", reassignTo, err)
+		}
+	}
+	if err := u.DeleteWithPolicy(ctx, from.ID, "drop", 0); err != domain.ErrBadParamInput {
+		t.Fatalf("expected ErrBadParamInput for an unknown policy, got %v", err)
+	}
+
+	if err := u.DeleteWithPolicy(ctx, from.ID, domain.TagDeleteReassign, to.ID); err != nil {
+		t.Fatal(err)
+	}
+	moved, err := articles.GetByID(ctx, a.ID)
+	if err != nil || moved.Tag.ID != to.ID || moved.Version != 2 {
+		t.Fatalf("GetByID returned %+v %v, want the article moved to %d", moved, err, to.ID)
+	}
+	if _, err = u.FetchByID(ctx, from.ID); err != domain.ErrNotFound {
+		t.Fatalf("expected ErrNotFound for the deleted tag, got %v", err)
+	}
+
+	if err = u.DeleteWithPolicy(ctx, to.ID, domain.TagDeleteCascadeDetach, 0); err != nil {
+		t.Fatal(err)
+	}
+	if detached, err := articles.GetByID(ctx, a.ID); err != nil || detached.Tag.ID != 0 {
+		t.Fatalf("GetByID returned %+v %v, want a detached article", detached, err)
+	}
+	if err = u.DeleteWithPolicy(ctx, to.ID, domain.TagDeleteCascadeDetach, 0); err != domain.ErrNotFound {
+		t.Fatalf("expected ErrNotFound for a deleted tag, got %v", err)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	u, articles := newTestUsecase()
+	ctx := context.Background()
+	golang := storeTag(t, u, "golang", 0)
+	generics := storeTag(t, u, "generics", golang.ID)
+	goTag := storeTag(t, u, "go", 0)
+	storeArticle(t, articles, "merged", golang.ID)
+	storeArticle(t, articles, "kept", goTag.ID)
+
+	if _, err := u.Merge(ctx, golang.ID, golang.ID); err != domain.ErrBadParamInput {
+		t.Fatalf("expected ErrBadParamInput for a merge into the tag itself, got %v", err)
+	}
+	if _, err := u.Merge(ctx, golang.ID, 999); err != domain.ErrBadParamInput {
+		t.Fatalf("expected ErrBadParamInput for an unknown tag, got %v", err)
+	}
+
+	merged, err := u.Merge(ctx, golang.ID, goTag.ID)
+	if err != nil || merged.ID != goTag.ID || merged.ArticleCount != 2 {
+		t.Fatalf("Merge returned %+v %v, want the tag go with 2 articles", merged, err)
+	}
+	// the name of the merged tag finds the tag it merged into and can not be used again
+	if got, err := u.FetchByName(ctx, "Golang"); err != nil || got.ID != goTag.ID {
+		t.Fatalf("FetchByName returned %+v %v", got, err)
+	}
+	if err = u.Store(ctx, &domain.Tag{Name: "golang"}); err != domain.ErrConflict {
+		t.Fatalf("expected ErrConflict for the alias of a merged tag, got %v", err)
+	}
+	if got, err := u.FetchByID(ctx, generics.ID); err != nil || got.ParentID != goTag.ID {
+		t.Fatalf("FetchByID returned %+v %v, want the child moved to the tag merged into", got, err)
+	}
+}
+
+func TestStoreAndUpdateValidateTheColor(t *testing.T) {
+	u, _ := newTestUsecase()
+	ctx := context.Background()
+
+	for _, color := range []string{"1e90ff", "#1e90f", "#ggg", "red"} {
+		if err := u.Store(ctx, &domain.Tag{Name: "go", Color: color}); err != domain.ErrBadParamInput {
+			t.Fatalf("expected ErrBadParamInput for the color %q, got %v", color, err)
+		}
+	}
+
+	tag := &domain.Tag{Name: "go", Color: "#1E90FF"}
+	if err := u.Store(ctx, tag); err != nil {
+		t.Fatal(err)
+	}
+	tag.Color = "blue"
+	if err := u.Update(ctx, tag); err != domain.ErrBadParamInput {
+		t.Fatalf("expected ErrBadParamInput, got %v", err)
+	}
+	tag.Color = "#abc"
+	if err := u.Update(ctx, tag); err != nil {
+		t.Fatal(err)
+	}
+	tag.Color = ""
+	if err := u.Update(ctx, tag); err != nil {
+		t.Fatal(err)
+	}
+}